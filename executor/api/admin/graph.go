@@ -0,0 +1,65 @@
+package admin
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+)
+
+// GraphNode is the view of a graph node returned by the graph endpoint.
+type GraphNode struct {
+	Name           string      `json:"name"`
+	Type           string      `json:"type,omitempty"`
+	Implementation string      `json:"implementation,omitempty"`
+	Methods        []string    `json:"methods,omitempty"`
+	Endpoint       string      `json:"endpoint,omitempty"`
+	Children       []GraphNode `json:"children,omitempty"`
+}
+
+func newGraphNode(node *v1.PredictiveUnit) GraphNode {
+	view := GraphNode{Name: node.Name}
+	if node.Type != nil {
+		view.Type = string(*node.Type)
+	}
+	if node.Implementation != nil {
+		view.Implementation = string(*node.Implementation)
+	}
+	if node.Methods != nil {
+		for _, method := range *node.Methods {
+			view.Methods = append(view.Methods, string(method))
+		}
+	}
+	if node.Endpoint != nil && node.Endpoint.ServiceHost != "" {
+		view.Endpoint = fmt.Sprintf("%s %s:%d", node.Endpoint.Type, node.Endpoint.ServiceHost, node.Endpoint.ServicePort)
+	}
+	for i := range node.Children {
+		view.Children = append(view.Children, newGraphNode(&node.Children[i]))
+	}
+	return view
+}
+
+// graphToDot renders the graph in the Graphviz DOT language with an edge from each node to its children.
+func graphToDot(predictorName string, graph GraphNode) string {
+	var sb strings.Builder
+	sb.WriteString("digraph " + strconv.Quote(predictorName) + " {\n")
+	sb.WriteString("  node [shape=box];\n")
+	var write func(node GraphNode)
+	write = func(node GraphNode) {
+		label := node.Name
+		for _, line := range []string{node.Type, node.Implementation, node.Endpoint} {
+			if line != "" {
+				label += "\n" + line
+			}
+		}
+		sb.WriteString(fmt.Sprintf("  %s [label=%s];\n", strconv.Quote(node.Name), strconv.Quote(label)))
+		for _, child := range node.Children {
+			sb.WriteString(fmt.Sprintf("  %s -> %s;\n", strconv.Quote(node.Name), strconv.Quote(child.Name)))
+			write(child)
+		}
+	}
+	write(graph)
+	sb.WriteString("}\n")
+	return sb.String()
+}
@@ -0,0 +1,79 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Redacted replaces the values of secrets in the configuration dump.
+const Redacted = "<redacted>"
+
+var sensitiveName = regexp.MustCompile(`(?i)(password|passwd|secret|token|credential|api[_-]?key|access[_-]?key|private[_-]?key|authorization)`)
+
+// redactConfig returns a JSON compatible copy of v with secrets redacted: values of fields with a sensitive name,
+// the value of name/value pairs such as env vars and parameters with a sensitive name, and passwords in URLs.
+func redactConfig(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return redactValue(doc), nil
+}
+
+func redactValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		if name, ok := val["name"].(string); ok && sensitiveName.MatchString(name) {
+			if _, ok := val["value"]; ok {
+				val["value"] = Redacted
+			}
+		}
+		for key, field := range val {
+			if _, isString := field.(string); isString && sensitiveName.MatchString(key) {
+				val[key] = Redacted
+			} else {
+				val[key] = redactValue(field)
+			}
+		}
+		return val
+	case []interface{}:
+		for i, item := range val {
+			val[i] = redactValue(item)
+		}
+		return val
+	case string:
+		return redactURL(val)
+	default:
+		return v
+	}
+}
+
+// redactURL hides the password of a URL with credentials, such as a kafka broker given as user:password@host:port,
+// and returns any other string unchanged.
+func redactURL(s string) string {
+	if !strings.Contains(s, "@") {
+		return s
+	}
+	schemeless := !strings.Contains(s, "://")
+	raw := s
+	if schemeless {
+		raw = "//" + s
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.User == nil {
+		return s
+	}
+	if _, ok := u.User.Password(); !ok {
+		return s
+	}
+	if schemeless {
+		return strings.TrimPrefix(u.Redacted(), "//")
+	}
+	return u.Redacted()
+}
@@ -0,0 +1,122 @@
+package admin
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"net/http/pprof"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/gorilla/mux"
+	"github.com/seldonio/seldon-core/executor/predictor"
+	"go.uber.org/zap"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	LogLevelPath = "/loglevel"
+	ConfigPath   = "/config"
+	GraphPath    = "/graph"
+	PprofPath    = "/debug/pprof/"
+
+	GraphFormatDot = "dot"
+)
+
+// AdminServer serves the operational endpoints of the executor on their own listener: the runtime log level, a
+// dump of the effective configuration with secrets redacted, the current graph and pprof profiles.
+type AdminServer struct {
+	Router         *mux.Router
+	Log            logr.Logger
+	LogLevel       zap.AtomicLevel
+	predictorStore *predictor.PredictorStore
+	// Annotations and Settings are included in the configuration dump. Settings holds the executor flags.
+	Annotations map[string]string
+	Settings    map[string]string
+	// Token, if set, must be sent as a bearer token with each request.
+	Token string
+}
+
+func NewAdminServer(predictorStore *predictor.PredictorStore, logLevel zap.AtomicLevel, annotations map[string]string, settings map[string]string, token string) *AdminServer {
+	return &AdminServer{
+		Router:         mux.NewRouter(),
+		Log:            logf.Log.WithName("AdminServer"),
+		LogLevel:       logLevel,
+		predictorStore: predictorStore,
+		Annotations:    annotations,
+		Settings:       settings,
+		Token:          token,
+	}
+}
+
+func (a *AdminServer) Initialise() {
+	if a.Token != "" {
+		a.Router.Use(a.authenticate)
+	}
+	// The zap level handler returns the level on GET and sets it from a JSON body such as {"level":"debug"} on PUT
+	a.Router.Handle(LogLevelPath, a.LogLevel).Methods("GET", "PUT")
+	a.Router.HandleFunc(ConfigPath, a.config).Methods("GET")
+	a.Router.HandleFunc(GraphPath, a.graph).Methods("GET")
+	a.Router.HandleFunc(PprofPath+"cmdline", pprof.Cmdline)
+	a.Router.HandleFunc(PprofPath+"profile", pprof.Profile)
+	a.Router.HandleFunc(PprofPath+"symbol", pprof.Symbol)
+	a.Router.HandleFunc(PprofPath+"trace", pprof.Trace)
+	a.Router.PathPrefix(PprofPath).HandlerFunc(pprof.Index)
+}
+
+func (a *AdminServer) CreateHttpServer(address string) *http.Server {
+	a.Log.Info("Listening", "Address", address)
+	return &http.Server{
+		Handler:     a.Router,
+		Addr:        address,
+		IdleTimeout: 65 * time.Second,
+	}
+}
+
+func (a *AdminServer) authenticate(next http.Handler) http.Handler {
+	expected := []byte("Bearer " + a.Token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (a *AdminServer) respondWithJSON(w http.ResponseWriter, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+func (a *AdminServer) config(w http.ResponseWriter, r *http.Request) {
+	config, err := redactConfig(map[string]interface{}{
+		"logLevel":    a.LogLevel.String(),
+		"settings":    a.Settings,
+		"annotations": a.Annotations,
+		"predictor":   a.predictorStore.Get(),
+	})
+	if err != nil {
+		a.Log.Error(err, "Failed to create configuration dump")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	a.respondWithJSON(w, config)
+}
+
+// graph returns the graph being served as JSON, or as DOT with ?format=dot.
+func (a *AdminServer) graph(w http.ResponseWriter, r *http.Request) {
+	spec := a.predictorStore.Get()
+	graph := newGraphNode(&spec.Graph)
+	if r.URL.Query().Get("format") == GraphFormatDot {
+		w.Header().Set("Content-Type", "text/vnd.graphviz")
+		w.Write([]byte(graphToDot(spec.Name, graph)))
+		return
+	}
+	a.respondWithJSON(w, graph)
+}
@@ -7,9 +7,12 @@ import (
 	grpc_opentracing "github.com/grpc-ecosystem/go-grpc-middleware/tracing/opentracing"
 	"github.com/opentracing/opentracing-go"
 	"github.com/seldonio/seldon-core/executor/api/metric"
+	"github.com/seldonio/seldon-core/executor/api/tracing"
+	"github.com/seldonio/seldon-core/executor/api/util"
 	"github.com/seldonio/seldon-core/executor/k8s"
 	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/metadata"
 	"strconv"
 	"time"
@@ -24,9 +27,24 @@ func AddMetadataToOutgoingGrpcContext(ctx context.Context, meta map[string][]str
 	return ctx
 }
 
+// AddTransportCredentials returns the dial option securing connections to the predictor's graph nodes.
+// Connections use TLS when client TLS is enabled on the predictor and are insecure otherwise.
+func AddTransportCredentials(predictor *v1.PredictorSpec) (grpc.DialOption, error) {
+	tlsConfig, err := util.GetClientTLSConfig(predictor)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		return grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)), nil
+	}
+	return grpc.WithInsecure(), nil
+}
+
 func AddClientInterceptors(predictor *v1.PredictorSpec, deploymentName, modelName string, annotations map[string]string, log logr.Logger) grpc.DialOption {
 	interceptors := []grpc.UnaryClientInterceptor{metric.NewClientMetrics(predictor, deploymentName, modelName).UnaryClientInterceptor()}
-	if opentracing.IsGlobalTracerRegistered() {
+	if tracing.OpenTelemetryEnabled() {
+		interceptors = append(interceptors, tracing.UnaryClientInterceptor())
+	} else if opentracing.IsGlobalTracerRegistered() {
 		interceptors = append(interceptors, grpc_opentracing.UnaryClientInterceptor())
 	}
 	if annotations != nil {
@@ -0,0 +1,89 @@
+package health
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/seldonio/seldon-core/executor/api/util"
+	"github.com/seldonio/seldon-core/executor/predictor"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const DefaultWatchInterval = 5 * time.Second
+
+// GrpcHealthServer implements the gRPC health checking protocol with the readiness checks of the REST
+// /ready endpoint, so it reports NOT_SERVING while draining or when the graph nodes aren't ready.
+type GrpcHealthServer struct {
+	predictorStore  *predictor.PredictorStore
+	Log             logr.Logger
+	Protocol        string
+	FullHealthCheck bool
+	// WatchInterval is how often the status is checked for Watch streams.
+	WatchInterval time.Duration
+	services      map[string]bool
+}
+
+// NewGrpcHealthServer creates a health server answering for the whole server, as the empty service name, and for
+// each of the services.
+func NewGrpcHealthServer(predictorStore *predictor.PredictorStore, protocol string, fullHealthCheck bool, services []string) *GrpcHealthServer {
+	known := make(map[string]bool, len(services))
+	for _, service := range services {
+		known[service] = true
+	}
+	return &GrpcHealthServer{
+		predictorStore:  predictorStore,
+		Log:             logf.Log.WithName("GrpcHealthServer"),
+		Protocol:        protocol,
+		FullHealthCheck: fullHealthCheck,
+		WatchInterval:   DefaultWatchInterval,
+		services:        known,
+	}
+}
+
+func (h *GrpcHealthServer) servingStatus() healthpb.HealthCheckResponse_ServingStatus {
+	if util.IsDraining() {
+		return healthpb.HealthCheckResponse_NOT_SERVING
+	}
+	if err := predictor.CheckReady(h.Protocol, h.predictorStore.Get(), h.FullHealthCheck); err != nil {
+		h.Log.Error(err, "Ready check failed")
+		return healthpb.HealthCheckResponse_NOT_SERVING
+	}
+	return healthpb.HealthCheckResponse_SERVING
+}
+
+func (h *GrpcHealthServer) Check(ctx context.Context, request *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	if request.Service != "" && !h.services[request.Service] {
+		return nil, status.Errorf(codes.NotFound, "unknown service %s", request.Service)
+	}
+	return &healthpb.HealthCheckResponse{Status: h.servingStatus()}, nil
+}
+
+// Watch sends the status when the stream starts and then whenever it changes. Unknown services are reported
+// as SERVICE_UNKNOWN as the protocol requires.
+func (h *GrpcHealthServer) Watch(request *healthpb.HealthCheckRequest, stream healthpb.Health_WatchServer) error {
+	known := request.Service == "" || h.services[request.Service]
+	last := healthpb.HealthCheckResponse_UNKNOWN
+	ticker := time.NewTicker(h.WatchInterval)
+	defer ticker.Stop()
+	for {
+		current := healthpb.HealthCheckResponse_SERVICE_UNKNOWN
+		if known {
+			current = h.servingStatus()
+		}
+		if current != last {
+			if err := stream.Send(&healthpb.HealthCheckResponse{Status: current}); err != nil {
+				return err
+			}
+			last = current
+		}
+		select {
+		case <-stream.Context().Done():
+			return status.Error(codes.Canceled, "stream has ended")
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package health
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/seldonio/seldon-core/executor/api"
+	"github.com/seldonio/seldon-core/executor/api/util"
+	"github.com/seldonio/seldon-core/executor/predictor"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+func TestCheck(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	model := v1.MODEL
+	spec := &v1.PredictorSpec{Name: "p", Graph: v1.PredictiveUnit{Name: "model", Type: &model}}
+	h := NewGrpcHealthServer(predictor.NewPredictorStore(spec), api.ProtocolSeldon, true, []string{"seldon.protos.Seldon"})
+
+	res, err := h.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	g.Expect(err).To(BeNil())
+	g.Expect(res.Status).To(Equal(healthpb.HealthCheckResponse_SERVING))
+
+	res, err = h.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "seldon.protos.Seldon"})
+	g.Expect(err).To(BeNil())
+	g.Expect(res.Status).To(Equal(healthpb.HealthCheckResponse_SERVING))
+
+	_, err = h.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "unknown"})
+	g.Expect(status.Code(err)).To(Equal(codes.NotFound))
+
+	util.SetDraining(true)
+	defer util.SetDraining(false)
+	res, err = h.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	g.Expect(err).To(BeNil())
+	g.Expect(res.Status).To(Equal(healthpb.HealthCheckResponse_NOT_SERVING))
+}
+
+func TestCheckNodeNotReady(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	model := v1.MODEL
+	spec := &v1.PredictorSpec{Name: "p", Graph: v1.PredictiveUnit{
+		Name:     "model",
+		Type:     &model,
+		Endpoint: &v1.Endpoint{ServiceHost: "127.0.0.1", ServicePort: 1, Type: v1.REST},
+	}}
+	h := NewGrpcHealthServer(predictor.NewPredictorStore(spec), api.ProtocolSeldon, true, []string{"seldon.protos.Seldon"})
+	res, err := h.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	g.Expect(err).To(BeNil())
+	g.Expect(res.Status).To(Equal(healthpb.HealthCheckResponse_NOT_SERVING))
+}
+
+func TestWatch(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	model := v1.MODEL
+	spec := &v1.PredictorSpec{Name: "p", Graph: v1.PredictiveUnit{Name: "model", Type: &model}}
+	h := NewGrpcHealthServer(predictor.NewPredictorStore(spec), api.ProtocolSeldon, true, []string{"seldon.protos.Seldon"})
+	h.WatchInterval = 10 * time.Millisecond
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	g.Expect(err).To(BeNil())
+	server := grpc.NewServer()
+	healthpb.RegisterHealthServer(server, h)
+	go server.Serve(lis)
+	defer server.Stop()
+
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure())
+	g.Expect(err).To(BeNil())
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := healthpb.NewHealthClient(conn).Watch(ctx, &healthpb.HealthCheckRequest{})
+	g.Expect(err).To(BeNil())
+	res, err := stream.Recv()
+	g.Expect(err).To(BeNil())
+	g.Expect(res.Status).To(Equal(healthpb.HealthCheckResponse_SERVING))
+
+	util.SetDraining(true)
+	defer util.SetDraining(false)
+	res, err = stream.Recv()
+	g.Expect(err).To(BeNil())
+	g.Expect(res.Status).To(Equal(healthpb.HealthCheckResponse_NOT_SERVING))
+
+	unknown, err := healthpb.NewHealthClient(conn).Watch(ctx, &healthpb.HealthCheckRequest{Service: "unknown"})
+	g.Expect(err).To(BeNil())
+	res, err = unknown.Recv()
+	g.Expect(err).To(BeNil())
+	g.Expect(res.Status).To(Equal(healthpb.HealthCheckResponse_SERVICE_UNKNOWN))
+}
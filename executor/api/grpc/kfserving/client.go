@@ -55,8 +55,12 @@ func (s *KFServingGrpcClient) getConnection(host string, port int32, modelName s
 	if conn, ok := s.conns[k]; ok {
 		return conn, nil
 	} else {
+		transportCredentials, err := grpc2.AddTransportCredentials(s.Predictor)
+		if err != nil {
+			return nil, err
+		}
 		opts := []grpc.DialOption{
-			grpc.WithInsecure(),
+			transportCredentials,
 		}
 		opts = append(opts, grpc2.AddClientInterceptors(s.Predictor, s.DeploymentName, modelName, s.annotations, s.Log))
 		conn, err := grpc.Dial(fmt.Sprintf("%s:%d", host, port), opts...)
@@ -110,17 +114,20 @@ func (s *KFServingGrpcClient) Feedback(ctx context.Context, modelName string, ho
 }
 
 func (s *KFServingGrpcClient) Chain(ctx context.Context, modelName string, msg payload.SeldonPayload) (payload.SeldonPayload, error) {
+	return ChainModelInfer(modelName, msg)
+}
+
+// ChainModelInfer converts a ModelInferResponse payload into a ModelInferRequest for modelName so it can be
+// sent to the next node in the graph. Requests are passed through with their model name set.
+func ChainModelInfer(modelName string, msg payload.SeldonPayload) (payload.SeldonPayload, error) {
 	switch v := msg.GetPayload().(type) {
 	case *inference.ModelInferRequest:
-		s.Log.Info("Identity chain")
 		if v.ModelName == "" {
 			v.ModelName = modelName
 		}
 
 		return msg, nil
 	case *inference.ModelInferResponse:
-		s.Log.Info("Chain!")
-
 		inputTensors := make([]*inference.ModelInferRequest_InferInputTensor, len(v.Outputs))
 		for idx, oTensor := range v.Outputs {
 			inputTensor := &inference.ModelInferRequest_InferInputTensor{
@@ -195,6 +202,7 @@ func (s *KFServingGrpcClient) Marshall(out io.Writer, msg payload.SeldonPayload)
 	panic("implement me")
 }
 
+// CreateErrorPayload returns the V2 JSON error object as gRPC has no error message for the protocol.
 func (s *KFServingGrpcClient) CreateErrorPayload(err error) payload.SeldonPayload {
-	panic("implement me")
+	return payload.NewJSONErrorPayload(err)
 }
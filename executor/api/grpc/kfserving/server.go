@@ -3,10 +3,12 @@ package kfserving
 import (
 	"context"
 	"github.com/go-logr/logr"
+	"github.com/seldonio/seldon-core/executor/api"
 	"github.com/seldonio/seldon-core/executor/api/client"
 	"github.com/seldonio/seldon-core/executor/api/grpc"
 	"github.com/seldonio/seldon-core/executor/api/grpc/kfserving/inference"
 	"github.com/seldonio/seldon-core/executor/api/payload"
+	"github.com/seldonio/seldon-core/executor/api/util"
 	"github.com/seldonio/seldon-core/executor/predictor"
 	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
 	protoGrpc "google.golang.org/grpc"
@@ -16,29 +18,42 @@ import (
 )
 
 type GrpcKFServingServer struct {
-	Client    client.SeldonApiClient
-	predictor *v1.PredictorSpec
-	Log       logr.Logger
-	ServerUrl *url.URL
-	Namespace string
+	Client         client.SeldonApiClient
+	predictorStore *predictor.PredictorStore
+	Log            logr.Logger
+	ServerUrl      *url.URL
+	Namespace      string
+	// FullHealthCheck makes ServerReady check the models with their health APIs rather than only connecting.
+	FullHealthCheck bool
 }
 
-func NewGrpcKFServingServer(predictor *v1.PredictorSpec, client client.SeldonApiClient, serverUrl *url.URL, namespace string) *GrpcKFServingServer {
+func NewGrpcKFServingServer(spec *v1.PredictorSpec, client client.SeldonApiClient, serverUrl *url.URL, namespace string, fullHealthCheck bool) *GrpcKFServingServer {
 	return &GrpcKFServingServer{
-		Client:    client,
-		predictor: predictor,
-		Log:       logf.Log.WithName("KFServingGrpcApi"),
-		ServerUrl: serverUrl,
-		Namespace: namespace,
+		Client:          client,
+		predictorStore:  predictor.NewPredictorStore(spec),
+		Log:             logf.Log.WithName("KFServingGrpcApi"),
+		ServerUrl:       serverUrl,
+		Namespace:       namespace,
+		FullHealthCheck: fullHealthCheck,
 	}
 }
 
+// SetPredictorStore makes the inference and readiness calls use the graph in store.
+func (g *GrpcKFServingServer) SetPredictorStore(store *predictor.PredictorStore) {
+	g.predictorStore = store
+}
+
 func (g GrpcKFServingServer) ServerLive(ctx context.Context, request *inference.ServerLiveRequest) (*inference.ServerLiveResponse, error) {
-	panic("Not Implemented")
+	return &inference.ServerLiveResponse{Live: true}, nil
 }
 
+// ServerReady reports not ready once the executor starts draining so clients stop sending new requests.
 func (g GrpcKFServingServer) ServerReady(ctx context.Context, request *inference.ServerReadyRequest) (*inference.ServerReadyResponse, error) {
-	panic("Not Implemented")
+	if util.IsDraining() {
+		return &inference.ServerReadyResponse{Ready: false}, nil
+	}
+	err := predictor.CheckReady(api.ProtocolV2, g.predictorStore.Get(), g.FullHealthCheck)
+	return &inference.ServerReadyResponse{Ready: err == nil}, nil
 }
 
 func (g GrpcKFServingServer) ModelReady(ctx context.Context, request *inference.ModelReadyRequest) (*inference.ModelReadyResponse, error) {
@@ -48,7 +63,7 @@ func (g GrpcKFServingServer) ModelReady(ctx context.Context, request *inference.
 	ctx = context.WithValue(ctx, payload.SeldonPUIDHeader, md.Get(payload.SeldonPUIDHeader)[0])
 	seldonPredictorProcess := predictor.NewPredictorProcess(ctx, g.Client, logf.Log.WithName("infer"), g.ServerUrl, g.Namespace, md, request.GetName())
 	reqPayload := payload.ProtoPayload{Msg: request}
-	resPayload, err := seldonPredictorProcess.Status(&g.predictor.Graph, request.Name, &reqPayload)
+	resPayload, err := seldonPredictorProcess.Status(&g.predictorStore.Get().Graph, request.Name, &reqPayload)
 	if err != nil {
 		return nil, err
 	}
@@ -66,7 +81,7 @@ func (g GrpcKFServingServer) ModelMetadata(ctx context.Context, request *inferen
 	ctx = context.WithValue(ctx, payload.SeldonPUIDHeader, md.Get(payload.SeldonPUIDHeader)[0])
 	seldonPredictorProcess := predictor.NewPredictorProcess(ctx, g.Client, logf.Log.WithName("infer"), g.ServerUrl, g.Namespace, md, request.GetName())
 	reqPayload := payload.ProtoPayload{Msg: request}
-	resPayload, err := seldonPredictorProcess.Metadata(&g.predictor.Graph, request.Name, &reqPayload)
+	resPayload, err := seldonPredictorProcess.Metadata(&g.predictorStore.Get().Graph, request.Name, &reqPayload)
 	if err != nil {
 		return nil, err
 	}
@@ -80,7 +95,7 @@ func (g GrpcKFServingServer) ModelInfer(ctx context.Context, request *inference.
 	ctx = context.WithValue(ctx, payload.SeldonPUIDHeader, md.Get(payload.SeldonPUIDHeader)[0])
 	seldonPredictorProcess := predictor.NewPredictorProcess(ctx, g.Client, logf.Log.WithName("infer"), g.ServerUrl, g.Namespace, md, request.GetModelName())
 	reqPayload := payload.ProtoPayload{Msg: request}
-	resPayload, err := seldonPredictorProcess.Predict(&g.predictor.Graph, &reqPayload)
+	resPayload, err := seldonPredictorProcess.Predict(&g.predictorStore.Get().Graph, &reqPayload)
 	if err != nil {
 		return nil, err
 	}
@@ -99,8 +99,12 @@ func (s *SeldonMessageGrpcClient) getConnection(host string, port int32, modelNa
 }
 
 func (s *SeldonMessageGrpcClient) createNewConn(modelName, host string, port int32) (*grpc.ClientConn, error) {
+	transportCredentials, err := grpc2.AddTransportCredentials(s.Predictor)
+	if err != nil {
+		return nil, err
+	}
 	opts := []grpc.DialOption{
-		grpc.WithInsecure(),
+		transportCredentials,
 	}
 
 	opts = append(opts, grpc2.AddClientInterceptors(s.Predictor, s.DeploymentName, modelName, s.annotations, s.Log))
@@ -17,23 +17,28 @@ import (
 )
 
 type GrpcSeldonServer struct {
-	Client    client.SeldonApiClient
-	predictor *v1.PredictorSpec
-	Log       logr.Logger
-	ServerUrl *url.URL
-	Namespace string
+	Client         client.SeldonApiClient
+	predictorStore *predictor.PredictorStore
+	Log            logr.Logger
+	ServerUrl      *url.URL
+	Namespace      string
 }
 
-func NewGrpcSeldonServer(predictor *v1.PredictorSpec, client client.SeldonApiClient, serverUrl *url.URL, namespace string) *GrpcSeldonServer {
+func NewGrpcSeldonServer(spec *v1.PredictorSpec, client client.SeldonApiClient, serverUrl *url.URL, namespace string) *GrpcSeldonServer {
 	return &GrpcSeldonServer{
-		Client:    client,
-		predictor: predictor,
-		Log:       logf.Log.WithName("SeldonGrpcApi"),
-		ServerUrl: serverUrl,
-		Namespace: namespace,
+		Client:         client,
+		predictorStore: predictor.NewPredictorStore(spec),
+		Log:            logf.Log.WithName("SeldonGrpcApi"),
+		ServerUrl:      serverUrl,
+		Namespace:      namespace,
 	}
 }
 
+// SetPredictorStore makes the Seldon gRPC calls run against the graph in store.
+func (g *GrpcSeldonServer) SetPredictorStore(store *predictor.PredictorStore) {
+	g.predictorStore = store
+}
+
 func (g GrpcSeldonServer) Predict(ctx context.Context, req *proto.SeldonMessage) (*proto.SeldonMessage, error) {
 	md := grpc.CollectMetadata(ctx)
 	header := protoGrpcMetadata.Pairs(payload.SeldonPUIDHeader, md.Get(payload.SeldonPUIDHeader)[0])
@@ -41,7 +46,7 @@ func (g GrpcSeldonServer) Predict(ctx context.Context, req *proto.SeldonMessage)
 	ctx = context.WithValue(ctx, payload.SeldonPUIDHeader, md.Get(payload.SeldonPUIDHeader)[0])
 	seldonPredictorProcess := predictor.NewPredictorProcess(ctx, g.Client, logf.Log.WithName("SeldonMessageRestClient"), g.ServerUrl, g.Namespace, md, "")
 	reqPayload := payload.ProtoPayload{Msg: req}
-	resPayload, err := seldonPredictorProcess.Predict(&g.predictor.Graph, &reqPayload)
+	resPayload, err := seldonPredictorProcess.Predict(&g.predictorStore.Get().Graph, &reqPayload)
 	if err != nil {
 		g.Log.Error(err, "Failed to call predict")
 		return payloadToMessage(resPayload), err
@@ -55,7 +60,7 @@ func (g GrpcSeldonServer) SendFeedback(ctx context.Context, req *proto.Feedback)
 	protoGrpc.SetHeader(ctx, header)
 	seldonPredictorProcess := predictor.NewPredictorProcess(ctx, g.Client, logf.Log.WithName("SeldonMessageRestClient"), g.ServerUrl, g.Namespace, md, "")
 	reqPayload := payload.ProtoPayload{Msg: req}
-	resPayload, err := seldonPredictorProcess.Feedback(&g.predictor.Graph, &reqPayload)
+	resPayload, err := seldonPredictorProcess.Feedback(&g.predictorStore.Get().Graph, &reqPayload)
 	if err != nil {
 		g.Log.Error(err, "Failed to call feedback")
 		return payloadToMessage(resPayload), err
@@ -65,7 +70,7 @@ func (g GrpcSeldonServer) SendFeedback(ctx context.Context, req *proto.Feedback)
 
 func (g GrpcSeldonServer) ModelMetadata(ctx context.Context, req *proto.SeldonModelMetadataRequest) (*proto.SeldonModelMetadata, error) {
 	seldonPredictorProcess := predictor.NewPredictorProcess(ctx, g.Client, logf.Log.WithName("SeldonMessageRestClient"), g.ServerUrl, g.Namespace, grpc.CollectMetadata(ctx), req.GetName())
-	resPayload, err := seldonPredictorProcess.Metadata(&g.predictor.Graph, req.GetName(), nil)
+	resPayload, err := seldonPredictorProcess.Metadata(&g.predictorStore.Get().Graph, req.GetName(), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -76,7 +81,7 @@ func (g GrpcSeldonServer) GraphMetadata(ctx context.Context, req *empty.Empty) (
 
 	seldonPredictorProcess := predictor.NewPredictorProcess(ctx, g.Client, logf.Log.WithName("SeldonMessageRestClient"), g.ServerUrl, g.Namespace, grpc.CollectMetadata(ctx), "")
 
-	graphMetadata, err := seldonPredictorProcess.GraphMetadata(g.predictor)
+	graphMetadata, err := seldonPredictorProcess.GraphMetadata(g.predictorStore.Get())
 	if err != nil {
 		return nil, err
 	}
@@ -12,6 +12,7 @@ import (
 	"github.com/opentracing/opentracing-go"
 	"github.com/seldonio/seldon-core/executor/api/metric"
 	"github.com/seldonio/seldon-core/executor/api/payload"
+	"github.com/seldonio/seldon-core/executor/api/tracing"
 	"github.com/seldonio/seldon-core/executor/k8s"
 	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
 	"google.golang.org/grpc"
@@ -55,7 +56,9 @@ func CreateGrpcServer(spec *v1.PredictorSpec, deploymentName string, annotations
 	}
 
 	interceptors := []grpc.UnaryServerInterceptor{metric.NewServerMetrics(spec, deploymentName).UnaryServerInterceptor()}
-	if opentracing.IsGlobalTracerRegistered() {
+	if tracing.OpenTelemetryEnabled() {
+		interceptors = append(interceptors, tracing.UnaryServerInterceptor())
+	} else if opentracing.IsGlobalTracerRegistered() {
 		interceptors = append(interceptors, grpc_opentracing.UnaryServerInterceptor())
 	}
 	opts = append(opts, grpc.UnaryInterceptor(grpc_middleware.ChainUnaryServer(interceptors...)))
@@ -53,8 +53,12 @@ func (s *TensorflowGrpcClient) getConnection(host string, port int32, modelName
 	if conn, ok := s.conns[k]; ok {
 		return conn, nil
 	} else {
+		transportCredentials, err := grpc2.AddTransportCredentials(s.Predictor)
+		if err != nil {
+			return nil, err
+		}
 		opts := []grpc.DialOption{
-			grpc.WithInsecure(),
+			transportCredentials,
 		}
 		opts = append(opts, grpc2.AddClientInterceptors(s.Predictor, s.DeploymentName, modelName, s.annotations, s.Log))
 		conn, err := grpc.Dial(fmt.Sprintf("%s:%d", host, port), opts...)
@@ -17,29 +17,34 @@ import (
 )
 
 type GrpcTensorflowServer struct {
-	Client    client.SeldonApiClient
-	predictor *v1.PredictorSpec
-	Log       logr.Logger
-	ServerUrl *url.URL
-	Namespace string
+	Client         client.SeldonApiClient
+	predictorStore *predictor.PredictorStore
+	Log            logr.Logger
+	ServerUrl      *url.URL
+	Namespace      string
 }
 
-func NewGrpcTensorflowServer(predictor *v1.PredictorSpec, client client.SeldonApiClient, serverUrl *url.URL, namespace string) *GrpcTensorflowServer {
+func NewGrpcTensorflowServer(spec *v1.PredictorSpec, client client.SeldonApiClient, serverUrl *url.URL, namespace string) *GrpcTensorflowServer {
 	return &GrpcTensorflowServer{
-		Client:    client,
-		predictor: predictor,
-		Log:       logf.Log.WithName("SeldonGrpcApi"),
-		ServerUrl: serverUrl,
-		Namespace: namespace,
+		Client:         client,
+		predictorStore: predictor.NewPredictorStore(spec),
+		Log:            logf.Log.WithName("SeldonGrpcApi"),
+		ServerUrl:      serverUrl,
+		Namespace:      namespace,
 	}
 }
 
+// SetPredictorStore makes the TensorFlow Serving calls run against the graph in store.
+func (g *GrpcTensorflowServer) SetPredictorStore(store *predictor.PredictorStore) {
+	g.predictorStore = store
+}
+
 func (g *GrpcTensorflowServer) execute(ctx context.Context, req proto.Message, method string, modelName string) (payload.SeldonPayload, error) {
 	md := grpc.CollectMetadata(ctx)
 	ctx = context.WithValue(ctx, payload.SeldonPUIDHeader, md.Get(payload.SeldonPUIDHeader)[0])
 	seldonPredictorProcess := predictor.NewPredictorProcess(ctx, g.Client, logf.Log.WithName(method), g.ServerUrl, g.Namespace, md, modelName)
 	reqPayload := payload.ProtoPayload{Msg: req}
-	return seldonPredictorProcess.Predict(&g.predictor.Graph, &reqPayload)
+	return seldonPredictorProcess.Predict(&g.predictorStore.Get().Graph, &reqPayload)
 }
 
 func (g *GrpcTensorflowServer) Classify(ctx context.Context, req *serving.ClassificationRequest) (*serving.ClassificationResponse, error) {
@@ -79,7 +84,7 @@ func (g *GrpcTensorflowServer) MultiInference(ctx context.Context, req *serving.
 func (g *GrpcTensorflowServer) GetModelMetadata(ctx context.Context, req *serving.GetModelMetadataRequest) (*serving.GetModelMetadataResponse, error) {
 	seldonPredictorProcess := predictor.NewPredictorProcess(ctx, g.Client, logf.Log.WithName("GrpcGetModelMetadata"), g.ServerUrl, g.Namespace, grpc.CollectMetadata(ctx), "")
 	reqPayload := payload.ProtoPayload{Msg: req}
-	resPayload, err := seldonPredictorProcess.Metadata(&g.predictor.Graph, req.ModelSpec.Name, &reqPayload)
+	resPayload, err := seldonPredictorProcess.Metadata(&g.predictorStore.Get().Graph, req.ModelSpec.Name, &reqPayload)
 	if err != nil {
 		return nil, err
 	}
@@ -89,7 +94,7 @@ func (g *GrpcTensorflowServer) GetModelMetadata(ctx context.Context, req *servin
 func (g *GrpcTensorflowServer) GetModelStatus(ctx context.Context, req *serving.GetModelStatusRequest) (*serving.GetModelStatusResponse, error) {
 	seldonPredictorProcess := predictor.NewPredictorProcess(ctx, g.Client, logf.Log.WithName("GrpcGetModelStatus"), g.ServerUrl, g.Namespace, grpc.CollectMetadata(ctx), "")
 	reqPayload := payload.ProtoPayload{Msg: req}
-	resPayload, err := seldonPredictorProcess.Status(&g.predictor.Graph, req.ModelSpec.Name, &reqPayload)
+	resPayload, err := seldonPredictorProcess.Status(&g.predictorStore.Get().Graph, req.ModelSpec.Name, &reqPayload)
 	if err != nil {
 		return nil, err
 	}
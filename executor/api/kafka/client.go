@@ -3,17 +3,28 @@ package kafka
 import (
 	"context"
 	"fmt"
+	"github.com/cloudevents/sdk-go/pkg/bindings/http"
+	"github.com/confluentinc/confluent-kafka-go/kafka"
 	"github.com/go-logr/logr"
+	"github.com/golang/protobuf/jsonpb"
 	"github.com/pkg/errors"
 	"github.com/seldonio/seldon-core/executor/api"
 	"github.com/seldonio/seldon-core/executor/api/client"
+	"github.com/seldonio/seldon-core/executor/api/grpc/kfserving"
+	"github.com/seldonio/seldon-core/executor/api/grpc/seldon/proto"
 	"github.com/seldonio/seldon-core/executor/api/payload"
 	"github.com/seldonio/seldon-core/executor/api/rest"
 	"github.com/seldonio/seldon-core/executor/api/util"
+	"github.com/seldonio/seldon-core/executor/k8s"
 	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
 	"io"
+	nethttp "net/http"
+	"strconv"
+	"time"
 )
 
+const defaultRPCTimeout = 60 * time.Second
+
 type KafkaClient struct {
 	Hostname       string
 	DeploymentName string
@@ -23,14 +34,36 @@ type KafkaClient struct {
 	predictor      *v1.PredictorSpec
 	Broker         string
 	Log            logr.Logger
+	Timeout        time.Duration
 	topicHandlers  map[string]*KafkaRPC
+	ctx            context.Context
+	cancel         context.CancelFunc
 }
 
 func (kc *KafkaClient) IsGrpc() bool {
 	return false
 }
 
-func NewKafkaClient(hostname, deploymentName, namespace, protocol, transport string, predictor *v1.PredictorSpec, broker string, log logr.Logger) client.SeldonApiClient {
+// getRPCTimeout returns the timeout for calls to each node from the timeout annotation of the transport
+// the models would otherwise be called with.
+func getRPCTimeout(transport string, annotations map[string]string, log logr.Logger) time.Duration {
+	key := k8s.ANNOTATION_REST_TIMEOUT
+	if transport == api.TransportGrpc {
+		key = k8s.ANNOTATION_GRPC_TIMEOUT
+	}
+	if val := annotations[key]; val != "" {
+		timeout, err := strconv.Atoi(val)
+		if err != nil {
+			log.Error(err, "Failed to parse annotation to int so will use default", key, val)
+		} else {
+			return time.Duration(timeout) * time.Millisecond
+		}
+	}
+	return defaultRPCTimeout
+}
+
+func NewKafkaClient(hostname, deploymentName, namespace, protocol, transport string, predictor *v1.PredictorSpec, broker string, annotations map[string]string, log logr.Logger) client.SeldonApiClient {
+	ctx, cancel := context.WithCancel(context.Background())
 	skc := &KafkaClient{
 		Hostname:       hostname,
 		DeploymentName: deploymentName,
@@ -40,12 +73,20 @@ func NewKafkaClient(hostname, deploymentName, namespace, protocol, transport str
 		predictor:      predictor,
 		Broker:         broker,
 		Log:            log.WithName("KafkaClient"),
+		Timeout:        getRPCTimeout(transport, annotations, log),
 		topicHandlers:  make(map[string]*KafkaRPC),
+		ctx:            ctx,
+		cancel:         cancel,
 	}
 	skc.createTopicHandlers(&predictor.Graph)
 	return skc
 }
 
+// Close stops the topic consumers and fails any calls still waiting for a response.
+func (kc *KafkaClient) Close() {
+	kc.cancel()
+}
+
 func (kc *KafkaClient) createTopicHandlers(node *v1.PredictiveUnit) error {
 	th, err := NewKafkaRPC(kc, node.Name)
 	if err != nil {
@@ -74,7 +115,7 @@ func getPuidFromMeta(meta map[string][]string) (string, error) {
 	}
 }
 
-func (kc *KafkaClient) kafkaRPC(msg payload.SeldonPayload, meta map[string][]string, modelName string, method string) (payload.SeldonPayload, error) {
+func (kc *KafkaClient) kafkaRPC(ctx context.Context, msg payload.SeldonPayload, meta map[string][]string, modelName string, method string) (payload.SeldonPayload, error) {
 	bytes, err := msg.GetBytes()
 	if err != nil {
 		kc.Log.Error(err, "Failed to get bytes from request")
@@ -84,23 +125,24 @@ func (kc *KafkaClient) kafkaRPC(msg payload.SeldonPayload, meta map[string][]str
 	if err != nil {
 		return nil, err
 	}
+	headers := append(createProtoHeaders(msg), kafka.Header{Key: http.ContentType, Value: []byte(msg.GetContentType())})
 	if kafkaRPC, ok := kc.topicHandlers[modelName]; ok {
-		return kafkaRPC.call(bytes, puid, method)
+		return kafkaRPC.call(ctx, bytes, headers, puid, method)
 	} else {
 		return nil, fmt.Errorf("Failed to find topic handler for model name %s", modelName)
 	}
 }
 
 func (kc *KafkaClient) Predict(ctx context.Context, modelName string, host string, port int32, msg payload.SeldonPayload, meta map[string][]string) (payload.SeldonPayload, error) {
-	return kc.kafkaRPC(msg, meta, modelName, client.SeldonPredictPath)
+	return kc.kafkaRPC(ctx, msg, meta, modelName, client.SeldonPredictPath)
 }
 
 func (kc *KafkaClient) TransformInput(ctx context.Context, modelName string, host string, port int32, msg payload.SeldonPayload, meta map[string][]string) (payload.SeldonPayload, error) {
-	return kc.kafkaRPC(msg, meta, modelName, client.SeldonTransformInputPath)
+	return kc.kafkaRPC(ctx, msg, meta, modelName, client.SeldonTransformInputPath)
 }
 
 func (kc *KafkaClient) Route(ctx context.Context, modelName string, host string, port int32, msg payload.SeldonPayload, meta map[string][]string) (int, error) {
-	res, err := kc.kafkaRPC(msg, meta, modelName, client.SeldonRoutePath)
+	res, err := kc.kafkaRPC(ctx, msg, meta, modelName, client.SeldonRoutePath)
 	if err != nil {
 		return 0, err
 	} else {
@@ -113,15 +155,15 @@ func (kc *KafkaClient) Combine(ctx context.Context, modelName string, host strin
 	if err != nil {
 		return nil, err
 	}
-	return kc.kafkaRPC(req, meta, modelName, client.SeldonCombinePath)
+	return kc.kafkaRPC(ctx, req, meta, modelName, client.SeldonCombinePath)
 }
 
 func (kc *KafkaClient) TransformOutput(ctx context.Context, modelName string, host string, port int32, msg payload.SeldonPayload, meta map[string][]string) (payload.SeldonPayload, error) {
-	return kc.kafkaRPC(msg, meta, modelName, client.SeldonTransformOutputPath)
+	return kc.kafkaRPC(ctx, msg, meta, modelName, client.SeldonTransformOutputPath)
 }
 
 func (kc *KafkaClient) Feedback(ctx context.Context, modelName string, host string, port int32, msg payload.SeldonPayload, meta map[string][]string) (payload.SeldonPayload, error) {
-	return kc.kafkaRPC(msg, meta, modelName, client.SeldonFeedbackPath)
+	return kc.kafkaRPC(ctx, msg, meta, modelName, client.SeldonFeedbackPath)
 }
 
 func (kc *KafkaClient) Chain(ctx context.Context, modelName string, msg payload.SeldonPayload) (payload.SeldonPayload, error) {
@@ -130,6 +172,12 @@ func (kc *KafkaClient) Chain(ctx context.Context, modelName string, msg payload.
 		return msg, nil
 	case api.ProtocolTensorflow: // Attempt to chain tensorflow Payload
 		return rest.ChainTensorflow(msg)
+	case api.ProtocolV2, api.ProtocolKFServing:
+		// Protobuf responses are decoded using their proto name header so can be chained as messages
+		if _, ok := msg.GetPayload().([]byte); ok {
+			return rest.ChainKFserving(msg)
+		}
+		return kfserving.ChainModelInfer(modelName, msg)
 	}
 	return nil, errors.Errorf("Unknown protocol %s", kc.Protocol)
 }
@@ -142,8 +190,22 @@ func (kc *KafkaClient) Metadata(ctx context.Context, modelName string, host stri
 	panic("Not implemented")
 }
 
+// CreateErrorPayload returns a failed SeldonMessage status for the Seldon protocol and, for the other protocols,
+// the JSON error object their REST servers return.
 func (kc *KafkaClient) CreateErrorPayload(err error) payload.SeldonPayload {
-	panic("Not implemented")
+	if kc.Protocol == api.ProtocolSeldon {
+		respFailed := proto.SeldonMessage{
+			Status: &proto.Status{
+				Code:   nethttp.StatusInternalServerError,
+				Info:   err.Error(),
+				Status: proto.Status_FAILURE,
+			},
+		}
+		m := jsonpb.Marshaler{}
+		jStr, _ := m.MarshalToString(&respFailed)
+		return &payload.BytesPayload{Msg: []byte(jStr), ContentType: rest.ContentTypeJSON}
+	}
+	return payload.NewJSONErrorPayload(err)
 }
 
 func (kc *KafkaClient) Marshall(w io.Writer, msg payload.SeldonPayload) error {
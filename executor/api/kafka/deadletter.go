@@ -0,0 +1,141 @@
+package kafka
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/seldonio/seldon-core/executor/api/messaging"
+	"github.com/seldonio/seldon-core/executor/api/metric"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+)
+
+const (
+	ENV_KAFKA_DEAD_LETTER_TOPIC = "KAFKA_DEAD_LETTER_TOPIC"
+	ENV_KAFKA_MAX_RETRIES       = "KAFKA_MAX_RETRIES"
+	ENV_KAFKA_RETRY_BACKOFF     = "KAFKA_RETRY_BACKOFF"
+	ENV_KAFKA_ERROR_REPLIES     = "KAFKA_ERROR_REPLIES"
+
+	HeaderError          = messaging.HeaderError
+	HeaderErrorNode      = messaging.HeaderErrorNode
+	HeaderAttempts       = messaging.HeaderAttempts
+	HeaderOriginalTopic  = "Seldon-Original-Topic"
+	HeaderOriginalPart   = "Seldon-Original-Partition"
+	HeaderOriginalOffset = "Seldon-Original-Offset"
+
+	maxRetryBackoff = 30 * time.Second
+)
+
+// DeadLetterConfig controls how the kafka server handles requests which fail prediction.
+type DeadLetterConfig struct {
+	// Topic failed inputs are published to. Empty disables the dead-letter topic.
+	Topic string
+	// MaxRetries is the number of times a failed prediction is retried before giving up.
+	MaxRetries int
+	// RetryBackoff is the delay before the first retry, doubled on each further attempt.
+	RetryBackoff time.Duration
+	// ErrorReplies publishes an error payload to the output topic for failed requests.
+	ErrorReplies bool
+}
+
+func (d DeadLetterConfig) backoff(attempt int) time.Duration {
+	backoff := d.RetryBackoff
+	for i := 1; i < attempt && backoff < maxRetryBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxRetryBackoff {
+		return maxRetryBackoff
+	}
+	return backoff
+}
+
+func createDeadLetterHeaders(msg *kafka.Message, puid string, err error, node string, attempts int) []kafka.Header {
+	headers := append([]kafka.Header{}, msg.Headers...)
+	headers = append(headers,
+		kafka.Header{Key: HeaderError, Value: []byte(err.Error())},
+		kafka.Header{Key: HeaderErrorNode, Value: []byte(node)},
+		kafka.Header{Key: HeaderAttempts, Value: []byte(strconv.Itoa(attempts))},
+		kafka.Header{Key: HeaderOriginalPart, Value: []byte(strconv.Itoa(int(msg.TopicPartition.Partition)))},
+		kafka.Header{Key: HeaderOriginalOffset, Value: []byte(msg.TopicPartition.Offset.String())},
+	)
+	if msg.TopicPartition.Topic != nil {
+		headers = append(headers, kafka.Header{Key: HeaderOriginalTopic, Value: []byte(*msg.TopicPartition.Topic)})
+	}
+	if !hasHeader(msg.Headers, payload.SeldonPUIDHeader) {
+		headers = append(headers, kafka.Header{Key: payload.SeldonPUIDHeader, Value: []byte(puid)})
+	}
+	return headers
+}
+
+func hasHeader(headers []kafka.Header, key string) bool {
+	for _, header := range headers {
+		if header.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// handleFailure publishes the failed request to the dead-letter topic and an error payload to the output topic
+// as configured. It returns true if anything was produced for the request, in which case its offset is released
+// once delivery is confirmed or given up on. With a dead-letter topic that is the delivery of the dead-letter message.
+func (ks *SeldonKafkaServer) handleFailure(ctx context.Context, job *KafkaJob, puid string, err error, node string, attempts int) bool {
+	produced := false
+	if ks.DeadLetter.ErrorReplies {
+		errPayload := ks.Client.CreateErrorPayload(err)
+		errBytes, berr := errPayload.GetBytes()
+		if berr != nil {
+			ks.Log.Error(berr, "Failed to get bytes from error payload")
+			ks.metrics.MessageFailed(ks.TopicIn, metric.KafkaStageProduce)
+		} else {
+			ks.produce(&delivery{
+				msg: &kafka.Message{
+					TopicPartition: kafka.TopicPartition{Topic: &ks.TopicOut, Partition: kafka.PartitionAny},
+					Key:            job.message.Key,
+					Value:          errBytes,
+					Headers:        injectTraceHeaders(ctx, append(ks.Output.createOutputHeaders(job.message, puid, errPayload), kafka.Header{Key: HeaderError, Value: []byte(err.Error())})),
+				},
+				input:     job.message,
+				puid:      puid,
+				untracked: ks.DeadLetter.Topic != "",
+			})
+			produced = true
+		}
+	}
+
+	if ks.DeadLetter.Topic == "" {
+		return produced
+	}
+	ks.produce(ks.deadLetterDelivery(job.message, puid, err, node, attempts))
+	return true
+}
+
+// unmarshalFailed sends an input which couldn't be decoded to the dead-letter topic, if there is one, tracking its
+// offset until the dead-letter message is delivered. Otherwise the input is dropped.
+func (ks *SeldonKafkaServer) unmarshalFailed(input *kafka.Message, puid string, err error) {
+	ks.Log.Error(err, "Failed to unmarshall payload", "puid", puid, "partition", input.TopicPartition.Partition, "offset", input.TopicPartition.Offset)
+	ks.metrics.MessageFailed(ks.TopicIn, metric.KafkaStageUnmarshal)
+	if !ks.AutoCommit {
+		ks.offsets.add(input.TopicPartition)
+	}
+	if ks.DeadLetter.Topic == "" {
+		ks.done(input)
+		return
+	}
+	ks.produce(ks.deadLetterDelivery(input, puid, err, "", 0))
+}
+
+func (ks *SeldonKafkaServer) deadLetterDelivery(input *kafka.Message, puid string, err error, node string, attempts int) *delivery {
+	return &delivery{
+		msg: &kafka.Message{
+			TopicPartition: kafka.TopicPartition{Topic: &ks.DeadLetter.Topic, Partition: kafka.PartitionAny},
+			Key:            input.Key,
+			Value:          input.Value,
+			Headers:        createDeadLetterHeaders(input, puid, err, node, attempts),
+		},
+		input:      input,
+		puid:       puid,
+		deadLetter: true,
+	}
+}
@@ -0,0 +1,128 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	. "github.com/onsi/gomega"
+	"github.com/seldonio/seldon-core/executor/api"
+	"github.com/seldonio/seldon-core/executor/api/metric"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func TestDeadLetterBackoff(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	config := DeadLetterConfig{RetryBackoff: time.Second}
+	g.Expect(config.backoff(1)).To(Equal(time.Second))
+	g.Expect(config.backoff(2)).To(Equal(2 * time.Second))
+	g.Expect(config.backoff(3)).To(Equal(4 * time.Second))
+	g.Expect(config.backoff(20)).To(Equal(maxRetryBackoff))
+}
+
+func TestCreateDeadLetterHeaders(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	topic := "input"
+	msg := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: 2, Offset: 42},
+		Headers:        []kafka.Header{{Key: "user", Value: []byte("abc")}},
+	}
+	headers := createDeadLetterHeaders(msg, "puid1", errors.New("failed"), "model", 3)
+
+	found := map[string]string{}
+	for _, header := range headers {
+		found[header.Key] = string(header.Value)
+	}
+	g.Expect(found).To(Equal(map[string]string{
+		"user":                   "abc",
+		HeaderError:              "failed",
+		HeaderErrorNode:          "model",
+		HeaderAttempts:           "3",
+		HeaderOriginalTopic:      "input",
+		HeaderOriginalPart:       "2",
+		HeaderOriginalOffset:     "42",
+		payload.SeldonPUIDHeader: "puid1",
+	}))
+	// The input message headers are left untouched
+	g.Expect(msg.Headers).To(HaveLen(1))
+}
+
+func TestUnmarshalFailedDeadLetter(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	producer, err := kafka.NewProducer(&kafka.ConfigMap{"bootstrap.servers": "localhost:0", "message.timeout.ms": 100, "go.delivery.report.fields": "key,value,headers"})
+	g.Expect(err).To(BeNil())
+	defer producer.Close()
+	ks := &SeldonKafkaServer{
+		Producer:   producer,
+		TopicIn:    "input",
+		Log:        logf.Log,
+		DeadLetter: DeadLetterConfig{Topic: "dead"},
+		offsets:    newOffsetTracker(),
+		metrics:    metric.NewKafkaMetrics("dep", "p"),
+	}
+
+	topic := "input"
+	input := &kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: 0, Offset: 7}, Value: []byte("not json")}
+	ks.unmarshalFailed(input, "puid1", errors.New("bad payload"))
+	// The offset is held until the dead-letter message is delivered
+	g.Expect(ks.offsets.commitable()).To(BeEmpty())
+
+	var msg *kafka.Message
+	g.Eventually(producer.Events(), 10*time.Second).Should(Receive(&msg))
+	g.Expect(*msg.TopicPartition.Topic).To(Equal("dead"))
+	g.Expect(msg.Value).To(Equal([]byte("not json")))
+	found := map[string]string{}
+	for _, header := range msg.Headers {
+		found[header.Key] = string(header.Value)
+	}
+	g.Expect(found).To(HaveKeyWithValue(HeaderError, "bad payload"))
+	g.Expect(found).To(HaveKeyWithValue(HeaderOriginalTopic, "input"))
+	g.Expect(found).To(HaveKeyWithValue(HeaderOriginalPart, "0"))
+	g.Expect(found).To(HaveKeyWithValue(HeaderOriginalOffset, "7"))
+
+	msg.TopicPartition.Error = nil
+	ks.delivered(msg)
+	g.Expect(ks.offsets.commitable()).To(ConsistOf(kafka.TopicPartition{Topic: &topic, Partition: 0, Offset: 8}))
+}
+
+func TestHandleFailureErrorReplyLeavesOffsetToDeadLetter(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	producer, err := kafka.NewProducer(&kafka.ConfigMap{"bootstrap.servers": "localhost:0", "message.timeout.ms": 100})
+	g.Expect(err).To(BeNil())
+	defer producer.Close()
+	ks := &SeldonKafkaServer{
+		Client:     &KafkaClient{Protocol: api.ProtocolV2},
+		Producer:   producer,
+		TopicIn:    "input",
+		TopicOut:   "output",
+		Log:        logf.Log,
+		DeadLetter: DeadLetterConfig{Topic: "dead", ErrorReplies: true},
+		offsets:    newOffsetTracker(),
+		metrics:    metric.NewKafkaMetrics("dep", "p"),
+	}
+
+	topic := "input"
+	input := &kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: 0, Offset: 3}}
+	ks.offsets.add(input.TopicPartition)
+	g.Expect(ks.handleFailure(context.Background(), &KafkaJob{message: input}, "puid1", errors.New("failed"), "model", 1)).To(BeTrue())
+
+	delivered := map[string]*kafka.Message{}
+	for len(delivered) < 2 {
+		var msg *kafka.Message
+		g.Eventually(producer.Events(), 10*time.Second).Should(Receive(&msg))
+		msg.TopicPartition.Error = nil
+		delivered[*msg.TopicPartition.Topic] = msg
+	}
+
+	ks.delivered(delivered["output"])
+	g.Expect(ks.offsets.commitable()).To(BeEmpty())
+	ks.delivered(delivered["dead"])
+	g.Expect(ks.offsets.commitable()).To(ConsistOf(kafka.TopicPartition{Topic: &topic, Partition: 0, Offset: 4}))
+}
@@ -0,0 +1,69 @@
+package kafka
+
+import (
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/go-logr/logr"
+	"github.com/seldonio/seldon-core/executor/api/metric"
+)
+
+const (
+	lagInterval  = 10 * time.Second
+	lagTimeoutMs = 5000
+)
+
+// consumerLag is the number of messages after the committed offset up to the high watermark.
+// Partitions with nothing committed yet lag from the low watermark.
+func consumerLag(committed kafka.Offset, low int64, high int64) int64 {
+	offset := int64(committed)
+	if committed < 0 {
+		offset = low
+	}
+	if lag := high - offset; lag > 0 {
+		return lag
+	}
+	return 0
+}
+
+// reportConsumerLag sets the lag of each partition assigned to c from its committed and cached high watermark offsets.
+func reportConsumerLag(c *kafka.Consumer, metrics *metric.KafkaMetrics, log logr.Logger) {
+	assigned, err := c.Assignment()
+	if err != nil {
+		log.Error(err, "Failed to get partition assignment")
+		return
+	}
+	if len(assigned) == 0 {
+		return
+	}
+	committed, err := c.Committed(assigned, lagTimeoutMs)
+	if err != nil {
+		log.Error(err, "Failed to get committed offsets")
+		return
+	}
+	for _, tp := range committed {
+		if tp.Topic == nil {
+			continue
+		}
+		low, high, err := c.GetWatermarkOffsets(*tp.Topic, tp.Partition)
+		// The watermarks are unknown until the first fetch from the partition
+		if err != nil || high < 0 {
+			continue
+		}
+		metrics.SetConsumerLag(*tp.Topic, tp.Partition, consumerLag(tp.Offset, low, high))
+	}
+}
+
+// watchConsumerLag reports the consumer lag every lagInterval until stop is closed.
+func watchConsumerLag(c *kafka.Consumer, metrics *metric.KafkaMetrics, stop <-chan struct{}, log logr.Logger) {
+	ticker := time.NewTicker(lagInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			reportConsumerLag(c, metrics, log)
+		}
+	}
+}
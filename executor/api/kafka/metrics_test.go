@@ -0,0 +1,17 @@
+package kafka
+
+import (
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	. "github.com/onsi/gomega"
+)
+
+func TestConsumerLag(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	g.Expect(consumerLag(kafka.Offset(90), 0, 100)).To(Equal(int64(10)))
+	// Nothing committed yet so all retained messages are lag
+	g.Expect(consumerLag(kafka.OffsetInvalid, 20, 100)).To(Equal(int64(80)))
+	g.Expect(consumerLag(kafka.Offset(100), 0, 100)).To(Equal(int64(0)))
+}
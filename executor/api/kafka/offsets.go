@@ -0,0 +1,215 @@
+package kafka
+
+import (
+	"sync"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/seldonio/seldon-core/executor/api/metric"
+)
+
+const commitInterval = time.Second
+
+type partitionKey struct {
+	topic     string
+	partition int32
+}
+
+type partitionOffsets struct {
+	pending   map[kafka.Offset]struct{}
+	next      kafka.Offset
+	committed kafka.Offset
+}
+
+// watermark is the offset to commit: the lowest unfinished offset or, if all are finished, the offset after the last consumed.
+func (p *partitionOffsets) watermark() kafka.Offset {
+	watermark := p.next
+	for offset := range p.pending {
+		if offset < watermark {
+			watermark = offset
+		}
+	}
+	return watermark
+}
+
+// offsetTracker tracks the messages being processed per partition so that offsets are only committed
+// up to the lowest unfinished message, whatever order the workers finish in.
+type offsetTracker struct {
+	sync.Mutex
+	partitions map[partitionKey]*partitionOffsets
+}
+
+func newOffsetTracker() *offsetTracker {
+	return &offsetTracker{
+		partitions: make(map[partitionKey]*partitionOffsets),
+	}
+}
+
+func keyFor(tp kafka.TopicPartition) partitionKey {
+	key := partitionKey{partition: tp.Partition}
+	if tp.Topic != nil {
+		key.topic = *tp.Topic
+	}
+	return key
+}
+
+// add registers a consumed message as in flight.
+func (t *offsetTracker) add(tp kafka.TopicPartition) {
+	t.Lock()
+	defer t.Unlock()
+	key := keyFor(tp)
+	p, ok := t.partitions[key]
+	if !ok {
+		p = &partitionOffsets{
+			pending:   make(map[kafka.Offset]struct{}),
+			committed: tp.Offset,
+		}
+		t.partitions[key] = p
+	}
+	p.pending[tp.Offset] = struct{}{}
+	if tp.Offset >= p.next {
+		p.next = tp.Offset + 1
+	}
+}
+
+// done marks a message as fully processed. Messages from partitions no longer tracked are ignored.
+func (t *offsetTracker) done(tp kafka.TopicPartition) {
+	t.Lock()
+	defer t.Unlock()
+	if p, ok := t.partitions[keyFor(tp)]; ok {
+		delete(p.pending, tp.Offset)
+	}
+}
+
+// commitable returns the partitions whose watermark has advanced since the last call.
+func (t *offsetTracker) commitable() []kafka.TopicPartition {
+	t.Lock()
+	defer t.Unlock()
+	var offsets []kafka.TopicPartition
+	for key, p := range t.partitions {
+		watermark := p.watermark()
+		if watermark > p.committed {
+			topic := key.topic
+			offsets = append(offsets, kafka.TopicPartition{Topic: &topic, Partition: key.partition, Offset: watermark})
+			p.committed = watermark
+		}
+	}
+	return offsets
+}
+
+// revoke stops tracking the given partitions.
+func (t *offsetTracker) revoke(partitions []kafka.TopicPartition) {
+	t.Lock()
+	defer t.Unlock()
+	for _, tp := range partitions {
+		delete(t.partitions, keyFor(tp))
+	}
+}
+
+func (ks *SeldonKafkaServer) commitOffsets() {
+	offsets := ks.offsets.commitable()
+	if len(offsets) == 0 {
+		return
+	}
+	if _, err := ks.Consumer.CommitOffsets(offsets); err != nil {
+		ks.Log.Error(err, "Failed to commit offsets")
+	}
+}
+
+// delivery is the Opaque of a message produced for an input message. The input's offset is released once
+// the message is delivered or, after retries and the dead-letter topic have failed, dropped.
+type delivery struct {
+	msg        *kafka.Message
+	input      *kafka.Message
+	puid       string
+	attempts   int
+	deadLetter bool
+	// untracked messages leave the input's offset to another message produced for it
+	untracked bool
+}
+
+// produce sends the message of d, handing any error to deliveryFailed. Once the server is stopping nothing more
+// is produced and the input offset is left uncommitted so the message is processed again after the restart.
+func (ks *SeldonKafkaServer) produce(d *delivery) {
+	ks.produceLock.RLock()
+	if ks.stopping {
+		ks.produceLock.RUnlock()
+		ks.Log.Info("Not producing while stopping", "topic", *d.msg.TopicPartition.Topic, "puid", d.puid)
+		return
+	}
+	d.attempts++
+	d.msg.Opaque = d
+	err := ks.Producer.Produce(d.msg, nil)
+	ks.produceLock.RUnlock()
+	if err != nil {
+		ks.deliveryFailed(d, err)
+		return
+	}
+	ks.metrics.MessageProduced(*d.msg.TopicPartition.Topic)
+}
+
+// deliveryFailed retries a message which could not be produced or delivered with the backoff of failed
+// predictions. Once the retries are used up an output's input goes to the dead-letter topic, if there is
+// one, and otherwise the message is dropped so it can't hold back the offsets committed for its partition.
+func (ks *SeldonKafkaServer) deliveryFailed(d *delivery, err error) {
+	ks.metrics.MessageFailed(ks.TopicIn, metric.KafkaStageProduce)
+	topic := *d.msg.TopicPartition.Topic
+	if d.attempts <= ks.DeadLetter.MaxRetries {
+		backoff := ks.DeadLetter.backoff(d.attempts)
+		ks.Log.Info("Retrying failed produce", "topic", topic, "puid", d.puid, "attempt", d.attempts, "backoff", backoff.String(), "error", err.Error())
+		time.AfterFunc(backoff, func() { ks.produce(d) })
+		return
+	}
+	if d.untracked {
+		ks.Log.Error(err, "Failed to produce, dropping message", "topic", topic, "puid", d.puid, "attempts", d.attempts)
+		return
+	}
+	if !d.deadLetter && ks.DeadLetter.Topic != "" {
+		ks.Log.Error(err, "Failed to produce, sending input to dead-letter topic", "topic", topic, "puid", d.puid, "attempts", d.attempts)
+		ks.produce(ks.deadLetterDelivery(d.input, d.puid, err, "", d.attempts))
+		return
+	}
+	ks.Log.Error(err, "Failed to produce, dropping message", "topic", topic, "puid", d.puid, "attempts", d.attempts,
+		"partition", d.input.TopicPartition.Partition, "offset", d.input.TopicPartition.Offset)
+	ks.done(d.input)
+}
+
+// delivered handles the delivery report of a produced message.
+func (ks *SeldonKafkaServer) delivered(msg *kafka.Message) {
+	d, ok := msg.Opaque.(*delivery)
+	if !ok {
+		return
+	}
+	if msg.TopicPartition.Error != nil {
+		ks.deliveryFailed(d, msg.TopicPartition.Error)
+		return
+	}
+	if !d.untracked {
+		ks.offsets.done(d.input.TopicPartition)
+	}
+}
+
+// handleDeliveries marks input messages done once the message produced for them is delivered, retrying failed
+// deliveries, and periodically commits the contiguous offsets. It runs until the producer is closed.
+func (ks *SeldonKafkaServer) handleDeliveries(done chan struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(commitInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case ev, ok := <-ks.Producer.Events():
+			if !ok {
+				ks.commitOffsets()
+				return
+			}
+			switch e := ev.(type) {
+			case *kafka.Message:
+				ks.delivered(e)
+			case kafka.Error:
+				ks.Log.Error(e, "Received kafka producer error")
+			}
+		case <-ticker.C:
+			ks.commitOffsets()
+		}
+	}
+}
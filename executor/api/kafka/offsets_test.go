@@ -0,0 +1,120 @@
+package kafka
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	. "github.com/onsi/gomega"
+	"github.com/seldonio/seldon-core/executor/api/metric"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func TestOffsetTrackerCommitsContiguousOffsets(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	topic := "input"
+	tp := func(partition int32, offset kafka.Offset) kafka.TopicPartition {
+		return kafka.TopicPartition{Topic: &topic, Partition: partition, Offset: offset}
+	}
+
+	tracker := newOffsetTracker()
+	for offset := kafka.Offset(10); offset < 13; offset++ {
+		tracker.add(tp(0, offset))
+	}
+	tracker.add(tp(1, 5))
+	g.Expect(tracker.commitable()).To(BeEmpty())
+
+	// Later offsets finishing first must not move the watermark past an unfinished message
+	tracker.done(tp(0, 12))
+	tracker.done(tp(0, 11))
+	g.Expect(tracker.commitable()).To(BeEmpty())
+
+	tracker.done(tp(0, 10))
+	tracker.done(tp(1, 5))
+	offsets := tracker.commitable()
+	g.Expect(offsets).To(ConsistOf(tp(0, 13), tp(1, 6)))
+	g.Expect(tracker.commitable()).To(BeEmpty())
+
+	tracker.add(tp(0, 13))
+	tracker.revoke([]kafka.TopicPartition{tp(0, 0)})
+	tracker.done(tp(0, 13))
+	g.Expect(tracker.commitable()).To(BeEmpty())
+}
+
+func TestProduceFailureReleasesOffset(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	// Outputs over the maximum message size fail to produce straight away
+	producer, err := kafka.NewProducer(&kafka.ConfigMap{"bootstrap.servers": "localhost:0", "message.max.bytes": 1000})
+	g.Expect(err).To(BeNil())
+	defer producer.Close()
+	ks := &SeldonKafkaServer{
+		Producer:   producer,
+		TopicIn:    "input",
+		Log:        logf.Log,
+		DeadLetter: DeadLetterConfig{MaxRetries: 2, RetryBackoff: 10 * time.Millisecond},
+		offsets:    newOffsetTracker(),
+		metrics:    metric.NewKafkaMetrics("dep", "p"),
+	}
+
+	topicIn, topicOut := "input", "output"
+	input := &kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &topicIn, Partition: 0, Offset: 5}}
+	ks.offsets.add(input.TopicPartition)
+	d := &delivery{
+		msg:   &kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &topicOut, Partition: kafka.PartitionAny}, Value: make([]byte, 2000)},
+		input: input,
+		puid:  "1",
+	}
+	ks.produce(d)
+	g.Eventually(ks.offsets.commitable, 5*time.Second, 10*time.Millisecond).Should(ConsistOf(kafka.TopicPartition{Topic: &topicIn, Partition: 0, Offset: 6}))
+	g.Expect(d.attempts).To(Equal(3))
+}
+
+func TestDeliveryFailureDeadLettersInput(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	// Without a broker every delivery fails once the message times out
+	producer, err := kafka.NewProducer(&kafka.ConfigMap{"bootstrap.servers": "localhost:0", "message.timeout.ms": 100})
+	g.Expect(err).To(BeNil())
+	ks := &SeldonKafkaServer{
+		Producer:   producer,
+		TopicIn:    "input",
+		Log:        logf.Log,
+		DeadLetter: DeadLetterConfig{Topic: "dead", MaxRetries: 1, RetryBackoff: 10 * time.Millisecond},
+		offsets:    newOffsetTracker(),
+		metrics:    metric.NewKafkaMetrics("dep", "p"),
+	}
+	var lock sync.Mutex
+	var topics []string
+	go func() {
+		for ev := range producer.Events() {
+			if msg, ok := ev.(*kafka.Message); ok {
+				lock.Lock()
+				topics = append(topics, *msg.TopicPartition.Topic)
+				lock.Unlock()
+				ks.delivered(msg)
+			}
+		}
+	}()
+	defer func() {
+		ks.produceLock.Lock()
+		ks.stopping = true
+		ks.produceLock.Unlock()
+		producer.Close()
+	}()
+
+	topicIn, topicOut := "input", "output"
+	input := &kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &topicIn, Partition: 0, Offset: 5}, Value: []byte("request")}
+	ks.offsets.add(input.TopicPartition)
+	ks.produce(&delivery{
+		msg:   &kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &topicOut, Partition: kafka.PartitionAny}, Value: []byte("response")},
+		input: input,
+		puid:  "1",
+	})
+	g.Eventually(ks.offsets.commitable, 10*time.Second, 10*time.Millisecond).Should(ConsistOf(kafka.TopicPartition{Topic: &topicIn, Partition: 0, Offset: 6}))
+	lock.Lock()
+	defer lock.Unlock()
+	g.Expect(topics).To(Equal([]string{"output", "output", "dead", "dead"}))
+}
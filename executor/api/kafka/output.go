@@ -0,0 +1,130 @@
+package kafka
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cloudevents/sdk-go/pkg/bindings/http"
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/golang/protobuf/jsonpb"
+	proto2 "github.com/golang/protobuf/proto"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+)
+
+const (
+	ENV_KAFKA_HEADER_PASSTHROUGH = "KAFKA_HEADER_PASSTHROUGH"
+	ENV_KAFKA_OUTPUT_KEY_FIELD   = "KAFKA_OUTPUT_KEY_FIELD"
+
+	allHeaders = "*"
+)
+
+// OutputConfig controls the headers and key of the messages produced to the output topic.
+type OutputConfig struct {
+	// Headers are the input headers copied to outputs. A single "*" copies all headers.
+	Headers []string
+	// KeyField is the dotted path of a response field used as the output message key, so outputs are
+	// partitioned by it. Empty keeps the input message key.
+	KeyField string
+}
+
+// ParseHeaderPassthrough splits a comma separated list of header names.
+func ParseHeaderPassthrough(val string) []string {
+	var headers []string
+	for _, header := range strings.Split(val, ",") {
+		if header = strings.TrimSpace(header); header != "" {
+			headers = append(headers, header)
+		}
+	}
+	return headers
+}
+
+func (o OutputConfig) passthrough(key string) bool {
+	for _, header := range o.Headers {
+		if header == allHeaders || strings.EqualFold(header, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// generatedHeader is true for the headers createOutputHeaders sets itself, whatever the case of key.
+func generatedHeader(key string) bool {
+	for _, generated := range []string{payload.SeldonPUIDHeader, http.ContentType, KeyProtoName} {
+		if strings.EqualFold(key, generated) {
+			return true
+		}
+	}
+	return false
+}
+
+// createOutputHeaders copies the configured input headers and sets the puid, content type and proto name of res.
+func (o OutputConfig) createOutputHeaders(input *kafka.Message, puid string, res payload.SeldonPayload) []kafka.Header {
+	headers := make([]kafka.Header, 0)
+	for _, header := range input.Headers {
+		if generatedHeader(header.Key) {
+			continue
+		}
+		if o.passthrough(header.Key) {
+			headers = append(headers, header)
+		}
+	}
+	headers = append(headers,
+		kafka.Header{Key: payload.SeldonPUIDHeader, Value: []byte(puid)},
+		kafka.Header{Key: http.ContentType, Value: []byte(res.GetContentType())},
+	)
+	return append(headers, createProtoHeaders(res)...)
+}
+
+// outputKey returns the value of KeyField in res, or the input key if no field is configured.
+func (o OutputConfig) outputKey(input *kafka.Message, res payload.SeldonPayload) ([]byte, error) {
+	if o.KeyField == "" {
+		return input.Key, nil
+	}
+	var data []byte
+	var err error
+	if pm, ok := res.GetPayload().(proto2.Message); ok {
+		ma := jsonpb.Marshaler{OrigName: true}
+		var s string
+		s, err = ma.MarshalToString(pm)
+		data = []byte(s)
+	} else {
+		data, err = payload.DecompressSeldonPayload(res)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var f interface{}
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	return extractField(f, o.KeyField)
+}
+
+// extractField walks the dotted path through JSON objects and arrays. Strings are returned as is and
+// other values as JSON.
+func extractField(f interface{}, path string) ([]byte, error) {
+	for _, part := range strings.Split(path, ".") {
+		switch v := f.(type) {
+		case map[string]interface{}:
+			val, ok := v[part]
+			if !ok {
+				return nil, fmt.Errorf("Failed to find field %s in response", path)
+			}
+			f = val
+		case []interface{}:
+			idx, err := strconv.Atoi(part)
+			if err != nil || idx < 0 || idx >= len(v) {
+				return nil, fmt.Errorf("Invalid index %s for field %s in response", part, path)
+			}
+			f = v[idx]
+		default:
+			return nil, fmt.Errorf("Failed to find field %s in response", path)
+		}
+	}
+	if s, ok := f.(string); ok {
+		return []byte(s), nil
+	}
+	return json.Marshal(f)
+}
@@ -0,0 +1,72 @@
+package kafka
+
+import (
+	"testing"
+
+	"github.com/cloudevents/sdk-go/pkg/bindings/http"
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	. "github.com/onsi/gomega"
+	"github.com/seldonio/seldon-core/executor/api/grpc/kfserving/inference"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	"github.com/seldonio/seldon-core/executor/api/rest"
+)
+
+func headerValues(headers []kafka.Header) map[string]string {
+	values := make(map[string]string)
+	for _, header := range headers {
+		values[header.Key] = string(header.Value)
+	}
+	return values
+}
+
+func TestCreateOutputHeaders(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	input := &kafka.Message{Headers: []kafka.Header{
+		{Key: "correlation-id", Value: []byte("abc")},
+		{Key: "other", Value: []byte("x")},
+		{Key: http.ContentType, Value: []byte("text/plain")},
+		{Key: "content-type", Value: []byte("text/plain")},
+		{Key: "seldon-puid", Value: []byte("2")},
+	}}
+	res := &payload.BytesPayload{Msg: []byte(`{}`), ContentType: rest.ContentTypeJSON}
+
+	headers := headerValues(OutputConfig{}.createOutputHeaders(input, "1", res))
+	g.Expect(headers).To(Equal(map[string]string{payload.SeldonPUIDHeader: "1", http.ContentType: rest.ContentTypeJSON}))
+
+	headers = headerValues(OutputConfig{Headers: ParseHeaderPassthrough("Correlation-Id, ")}.createOutputHeaders(input, "1", res))
+	g.Expect(headers).To(HaveKeyWithValue("correlation-id", "abc"))
+	g.Expect(headers).ToNot(HaveKey("other"))
+
+	headers = headerValues(OutputConfig{Headers: []string{"*"}}.createOutputHeaders(input, "1", res))
+	g.Expect(headers).To(HaveKey("other"))
+	g.Expect(headers).To(HaveKeyWithValue(http.ContentType, rest.ContentTypeJSON))
+	g.Expect(headers).ToNot(HaveKey("content-type"))
+	g.Expect(headers).ToNot(HaveKey("seldon-puid"))
+}
+
+func TestOutputKey(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	input := &kafka.Message{Key: []byte("input-key")}
+	res := &payload.BytesPayload{Msg: []byte(`{"meta":{"tags":{"customer":"c1"}},"data":{"ndarray":[[7,8]]}}`), ContentType: rest.ContentTypeJSON}
+
+	key, err := OutputConfig{}.outputKey(input, res)
+	g.Expect(err).To(BeNil())
+	g.Expect(key).To(Equal([]byte("input-key")))
+
+	key, err = OutputConfig{KeyField: "meta.tags.customer"}.outputKey(input, res)
+	g.Expect(err).To(BeNil())
+	g.Expect(key).To(Equal([]byte("c1")))
+
+	key, err = OutputConfig{KeyField: "data.ndarray.0.1"}.outputKey(input, res)
+	g.Expect(err).To(BeNil())
+	g.Expect(key).To(Equal([]byte("8")))
+
+	_, err = OutputConfig{KeyField: "meta.missing"}.outputKey(input, res)
+	g.Expect(err).ToNot(BeNil())
+
+	key, err = OutputConfig{KeyField: "model_name"}.outputKey(input, &payload.ProtoPayload{Msg: &inference.ModelInferResponse{ModelName: "m1"}})
+	g.Expect(err).To(BeNil())
+	g.Expect(key).To(Equal([]byte("m1")))
+}
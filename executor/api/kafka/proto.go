@@ -0,0 +1,16 @@
+package kafka
+
+import (
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/seldonio/seldon-core/executor/api/messaging"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+)
+
+// createProtoHeaders adds the proto name header for protobuf payloads so consumers know which message to decode.
+func createProtoHeaders(msg payload.SeldonPayload) []kafka.Header {
+	headers := make([]kafka.Header, 0)
+	if protoName := messaging.ProtoName(msg); protoName != "" {
+		headers = append(headers, kafka.Header{Key: KeyProtoName, Value: []byte(protoName)})
+	}
+	return headers
+}
@@ -2,15 +2,19 @@ package kafka
 
 import (
 	"context"
+	"fmt"
 	"github.com/cloudevents/sdk-go/pkg/bindings/http"
 	"github.com/confluentinc/confluent-kafka-go/kafka"
 	"github.com/go-logr/logr"
 	"github.com/seldonio/seldon-core/executor/api/client"
+	"github.com/seldonio/seldon-core/executor/api/messaging"
+	"github.com/seldonio/seldon-core/executor/api/metric"
 	"github.com/seldonio/seldon-core/executor/api/payload"
 	"github.com/seldonio/seldon-core/executor/api/rest"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 )
 
 type KafkaProxy struct {
@@ -23,6 +27,7 @@ type KafkaProxy struct {
 	Hostname       string
 	Port           int32
 	Log            logr.Logger
+	metrics        *metric.KafkaMetrics
 }
 
 func NewKafkaProxy(client client.SeldonApiClient, modelName, predictorName, deploymentName, namespace, broker, hostname string, port int32, log logr.Logger) *KafkaProxy {
@@ -36,6 +41,7 @@ func NewKafkaProxy(client client.SeldonApiClient, modelName, predictorName, depl
 		Hostname:       hostname,
 		Port:           port,
 		Log:            log,
+		metrics:        metric.NewKafkaMetrics(deploymentName, predictorName),
 	}
 }
 
@@ -75,6 +81,10 @@ func (kp *KafkaProxy) Consume() error {
 	}
 	kp.Log.Info("Subscribed", "topic", kp.getTopicIn())
 
+	lagStop := make(chan struct{})
+	defer close(lagStop)
+	go watchConsumerLag(c, kp.metrics, lagStop, kp.Log)
+
 	run := true
 	sigchan := make(chan os.Signal, 1)
 	signal.Notify(sigchan, syscall.SIGINT, syscall.SIGTERM)
@@ -92,90 +102,7 @@ func (kp *KafkaProxy) Consume() error {
 
 			switch e := ev.(type) {
 			case *kafka.Message:
-				kp.Log.Info("Message", "Partition", e.TopicPartition)
-				if e.Headers != nil {
-					kp.Log.Info("Received", "headers", e.Headers)
-				}
-
-				puid := ""
-				responseTopic := ""
-				method := ""
-				for _, header := range e.Headers {
-					switch header.Key {
-					case payload.SeldonPUIDHeader:
-						puid = string(header.Value)
-					case KeyTopicResponse:
-						responseTopic = string(header.Value)
-					case KeyMethod:
-						method = string(header.Value)
-					default:
-						kp.Log.Info("Skipping", "header", string(header.Value))
-					}
-				}
-				kp.Log.Info("Extracted headers", payload.SeldonPUIDHeader, puid, KeyTopicResponse, responseTopic, KeyMethod, method)
-				if responseTopic == "" {
-					responseTopic = kp.getDefaultTopicResponse()
-				}
-				if puid == "" {
-					kp.Log.Info("No puid found")
-					puid = "0"
-				}
-				if method == "" {
-					kp.Log.Info("No method found will use default")
-					method = client.SeldonPredictPath
-				}
-				kp.Log.Info("Extracted headers with defaults", payload.SeldonPUIDHeader, puid, KeyTopicResponse, responseTopic, KeyMethod, method)
-
-				headers := collectHeaders(e.Headers)
-				ctx := context.Background()
-				// Add Seldon Puid to Context
-				ctx = context.WithValue(ctx, payload.SeldonPUIDHeader, puid)
-
-				// Assume JSON if no content type - should maybe be application/octet-stream?
-				contentType := rest.ContentTypeJSON
-				if ct, ok := headers[http.ContentType]; ok {
-					if len(ct) == 1 {
-						contentType = ct[0]
-					}
-				}
-				reqPayload, err := kp.Client.Unmarshall(e.Value, contentType)
-				if err != nil {
-					kp.Log.Error(err, "Failed to unmarshall Payload")
-					continue
-				}
-
-				var resPayload payload.SeldonPayload
-
-				switch method {
-				case client.SeldonPredictPath:
-					resPayload, err = kp.Client.Predict(ctx, kp.ModelName, kp.Hostname, kp.Port, reqPayload, headers)
-				case client.SeldonCombinePath:
-					msgs, err := rest.ExtractSeldonMessagesFromJson(reqPayload)
-					if err != nil {
-						kp.Log.Error(err, "Failed to extract Payload")
-						continue
-					}
-					resPayload, err = kp.Client.Combine(ctx, kp.ModelName, kp.Hostname, kp.Port, msgs, headers)
-				}
-
-				if err != nil {
-					kp.Log.Error(err, "Failed prediction")
-					continue
-				}
-				resBytes, err := resPayload.GetBytes()
-				if err != nil {
-					kp.Log.Error(err, "Failed to get bytes from prediction response")
-				}
-
-				err = p.Produce(&kafka.Message{
-					TopicPartition: kafka.TopicPartition{Topic: &responseTopic, Partition: kafka.PartitionAny},
-					Value:          resBytes,
-					Headers:        []kafka.Header{{Key: payload.SeldonPUIDHeader, Value: []byte(puid)}},
-				}, nil)
-				if err != nil {
-					kp.Log.Error(err, "Failed to produce response")
-				}
-				kp.Log.Info("Produced message", "topic", responseTopic)
+				kp.processMessage(p, e)
 
 			case kafka.Error:
 				// Errors should generally be considered
@@ -197,3 +124,109 @@ func (kp *KafkaProxy) Consume() error {
 	c.Close()
 	return nil
 }
+
+// processMessage calls the model with a request from the model topic and produces its response to the reply topic.
+func (kp *KafkaProxy) processMessage(p *kafka.Producer, e *kafka.Message) {
+	kp.Log.Info("Message", "Partition", e.TopicPartition)
+	start := time.Now()
+	kp.metrics.MessageConsumed(kp.getTopicIn())
+	if e.Headers != nil {
+		kp.Log.Info("Received", "headers", e.Headers)
+	}
+
+	puid := ""
+	responseTopic := ""
+	method := ""
+	for _, header := range e.Headers {
+		switch header.Key {
+		case payload.SeldonPUIDHeader:
+			puid = string(header.Value)
+		case KeyTopicResponse:
+			responseTopic = string(header.Value)
+		case KeyMethod:
+			method = string(header.Value)
+		default:
+			kp.Log.Info("Skipping", "header", string(header.Value))
+		}
+	}
+	kp.Log.Info("Extracted headers", payload.SeldonPUIDHeader, puid, KeyTopicResponse, responseTopic, KeyMethod, method)
+	if responseTopic == "" {
+		responseTopic = kp.getDefaultTopicResponse()
+	}
+	if puid == "" {
+		kp.Log.Info("No puid found")
+		puid = "0"
+	}
+	if method == "" {
+		kp.Log.Info("No method found will use default")
+		method = client.SeldonPredictPath
+	}
+	kp.Log.Info("Extracted headers with defaults", payload.SeldonPUIDHeader, puid, KeyTopicResponse, responseTopic, KeyMethod, method)
+
+	headers := collectHeaders(e.Headers)
+	ctx := context.Background()
+	// Add Seldon Puid to Context
+	ctx = context.WithValue(ctx, payload.SeldonPUIDHeader, puid)
+
+	// Apply tracing if active, continuing the trace of the executor which sent the request
+	var err error
+	ctx, finishSpan := startConsumerSpan(ctx, "kafkaProxy", e.Headers)
+	defer func() { finishSpan(err) }()
+
+	reqPayload, err := messaging.UnmarshallWithProtoName(kp.Client, headers, e.Value)
+	if err != nil {
+		kp.Log.Error(err, "Failed to unmarshall Payload")
+		kp.metrics.MessageFailed(kp.getTopicIn(), metric.KafkaStageUnmarshal)
+		return
+	}
+
+	var resPayload payload.SeldonPayload
+
+	switch method {
+	case client.SeldonPredictPath:
+		resPayload, err = kp.Client.Predict(ctx, kp.ModelName, kp.Hostname, kp.Port, reqPayload, headers)
+	case client.SeldonCombinePath:
+		var msgs []payload.SeldonPayload
+		msgs, err = rest.ExtractSeldonMessagesFromJson(reqPayload)
+		if err != nil {
+			kp.Log.Error(err, "Failed to extract Payload")
+			kp.metrics.MessageFailed(kp.getTopicIn(), metric.KafkaStageUnmarshal)
+			return
+		}
+		resPayload, err = kp.Client.Combine(ctx, kp.ModelName, kp.Hostname, kp.Port, msgs, headers)
+	default:
+		err = fmt.Errorf("unknown method %s", method)
+	}
+	if err == nil && resPayload == nil {
+		err = fmt.Errorf("no response from %s for method %s", kp.ModelName, method)
+	}
+
+	if err != nil {
+		kp.Log.Error(err, "Failed prediction", "method", method)
+		kp.metrics.MessageFailed(kp.getTopicIn(), metric.KafkaStagePredict)
+		return
+	}
+	resBytes, err := resPayload.GetBytes()
+	if err != nil {
+		kp.Log.Error(err, "Failed to get bytes from prediction response")
+		kp.metrics.MessageFailed(kp.getTopicIn(), metric.KafkaStageProduce)
+		return
+	}
+
+	err = p.Produce(&kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &responseTopic, Partition: kafka.PartitionAny},
+		Value:          resBytes,
+		Headers: injectTraceHeaders(ctx, append(createProtoHeaders(resPayload),
+			kafka.Header{Key: payload.SeldonPUIDHeader, Value: []byte(puid)},
+			kafka.Header{Key: http.ContentType, Value: []byte(resPayload.GetContentType())},
+		)),
+	}, nil)
+	if err != nil {
+		kp.Log.Error(err, "Failed to produce response")
+		kp.metrics.MessageFailed(kp.getTopicIn(), metric.KafkaStageProduce)
+		return
+	}
+	kp.metrics.MessageProduced(responseTopic)
+	kp.metrics.MessageProcessed(kp.getTopicIn(), start)
+	kp.Log.Info("Produced message", "topic", responseTopic)
+}
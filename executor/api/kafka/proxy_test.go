@@ -0,0 +1,42 @@
+package kafka
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/seldonio/seldon-core/executor/api/client"
+	"github.com/seldonio/seldon-core/executor/api/metric"
+	"github.com/seldonio/seldon-core/executor/api/test"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func TestProxyCombineError(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	producer, err := kafka.NewProducer(&kafka.ConfigMap{"bootstrap.servers": "localhost:0"})
+	g.Expect(err).To(BeNil())
+	defer producer.Close()
+
+	aggregate := v1.AGGREGATE
+	kp := NewKafkaProxy(test.SeldonMessageTestClient{ErrMethod: &aggregate, Err: errors.New("combine failed")}, "combiner", "p-combine", "dep", "default", "localhost:0", "localhost", 9000, logf.Log)
+	kp.processMessage(producer, &kafka.Message{
+		Value: []byte(`[{"data":{"ndarray":[1]}},{"data":{"ndarray":[2]}}]`),
+		Headers: []kafka.Header{
+			{Key: KeyMethod, Value: []byte(client.SeldonCombinePath)},
+		},
+	})
+
+	g.Expect(testutil.ToFloat64(kp.metrics.Failures.WithLabelValues("dep", "p-combine", kp.getTopicIn(), metric.KafkaStagePredict))).To(Equal(1.0))
+	g.Expect(testutil.ToFloat64(kp.metrics.Produced.WithLabelValues("dep", "p-combine", kp.getDefaultTopicResponse()))).To(Equal(0.0))
+
+	kp.processMessage(producer, &kafka.Message{
+		Value:   []byte(`{}`),
+		Headers: []kafka.Header{{Key: KeyMethod, Value: []byte("unknown")}},
+	})
+	g.Expect(testutil.ToFloat64(kp.metrics.Failures.WithLabelValues("dep", "p-combine", kp.getTopicIn(), metric.KafkaStagePredict))).To(Equal(2.0))
+	g.Expect(testutil.ToFloat64(kp.metrics.Produced.WithLabelValues("dep", "p-combine", kp.getDefaultTopicResponse()))).To(Equal(0.0))
+}
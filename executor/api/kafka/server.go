@@ -5,19 +5,19 @@ import (
 	"net/url"
 	"os"
 	"os/signal"
-	"reflect"
+	"sync"
 	"syscall"
 	"time"
 
-	"github.com/cloudevents/sdk-go/pkg/bindings/http"
 	"github.com/confluentinc/confluent-kafka-go/kafka"
 	"github.com/go-logr/logr"
-	proto2 "github.com/golang/protobuf/proto"
-	guuid "github.com/google/uuid"
 	"github.com/seldonio/seldon-core/executor/api"
 	"github.com/seldonio/seldon-core/executor/api/client"
+	"github.com/seldonio/seldon-core/executor/api/grpc/kfserving"
 	"github.com/seldonio/seldon-core/executor/api/grpc/seldon"
 	"github.com/seldonio/seldon-core/executor/api/grpc/tensorflow"
+	"github.com/seldonio/seldon-core/executor/api/messaging"
+	"github.com/seldonio/seldon-core/executor/api/metric"
 	"github.com/seldonio/seldon-core/executor/api/payload"
 	"github.com/seldonio/seldon-core/executor/api/rest"
 	"github.com/seldonio/seldon-core/executor/api/util"
@@ -28,6 +28,8 @@ import (
 const (
 	kafkaPayloadJson  = "json"
 	kafkaPayloadProto = "proto"
+
+	drainFlushTimeoutMs = 10000
 )
 
 const (
@@ -46,7 +48,7 @@ type SeldonKafkaServer struct {
 	DeploymentName  string
 	Namespace       string
 	Transport       string
-	Predictor       *v1.PredictorSpec
+	predictorStore  *predictor.PredictorStore
 	Broker          string
 	TopicIn         string
 	TopicOut        string
@@ -56,6 +58,12 @@ type SeldonKafkaServer struct {
 	Protocol        string
 	FullHealthCheck bool
 	AutoCommit      bool
+	DeadLetter      DeadLetterConfig
+	Output          OutputConfig
+	offsets         *offsetTracker
+	metrics         *metric.KafkaMetrics
+	produceLock     sync.RWMutex
+	stopping        bool
 }
 
 func NewKafkaServer(
@@ -67,34 +75,41 @@ func NewKafkaServer(
 	transport string,
 	annotations map[string]string,
 	serverUrl *url.URL,
-	predictor *v1.PredictorSpec,
+	spec *v1.PredictorSpec,
 	broker,
 	topicIn,
 	topicOut string,
 	log logr.Logger,
 	fullHealthCheck bool,
 	autoCommit bool,
+	deadLetter DeadLetterConfig,
+	output OutputConfig,
 ) (*SeldonKafkaServer, error) {
 	var apiClient client.SeldonApiClient
 	var err error
 
 	if fullGraph {
 		log.Info("Starting full graph kafka server")
-		apiClient = NewKafkaClient(serverUrl.Hostname(), deploymentName, namespace, protocol, transport, predictor, broker, log)
+		apiClient = NewKafkaClient(serverUrl.Hostname(), deploymentName, namespace, protocol, transport, spec, broker, annotations, log)
 	} else {
 		switch transport {
 		case api.TransportRest:
 			log.Info("Start http kafka graph")
-			apiClient, err = rest.NewJSONRestClient(protocol, deploymentName, predictor, annotations)
+			apiClient, err = rest.NewJSONRestClient(protocol, deploymentName, spec, annotations)
 			if err != nil {
 				return nil, err
 			}
 		case api.TransportGrpc:
 			log.Info("Start grpc kafka graph")
-			if protocol == "seldon" {
-				apiClient = seldon.NewSeldonGrpcClient(predictor, deploymentName, annotations)
-			} else {
-				apiClient = tensorflow.NewTensorflowGrpcClient(predictor, deploymentName, annotations)
+			switch protocol {
+			case api.ProtocolSeldon:
+				apiClient = seldon.NewSeldonGrpcClient(spec, deploymentName, annotations)
+			case api.ProtocolTensorflow:
+				apiClient = tensorflow.NewTensorflowGrpcClient(spec, deploymentName, annotations)
+			case api.ProtocolV2, api.ProtocolKFServing:
+				apiClient = kfserving.NewKFServingGrpcClient(spec, deploymentName, annotations)
+			default:
+				return nil, fmt.Errorf("Unknown protocol %s", protocol)
 			}
 		default:
 			return nil, fmt.Errorf("Unknown transport %s", transport)
@@ -104,10 +119,10 @@ func NewKafkaServer(
 	var producerConfig *kafka.ConfigMap
 	if broker != "" {
 		producerConfig = util.GetKafkaProducerConfig(broker)
-	}
-
-	if !autoCommit && workers > 1 {
-		log.Info("Disabling auto commit for kafka can have undesired side effects with multiple workers")
+		// Without auto commit offsets are only committed once delivery of the output is confirmed
+		if !autoCommit {
+			producerConfig.SetKey("go.delivery.reports", true)
+		}
 	}
 
 	// Create Producer
@@ -124,7 +139,7 @@ func NewKafkaServer(
 		DeploymentName:  deploymentName,
 		Namespace:       namespace,
 		Transport:       transport,
-		Predictor:       predictor,
+		predictorStore:  predictor.NewPredictorStore(spec),
 		Broker:          broker,
 		TopicIn:         topicIn,
 		TopicOut:        topicOut,
@@ -134,43 +149,37 @@ func NewKafkaServer(
 		Protocol:        protocol,
 		FullHealthCheck: fullHealthCheck,
 		AutoCommit:      autoCommit,
+		DeadLetter:      deadLetter,
+		Output:          output,
+		offsets:         newOffsetTracker(),
+		metrics:         metric.NewKafkaMetrics(deploymentName, spec.Name),
 	}, nil
 }
 
+// SetPredictorStore makes consumed requests run through the graph in store.
+func (ks *SeldonKafkaServer) SetPredictorStore(store *predictor.PredictorStore) {
+	ks.predictorStore = store
+}
+
 func (ks *SeldonKafkaServer) getGroupName() string {
-	return ks.Predictor.Name + "." + ks.DeploymentName + "." + ks.Namespace
+	return ks.predictorStore.Get().Name + "." + ks.DeploymentName + "." + ks.Namespace
 }
 
 func collectHeaders(headers []kafka.Header) map[string][]string {
 	sheaders := make(map[string][]string)
-	foundPuid := false
-	if headers != nil {
-		for _, header := range headers {
-			if header.Key == payload.SeldonPUIDHeader {
-				foundPuid = true
-			}
-			if _, ok := sheaders[header.Key]; ok {
-				sheaders[header.Key] = append(sheaders[header.Key], string(header.Value))
-			} else {
-				sheaders[header.Key] = []string{string(header.Value)}
-			}
-		}
-	}
-	// PUID if not found
-	if !foundPuid {
-		sheaders[payload.SeldonPUIDHeader] = []string{guuid.New().String()}
+	for _, header := range headers {
+		sheaders[header.Key] = append(sheaders[header.Key], string(header.Value))
 	}
+	messaging.AddPuid(sheaders)
 	return sheaders
 }
 
-func getProto(messageType string, messageBytes []byte) (proto2.Message, error) {
-	pbtype := proto2.MessageType(messageType)
-	msg := reflect.New(pbtype.Elem()).Interface().(proto2.Message)
-	err := proto2.Unmarshal(messageBytes, msg)
-	return msg, err
-}
-
 func (ks *SeldonKafkaServer) Serve() error {
+	tlsConfig, err := util.GetClientTLSConfig(ks.predictorStore.Get())
+	if err != nil {
+		return err
+	}
+
 	consumerConfig := util.GetKafkaConsumerConfig(ks.Broker, ks.AutoCommit, ks.getGroupName())
 	c, err := kafka.NewConsumer(consumerConfig)
 	if err != nil {
@@ -180,25 +189,36 @@ func (ks *SeldonKafkaServer) Serve() error {
 	ks.Consumer = c
 	ks.Log.Info("Created", "consumer", c.String(), "consumer group", ks.getGroupName(), "topic", ks.TopicIn)
 
-	err = c.SubscribeTopics([]string{ks.TopicIn}, nil)
+	err = c.SubscribeTopics([]string{ks.TopicIn}, ks.rebalance)
 	if err != nil {
 		return err
 	}
 
+	lagStop := make(chan struct{})
+	go watchConsumerLag(c, ks.metrics, lagStop, ks.Log)
+
+	deliveryDone := make(chan struct{})
+	if !ks.AutoCommit {
+		go ks.handleDeliveries(deliveryDone)
+	} else {
+		close(deliveryDone)
+	}
+
 	run := true
 	sigchan := make(chan os.Signal, 1)
 	signal.Notify(sigchan, syscall.SIGINT, syscall.SIGTERM)
 
-	cancelChan := make(chan struct{})
+	workers := sync.WaitGroup{}
 	jobChan := make(chan *KafkaJob, ks.Workers)
 	for i := 0; i < ks.Workers; i++ {
-		go ks.worker(jobChan, cancelChan)
+		workers.Add(1)
+		go ks.worker(jobChan, &workers)
 	}
 
 	//wait for graph to be ready
 	ready := false
 	for ready == false {
-		err := predictor.Ready(ks.Protocol, &ks.Predictor.Graph, ks.FullHealthCheck)
+		err := predictor.Ready(ks.Protocol, &ks.predictorStore.Get().Graph, ks.FullHealthCheck, tlsConfig)
 		ready = err == nil
 		if !ready {
 			ks.Log.Info("Waiting for graph to be ready")
@@ -214,6 +234,7 @@ func (ks *SeldonKafkaServer) Serve() error {
 			run = false
 		default:
 			ev := c.Poll(100)
+			ks.metrics.SetQueueDepth(ks.TopicIn, len(jobChan))
 			if ev == nil {
 				continue
 			}
@@ -224,47 +245,25 @@ func (ks *SeldonKafkaServer) Serve() error {
 				if cnt%1000 == 0 {
 					ks.Log.Info("Processed", "messages", cnt)
 				}
+				ks.metrics.MessageConsumed(ks.TopicIn)
 				headers := collectHeaders(e.Headers)
 
-				var reqPayload payload.SeldonPayload
-				var err error
-				switch ks.Transport {
-				case api.TransportRest:
-					// Assume JSON if no content type - should maybe be application/octet-stream?
-					contentType := rest.ContentTypeJSON
-					if ct, ok := headers[http.ContentType]; ok {
-						if len(ct) == 1 {
-							contentType = ct[0]
-						}
-					}
-					reqPayload, err = ks.Client.Unmarshall(e.Value, contentType)
-					if err != nil {
-						ks.Log.Error(err, "Failed to unmarshall Payload")
-						continue
-					}
-				case api.TransportGrpc:
-					if val, ok := headers[KeyProtoName]; ok && len(val) == 1 {
-						protoName := val[0]
-						proto, err := getProto(protoName, e.Value)
-						if err != nil {
-							ks.Log.Error(err, "Failed to get proto from bytes")
-							continue
-						}
-						reqPayload = &payload.ProtoPayload{Msg: proto}
-
-					} else {
-						ks.Log.Info("Failed to find proto name in headers")
-						continue
-					}
-
+				reqPayload, err := messaging.UnmarshallRequest(ks.Client, ks.Transport, ks.Protocol, headers, e.Value)
+				if err != nil {
+					ks.unmarshalFailed(e, headers[payload.SeldonPUIDHeader][0], err)
+					continue
 				}
 
 				job := KafkaJob{
 					headers:    headers,
 					message:    e,
 					reqPayload: reqPayload,
+					start:      time.Now(),
 				}
 				// enqueue a job
+				if !ks.AutoCommit {
+					ks.offsets.add(e.TopicPartition)
+				}
 				jobChan <- &job
 
 			case kafka.Error:
@@ -284,8 +283,61 @@ func (ks *SeldonKafkaServer) Serve() error {
 	}
 
 	ks.Log.Info("Final Processed", "messages", cnt)
+	ks.drain(jobChan, &workers, deliveryDone)
+	close(lagStop)
 	ks.Log.Info("Closing consumer")
-	close(cancelChan)
 	c.Close()
 	return nil
 }
+
+// drain stops new work being consumed, waits for queued and in-flight jobs to finish and
+// then flushes the producer and commits consumed offsets before the consumer is closed.
+func (ks *SeldonKafkaServer) drain(jobChan chan *KafkaJob, workers *sync.WaitGroup, deliveryDone chan struct{}) {
+	ks.Log.Info("Draining workers", "queued", len(jobChan))
+	close(jobChan)
+	workers.Wait()
+
+	// Full graph requests have all finished so the topic consumers can be stopped
+	if kc, ok := ks.Client.(*KafkaClient); ok {
+		kc.Close()
+	}
+
+	remaining := ks.Producer.Flush(drainFlushTimeoutMs)
+	if remaining > 0 {
+		ks.Log.Info("Producer messages not delivered before shutdown", "remaining", remaining)
+	}
+
+	// Outputs still being retried are given up on, leaving their inputs to be processed again after the restart
+	ks.produceLock.Lock()
+	ks.stopping = true
+	ks.produceLock.Unlock()
+
+	if ks.AutoCommit {
+		if _, err := ks.Consumer.Commit(); err != nil {
+			if kerr, ok := err.(kafka.Error); !ok || kerr.Code() != kafka.ErrNoOffset {
+				ks.Log.Error(err, "Failed to commit offsets on shutdown")
+			}
+		}
+	} else {
+		// Closing the producer ends the delivery reports, after which the final offsets are committed
+		ks.Producer.Close()
+		<-deliveryDone
+	}
+}
+
+// rebalance commits the offsets of revoked partitions and stops tracking them. Jobs still in flight for
+// those partitions will be processed again by the new owner. The lag of revoked partitions is no longer reported.
+func (ks *SeldonKafkaServer) rebalance(c *kafka.Consumer, ev kafka.Event) error {
+	if e, ok := ev.(kafka.RevokedPartitions); ok {
+		if !ks.AutoCommit {
+			ks.commitOffsets()
+			ks.offsets.revoke(e.Partitions)
+		}
+		for _, tp := range e.Partitions {
+			if tp.Topic != nil {
+				ks.metrics.DeleteConsumerLag(*tp.Topic, tp.Partition)
+			}
+		}
+	}
+	return nil
+}
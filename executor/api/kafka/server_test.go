@@ -1,11 +1,25 @@
 package kafka
 
 import (
+	"context"
+	"fmt"
+	"github.com/confluentinc/confluent-kafka-go/kafka"
 	"github.com/golang/protobuf/jsonpb"
 	"github.com/golang/protobuf/proto"
 	. "github.com/onsi/gomega"
+	"github.com/seldonio/seldon-core/executor/api"
+	"github.com/seldonio/seldon-core/executor/api/grpc/kfserving/inference"
 	seldon "github.com/seldonio/seldon-core/executor/api/grpc/seldon/proto"
+	"github.com/seldonio/seldon-core/executor/api/messaging"
+	"github.com/seldonio/seldon-core/executor/api/metric"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	"github.com/seldonio/seldon-core/executor/api/rest"
+	"github.com/seldonio/seldon-core/executor/predictor"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	"net/url"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"testing"
+	"time"
 )
 
 func TestGetProtoSeldonMessage(t *testing.T) {
@@ -18,8 +32,110 @@ func TestGetProtoSeldonMessage(t *testing.T) {
 	b, err := proto.Marshal(&sm)
 	g.Expect(err).To(BeNil())
 
-	sm2, err := getProto("seldon.protos.SeldonMessage", b)
+	sm2, err := messaging.GetProto("seldon.protos.SeldonMessage", b)
 	g.Expect(err).To(BeNil())
 
 	g.Expect(proto.Equal(sm2, &sm)).Should(Equal(true))
 }
+
+func TestGetProtoModelInferRequest(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	req := &inference.ModelInferRequest{
+		ModelName: "model",
+		Inputs: []*inference.ModelInferRequest_InferInputTensor{
+			{Name: "input", Datatype: "FP32", Shape: []int64{1, 2}, Contents: &inference.InferTensorContents{Fp32Contents: []float32{1.1, 2}}},
+		},
+	}
+	b, err := proto.Marshal(req)
+	g.Expect(err).To(BeNil())
+
+	msg, err := messaging.GetProto(messaging.DefaultProtoName(api.ProtocolV2), b)
+	g.Expect(err).To(BeNil())
+	g.Expect(proto.Equal(msg, req)).Should(Equal(true))
+
+	headers := createProtoHeaders(&payload.ProtoPayload{Msg: msg})
+	g.Expect(headers).To(HaveLen(1))
+	g.Expect(string(headers[0].Value)).To(Equal("inference.ModelInferRequest"))
+
+	_, err = messaging.GetProto("unknown.Message", b)
+	g.Expect(err).ToNot(BeNil())
+}
+
+func TestKafkaClientChainV2(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	kc := &KafkaClient{Protocol: api.ProtocolV2}
+
+	res, err := kc.Chain(context.Background(), "model", &payload.BytesPayload{Msg: []byte(`{"outputs":[{"name":"a","datatype":"FP32","shape":[1],"data":[1]}]}`), ContentType: rest.ContentTypeJSON})
+	g.Expect(err).To(BeNil())
+	b, err := res.GetBytes()
+	g.Expect(err).To(BeNil())
+	g.Expect(string(b)).To(ContainSubstring(`"inputs"`))
+
+	res, err = kc.Chain(context.Background(), "model", &payload.ProtoPayload{Msg: &inference.ModelInferResponse{
+		Outputs: []*inference.ModelInferResponse_InferOutputTensor{{Name: "a", Datatype: "FP32", Shape: []int64{1}}},
+	}})
+	g.Expect(err).To(BeNil())
+	req, ok := res.GetPayload().(*inference.ModelInferRequest)
+	g.Expect(ok).To(BeTrue())
+	g.Expect(req.ModelName).To(Equal("model"))
+	g.Expect(req.Inputs[0].Name).To(Equal("a"))
+}
+
+func TestFullGraphErrorReply(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	producer, err := kafka.NewProducer(&kafka.ConfigMap{"bootstrap.servers": "localhost:0", "message.timeout.ms": 100})
+	g.Expect(err).To(BeNil())
+	defer producer.Close()
+
+	// The full graph client has no topic handler for the model so the prediction fails
+	model := v1.MODEL
+	spec := &v1.PredictorSpec{Name: "p", Graph: v1.PredictiveUnit{Name: "model", Type: &model, Endpoint: &v1.Endpoint{ServiceHost: "localhost", ServicePort: 9000}}}
+	ks := &SeldonKafkaServer{
+		Client:         &KafkaClient{Protocol: api.ProtocolSeldon, predictor: spec, Log: logf.Log, topicHandlers: map[string]*KafkaRPC{}},
+		Producer:       producer,
+		predictorStore: predictor.NewPredictorStore(spec),
+		TopicIn:        "input",
+		TopicOut:       "output",
+		ServerUrl:      &url.URL{},
+		Log:            logf.Log,
+		Protocol:       api.ProtocolSeldon,
+		DeadLetter:     DeadLetterConfig{ErrorReplies: true},
+		offsets:        newOffsetTracker(),
+		metrics:        metric.NewKafkaMetrics("dep", spec.Name),
+	}
+	topicIn := "input"
+	ks.processKafkaRequest(&KafkaJob{
+		headers:    map[string][]string{payload.SeldonPUIDHeader: {"1"}},
+		message:    &kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &topicIn}, Key: []byte("key")},
+		reqPayload: &payload.BytesPayload{Msg: []byte(`{"data":{"ndarray":[[1]]}}`), ContentType: rest.ContentTypeJSON},
+		start:      time.Now(),
+	})
+
+	var reply *kafka.Message
+	g.Eventually(func() bool {
+		select {
+		case ev := <-producer.Events():
+			reply, _ = ev.(*kafka.Message)
+		default:
+		}
+		return reply != nil
+	}, 10*time.Second, 10*time.Millisecond).Should(BeTrue())
+	g.Expect(*reply.TopicPartition.Topic).To(Equal("output"))
+	g.Expect(reply.Key).To(Equal([]byte("key")))
+	var sm seldon.SeldonMessage
+	g.Expect(jsonpb.UnmarshalString(string(reply.Value), &sm)).To(BeNil())
+	g.Expect(sm.Status.Status).To(Equal(seldon.Status_FAILURE))
+	g.Expect(sm.Status.Info).To(ContainSubstring("model"))
+}
+
+func TestKafkaClientCreateErrorPayload(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	kc := &KafkaClient{Protocol: api.ProtocolV2}
+	b, err := kc.CreateErrorPayload(fmt.Errorf("failed")).GetBytes()
+	g.Expect(err).To(BeNil())
+	g.Expect(string(b)).To(MatchJSON(`{"error":"failed"}`))
+}
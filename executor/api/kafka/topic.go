@@ -1,22 +1,21 @@
 package kafka
 
 import (
+	"context"
 	"fmt"
-	"github.com/cloudevents/sdk-go/pkg/bindings/http"
 	"github.com/confluentinc/confluent-kafka-go/kafka"
 	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/seldonio/seldon-core/executor/api/messaging"
+	"github.com/seldonio/seldon-core/executor/api/metric"
 	"github.com/seldonio/seldon-core/executor/api/payload"
-	"github.com/seldonio/seldon-core/executor/api/rest"
-	"os"
-	"os/signal"
 	"sync"
-	"syscall"
 )
 
 const (
 	KeyTopicResponse = "topic-response"
 	KeyMethod        = "seldon-method"
-	KeyProtoName     = "proto-name"
+	KeyProtoName     = messaging.HeaderProtoName
 )
 
 type KafkaRPC struct {
@@ -29,6 +28,7 @@ type KafkaRPC struct {
 	Receivers    map[string]chan<- payload.SeldonPayload
 	Lock         sync.RWMutex
 	Log          logr.Logger
+	outstanding  prometheus.Gauge
 }
 
 func getTopicReceiveForModel(modelName string, kc *KafkaClient) string {
@@ -41,6 +41,7 @@ func getTopicSendForModel(modelName string, kc *KafkaClient) string {
 
 func NewKafkaRPC(client *KafkaClient, modelName string) (*KafkaRPC, error) {
 	topicReceive := getTopicReceiveForModel(modelName, client)
+	topicSend := getTopicSendForModel(modelName, client)
 	// Set group name to be same as receive topic
 	groupId := topicReceive
 
@@ -56,11 +57,12 @@ func NewKafkaRPC(client *KafkaClient, modelName string) (*KafkaRPC, error) {
 		Producer:     p,
 		Broker:       client.Broker,
 		GroupId:      groupId,
-		TopicSend:    getTopicSendForModel(modelName, client),
+		TopicSend:    topicSend,
 		TopicReceive: topicReceive,
 		Receivers:    make(map[string]chan<- payload.SeldonPayload),
 		Lock:         sync.RWMutex{},
 		Log:          client.Log.WithName("KafkaRPC"),
+		outstanding:  metric.NewKafkaRPCOutstandingGauge().WithLabelValues(client.DeploymentName, client.predictor.Name, modelName, topicSend),
 	}, nil
 }
 
@@ -93,13 +95,11 @@ func (tp *KafkaRPC) start() {
 
 		tp.Log.Info("Created", "consumer", c.String(), "topic", tp.TopicReceive)
 		run := true
-		sigchan := make(chan os.Signal, 1)
-		signal.Notify(sigchan, syscall.SIGINT, syscall.SIGTERM)
 
 		for run == true {
 			select {
-			case sig := <-sigchan:
-				tp.Log.Info("Terminating", "signal", sig)
+			case <-tp.Client.ctx.Done():
+				tp.Log.Info("Terminating", "topic", tp.TopicReceive)
 				run = false
 			default:
 				ev := c.Poll(100)
@@ -112,15 +112,7 @@ func (tp *KafkaRPC) start() {
 					tp.Log.Info("Message", "Partition", e.TopicPartition)
 
 					headers := collectHeaders(e.Headers)
-
-					// Assume JSON if no content type - should maybe be application/octet-stream?
-					contentType := rest.ContentTypeJSON
-					if ct, ok := headers[http.ContentType]; ok {
-						if len(ct) == 1 {
-							contentType = ct[0]
-						}
-					}
-					msg, err := tp.Client.Unmarshall(e.Value, contentType)
+					msg, err := messaging.UnmarshallWithProtoName(tp.Client, headers, e.Value)
 					if err != nil {
 						tp.Log.Error(err, "Failed to unmarshal consume", "topic")
 					} else {
@@ -128,13 +120,18 @@ func (tp *KafkaRPC) start() {
 						if puid == "" {
 							tp.Log.Info("Failed to find puid in message", "topic", tp.TopicReceive)
 						} else {
-							tp.Lock.Lock()
+							tp.Lock.RLock()
 							if c, ok := tp.Receivers[puid]; ok {
-								c <- msg
+								// Receivers buffer a single response so duplicates are dropped rather than blocking the consumer
+								select {
+								case c <- msg:
+								default:
+									tp.Log.Info("Dropping duplicate response", "puid", puid)
+								}
 							} else {
 								tp.Log.Info("Failed to find receiver key for", "puid", puid)
 							}
-							tp.Lock.Unlock()
+							tp.Lock.RUnlock()
 						}
 					}
 
@@ -159,33 +156,57 @@ func (tp *KafkaRPC) start() {
 	}()
 }
 
-func (tp *KafkaRPC) call(msg []byte, puid string, method string) (payload.SeldonPayload, error) {
-	//add to receivers
-	c := make(chan payload.SeldonPayload)
+func (tp *KafkaRPC) addReceiver(puid string, c chan payload.SeldonPayload) {
 	tp.Lock.Lock()
+	defer tp.Lock.Unlock()
 	tp.Receivers[puid] = c
-	tp.Lock.Unlock()
+}
+
+// removeReceiver deletes the receiver for puid unless it has been replaced by a later call.
+func (tp *KafkaRPC) removeReceiver(puid string, c chan payload.SeldonPayload) {
+	tp.Lock.Lock()
+	defer tp.Lock.Unlock()
+	if existing, ok := tp.Receivers[puid]; ok && existing == c {
+		delete(tp.Receivers, puid)
+	}
+}
+
+// call sends msg to the model topic and waits for the response with the same puid. It gives up when ctx is done,
+// after the client timeout if ctx has no deadline, or when the client is closed.
+func (tp *KafkaRPC) call(ctx context.Context, msg []byte, headers []kafka.Header, puid string, method string) (res payload.SeldonPayload, err error) {
+	if _, ok := ctx.Deadline(); !ok && tp.Client.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, tp.Client.Timeout)
+		defer cancel()
+	}
+	//add to receivers
+	c := make(chan payload.SeldonPayload, 1)
+	tp.addReceiver(puid, c)
+	defer tp.removeReceiver(puid, c)
+	tp.outstanding.Inc()
+	defer tp.outstanding.Dec()
+	// The span covers the wait for the response so it shows the time taken by the model hop
+	headers, finishSpan := startProducerSpan(ctx, tp.TopicSend+" send", headers)
+	defer func() { finishSpan(err) }()
 	//produce msg with topic for reply in headers
-	err := tp.Producer.Produce(&kafka.Message{
+	err = tp.Producer.Produce(&kafka.Message{
 		TopicPartition: kafka.TopicPartition{Topic: &tp.TopicSend, Partition: kafka.PartitionAny},
 		Value:          msg,
-		Headers: []kafka.Header{
-			{Key: payload.SeldonPUIDHeader, Value: []byte(puid)},
-			{Key: KeyTopicResponse, Value: []byte(tp.TopicReceive)},
-			{Key: KeyMethod, Value: []byte(method)},
-		}}, nil)
+		Headers: append(headers,
+			kafka.Header{Key: payload.SeldonPUIDHeader, Value: []byte(puid)},
+			kafka.Header{Key: KeyTopicResponse, Value: []byte(tp.TopicReceive)},
+			kafka.Header{Key: KeyMethod, Value: []byte(method)},
+		)}, nil)
 	if err != nil {
 		tp.Log.Error(err, "Failed to produce request", "topic", tp.TopicSend)
 		return nil, err
 	}
 	//wait for response
-
-	sigchan := make(chan os.Signal, 1)
-	signal.Notify(sigchan, syscall.SIGINT, syscall.SIGTERM)
 	select {
-	case sig := <-sigchan:
-		tp.Log.Info("Terminating", "signal", sig)
-		return nil, fmt.Errorf("Terminated")
+	case <-tp.Client.ctx.Done():
+		return nil, fmt.Errorf("Kafka client closed waiting for response from %s", tp.TopicSend)
+	case <-ctx.Done():
+		return nil, fmt.Errorf("No response from %s for puid %s: %w", tp.TopicSend, puid, ctx.Err())
 	case res := <-c:
 		return res, nil
 	}
@@ -0,0 +1,65 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func TestKafkaRPCCall(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	kc := &KafkaClient{
+		Hostname:       "localhost",
+		DeploymentName: "dep",
+		Namespace:      "default",
+		predictor:      &v1.PredictorSpec{Name: "p1"},
+		Broker:         "localhost:0",
+		Log:            logf.Log,
+		Timeout:        time.Minute,
+		ctx:            ctx,
+		cancel:         cancel,
+	}
+	tp, err := NewKafkaRPC(kc, "model")
+	g.Expect(err).To(BeNil())
+	defer tp.Producer.Close()
+
+	// A response received for the call is returned
+	go func() {
+		for {
+			tp.Lock.RLock()
+			c, ok := tp.Receivers["1"]
+			tp.Lock.RUnlock()
+			if ok {
+				c <- &payload.BytesPayload{Msg: []byte("ok")}
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+	res, err := tp.call(context.Background(), []byte("{}"), nil, "1", "/predict")
+	g.Expect(err).To(BeNil())
+	g.Expect(res.GetPayload()).To(Equal([]byte("ok")))
+	g.Expect(tp.Receivers).To(BeEmpty())
+
+	// Calls without a response time out
+	kc.Timeout = 50 * time.Millisecond
+	_, err = tp.call(context.Background(), []byte("{}"), nil, "2", "/predict")
+	g.Expect(err).ToNot(BeNil())
+	g.Expect(tp.Receivers).To(BeEmpty())
+	g.Expect(testutil.ToFloat64(tp.outstanding)).To(Equal(0.0))
+
+	// Calls fail once the client is closed
+	kc.Timeout = time.Minute
+	kc.Close()
+	_, err = tp.call(context.Background(), []byte("{}"), nil, "3", "/predict")
+	g.Expect(err).ToNot(BeNil())
+	g.Expect(tp.Receivers).To(BeEmpty())
+}
@@ -0,0 +1,111 @@
+package kafka
+
+import (
+	"context"
+	"strings"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/seldonio/seldon-core/executor/api/tracing"
+)
+
+// headerCarrier reads and writes the trace context in kafka message headers. It is both an opentracing TextMap
+// and an OpenTelemetry TextMapCarrier so traces continue across topics with either backend.
+type headerCarrier struct {
+	headers *[]kafka.Header
+}
+
+func (c headerCarrier) Get(key string) string {
+	for _, header := range *c.headers {
+		if strings.EqualFold(header.Key, key) {
+			return string(header.Value)
+		}
+	}
+	return ""
+}
+
+// Set replaces any header with the key so a trace context copied from the input is not sent twice.
+func (c headerCarrier) Set(key string, value string) {
+	for i, header := range *c.headers {
+		if strings.EqualFold(header.Key, key) {
+			(*c.headers)[i].Value = []byte(value)
+			return
+		}
+	}
+	*c.headers = append(*c.headers, kafka.Header{Key: key, Value: []byte(value)})
+}
+
+func (c headerCarrier) Keys() []string {
+	keys := make([]string, 0, len(*c.headers))
+	for _, header := range *c.headers {
+		keys = append(keys, header.Key)
+	}
+	return keys
+}
+
+func (c headerCarrier) ForeachKey(handler func(key, val string) error) error {
+	for _, header := range *c.headers {
+		if err := handler(header.Key, string(header.Value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// startConsumerSpan starts the span of processing a message, continuing the trace in its headers if the producer
+// sent one. The returned function ends the span, recording any error.
+func startConsumerSpan(ctx context.Context, name string, headers []kafka.Header) (context.Context, func(error)) {
+	carrier := headerCarrier{headers: &headers}
+	if tracing.OpenTelemetryEnabled() {
+		ctx, span := tracing.StartConsumerSpan(ctx, name, carrier)
+		return ctx, func(err error) { tracing.EndSpan(span, err) }
+	}
+	if !opentracing.IsGlobalTracerRegistered() {
+		return ctx, func(error) {}
+	}
+	tracer := opentracing.GlobalTracer()
+	spanCtx, _ := tracer.Extract(opentracing.TextMap, carrier)
+	span := tracer.StartSpan(name, ext.RPCServerOption(spanCtx))
+	return opentracing.ContextWithSpan(ctx, span), func(err error) { finishSpan(span, err) }
+}
+
+// startProducerSpan starts the span of sending a message as a child of any span in ctx and adds its trace context
+// to the headers.
+func startProducerSpan(ctx context.Context, name string, headers []kafka.Header) ([]kafka.Header, func(error)) {
+	carrier := headerCarrier{headers: &headers}
+	if tracing.OpenTelemetryEnabled() {
+		_, span := tracing.StartProducerSpan(ctx, name, carrier)
+		return headers, func(err error) { tracing.EndSpan(span, err) }
+	}
+	if !opentracing.IsGlobalTracerRegistered() {
+		return headers, func(error) {}
+	}
+	tracer := opentracing.GlobalTracer()
+	opts := []opentracing.StartSpanOption{ext.SpanKindProducer}
+	if parent := opentracing.SpanFromContext(ctx); parent != nil {
+		opts = append(opts, opentracing.ChildOf(parent.Context()))
+	}
+	span := tracer.StartSpan(name, opts...)
+	tracer.Inject(span.Context(), opentracing.TextMap, carrier)
+	return headers, func(err error) { finishSpan(span, err) }
+}
+
+// injectTraceHeaders adds the trace context of ctx to the headers of a message produced.
+func injectTraceHeaders(ctx context.Context, headers []kafka.Header) []kafka.Header {
+	carrier := headerCarrier{headers: &headers}
+	if tracing.OpenTelemetryEnabled() {
+		tracing.Inject(ctx, carrier)
+	} else if span := opentracing.SpanFromContext(ctx); span != nil {
+		opentracing.GlobalTracer().Inject(span.Context(), opentracing.TextMap, carrier)
+	}
+	return headers
+}
+
+func finishSpan(span opentracing.Span, err error) {
+	if err != nil {
+		ext.Error.Set(span, true)
+		span.LogKV("error", err.Error())
+	}
+	span.Finish()
+}
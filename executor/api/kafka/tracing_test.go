@@ -0,0 +1,107 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	. "github.com/onsi/gomega"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/seldonio/seldon-core/executor/api/tracing"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func countHeaders(headers []kafka.Header, key string) int {
+	n := 0
+	for _, header := range headers {
+		if header.Key == key {
+			n++
+		}
+	}
+	return n
+}
+
+func TestKafkaTraceContextOpenTracing(t *testing.T) {
+	g := NewGomegaWithT(t)
+	tracer := mocktracer.New()
+	opentracing.SetGlobalTracer(tracer)
+	defer opentracing.SetGlobalTracer(opentracing.NoopTracer{})
+
+	parent := tracer.StartSpan("predict")
+	ctx := opentracing.ContextWithSpan(context.Background(), parent)
+	headers, finishProducer := startProducerSpan(ctx, "model send", []kafka.Header{{Key: "seldon-puid", Value: []byte("1")}})
+	finishProducer(nil)
+
+	_, finishConsumer := startConsumerSpan(context.Background(), "kafkaProxy", headers)
+	finishConsumer(nil)
+	parent.Finish()
+
+	spans := tracer.FinishedSpans()
+	g.Expect(spans).To(HaveLen(3))
+	producer, consumer := spans[0], spans[1]
+	g.Expect(producer.ParentID).To(Equal(parent.(*mocktracer.MockSpan).SpanContext.SpanID))
+	g.Expect(consumer.ParentID).To(Equal(producer.SpanContext.SpanID))
+	g.Expect(consumer.SpanContext.TraceID).To(Equal(producer.SpanContext.TraceID))
+}
+
+func TestKafkaTraceContextOpenTelemetry(t *testing.T) {
+	g := NewGomegaWithT(t)
+	recorder := tracetest.NewSpanRecorder()
+	tracing.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer tracing.SetTracerProvider(nil)
+
+	traceparent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	input := []kafka.Header{{Key: "traceparent", Value: []byte(traceparent)}}
+	ctx, finish := startConsumerSpan(context.Background(), "kafkaServer", input)
+
+	// The output copies the input headers so the trace context must be replaced, not added
+	output := injectTraceHeaders(ctx, append([]kafka.Header{}, input...))
+	finish(nil)
+
+	spans := recorder.Ended()
+	g.Expect(spans).To(HaveLen(1))
+	g.Expect(spans[0].SpanKind()).To(Equal(trace.SpanKindConsumer))
+	g.Expect(spans[0].SpanContext().TraceID().String()).To(Equal("4bf92f3577b34da6a3ce929d0e0e4736"))
+	g.Expect(spans[0].Parent().SpanID().String()).To(Equal("00f067aa0ba902b7"))
+	g.Expect(countHeaders(output, "traceparent")).To(Equal(1))
+	g.Expect(headerCarrier{headers: &output}.Get("traceparent")).To(ContainSubstring(spans[0].SpanContext().SpanID().String()))
+}
+
+func TestKafkaRPCCallSpan(t *testing.T) {
+	g := NewGomegaWithT(t)
+	recorder := tracetest.NewSpanRecorder()
+	tracing.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer tracing.SetTracerProvider(nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	kc := &KafkaClient{
+		Hostname:       "localhost",
+		DeploymentName: "dep",
+		Namespace:      "default",
+		predictor:      &v1.PredictorSpec{Name: "p1"},
+		Broker:         "localhost:0",
+		Log:            logf.Log,
+		Timeout:        50 * time.Millisecond,
+		ctx:            ctx,
+		cancel:         cancel,
+	}
+	tp, err := NewKafkaRPC(kc, "model")
+	g.Expect(err).To(BeNil())
+	defer tp.Producer.Close()
+
+	_, err = tp.call(context.Background(), []byte("{}"), nil, "1", "/predict")
+	g.Expect(err).ToNot(BeNil())
+
+	spans := recorder.Ended()
+	g.Expect(spans).To(HaveLen(1))
+	g.Expect(spans[0].Name()).To(Equal(tp.TopicSend + " send"))
+	g.Expect(spans[0].SpanKind()).To(Equal(trace.SpanKindProducer))
+	g.Expect(spans[0].Status().Code).To(Equal(codes.Error))
+}
@@ -2,10 +2,11 @@ package kafka
 
 import (
 	"context"
+	"sync"
+	"time"
 
 	"github.com/confluentinc/confluent-kafka-go/kafka"
-	"github.com/opentracing/opentracing-go"
-	"github.com/opentracing/opentracing-go/ext"
+	"github.com/seldonio/seldon-core/executor/api/metric"
 	"github.com/seldonio/seldon-core/executor/api/payload"
 	"github.com/seldonio/seldon-core/executor/predictor"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
@@ -15,71 +16,81 @@ type KafkaJob struct {
 	headers    map[string][]string
 	message    *kafka.Message
 	reqPayload payload.SeldonPayload
+	start      time.Time
 }
 
-func (ks *SeldonKafkaServer) worker(jobChan <-chan *KafkaJob, cancelChan <-chan struct{}) {
-	for {
-		select {
-		case <-cancelChan:
-			return
-
-		case job := <-jobChan:
-			ks.processKafkaRequest(job)
-		}
+// worker processes jobs until jobChan is closed so queued requests are completed on shutdown.
+func (ks *SeldonKafkaServer) worker(jobChan <-chan *KafkaJob, wg *sync.WaitGroup) {
+	defer wg.Done()
+	for job := range jobChan {
+		ks.processKafkaRequest(job)
 	}
 }
 
 func (ks *SeldonKafkaServer) processKafkaRequest(job *KafkaJob) {
 	ctx := context.Background()
+	puid := job.headers[payload.SeldonPUIDHeader][0]
 	// Add Seldon Puid to Context
-	ctx = context.WithValue(ctx, payload.SeldonPUIDHeader, job.headers[payload.SeldonPUIDHeader][0])
+	ctx = context.WithValue(ctx, payload.SeldonPUIDHeader, puid)
 
-	// Apply tracing if active
-	if opentracing.IsGlobalTracerRegistered() {
-		tracer := opentracing.GlobalTracer()
-		serverSpan := tracer.StartSpan("kafkaServer", ext.RPCServerOption(nil))
-		ctx = opentracing.ContextWithSpan(ctx, serverSpan)
-		defer serverSpan.Finish()
-	}
+	var resPayload payload.SeldonPayload
+	var err error
 
-	seldonPredictorProcess := predictor.NewPredictorProcess(ctx, ks.Client, logf.Log.WithName("KafkaClient"), ks.ServerUrl, ks.Namespace, job.headers, "")
+	// Apply tracing if active, continuing any trace of the producer
+	ctx, finishSpan := startConsumerSpan(ctx, "kafkaServer", job.message.Headers)
+	defer func() { finishSpan(err) }()
 
-	resPayload, err := seldonPredictorProcess.Predict(&ks.Predictor.Graph, job.reqPayload)
-	if err != nil {
-		ks.Log.Error(err, "Failed prediction")
-		return
+	attempts := 0
+	for {
+		attempts++
+		seldonPredictorProcess := predictor.NewPredictorProcess(ctx, ks.Client, logf.Log.WithName("KafkaClient"), ks.ServerUrl, ks.Namespace, job.headers, "")
+		resPayload, err = seldonPredictorProcess.Predict(&ks.predictorStore.Get().Graph, job.reqPayload)
+		if err == nil {
+			break
+		}
+		if attempts > ks.DeadLetter.MaxRetries {
+			ks.Log.Error(err, "Failed prediction", "puid", puid, "node", seldonPredictorProcess.FailedNode, "attempts", attempts)
+			ks.metrics.MessageFailed(ks.TopicIn, metric.KafkaStagePredict)
+			if !ks.handleFailure(ctx, job, puid, err, seldonPredictorProcess.FailedNode, attempts) {
+				// Nothing was produced for the request so it is finished with
+				ks.done(job.message)
+			}
+			return
+		}
+		backoff := ks.DeadLetter.backoff(attempts)
+		ks.Log.Info("Retrying failed prediction", "puid", puid, "attempt", attempts, "backoff", backoff.String(), "error", err.Error())
+		time.Sleep(backoff)
 	}
 	resBytes, err := resPayload.GetBytes()
 	if err != nil {
 		ks.Log.Error(err, "Failed to get bytes from prediction response")
+		ks.metrics.MessageFailed(ks.TopicIn, metric.KafkaStageProduce)
+		ks.done(job.message)
 		return
 	}
 
-	kafkaHeaders := make([]kafka.Header, 0)
-	// Could in the future add the proto message name. At present seems we need to know the class to cast to so would need to do
-	// an exhaustive check, e.g. check its a tensorflow_serving.predict_pb2.PredictResponse, etc
-	//if ks.Transport == api.TransportGrpc {
-	//	kafkaHeaders = []kafka.Header{{Key: KeyProtoName, Value: []byte(proto2.MessageName(*resPayload.GetPayload().(*proto2.Message)))}}
-	//}
-
-	err = ks.Producer.Produce(&kafka.Message{
-		TopicPartition: kafka.TopicPartition{Topic: &ks.TopicOut, Partition: kafka.PartitionAny},
-		Key:            job.message.Key,
-		Value:          resBytes,
-		Headers:        kafkaHeaders,
-	}, nil)
-
+	key, err := ks.Output.outputKey(job.message, resPayload)
 	if err != nil {
-		ks.Log.Error(err, "Failed to produce response")
+		ks.Log.Error(err, "Failed to get output key from response, using input key", "field", ks.Output.KeyField)
+		key = job.message.Key
 	}
 
-	// Commit the messages here
-	if !ks.AutoCommit {
-		_, err = ks.Consumer.CommitMessage(job.message)
+	ks.produce(&delivery{
+		msg: &kafka.Message{
+			TopicPartition: kafka.TopicPartition{Topic: &ks.TopicOut, Partition: kafka.PartitionAny},
+			Key:            key,
+			Value:          resBytes,
+			Headers:        injectTraceHeaders(ctx, ks.Output.createOutputHeaders(job.message, puid, resPayload)),
+		},
+		input: job.message,
+		puid:  puid,
+	})
+	ks.metrics.MessageProcessed(ks.TopicIn, job.start)
+}
 
-		if err != nil {
-			ks.Log.Error(err, "Failed to commit offsets")
-		}
+// done marks an input message finished without waiting on a delivery report
+func (ks *SeldonKafkaServer) done(input *kafka.Message) {
+	if !ks.AutoCommit {
+		ks.offsets.done(input.TopicPartition)
 	}
-
 }
@@ -0,0 +1,123 @@
+// Package messaging holds the request handling shared by the servers consuming requests from a message broker.
+package messaging
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/cloudevents/sdk-go/pkg/bindings/http"
+	proto2 "github.com/golang/protobuf/proto"
+	guuid "github.com/google/uuid"
+	"github.com/seldonio/seldon-core/executor/api"
+	"github.com/seldonio/seldon-core/executor/api/client"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	"github.com/seldonio/seldon-core/executor/api/rest"
+)
+
+const (
+	// HeaderProtoName names the protobuf message of a payload so consumers know which message to decode.
+	HeaderProtoName = "proto-name"
+
+	// Headers added to requests sent to a dead-letter topic or subject.
+	HeaderError     = "Seldon-Error"
+	HeaderErrorNode = "Seldon-Error-Node"
+	HeaderAttempts  = "Seldon-Attempts"
+
+	protoNameModelInferRequest = "inference.ModelInferRequest"
+)
+
+var errNoProtoName = errors.New("Failed to find proto name in headers")
+
+// Get returns the first value of the header key, matching its name whatever the case.
+func Get(headers map[string][]string, key string) string {
+	if values, ok := headers[key]; ok && len(values) > 0 {
+		return values[0]
+	}
+	for k, values := range headers {
+		if strings.EqualFold(k, key) && len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
+
+// AddPuid sets the PUID header under its canonical name, with a new PUID if the message didn't come with one.
+func AddPuid(headers map[string][]string) {
+	puid := Get(headers, payload.SeldonPUIDHeader)
+	if puid == "" {
+		puid = guuid.New().String()
+	}
+	headers[payload.SeldonPUIDHeader] = []string{puid}
+}
+
+// ContentType returns the content type in headers, assuming JSON if there is none.
+func ContentType(headers map[string][]string) string {
+	if contentType := Get(headers, http.ContentType); contentType != "" {
+		return contentType
+	}
+	return rest.ContentTypeJSON
+}
+
+// DefaultProtoName is the proto assumed for gRPC requests which arrive without a proto name header.
+func DefaultProtoName(protocol string) string {
+	switch protocol {
+	case api.ProtocolV2, api.ProtocolKFServing:
+		return protoNameModelInferRequest
+	}
+	return ""
+}
+
+// ProtoName returns the name of the message of protobuf payloads and an empty string for others.
+func ProtoName(msg payload.SeldonPayload) string {
+	if pm, ok := msg.GetPayload().(proto2.Message); ok {
+		return proto2.MessageName(pm)
+	}
+	return ""
+}
+
+// GetProto decodes messageBytes as the registered proto messageType.
+func GetProto(messageType string, messageBytes []byte) (proto2.Message, error) {
+	pbtype := proto2.MessageType(messageType)
+	if pbtype == nil {
+		return nil, fmt.Errorf("Unknown proto %s", messageType)
+	}
+	msg := reflect.New(pbtype.Elem()).Interface().(proto2.Message)
+	err := proto2.Unmarshal(messageBytes, msg)
+	return msg, err
+}
+
+// UnmarshallRequest decodes a request consumed for the graph. With the gRPC transport requests are protobuf
+// messages, named by the proto name header or the default request of the protocol, and otherwise they are
+// decoded by the client from their content type.
+func UnmarshallRequest(c client.SeldonApiClient, transport string, protocol string, headers map[string][]string, value []byte) (payload.SeldonPayload, error) {
+	if transport != api.TransportGrpc {
+		return c.Unmarshall(value, ContentType(headers))
+	}
+	protoName := Get(headers, HeaderProtoName)
+	if protoName == "" {
+		protoName = DefaultProtoName(protocol)
+	}
+	if protoName == "" {
+		return nil, errNoProtoName
+	}
+	msg, err := GetProto(protoName, value)
+	if err != nil {
+		return nil, err
+	}
+	return &payload.ProtoPayload{Msg: msg}, nil
+}
+
+// UnmarshallWithProtoName decodes value as the proto named in the headers, falling back to the client for
+// payloads without a proto name.
+func UnmarshallWithProtoName(c client.SeldonApiClient, headers map[string][]string, value []byte) (payload.SeldonPayload, error) {
+	if protoName := Get(headers, HeaderProtoName); protoName != "" {
+		msg, err := GetProto(protoName, value)
+		if err != nil {
+			return nil, err
+		}
+		return &payload.ProtoPayload{Msg: msg}, nil
+	}
+	return c.Unmarshall(value, ContentType(headers))
+}
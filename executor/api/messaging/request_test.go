@@ -0,0 +1,56 @@
+package messaging
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	. "github.com/onsi/gomega"
+	"github.com/seldonio/seldon-core/executor/api"
+	"github.com/seldonio/seldon-core/executor/api/grpc/kfserving/inference"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	"github.com/seldonio/seldon-core/executor/api/rest"
+	"github.com/seldonio/seldon-core/executor/api/test"
+)
+
+func TestAddPuid(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	headers := map[string][]string{"seldon-puid": {"1"}}
+	AddPuid(headers)
+	g.Expect(headers[payload.SeldonPUIDHeader]).To(Equal([]string{"1"}))
+
+	headers = map[string][]string{}
+	AddPuid(headers)
+	g.Expect(headers[payload.SeldonPUIDHeader]).To(HaveLen(1))
+	g.Expect(headers[payload.SeldonPUIDHeader][0]).ToNot(BeEmpty())
+}
+
+func TestUnmarshallRequest(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	req := &inference.ModelInferRequest{ModelName: "model"}
+	b, err := proto.Marshal(req)
+	g.Expect(err).To(BeNil())
+
+	// V2 gRPC requests default to ModelInferRequest
+	msg, err := UnmarshallRequest(test.SeldonMessageTestClient{}, api.TransportGrpc, api.ProtocolV2, map[string][]string{}, b)
+	g.Expect(err).To(BeNil())
+	g.Expect(proto.Equal(msg.GetPayload().(proto.Message), req)).To(BeTrue())
+	g.Expect(ProtoName(msg)).To(Equal("inference.ModelInferRequest"))
+
+	msg, err = UnmarshallRequest(test.SeldonMessageTestClient{}, api.TransportGrpc, api.ProtocolSeldon, map[string][]string{"Proto-Name": {"inference.ModelInferRequest"}}, b)
+	g.Expect(err).To(BeNil())
+	g.Expect(proto.Equal(msg.GetPayload().(proto.Message), req)).To(BeTrue())
+
+	_, err = UnmarshallRequest(test.SeldonMessageTestClient{}, api.TransportGrpc, api.ProtocolSeldon, map[string][]string{}, b)
+	g.Expect(err).ToNot(BeNil())
+
+	msg, err = UnmarshallRequest(test.SeldonMessageTestClient{}, api.TransportRest, api.ProtocolSeldon, map[string][]string{}, []byte(`{}`))
+	g.Expect(err).To(BeNil())
+	g.Expect(msg.GetContentType()).To(Equal(rest.ContentTypeJSON))
+	g.Expect(ProtoName(msg)).To(BeEmpty())
+
+	msg, err = UnmarshallWithProtoName(test.SeldonMessageTestClient{}, map[string][]string{HeaderProtoName: {"inference.ModelInferRequest"}}, b)
+	g.Expect(err).To(BeNil())
+	g.Expect(proto.Equal(msg.GetPayload().(proto.Message), req)).To(BeTrue())
+}
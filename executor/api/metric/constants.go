@@ -1,5 +1,7 @@
 package metric
 
+import "github.com/prometheus/client_golang/prometheus"
+
 const (
 	CodeMetric             = "code"    // 2xx, 5xx etc
 	HTTPMethodMetric       = "method"  // Http Method (Post, Get etc)
@@ -13,6 +15,51 @@ const (
 
 	ServerRequestsMetricName = "seldon_api_executor_server_requests_seconds"
 	ClientRequestsMetricName = "seldon_api_executor_client_requests_seconds"
+	ServerInFlightMetricName = "seldon_api_executor_server_requests_in_flight"
+
+	KafkaRPCOutstandingMetricName = "seldon_api_executor_kafka_rpc_outstanding"
+	KafkaConsumedMetricName       = "seldon_api_executor_kafka_messages_consumed_total"
+	KafkaProducedMetricName       = "seldon_api_executor_kafka_messages_produced_total"
+	KafkaProcessingMetricName     = "seldon_api_executor_kafka_processing_seconds"
+	KafkaFailuresMetricName       = "seldon_api_executor_kafka_failures_total"
+	KafkaQueueDepthMetricName     = "seldon_api_executor_kafka_queue_depth"
+	KafkaConsumerLagMetricName    = "seldon_api_executor_kafka_consumer_lag"
+	KafkaTopicMetric              = "topic"
+	KafkaPartitionMetric          = "partition"
+	KafkaStageMetric              = "stage" // unmarshal, predict or produce
+
+	KafkaStageUnmarshal = "unmarshal"
+	KafkaStagePredict   = "predict"
+	KafkaStageProduce   = "produce"
+
+	NodeRequestBytesMetricName  = "seldon_api_executor_node_request_bytes"
+	NodeResponseBytesMetricName = "seldon_api_executor_node_response_bytes"
+	NodeFailuresMetricName      = "seldon_api_executor_node_failures_total"
+	NodeInFlightMetricName      = "seldon_api_executor_node_requests_in_flight"
+	RouterDecisionsMetricName   = "seldon_api_executor_router_decisions_total"
+	ErrorClassMetric            = "error_class" // timeout, connection_refused, http_503, grpc_Unavailable etc
+	RouterNameMetric            = "router_name"
+	RouteMetric                 = "route" // child index, -1 for all children or -2 for none
+
+	FeatureObservationsMetricName = "seldon_api_executor_feature_observations"
+	FeatureMeanMetricName         = "seldon_api_executor_feature_mean"
+	FeatureStddevMetricName       = "seldon_api_executor_feature_stddev"
+	FeatureMinMetricName          = "seldon_api_executor_feature_min"
+	FeatureMaxMetricName          = "seldon_api_executor_feature_max"
+	FeatureQuantileMetricName     = "seldon_api_executor_feature_quantile"
+	FeatureCategoryMetricName     = "seldon_api_executor_feature_category_frequency"
+	FeatureDriftMetricName        = "seldon_api_executor_feature_drift"
+	FeatureMetric                 = "feature"
+	QuantileMetric                = "quantile"
+	CategoryMetric                = "category"
+	DriftMethodMetric             = "method" // psi or ks
+
+	LoggerQueuedMetricName     = "seldon_api_executor_logger_events_queued_total"
+	LoggerSentMetricName       = "seldon_api_executor_logger_events_sent_total"
+	LoggerRetriedMetricName    = "seldon_api_executor_logger_events_retried_total"
+	LoggerDroppedMetricName    = "seldon_api_executor_logger_events_dropped_total"
+	LoggerSpilledMetricName    = "seldon_api_executor_logger_events_spilled_total"
+	LoggerSpillBytesMetricName = "seldon_api_executor_logger_spill_bytes"
 
 	PredictionHttpServiceName = "predictions"
 	StatusHttpServiceName     = "status"
@@ -21,6 +68,8 @@ const (
 )
 
 var (
-	DefBuckets    = []float64{0.005, 0.01, 0.025, 0.05, 0.075, 0.1, 0.25, 0.5, 0.75, 1, 2.5, 5, 7.5, 10}
-	DefObjectives = map[float64]float64{0.5: 0.05, 0.75: 0.025, 0.9: 0.01, 0.98: 0.002, 0.99: 0.001, 1.0: 0}
+	DefBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.075, 0.1, 0.25, 0.5, 0.75, 1, 2.5, 5, 7.5, 10}
+	// DefSizeBuckets are payload sizes in bytes from 64B to 64MiB
+	DefSizeBuckets = prometheus.ExponentialBuckets(64, 4, 11)
+	DefObjectives  = map[float64]float64{0.5: 0.05, 0.75: 0.025, 0.9: 0.01, 0.98: 0.002, 0.99: 0.001, 1.0: 0}
 )
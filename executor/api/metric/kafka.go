@@ -0,0 +1,136 @@
+package metric
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// KafkaMetrics records the throughput, latency, failures and lag of a kafka consumer and the outputs it produces.
+type KafkaMetrics struct {
+	Consumed       *prometheus.CounterVec
+	Produced       *prometheus.CounterVec
+	Processing     *prometheus.HistogramVec
+	Failures       *prometheus.CounterVec
+	QueueDepth     *prometheus.GaugeVec
+	ConsumerLag    *prometheus.GaugeVec
+	DeploymentName string
+	PredictorName  string
+}
+
+func registerCollector(collector prometheus.Collector) prometheus.Collector {
+	err := prometheus.Register(collector)
+	if err != nil {
+		if e, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			return e.ExistingCollector
+		}
+	}
+	return collector
+}
+
+func NewKafkaMetrics(deploymentName string, predictorName string) *KafkaMetrics {
+	labelNames := []string{DeploymentNameMetric, PredictorNameMetric, KafkaTopicMetric}
+
+	consumed := registerCollector(prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: KafkaConsumedMetricName,
+			Help: "The number of messages consumed from a kafka topic",
+		},
+		labelNames,
+	)).(*prometheus.CounterVec)
+
+	produced := registerCollector(prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: KafkaProducedMetricName,
+			Help: "The number of messages produced to a kafka topic",
+		},
+		labelNames,
+	)).(*prometheus.CounterVec)
+
+	processing := registerCollector(prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    KafkaProcessingMetricName,
+			Help:    "A histogram of the time from consuming a kafka message to producing its output",
+			Buckets: DefBuckets,
+		},
+		labelNames,
+	)).(*prometheus.HistogramVec)
+
+	failures := registerCollector(prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: KafkaFailuresMetricName,
+			Help: "The number of kafka messages which failed processing by stage",
+		},
+		[]string{DeploymentNameMetric, PredictorNameMetric, KafkaTopicMetric, KafkaStageMetric},
+	)).(*prometheus.CounterVec)
+
+	queueDepth := registerCollector(prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: KafkaQueueDepthMetricName,
+			Help: "The number of consumed kafka messages waiting for a worker",
+		},
+		labelNames,
+	)).(*prometheus.GaugeVec)
+
+	consumerLag := registerCollector(prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: KafkaConsumerLagMetricName,
+			Help: "The difference between the high watermark and committed offset of each assigned partition",
+		},
+		[]string{DeploymentNameMetric, PredictorNameMetric, KafkaTopicMetric, KafkaPartitionMetric},
+	)).(*prometheus.GaugeVec)
+
+	return &KafkaMetrics{
+		Consumed:       consumed,
+		Produced:       produced,
+		Processing:     processing,
+		Failures:       failures,
+		QueueDepth:     queueDepth,
+		ConsumerLag:    consumerLag,
+		DeploymentName: deploymentName,
+		PredictorName:  predictorName,
+	}
+}
+
+func (m *KafkaMetrics) MessageConsumed(topic string) {
+	m.Consumed.WithLabelValues(m.DeploymentName, m.PredictorName, topic).Inc()
+}
+
+func (m *KafkaMetrics) MessageProduced(topic string) {
+	m.Produced.WithLabelValues(m.DeploymentName, m.PredictorName, topic).Inc()
+}
+
+// MessageProcessed observes the time since the input message from topic was consumed.
+func (m *KafkaMetrics) MessageProcessed(topic string, start time.Time) {
+	m.Processing.WithLabelValues(m.DeploymentName, m.PredictorName, topic).Observe(time.Since(start).Seconds())
+}
+
+// MessageFailed counts a failure processing a message from topic at stage.
+func (m *KafkaMetrics) MessageFailed(topic string, stage string) {
+	m.Failures.WithLabelValues(m.DeploymentName, m.PredictorName, topic, stage).Inc()
+}
+
+func (m *KafkaMetrics) SetQueueDepth(topic string, depth int) {
+	m.QueueDepth.WithLabelValues(m.DeploymentName, m.PredictorName, topic).Set(float64(depth))
+}
+
+func (m *KafkaMetrics) SetConsumerLag(topic string, partition int32, lag int64) {
+	m.ConsumerLag.WithLabelValues(m.DeploymentName, m.PredictorName, topic, strconv.Itoa(int(partition))).Set(float64(lag))
+}
+
+// NewKafkaRPCOutstandingGauge returns the gauge of full graph kafka requests awaiting a reply from each model topic.
+func NewKafkaRPCOutstandingGauge() *prometheus.GaugeVec {
+	return registerCollector(prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: KafkaRPCOutstandingMetricName,
+			Help: "The number of kafka requests sent to a model topic which are waiting for a response",
+		},
+		[]string{DeploymentNameMetric, PredictorNameMetric, ModelNameMetric, KafkaTopicMetric},
+	)).(*prometheus.GaugeVec)
+}
+
+// DeleteConsumerLag removes the lag of a partition no longer assigned to the consumer.
+func (m *KafkaMetrics) DeleteConsumerLag(topic string, partition int32) {
+	m.ConsumerLag.DeleteLabelValues(m.DeploymentName, m.PredictorName, topic, strconv.Itoa(int(partition)))
+}
@@ -0,0 +1,33 @@
+package metric
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestKafkaMetrics(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	metrics := NewKafkaMetrics("dep", "p1")
+	metrics.MessageConsumed("input")
+	metrics.MessageProduced("output")
+	metrics.MessageProcessed("input", time.Now())
+	metrics.MessageFailed("input", KafkaStagePredict)
+	metrics.SetQueueDepth("input", 3)
+	metrics.SetConsumerLag("input", 1, 42)
+
+	g.Expect(testutil.ToFloat64(metrics.Consumed.WithLabelValues("dep", "p1", "input"))).To(Equal(1.0))
+	g.Expect(testutil.ToFloat64(metrics.Produced.WithLabelValues("dep", "p1", "output"))).To(Equal(1.0))
+	g.Expect(testutil.ToFloat64(metrics.Failures.WithLabelValues("dep", "p1", "input", KafkaStagePredict))).To(Equal(1.0))
+	g.Expect(testutil.ToFloat64(metrics.QueueDepth.WithLabelValues("dep", "p1", "input"))).To(Equal(3.0))
+	g.Expect(testutil.ToFloat64(metrics.ConsumerLag.WithLabelValues("dep", "p1", "input", "1"))).To(Equal(42.0))
+
+	// Metrics are shared when created again, e.g. by a proxy and server in one process
+	g.Expect(NewKafkaMetrics("dep", "p1").Consumed).To(BeIdenticalTo(metrics.Consumed))
+
+	metrics.DeleteConsumerLag("input", 1)
+	g.Expect(testutil.CollectAndCount(metrics.ConsumerLag)).To(Equal(0))
+}
@@ -0,0 +1,72 @@
+package metric
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// LoggerMetrics records the delivery of request logger events.
+type LoggerMetrics struct {
+	Queued         *prometheus.CounterVec
+	Sent           *prometheus.CounterVec
+	Retried        *prometheus.CounterVec
+	Dropped        *prometheus.CounterVec
+	Spilled        *prometheus.CounterVec
+	SpillBytes     *prometheus.GaugeVec
+	DeploymentName string
+	PredictorName  string
+}
+
+func newLoggerCounter(name string, help string) *prometheus.CounterVec {
+	return registerCollector(prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: name,
+			Help: help,
+		},
+		[]string{DeploymentNameMetric, PredictorNameMetric},
+	)).(*prometheus.CounterVec)
+}
+
+func NewLoggerMetrics(deploymentName string, predictorName string) *LoggerMetrics {
+	spillBytes := registerCollector(prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: LoggerSpillBytesMetricName,
+			Help: "The size of the on-disk spill queue of log events waiting to be sent",
+		},
+		[]string{DeploymentNameMetric, PredictorNameMetric},
+	)).(*prometheus.GaugeVec)
+
+	return &LoggerMetrics{
+		Queued:         newLoggerCounter(LoggerQueuedMetricName, "The number of log events added to the in-memory queue"),
+		Sent:           newLoggerCounter(LoggerSentMetricName, "The number of log events delivered to the logger endpoint"),
+		Retried:        newLoggerCounter(LoggerRetriedMetricName, "The number of retried attempts to deliver a log event"),
+		Dropped:        newLoggerCounter(LoggerDroppedMetricName, "The number of log events which could not be delivered or spilled"),
+		Spilled:        newLoggerCounter(LoggerSpilledMetricName, "The number of log events written to the on-disk spill queue"),
+		SpillBytes:     spillBytes,
+		DeploymentName: deploymentName,
+		PredictorName:  predictorName,
+	}
+}
+
+func (m *LoggerMetrics) EventQueued() {
+	m.Queued.WithLabelValues(m.DeploymentName, m.PredictorName).Inc()
+}
+
+func (m *LoggerMetrics) EventSent() {
+	m.Sent.WithLabelValues(m.DeploymentName, m.PredictorName).Inc()
+}
+
+func (m *LoggerMetrics) EventRetried() {
+	m.Retried.WithLabelValues(m.DeploymentName, m.PredictorName).Inc()
+}
+
+func (m *LoggerMetrics) EventDropped() {
+	m.Dropped.WithLabelValues(m.DeploymentName, m.PredictorName).Inc()
+}
+
+func (m *LoggerMetrics) EventSpilled() {
+	m.Spilled.WithLabelValues(m.DeploymentName, m.PredictorName).Inc()
+}
+
+func (m *LoggerMetrics) SetSpillBytes(size int64) {
+	m.SpillBytes.WithLabelValues(m.DeploymentName, m.PredictorName).Set(float64(size))
+}
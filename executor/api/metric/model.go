@@ -0,0 +1,210 @@
+package metric
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	CustomMetricCounter = "COUNTER"
+	CustomMetricGauge   = "GAUGE"
+	CustomMetricTimer   = "TIMER"
+
+	// CustomMetricPrefix starts the names of the metrics returned by models so they can't clash with the executor's
+	CustomMetricPrefix = "seldon_model_"
+	// MaxCustomMetricSeries limits the sets of tag values of a custom metric, as each is a new series
+	MaxCustomMetricSeries = 1000
+)
+
+// CustomMetric is a metric returned by a model in the meta.metrics of a seldon response or the parameters of a v2
+// response. TIMER values are in milliseconds.
+type CustomMetric struct {
+	Key   string            `json:"key"`
+	Type  string            `json:"type"`
+	Value float64           `json:"value"`
+	Tags  map[string]string `json:"tags,omitempty"`
+}
+
+type customCollector struct {
+	metricType string
+	labelNames []string
+	collector  prometheus.Collector
+	// series holds the label values the collector has been updated with
+	series map[string]struct{}
+}
+
+// customCollectors exposes the custom metrics of every model. Models can return the same key with different tags,
+// which prometheus doesn't allow of registered collectors, so they are collected unchecked.
+type customCollectors struct {
+	sync.Mutex
+	// types holds the type of each metric name, which must be the same whatever the tags
+	types map[string]string
+	// collectors are keyed by metric name and label names
+	collectors map[string]*customCollector
+}
+
+func (c *customCollectors) Describe(ch chan<- *prometheus.Desc) {
+}
+
+func (c *customCollectors) Collect(ch chan<- prometheus.Metric) {
+	c.Lock()
+	defer c.Unlock()
+	for _, collector := range c.collectors {
+		collector.collector.Collect(ch)
+	}
+}
+
+var (
+	customMetrics = &customCollectors{
+		types:      make(map[string]string),
+		collectors: make(map[string]*customCollector),
+	}
+	registerCustomMetrics sync.Once
+)
+
+// ModelMetrics exposes the custom metrics returned by models. A collector is created for each metric key and set of
+// tags the first time they are returned, labelled with the deployment, predictor and model along with the tags.
+type ModelMetrics struct {
+	DeploymentName string
+	PredictorName  string
+}
+
+func NewModelMetrics(deploymentName string, predictorName string) *ModelMetrics {
+	registerCustomMetrics.Do(func() {
+		prometheus.MustRegister(customMetrics)
+	})
+	return &ModelMetrics{
+		DeploymentName: deploymentName,
+		PredictorName:  predictorName,
+	}
+}
+
+// sanitizeMetricName replaces the characters prometheus doesn't allow in metric and label names.
+func sanitizeMetricName(name string, allowColon bool) string {
+	var b strings.Builder
+	for i, c := range name {
+		valid := (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '_' || (c == ':' && allowColon) || (c >= '0' && c <= '9' && i > 0)
+		if valid {
+			b.WriteRune(c)
+		} else {
+			b.WriteRune('_')
+		}
+	}
+	return b.String()
+}
+
+func newCustomCollector(name string, metricType string, labelNames []string) (prometheus.Collector, error) {
+	help := "Custom model metric " + name
+	switch metricType {
+	case CustomMetricCounter:
+		return prometheus.NewCounterVec(prometheus.CounterOpts{Name: name, Help: help}, labelNames), nil
+	case CustomMetricGauge:
+		return prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: name, Help: help}, labelNames), nil
+	case CustomMetricTimer:
+		return prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: name, Help: help, Buckets: DefBuckets}, labelNames), nil
+	default:
+		return nil, fmt.Errorf("unknown type %s of custom metric %s", metricType, name)
+	}
+}
+
+// collectorFor returns the collector for the metric, creating it the first time the name and labels are seen. It
+// fails if the name was returned as another type or the labels have too many values.
+func collectorFor(name string, metricType string, labelNames []string, labels prometheus.Labels) (*customCollector, error) {
+	customMetrics.Lock()
+	defer customMetrics.Unlock()
+	if existing, ok := customMetrics.types[name]; ok && existing != metricType {
+		return nil, fmt.Errorf("custom metric %s was returned as a %s, not a %s", name, existing, metricType)
+	}
+	key := name + "{" + strings.Join(labelNames, ",") + "}"
+	c, ok := customMetrics.collectors[key]
+	if !ok {
+		collector, err := newCustomCollector(name, metricType, labelNames)
+		if err != nil {
+			return nil, err
+		}
+		c = &customCollector{metricType: metricType, labelNames: labelNames, collector: collector, series: make(map[string]struct{})}
+		customMetrics.types[name] = metricType
+		customMetrics.collectors[key] = c
+	}
+	values := make([]string, len(labelNames))
+	for i, label := range labelNames {
+		values[i] = labels[label]
+	}
+	series := strings.Join(values, "\xff")
+	if _, ok := c.series[series]; !ok {
+		if len(c.series) >= MaxCustomMetricSeries {
+			return nil, fmt.Errorf("custom metric %s has more than %d sets of tag values", name, MaxCustomMetricSeries)
+		}
+		c.series[series] = struct{}{}
+	}
+	return c, nil
+}
+
+// Record updates the collectors of the metrics returned by a model. Counters are incremented by the value, gauges
+// set to it and timers observed in seconds.
+func (m *ModelMetrics) Record(modelName string, metrics []CustomMetric) error {
+	for _, cm := range metrics {
+		if cm.Key == "" {
+			return fmt.Errorf("custom metric of model %s has no key", modelName)
+		}
+		metricType := strings.ToUpper(cm.Type)
+		if metricType == "" {
+			// The zero value of the protobuf enum
+			metricType = CustomMetricCounter
+		}
+		tagNames := make([]string, 0, len(cm.Tags))
+		for tag := range cm.Tags {
+			tagNames = append(tagNames, tag)
+		}
+		sort.Strings(tagNames)
+		labelNames := []string{DeploymentNameMetric, PredictorNameMetric, ModelNameMetric}
+		labels := prometheus.Labels{
+			DeploymentNameMetric: m.DeploymentName,
+			PredictorNameMetric:  m.PredictorName,
+			ModelNameMetric:      modelName,
+		}
+		for _, tag := range tagNames {
+			label := sanitizeMetricName(tag, false)
+			if _, ok := labels[label]; ok {
+				return fmt.Errorf("tag %s of custom metric %s clashes with another label", tag, cm.Key)
+			}
+			labelNames = append(labelNames, label)
+			labels[label] = cm.Tags[tag]
+		}
+
+		c, err := collectorFor(CustomMetricPrefix+sanitizeMetricName(cm.Key, true), metricType, labelNames, labels)
+		if err != nil {
+			return err
+		}
+		switch collector := c.collector.(type) {
+		case *prometheus.CounterVec:
+			if cm.Value < 0 {
+				return fmt.Errorf("counter %s can't be decreased by %v", cm.Key, cm.Value)
+			}
+			counter, err := collector.GetMetricWith(labels)
+			if err != nil {
+				return err
+			}
+			counter.Add(cm.Value)
+		case *prometheus.GaugeVec:
+			gauge, err := collector.GetMetricWith(labels)
+			if err != nil {
+				return err
+			}
+			gauge.Set(cm.Value)
+		case *prometheus.HistogramVec:
+			histogram, err := collector.GetMetricWith(labels)
+			if err != nil {
+				return err
+			}
+			histogram.Observe(cm.Value / 1000)
+		default:
+			return fmt.Errorf("custom metric %s is registered as another kind of metric", cm.Key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package metric
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestModelMetrics(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	metrics := NewModelMetrics("dep", "p1")
+	err := metrics.Record("model", []CustomMetric{
+		{Key: "model_requests", Type: CustomMetricCounter, Value: 2, Tags: map[string]string{"class": "a"}},
+		{Key: "model_requests", Value: 1, Tags: map[string]string{"class": "a"}},
+		{Key: "model.confidence", Type: CustomMetricGauge, Value: 0.9},
+		{Key: "model_lookup", Type: CustomMetricTimer, Value: 20},
+	})
+	g.Expect(err).To(BeNil())
+
+	labels := prometheus.Labels{DeploymentNameMetric: "dep", PredictorNameMetric: "p1", ModelNameMetric: "model"}
+	labelNames := DeploymentNameMetric + "," + PredictorNameMetric + "," + ModelNameMetric
+	counter := customMetrics.collectors["seldon_model_model_requests{"+labelNames+",class}"].collector.(*prometheus.CounterVec)
+	g.Expect(testutil.ToFloat64(counter.With(prometheus.Labels{DeploymentNameMetric: "dep", PredictorNameMetric: "p1", ModelNameMetric: "model", "class": "a"}))).To(Equal(3.0))
+	gauge := customMetrics.collectors["seldon_model_model_confidence{"+labelNames+"}"].collector.(*prometheus.GaugeVec)
+	g.Expect(testutil.ToFloat64(gauge.With(labels))).To(Equal(0.9))
+	g.Expect(testutil.CollectAndCount(customMetrics, "seldon_model_model_lookup")).To(Equal(1))
+
+	// A key can't change type, but other tags give another collector
+	g.Expect(metrics.Record("model", []CustomMetric{{Key: "model_requests", Type: CustomMetricGauge, Value: 1}})).ToNot(Succeed())
+	g.Expect(metrics.Record("other", []CustomMetric{{Key: "model_requests", Value: 1}})).To(Succeed())
+	g.Expect(testutil.CollectAndCount(customMetrics, "seldon_model_model_requests")).To(Equal(2))
+	g.Expect(metrics.Record("model", []CustomMetric{{Key: "model_requests", Value: -1, Tags: map[string]string{"class": "a"}}})).ToNot(Succeed())
+	g.Expect(metrics.Record("model", []CustomMetric{{Key: "model_other", Type: "HISTOGRAM", Value: 1}})).ToNot(Succeed())
+
+	// Collectors are shared when created again
+	again := NewModelMetrics("dep", "p1")
+	g.Expect(again.Record("model", []CustomMetric{{Key: "model.confidence", Type: CustomMetricGauge, Value: 0.5}})).To(Succeed())
+	g.Expect(testutil.ToFloat64(gauge.With(labels))).To(Equal(0.5))
+
+	// Model metrics are exposed with the executor's and can't take their names
+	g.Expect(metrics.Record("model", []CustomMetric{{Key: ServerRequestsMetricName, Type: CustomMetricGauge, Value: 1}})).To(Succeed())
+	g.Expect(testutil.CollectAndCount(customMetrics, CustomMetricPrefix+ServerRequestsMetricName)).To(Equal(1))
+	_, err = prometheus.DefaultGatherer.Gather()
+	g.Expect(err).To(BeNil())
+}
+
+func TestModelMetricsLimitTagValues(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	metrics := NewModelMetrics("dep", "p1")
+	for i := 0; i < MaxCustomMetricSeries; i++ {
+		g.Expect(metrics.Record("model", []CustomMetric{{Key: "model_users", Value: 1, Tags: map[string]string{"user": fmt.Sprint(i)}}})).To(Succeed())
+	}
+	g.Expect(metrics.Record("model", []CustomMetric{{Key: "model_users", Value: 1, Tags: map[string]string{"user": "new"}}})).ToNot(Succeed())
+	g.Expect(metrics.Record("model", []CustomMetric{{Key: "model_users", Value: 1, Tags: map[string]string{"user": "0"}}})).To(Succeed())
+}
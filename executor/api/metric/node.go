@@ -0,0 +1,146 @@
+package metric
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"syscall"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	ErrorClassTimeout           = "timeout"
+	ErrorClassCanceled          = "canceled"
+	ErrorClassConnectionRefused = "connection_refused"
+	ErrorClassOther             = "other"
+)
+
+// httpStatusError is implemented by errors from calls answered with an unsuccessful HTTP status.
+type httpStatusError interface {
+	HTTPStatusCode() int
+}
+
+// ErrorClass groups a failed call to a node by its cause: a timeout, a refused connection, the HTTP status or gRPC
+// code returned, or other errors.
+func ErrorClass(err error) string {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrorClassTimeout
+	}
+	if errors.Is(err, context.Canceled) {
+		return ErrorClassCanceled
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return ErrorClassConnectionRefused
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return ErrorClassTimeout
+	}
+	var statusErr httpStatusError
+	if errors.As(err, &statusErr) {
+		return "http_" + strconv.Itoa(statusErr.HTTPStatusCode())
+	}
+	if st, ok := status.FromError(err); ok {
+		if st.Code() == codes.DeadlineExceeded {
+			return ErrorClassTimeout
+		}
+		return "grpc_" + st.Code().String()
+	}
+	return ErrorClassOther
+}
+
+// NodeMetrics records the payload sizes, failures and concurrency of calls to each node of the graph and the
+// decisions of its routers.
+type NodeMetrics struct {
+	RequestBytes    *prometheus.HistogramVec
+	ResponseBytes   *prometheus.HistogramVec
+	Failures        *prometheus.CounterVec
+	InFlight        *prometheus.GaugeVec
+	RouterDecisions *prometheus.CounterVec
+	DeploymentName  string
+	PredictorName   string
+}
+
+func NewNodeMetrics(deploymentName string, predictorName string) *NodeMetrics {
+	labelNames := []string{DeploymentNameMetric, PredictorNameMetric, ModelNameMetric}
+
+	requestBytes := registerCollector(prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    NodeRequestBytesMetricName,
+			Help:    "A histogram of the size in bytes of requests sent to a graph node",
+			Buckets: DefSizeBuckets,
+		},
+		labelNames,
+	)).(*prometheus.HistogramVec)
+
+	responseBytes := registerCollector(prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    NodeResponseBytesMetricName,
+			Help:    "A histogram of the size in bytes of responses returned by a graph node",
+			Buckets: DefSizeBuckets,
+		},
+		labelNames,
+	)).(*prometheus.HistogramVec)
+
+	failures := registerCollector(prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: NodeFailuresMetricName,
+			Help: "The number of failed calls to a graph node by class of error",
+		},
+		[]string{DeploymentNameMetric, PredictorNameMetric, ModelNameMetric, ErrorClassMetric},
+	)).(*prometheus.CounterVec)
+
+	inFlight := registerCollector(prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: NodeInFlightMetricName,
+			Help: "The number of calls to a graph node waiting for a response",
+		},
+		labelNames,
+	)).(*prometheus.GaugeVec)
+
+	routerDecisions := registerCollector(prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: RouterDecisionsMetricName,
+			Help: "The number of requests a router sent to each child",
+		},
+		[]string{DeploymentNameMetric, PredictorNameMetric, RouterNameMetric, RouteMetric},
+	)).(*prometheus.CounterVec)
+
+	return &NodeMetrics{
+		RequestBytes:    requestBytes,
+		ResponseBytes:   responseBytes,
+		Failures:        failures,
+		InFlight:        inFlight,
+		RouterDecisions: routerDecisions,
+		DeploymentName:  deploymentName,
+		PredictorName:   predictorName,
+	}
+}
+
+// CallStarted records a call to the node with a request of the given size, or -1 if unknown.
+func (m *NodeMetrics) CallStarted(nodeName string, requestBytes int) {
+	m.InFlight.WithLabelValues(m.DeploymentName, m.PredictorName, nodeName).Inc()
+	if requestBytes >= 0 {
+		m.RequestBytes.WithLabelValues(m.DeploymentName, m.PredictorName, nodeName).Observe(float64(requestBytes))
+	}
+}
+
+// CallFinished records the end of a call to the node, with the size of its response, or -1 if unknown, if the
+// call succeeded and the class of its error if not.
+func (m *NodeMetrics) CallFinished(nodeName string, responseBytes int, err error) {
+	m.InFlight.WithLabelValues(m.DeploymentName, m.PredictorName, nodeName).Dec()
+	if err != nil {
+		m.Failures.WithLabelValues(m.DeploymentName, m.PredictorName, nodeName, ErrorClass(err)).Inc()
+	} else if responseBytes >= 0 {
+		m.ResponseBytes.WithLabelValues(m.DeploymentName, m.PredictorName, nodeName).Observe(float64(responseBytes))
+	}
+}
+
+// RouteChosen counts a decision of the router to send a request to the child at route.
+func (m *NodeMetrics) RouteChosen(routerName string, route int) {
+	m.RouterDecisions.WithLabelValues(m.DeploymentName, m.PredictorName, routerName, strconv.Itoa(route)).Inc()
+}
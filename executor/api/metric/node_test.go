@@ -0,0 +1,59 @@
+package metric
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type testStatusError struct {
+	code int
+}
+
+func (e testStatusError) Error() string {
+	return "failed"
+}
+
+func (e testStatusError) HTTPStatusCode() int {
+	return e.code
+}
+
+func TestErrorClass(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}
+	g.Expect(ErrorClass(refused)).To(Equal(ErrorClassConnectionRefused))
+	g.Expect(ErrorClass(fmt.Errorf("post: %w", context.DeadlineExceeded))).To(Equal(ErrorClassTimeout))
+	g.Expect(ErrorClass(testStatusError{code: 503})).To(Equal("http_503"))
+	g.Expect(ErrorClass(status.Error(codes.Unavailable, "down"))).To(Equal("grpc_Unavailable"))
+	g.Expect(ErrorClass(status.Error(codes.DeadlineExceeded, "slow"))).To(Equal(ErrorClassTimeout))
+	g.Expect(ErrorClass(errors.New("failed"))).To(Equal(ErrorClassOther))
+}
+
+func TestNodeMetrics(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	metrics := NewNodeMetrics("dep", "p1")
+	metrics.CallStarted("model", 100)
+	g.Expect(testutil.ToFloat64(metrics.InFlight.WithLabelValues("dep", "p1", "model"))).To(Equal(1.0))
+	metrics.CallFinished("model", 10, nil)
+	metrics.CallStarted("model", -1)
+	metrics.CallFinished("model", -1, status.Error(codes.Unavailable, "down"))
+	metrics.RouteChosen("router", 1)
+	metrics.RouteChosen("router", 1)
+
+	g.Expect(testutil.ToFloat64(metrics.InFlight.WithLabelValues("dep", "p1", "model"))).To(Equal(0.0))
+	g.Expect(testutil.ToFloat64(metrics.Failures.WithLabelValues("dep", "p1", "model", "grpc_Unavailable"))).To(Equal(1.0))
+	g.Expect(testutil.ToFloat64(metrics.RouterDecisions.WithLabelValues("dep", "p1", "router", "1"))).To(Equal(2.0))
+	g.Expect(testutil.CollectAndCount(metrics.RequestBytes)).To(Equal(1))
+	g.Expect(testutil.CollectAndCount(metrics.ResponseBytes)).To(Equal(1))
+}
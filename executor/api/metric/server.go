@@ -12,10 +12,12 @@ import (
 
 var RecreateServerHistogram = false
 var RecreateServerSummary = false
+var RecreateServerInFlight = false
 
 type ServerMetrics struct {
 	ServerHandledHistogram *prometheus.HistogramVec
 	ServerHandledSummary   *prometheus.SummaryVec
+	ServerInFlightGauge    *prometheus.GaugeVec
 	Predictor              *v1.PredictorSpec
 	DeploymentName         string
 }
@@ -63,9 +65,29 @@ func NewServerMetrics(spec *v1.PredictorSpec, deploymentName string) *ServerMetr
 		}
 	}
 
+	inFlight := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: ServerInFlightMetricName,
+			Help: "The number of requests currently being handled by the executor server",
+		},
+		[]string{DeploymentNameMetric, PredictorNameMetric, PredictorVersionMetric, ServiceMetric},
+	)
+	err = prometheus.Register(inFlight)
+	if err != nil {
+		if e, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			if RecreateServerInFlight {
+				prometheus.Unregister(e.ExistingCollector)
+				prometheus.Register(inFlight)
+			} else {
+				inFlight = e.ExistingCollector.(*prometheus.GaugeVec)
+			}
+		}
+	}
+
 	return &ServerMetrics{
 		ServerHandledHistogram: histogram,
 		ServerHandledSummary:   summary,
+		ServerInFlightGauge:    inFlight,
 		Predictor:              spec,
 		DeploymentName:         deploymentName,
 	}
@@ -74,6 +96,9 @@ func NewServerMetrics(spec *v1.PredictorSpec, deploymentName string) *ServerMetr
 // UnaryServerInterceptor is a gRPC server-side interceptor that provides Prometheus monitoring for Unary RPCs.
 func (m *ServerMetrics) UnaryServerInterceptor() func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		inFlight := m.ServerInFlightGauge.WithLabelValues(m.DeploymentName, m.Predictor.Name, m.Predictor.Annotations["version"], info.FullMethod)
+		inFlight.Inc()
+		defer inFlight.Dec()
 		startTime := time.Now()
 		resp, err := handler(ctx, req)
 		st, _ := status.FromError(err)
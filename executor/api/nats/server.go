@@ -0,0 +1,371 @@
+package nats
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudevents/sdk-go/pkg/bindings/http"
+	"github.com/go-logr/logr"
+	"github.com/nats-io/nats.go"
+	"github.com/seldonio/seldon-core/executor/api"
+	"github.com/seldonio/seldon-core/executor/api/client"
+	"github.com/seldonio/seldon-core/executor/api/grpc/kfserving"
+	"github.com/seldonio/seldon-core/executor/api/grpc/seldon"
+	"github.com/seldonio/seldon-core/executor/api/grpc/tensorflow"
+	"github.com/seldonio/seldon-core/executor/api/messaging"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	"github.com/seldonio/seldon-core/executor/api/rest"
+	"github.com/seldonio/seldon-core/executor/api/util"
+	"github.com/seldonio/seldon-core/executor/predictor"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	ENV_NATS_URL            = "NATS_URL"
+	ENV_NATS_INPUT_SUBJECT  = "NATS_INPUT_SUBJECT"
+	ENV_NATS_OUTPUT_SUBJECT = "NATS_OUTPUT_SUBJECT"
+	ENV_NATS_STREAM         = "NATS_STREAM"
+	ENV_NATS_WORKERS        = "NATS_WORKERS"
+	ENV_NATS_MAX_DELIVER    = "NATS_MAX_DELIVER"
+	ENV_NATS_DEAD_LETTER    = "NATS_DEAD_LETTER_SUBJECT"
+
+	// HeaderReplySubject is the subject the response is published to instead of the output subject.
+	HeaderReplySubject = "Seldon-Reply-Subject"
+
+	// DefaultMaxDeliver is the number of deliveries of a failing request before it is dead-lettered.
+	DefaultMaxDeliver = 5
+
+	fetchWait = time.Second
+	nakDelay  = time.Second
+)
+
+type SeldonNatsServer struct {
+	Client          client.SeldonApiClient
+	DeploymentName  string
+	Namespace       string
+	Transport       string
+	Protocol        string
+	predictorStore  *predictor.PredictorStore
+	URL             string
+	Stream          string
+	SubjectIn       string
+	SubjectOut      string
+	ServerUrl       *url.URL
+	Workers         int
+	MaxDeliver      int
+	DeadLetter      string
+	Log             logr.Logger
+	FullHealthCheck bool
+	conn            *nats.Conn
+	js              nats.JetStreamContext
+}
+
+func NewNatsServer(
+	workers int,
+	deploymentName,
+	namespace,
+	protocol,
+	transport string,
+	annotations map[string]string,
+	serverUrl *url.URL,
+	spec *v1.PredictorSpec,
+	natsUrl,
+	stream,
+	subjectIn,
+	subjectOut string,
+	maxDeliver int,
+	deadLetter string,
+	log logr.Logger,
+	fullHealthCheck bool,
+) (*SeldonNatsServer, error) {
+	var apiClient client.SeldonApiClient
+	var err error
+
+	switch transport {
+	case api.TransportRest:
+		apiClient, err = rest.NewJSONRestClient(protocol, deploymentName, spec, annotations)
+		if err != nil {
+			return nil, err
+		}
+	case api.TransportGrpc:
+		switch protocol {
+		case api.ProtocolSeldon:
+			apiClient = seldon.NewSeldonGrpcClient(spec, deploymentName, annotations)
+		case api.ProtocolTensorflow:
+			apiClient = tensorflow.NewTensorflowGrpcClient(spec, deploymentName, annotations)
+		case api.ProtocolV2, api.ProtocolKFServing:
+			apiClient = kfserving.NewKFServingGrpcClient(spec, deploymentName, annotations)
+		default:
+			return nil, fmt.Errorf("Unknown protocol %s", protocol)
+		}
+	default:
+		return nil, fmt.Errorf("Unknown transport %s", transport)
+	}
+
+	return &SeldonNatsServer{
+		Client:          apiClient,
+		DeploymentName:  deploymentName,
+		Namespace:       namespace,
+		Transport:       transport,
+		Protocol:        protocol,
+		predictorStore:  predictor.NewPredictorStore(spec),
+		URL:             natsUrl,
+		Stream:          stream,
+		SubjectIn:       subjectIn,
+		SubjectOut:      subjectOut,
+		ServerUrl:       serverUrl,
+		Workers:         workers,
+		MaxDeliver:      maxDeliver,
+		DeadLetter:      deadLetter,
+		Log:             log.WithName("NatsServer"),
+		FullHealthCheck: fullHealthCheck,
+	}, nil
+}
+
+// SetPredictorStore makes consumed messages run through the graph in store.
+func (ns *SeldonNatsServer) SetPredictorStore(store *predictor.PredictorStore) {
+	ns.predictorStore = store
+}
+
+// getDurableName is the JetStream consumer shared by all replicas of the predictor. Durable names can't contain dots.
+func (ns *SeldonNatsServer) getDurableName() string {
+	return strings.ReplaceAll(ns.predictorStore.Get().Name+"-"+ns.DeploymentName+"-"+ns.Namespace, ".", "-")
+}
+
+// ensureStream creates the stream for the input, output and dead-letter subjects if it doesn't exist.
+func (ns *SeldonNatsServer) ensureStream() error {
+	_, err := ns.js.StreamInfo(ns.Stream)
+	if err == nats.ErrStreamNotFound {
+		ns.Log.Info("Creating stream", "stream", ns.Stream)
+		subjects := []string{ns.SubjectIn, ns.SubjectOut}
+		if ns.DeadLetter != "" {
+			subjects = append(subjects, ns.DeadLetter)
+		}
+		_, err = ns.js.AddStream(&nats.StreamConfig{
+			Name:     ns.Stream,
+			Subjects: subjects,
+		})
+	}
+	return err
+}
+
+// Serve processes requests until shutdown is signalled, finishing the requests in hand.
+func (ns *SeldonNatsServer) Serve(shutdown <-chan bool) error {
+	stop := make(chan struct{})
+	go func() {
+		<-shutdown
+		ns.Log.Info("Terminating")
+		close(stop)
+	}()
+	return ns.serve(stop)
+}
+
+func (ns *SeldonNatsServer) serve(stop <-chan struct{}) error {
+	tlsConfig, err := util.GetClientTLSConfig(ns.predictorStore.Get())
+	if err != nil {
+		return err
+	}
+
+	nc, err := nats.Connect(ns.URL, nats.Name(ns.getDurableName()))
+	if err != nil {
+		return err
+	}
+	// Closing rather than draining the connection keeps the durable consumer, which the library deletes on drain
+	defer nc.Close()
+	js, err := nc.JetStream()
+	if err != nil {
+		return err
+	}
+	ns.conn = nc
+	ns.js = js
+
+	if ns.Stream != "" {
+		if err := ns.ensureStream(); err != nil {
+			return err
+		}
+	}
+
+	// MaxDeliver is enforced by the server rather than the consumer so a request is only given up on once
+	// it has been dead-lettered
+	sub, err := js.PullSubscribe(ns.SubjectIn, ns.getDurableName(), nats.AckExplicit())
+	if err != nil {
+		return err
+	}
+	ns.Log.Info("Subscribed", "subject", ns.SubjectIn, "durable", ns.getDurableName())
+
+	//wait for graph to be ready
+	for {
+		err := predictor.Ready(ns.Protocol, &ns.predictorStore.Get().Graph, ns.FullHealthCheck, tlsConfig)
+		if err == nil {
+			break
+		}
+		ns.Log.Info("Waiting for graph to be ready")
+		select {
+		case <-stop:
+			return nil
+		case <-time.After(2 * time.Second):
+		}
+	}
+
+	workers := sync.WaitGroup{}
+	for i := 0; i < ns.Workers; i++ {
+		workers.Add(1)
+		go ns.worker(sub, stop, &workers)
+	}
+	workers.Wait()
+
+	// Make sure the final responses and acks reach the server before closing
+	if err := nc.Flush(); err != nil {
+		ns.Log.Error(err, "Failed to flush connection on shutdown")
+	}
+	return nil
+}
+
+// worker fetches and processes messages until stop is closed, finishing the message in hand first.
+func (ns *SeldonNatsServer) worker(sub *nats.Subscription, stop <-chan struct{}, wg *sync.WaitGroup) {
+	defer wg.Done()
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		msgs, err := sub.Fetch(1, nats.MaxWait(fetchWait))
+		if err != nil {
+			if err != nats.ErrTimeout {
+				ns.Log.Error(err, "Failed to fetch messages")
+				time.Sleep(fetchWait)
+			}
+			continue
+		}
+		for _, msg := range msgs {
+			ns.processNatsRequest(msg)
+		}
+	}
+}
+
+func collectHeaders(header nats.Header) map[string][]string {
+	headers := make(map[string][]string)
+	for key, values := range header {
+		headers[key] = append([]string{}, values...)
+	}
+	messaging.AddPuid(headers)
+	return headers
+}
+
+// processNatsRequest runs the graph for msg and publishes the response before acking msg. Failed requests are
+// redelivered by JetStream until they have been delivered MaxDeliver times and are then dead-lettered.
+func (ns *SeldonNatsServer) processNatsRequest(msg *nats.Msg) {
+	headers := collectHeaders(msg.Header)
+	puid := headers[payload.SeldonPUIDHeader][0]
+	delivered := 1
+	if meta, err := msg.Metadata(); err == nil {
+		delivered = int(meta.NumDelivered)
+	}
+	if ns.MaxDeliver > 0 && delivered > ns.MaxDeliver {
+		// Redelivered after a failure to dead-letter it, or after the executor stopped while processing it
+		ns.deadLetter(msg, puid, fmt.Errorf("Exceeded %d deliveries", ns.MaxDeliver), "", delivered)
+		return
+	}
+
+	reqPayload, err := messaging.UnmarshallRequest(ns.Client, ns.Transport, ns.Protocol, headers, msg.Data)
+	if err != nil {
+		// The message can never be processed so don't redeliver it
+		ns.Log.Error(err, "Failed to unmarshall payload", "puid", puid)
+		ns.deadLetter(msg, puid, err, "", delivered)
+		return
+	}
+
+	ctx := context.WithValue(context.Background(), payload.SeldonPUIDHeader, puid)
+	seldonPredictorProcess := predictor.NewPredictorProcess(ctx, ns.Client, logf.Log.WithName("NatsClient"), ns.ServerUrl, ns.Namespace, headers, "")
+	resPayload, err := seldonPredictorProcess.Predict(&ns.predictorStore.Get().Graph, reqPayload)
+	if err != nil {
+		ns.Log.Error(err, "Failed prediction", "puid", puid, "node", seldonPredictorProcess.FailedNode, "delivered", delivered)
+		ns.retry(msg, puid, err, seldonPredictorProcess.FailedNode, delivered)
+		return
+	}
+
+	resBytes, err := resPayload.GetBytes()
+	if err != nil {
+		ns.Log.Error(err, "Failed to get bytes from prediction response", "puid", puid)
+		ns.deadLetter(msg, puid, err, "", delivered)
+		return
+	}
+
+	out := nats.NewMsg(ns.SubjectOut)
+	out.Data = resBytes
+	out.Header.Set(payload.SeldonPUIDHeader, puid)
+	out.Header.Set(http.ContentType, resPayload.GetContentType())
+	if protoName := messaging.ProtoName(resPayload); protoName != "" {
+		out.Header.Set(messaging.HeaderProtoName, protoName)
+	}
+	if reply := msg.Header.Get(HeaderReplySubject); reply != "" {
+		// Reply subjects such as request inboxes needn't be in a stream, so the input is only acked once the
+		// server has confirmed it received the reply
+		out.Subject = reply
+		if err = ns.conn.PublishMsg(out); err == nil {
+			err = ns.conn.Flush()
+		}
+	} else {
+		// Publishing through JetStream waits for the output to be stored before the input is acked
+		_, err = ns.js.PublishMsg(out)
+	}
+	if err != nil {
+		ns.Log.Error(err, "Failed to publish response", "subject", out.Subject, "puid", puid)
+		ns.retry(msg, puid, err, "", delivered)
+		return
+	}
+
+	if err := msg.Ack(); err != nil {
+		ns.Log.Error(err, "Failed to ack message", "puid", puid)
+	}
+}
+
+// retry naks msg so it is redelivered, or dead-letters it on its last delivery.
+func (ns *SeldonNatsServer) retry(msg *nats.Msg, puid string, err error, node string, delivered int) {
+	if ns.MaxDeliver > 0 && delivered >= ns.MaxDeliver {
+		ns.deadLetter(msg, puid, err, node, delivered)
+		return
+	}
+	if err := msg.NakWithDelay(nakDelay); err != nil {
+		ns.Log.Error(err, "Failed to nak message", "puid", puid)
+	}
+}
+
+// deadLetter publishes msg with the error to the dead-letter subject and terminates it. Without a dead-letter
+// subject the message is dropped. If publishing fails the message is redelivered to try again.
+func (ns *SeldonNatsServer) deadLetter(msg *nats.Msg, puid string, err error, node string, delivered int) {
+	if ns.DeadLetter == "" {
+		ns.Log.Error(err, "Dropping failed request without a dead-letter subject", "puid", puid, "delivered", delivered)
+		if err := msg.Term(); err != nil {
+			ns.Log.Error(err, "Failed to terminate message", "puid", puid)
+		}
+		return
+	}
+	dead := nats.NewMsg(ns.DeadLetter)
+	dead.Data = msg.Data
+	for key, values := range msg.Header {
+		dead.Header[key] = append([]string{}, values...)
+	}
+	dead.Header.Set(payload.SeldonPUIDHeader, puid)
+	dead.Header.Set(messaging.HeaderError, err.Error())
+	dead.Header.Set(messaging.HeaderErrorNode, node)
+	dead.Header.Set(messaging.HeaderAttempts, strconv.Itoa(delivered))
+	if _, perr := ns.js.PublishMsg(dead); perr != nil {
+		ns.Log.Error(perr, "Failed to publish to dead-letter subject", "subject", ns.DeadLetter, "puid", puid)
+		if err := msg.NakWithDelay(nakDelay); err != nil {
+			ns.Log.Error(err, "Failed to nak message", "puid", puid)
+		}
+		return
+	}
+	ns.Log.Info("Sent failed request to dead-letter subject", "subject", ns.DeadLetter, "puid", puid, "delivered", delivered)
+	if err := msg.Term(); err != nil {
+		ns.Log.Error(err, "Failed to terminate message", "puid", puid)
+	}
+}
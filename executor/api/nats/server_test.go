@@ -0,0 +1,185 @@
+package nats
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	. "github.com/onsi/gomega"
+	"github.com/seldonio/seldon-core/executor/api"
+	"github.com/seldonio/seldon-core/executor/api/messaging"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	"github.com/seldonio/seldon-core/executor/api/test"
+	"github.com/seldonio/seldon-core/executor/predictor"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func startTestNatsServer(g *WithT, dir string) *server.Server {
+	s, err := server.NewServer(&server.Options{Host: "127.0.0.1", Port: -1, JetStream: true, StoreDir: dir})
+	g.Expect(err).To(BeNil())
+	go s.Start()
+	g.Expect(s.ReadyForConnections(5 * time.Second)).To(BeTrue())
+	return s
+}
+
+func TestNatsServerProcessesRequests(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	dir, err := ioutil.TempDir("", "seldon-nats")
+	g.Expect(err).To(BeNil())
+	defer os.RemoveAll(dir)
+	s := startTestNatsServer(g, dir)
+	defer s.Shutdown()
+
+	model := v1.MODEL
+	serverUrl, _ := url.Parse("http://localhost")
+	ns := &SeldonNatsServer{
+		Client:         test.SeldonMessageTestClient{},
+		DeploymentName: "dep",
+		Namespace:      "default",
+		Transport:      api.TransportRest,
+		Protocol:       api.ProtocolSeldon,
+		predictorStore: predictor.NewPredictorStore(&v1.PredictorSpec{Name: "p1", Graph: v1.PredictiveUnit{Name: "model", Type: &model, Endpoint: &v1.Endpoint{}}}),
+		URL:            s.ClientURL(),
+		Stream:         "seldon",
+		SubjectIn:      "seldon.in",
+		SubjectOut:     "seldon.out",
+		ServerUrl:      serverUrl,
+		Workers:        2,
+		Log:            logf.Log,
+	}
+	shutdown := make(chan bool, 1)
+	done := make(chan error)
+	go func() {
+		done <- ns.Serve(shutdown)
+	}()
+
+	nc, err := nats.Connect(s.ClientURL())
+	g.Expect(err).To(BeNil())
+	defer nc.Close()
+	out, err := nc.SubscribeSync("seldon.out")
+	g.Expect(err).To(BeNil())
+	reply, err := nc.SubscribeSync("client.reply")
+	g.Expect(err).To(BeNil())
+
+	js, err := nc.JetStream()
+	g.Expect(err).To(BeNil())
+	g.Eventually(func() error {
+		_, err := js.StreamInfo("seldon")
+		return err
+	}, 5*time.Second, 50*time.Millisecond).Should(BeNil())
+
+	msg := nats.NewMsg("seldon.in")
+	msg.Data = []byte(`{"data":{"ndarray":[1,2]}}`)
+	msg.Header.Set(payload.SeldonPUIDHeader, "puid-1")
+	_, err = js.PublishMsg(msg)
+	g.Expect(err).To(BeNil())
+
+	res, err := out.NextMsg(5 * time.Second)
+	g.Expect(err).To(BeNil())
+	g.Expect(string(res.Data)).To(Equal(`{"data":{"ndarray":[1,2]}}`))
+	g.Expect(res.Header.Get(payload.SeldonPUIDHeader)).To(Equal("puid-1"))
+
+	msg = nats.NewMsg("seldon.in")
+	msg.Data = []byte(`{"data":{"ndarray":[3]}}`)
+	msg.Header.Set(HeaderReplySubject, "client.reply")
+	_, err = js.PublishMsg(msg)
+	g.Expect(err).To(BeNil())
+
+	res, err = reply.NextMsg(5 * time.Second)
+	g.Expect(err).To(BeNil())
+	g.Expect(string(res.Data)).To(Equal(`{"data":{"ndarray":[3]}}`))
+	g.Expect(res.Header.Get(payload.SeldonPUIDHeader)).ToNot(BeEmpty())
+
+	// Both requests are acked so nothing is left for the consumer
+	g.Eventually(func() int {
+		info, err := js.ConsumerInfo("seldon", ns.getDurableName())
+		if err != nil {
+			return -1
+		}
+		return info.NumAckPending + int(info.NumPending)
+	}, 5*time.Second, 50*time.Millisecond).Should(Equal(0))
+
+	shutdown <- true
+	g.Expect(<-done).To(BeNil())
+}
+
+func TestNatsServerDeadLettersFailedRequests(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	dir, err := ioutil.TempDir("", "seldon-nats")
+	g.Expect(err).To(BeNil())
+	defer os.RemoveAll(dir)
+	s := startTestNatsServer(g, dir)
+	defer s.Shutdown()
+
+	model := v1.MODEL
+	errMethod := v1.TRANSFORM_INPUT
+	serverUrl, _ := url.Parse("http://localhost")
+	ns := &SeldonNatsServer{
+		Client:         test.SeldonMessageTestClient{ErrMethod: &errMethod, Err: errors.New("model failed")},
+		DeploymentName: "dep",
+		Namespace:      "default",
+		Transport:      api.TransportRest,
+		Protocol:       api.ProtocolSeldon,
+		predictorStore: predictor.NewPredictorStore(&v1.PredictorSpec{Name: "p1", Graph: v1.PredictiveUnit{Name: "model", Type: &model, Endpoint: &v1.Endpoint{}}}),
+		URL:            s.ClientURL(),
+		Stream:         "seldon",
+		SubjectIn:      "seldon.in",
+		SubjectOut:     "seldon.out",
+		ServerUrl:      serverUrl,
+		Workers:        1,
+		MaxDeliver:     2,
+		DeadLetter:     "seldon.dead",
+		Log:            logf.Log,
+	}
+	stop := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- ns.serve(stop)
+	}()
+
+	nc, err := nats.Connect(s.ClientURL())
+	g.Expect(err).To(BeNil())
+	defer nc.Close()
+	dead, err := nc.SubscribeSync("seldon.dead")
+	g.Expect(err).To(BeNil())
+
+	js, err := nc.JetStream()
+	g.Expect(err).To(BeNil())
+	g.Eventually(func() error {
+		_, err := js.StreamInfo("seldon")
+		return err
+	}, 5*time.Second, 50*time.Millisecond).Should(BeNil())
+
+	msg := nats.NewMsg("seldon.in")
+	msg.Data = []byte(`{"data":{"ndarray":[1,2]}}`)
+	msg.Header.Set(payload.SeldonPUIDHeader, "puid-1")
+	_, err = js.PublishMsg(msg)
+	g.Expect(err).To(BeNil())
+
+	res, err := dead.NextMsg(10 * time.Second)
+	g.Expect(err).To(BeNil())
+	g.Expect(string(res.Data)).To(Equal(`{"data":{"ndarray":[1,2]}}`))
+	g.Expect(res.Header.Get(payload.SeldonPUIDHeader)).To(Equal("puid-1"))
+	g.Expect(res.Header.Get(messaging.HeaderError)).To(Equal("model failed"))
+	g.Expect(res.Header.Get(messaging.HeaderAttempts)).To(Equal("2"))
+
+	// The request is terminated once dead-lettered so it isn't delivered again
+	g.Eventually(func() int {
+		info, err := js.ConsumerInfo("seldon", ns.getDurableName())
+		if err != nil {
+			return -1
+		}
+		return info.NumAckPending + info.NumRedelivered + int(info.NumPending)
+	}, 5*time.Second, 50*time.Millisecond).Should(Equal(0))
+
+	close(stop)
+	g.Expect(<-done).To(BeNil())
+}
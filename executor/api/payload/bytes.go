@@ -1,5 +1,7 @@
 package payload
 
+import "encoding/json"
+
 type BytesPayload struct {
 	Msg             []byte
 	ContentType     string
@@ -21,3 +23,9 @@ func (s *BytesPayload) GetContentEncoding() string {
 func (s *BytesPayload) GetBytes() ([]byte, error) {
 	return s.Msg, nil
 }
+
+// NewJSONErrorPayload returns err as the {"error": ...} object returned by Tensorflow Serving and V2 servers.
+func NewJSONErrorPayload(err error) *BytesPayload {
+	msg, _ := json.Marshal(map[string]string{"error": err.Error()})
+	return &BytesPayload{Msg: msg, ContentType: APPLICATION_TYPE_JSON}
+}
@@ -4,6 +4,7 @@ import "github.com/golang/protobuf/proto"
 
 const (
 	APPLICATION_TYPE_PROTOBUF = "application/protobuf"
+	APPLICATION_TYPE_JSON     = "application/json"
 )
 
 type ProtoPayload struct {
@@ -25,6 +25,7 @@ import (
 	"github.com/seldonio/seldon-core/executor/api/grpc/seldon/proto"
 	"github.com/seldonio/seldon-core/executor/api/metric"
 	"github.com/seldonio/seldon-core/executor/api/payload"
+	"github.com/seldonio/seldon-core/executor/api/tracing"
 	"github.com/seldonio/seldon-core/executor/api/util"
 	"github.com/seldonio/seldon-core/executor/k8s"
 	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
@@ -44,6 +45,8 @@ type JSONRestClient struct {
 	DeploymentName string
 	predictor      *v1.PredictorSpec
 	metrics        *metric.ClientMetrics
+	scheme         string
+	transport      http.RoundTripper
 }
 
 func (smc *JSONRestClient) IsGrpc() bool {
@@ -124,6 +127,19 @@ func NewJSONRestClient(protocol string, deploymentName string, predictor *v1.Pre
 		}
 	}
 
+	scheme := "http"
+	transport := http.DefaultTransport
+	tlsConfig, err := util.GetClientTLSConfig(predictor)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		tlsTransport := http.DefaultTransport.(*http.Transport).Clone()
+		tlsTransport.TLSClientConfig = tlsConfig
+		scheme = "https"
+		transport = tlsTransport
+	}
+
 	client := JSONRestClient{
 		httpClient,
 		logf.Log.WithName("JSONRestClient"),
@@ -131,6 +147,8 @@ func NewJSONRestClient(protocol string, deploymentName string, predictor *v1.Pre
 		deploymentName,
 		predictor,
 		metric.NewClientMetrics(predictor, deploymentName, ""),
+		scheme,
+		transport,
 	}
 	for i := range options {
 		options[i](&client)
@@ -158,7 +176,7 @@ func (smc *JSONRestClient) getMetricsRoundTripper(modelName string, service stri
 		metric.ModelNameMetric:        modelName,
 		metric.ModelImageMetric:       imageName,
 		metric.ModelVersionMetric:     imageVersion,
-	}), http.DefaultTransport)
+	}), smc.transport)
 
 	return promhttp.InstrumentRoundTripperDuration(smc.metrics.ClientHandledSummary.MustCurryWith(prometheus.Labels{
 		metric.DeploymentNameMetric:   smc.DeploymentName,
@@ -205,7 +223,10 @@ func (smc *JSONRestClient) doHttp(ctx context.Context, modelName string, method
 	// Add metadata passed in
 	smc.addHeaders(req, meta)
 
-	if opentracing.IsGlobalTracerRegistered() {
+	if tracing.OpenTelemetryEnabled() {
+		_, clientSpan := tracing.StartClientSpan(ctx, method, req.Header)
+		defer clientSpan.End()
+	} else if opentracing.IsGlobalTracerRegistered() {
 		tracer := opentracing.GlobalTracer()
 
 		startSpanOptions := make([]opentracing.StartSpanOption, 0)
@@ -286,7 +307,7 @@ func (smc *JSONRestClient) modifyMethod(method string, modelName string) string
 
 func (smc *JSONRestClient) call(ctx context.Context, modelName string, method string, host string, port int32, req payload.SeldonPayload, meta map[string][]string) (payload.SeldonPayload, error) {
 	url := url.URL{
-		Scheme: "http",
+		Scheme: smc.scheme,
 		Host:   net.JoinHostPort(host, strconv.Itoa(int(port))),
 		Path:   method,
 	}
@@ -14,6 +14,10 @@ func (e *httpStatusError) Error() string {
 	return fmt.Sprintf("Internal service call from executor failed calling %s status code %d", e.Url, e.StatusCode)
 }
 
+func (e *httpStatusError) HTTPStatusCode() int {
+	return e.StatusCode
+}
+
 func invalidPayload(msg string) error {
 	return fmt.Errorf("invalid payload: %s", msg)
 }
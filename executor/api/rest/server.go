@@ -21,7 +21,10 @@ import (
 	"github.com/seldonio/seldon-core/executor/api/client"
 	"github.com/seldonio/seldon-core/executor/api/metric"
 	"github.com/seldonio/seldon-core/executor/api/payload"
+	"github.com/seldonio/seldon-core/executor/api/tracing"
+	"github.com/seldonio/seldon-core/executor/api/util"
 	"github.com/seldonio/seldon-core/executor/predictor"
+	"github.com/seldonio/seldon-core/executor/record"
 	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 )
@@ -29,7 +32,7 @@ import (
 type SeldonRestApi struct {
 	Router          *mux.Router
 	Client          client.SeldonApiClient
-	predictor       *v1.PredictorSpec
+	predictorStore  *predictor.PredictorStore
 	Log             logr.Logger
 	ProbesOnly      bool
 	ServerUrl       *url.URL
@@ -39,17 +42,18 @@ type SeldonRestApi struct {
 	metrics         *metric.ServerMetrics
 	prometheusPath  string
 	fullHealthCheck bool
+	recorder        *record.Recorder
 }
 
-func NewServerRestApi(predictor *v1.PredictorSpec, client client.SeldonApiClient, probesOnly bool, serverUrl *url.URL, namespace string, protocol string, deploymentName string, prometheusPath string, fullHealthCheck bool) *SeldonRestApi {
+func NewServerRestApi(spec *v1.PredictorSpec, client client.SeldonApiClient, probesOnly bool, serverUrl *url.URL, namespace string, protocol string, deploymentName string, prometheusPath string, fullHealthCheck bool) *SeldonRestApi {
 	var serverMetrics *metric.ServerMetrics
 	if !probesOnly {
-		serverMetrics = metric.NewServerMetrics(predictor, deploymentName)
+		serverMetrics = metric.NewServerMetrics(spec, deploymentName)
 	}
 	return &SeldonRestApi{
 		mux.NewRouter(),
 		client,
-		predictor,
+		predictor.NewPredictorStore(spec),
 		logf.Log.WithName("SeldonRestApi"),
 		probesOnly,
 		serverUrl,
@@ -59,9 +63,20 @@ func NewServerRestApi(predictor *v1.PredictorSpec, client client.SeldonApiClient
 		serverMetrics,
 		prometheusPath,
 		fullHealthCheck,
+		nil,
 	}
 }
 
+// SetPredictorStore makes the REST handlers read the graph from store.
+func (r *SeldonRestApi) SetPredictorStore(store *predictor.PredictorStore) {
+	r.predictorStore = store
+}
+
+// SetRecorder records the prediction requests and responses with recorder. It must be called before Initialise.
+func (r *SeldonRestApi) SetRecorder(recorder *record.Recorder) {
+	r.recorder = recorder
+}
+
 func (r *SeldonRestApi) CreateHttpServer(port int) *http.Server {
 
 	address := fmt.Sprintf("0.0.0.0:%d", port)
@@ -116,21 +131,30 @@ func (r *SeldonRestApi) respondWithError(w http.ResponseWriter, payload payload.
 }
 
 func (r *SeldonRestApi) wrapMetrics(service string, baseHandler http.HandlerFunc) http.HandlerFunc {
+	spec := r.predictorStore.Get()
+	inFlightHandler := promhttp.InstrumentHandlerInFlight(
+		r.metrics.ServerInFlightGauge.With(prometheus.Labels{
+			metric.DeploymentNameMetric:   r.DeploymentName,
+			metric.PredictorNameMetric:    spec.Name,
+			metric.PredictorVersionMetric: spec.Annotations["version"],
+			metric.ServiceMetric:          service}),
+		baseHandler,
+	)
 
 	handler := promhttp.InstrumentHandlerDuration(
 		r.metrics.ServerHandledHistogram.MustCurryWith(prometheus.Labels{
 			metric.DeploymentNameMetric:   r.DeploymentName,
-			metric.PredictorNameMetric:    r.predictor.Name,
-			metric.PredictorVersionMetric: r.predictor.Annotations["version"],
+			metric.PredictorNameMetric:    spec.Name,
+			metric.PredictorVersionMetric: spec.Annotations["version"],
 			metric.ServiceMetric:          service}),
-		baseHandler,
+		inFlightHandler,
 	)
 
 	handler = promhttp.InstrumentHandlerDuration(
 		r.metrics.ServerHandledSummary.MustCurryWith(prometheus.Labels{
 			metric.DeploymentNameMetric:   r.DeploymentName,
-			metric.PredictorNameMetric:    r.predictor.Name,
-			metric.PredictorVersionMetric: r.predictor.Annotations["version"],
+			metric.PredictorNameMetric:    spec.Name,
+			metric.PredictorVersionMetric: spec.Annotations["version"],
 			metric.ServiceMetric:          service}),
 		handler,
 	)
@@ -142,7 +166,17 @@ func (r *SeldonRestApi) Initialise() {
 	r.Router.HandleFunc("/ready", r.checkReady)
 	r.Router.HandleFunc("/live", r.alive)
 	r.Router.Handle(r.prometheusPath, promhttp.Handler())
+	if collector := predictor.FeatureStats(); collector != nil {
+		r.Router.Handle("/stats/features", collector).Methods("GET")
+	}
+	if prober := predictor.Prober(); prober != nil {
+		r.Router.Handle("/ready/nodes", prober).Methods("GET")
+	}
 	if !r.ProbesOnly {
+		predictions := r.predictions
+		if r.recorder != nil {
+			predictions = r.recorder.Wrap(r.predictions)
+		}
 		cloudeventHeaderMiddleware := CloudeventHeaderMiddleware{deploymentName: r.DeploymentName, namespace: r.Namespace}
 		r.Router.Use(puidHeader)
 		r.Router.Use(cloudeventHeaderMiddleware.Middleware)
@@ -154,7 +188,7 @@ func (r *SeldonRestApi) Initialise() {
 		case api.ProtocolSeldon:
 			//v0.1 API
 			api01 := r.Router.PathPrefix("/api/v0.1").Methods("OPTIONS", "POST").Subrouter()
-			api01.Handle("/predictions", r.wrapMetrics(metric.PredictionHttpServiceName, r.predictions))
+			api01.Handle("/predictions", r.wrapMetrics(metric.PredictionHttpServiceName, predictions))
 			api01.Handle("/feedback", r.wrapMetrics(metric.FeedbackHttpServiceName, r.feedback))
 			r.Router.NewRoute().Path("/api/v0.1/status/{"+ModelHttpPathVariable+"}").Methods("GET", "OPTIONS").HandlerFunc(r.wrapMetrics(metric.StatusHttpServiceName, r.status))
 			r.Router.NewRoute().Path("/api/v0.1/metadata/{"+ModelHttpPathVariable+"}").Methods("GET", "OPTIONS").HandlerFunc(r.wrapMetrics(metric.MetadataHttpServiceName, r.metadata))
@@ -162,7 +196,7 @@ func (r *SeldonRestApi) Initialise() {
 			r.Router.NewRoute().PathPrefix("/api/v0.1/doc/").Handler(http.StripPrefix("/api/v0.1/doc/", http.FileServer(http.Dir("./openapi/"))))
 			//v1.0 API
 			api10 := r.Router.PathPrefix("/api/v1.0").Methods("OPTIONS", "POST").Subrouter()
-			api10.Handle("/predictions", r.wrapMetrics(metric.PredictionHttpServiceName, r.predictions))
+			api10.Handle("/predictions", r.wrapMetrics(metric.PredictionHttpServiceName, predictions))
 			api10.Handle("/feedback", r.wrapMetrics(metric.FeedbackHttpServiceName, r.feedback))
 			r.Router.NewRoute().Path("/api/v1.0/status/{"+ModelHttpPathVariable+"}").Methods("GET", "OPTIONS").HandlerFunc(r.wrapMetrics(metric.StatusHttpServiceName, r.status))
 			r.Router.NewRoute().Path("/api/v1.0/status").Methods("GET", "OPTIONS").HandlerFunc(r.wrapMetrics(metric.StatusHttpServiceName, r.checkReady))
@@ -172,18 +206,18 @@ func (r *SeldonRestApi) Initialise() {
 			//health
 			r.Router.NewRoute().Path("/api/v1.0/health/status").Methods("GET", "OPTIONS").HandlerFunc(r.wrapMetrics(metric.StatusHttpServiceName, r.checkReady))
 		case api.ProtocolTensorflow:
-			r.Router.NewRoute().Path("/v1/models/{"+ModelHttpPathVariable+"}/:predict").Methods("OPTIONS", "POST").HandlerFunc(r.wrapMetrics(metric.PredictionHttpServiceName, r.predictions))
-			r.Router.NewRoute().Path("/v1/models/{"+ModelHttpPathVariable+"}:predict").Methods("OPTIONS", "POST").HandlerFunc(r.wrapMetrics(metric.PredictionHttpServiceName, r.predictions))
+			r.Router.NewRoute().Path("/v1/models/{"+ModelHttpPathVariable+"}/:predict").Methods("OPTIONS", "POST").HandlerFunc(r.wrapMetrics(metric.PredictionHttpServiceName, predictions))
+			r.Router.NewRoute().Path("/v1/models/{"+ModelHttpPathVariable+"}:predict").Methods("OPTIONS", "POST").HandlerFunc(r.wrapMetrics(metric.PredictionHttpServiceName, predictions))
 			// Allow both :predict before and after final / in path.
-			r.Router.NewRoute().Path("/v1/models/:predict").Methods("OPTIONS", "POST").HandlerFunc(r.wrapMetrics(metric.PredictionHttpServiceName, r.predictions)) // Nonstandard path - Seldon extension
-			r.Router.NewRoute().Path("/v1/models:predict").Methods("OPTIONS", "POST").HandlerFunc(r.wrapMetrics(metric.PredictionHttpServiceName, r.predictions))  // Nonstandard path - Seldon extension
+			r.Router.NewRoute().Path("/v1/models/:predict").Methods("OPTIONS", "POST").HandlerFunc(r.wrapMetrics(metric.PredictionHttpServiceName, predictions)) // Nonstandard path - Seldon extension
+			r.Router.NewRoute().Path("/v1/models:predict").Methods("OPTIONS", "POST").HandlerFunc(r.wrapMetrics(metric.PredictionHttpServiceName, predictions))  // Nonstandard path - Seldon extension
 			r.Router.NewRoute().Path("/v1/models/{"+ModelHttpPathVariable+"}").Methods("GET", "OPTIONS").HandlerFunc(r.wrapMetrics(metric.StatusHttpServiceName, r.status))
 			r.Router.NewRoute().Path("/v1/models/{"+ModelHttpPathVariable+"}/metadata").Methods("GET", "OPTIONS").HandlerFunc(r.wrapMetrics(metric.MetadataHttpServiceName, r.metadata))
 			// Enabling for standard seldon core feedback API endpoint with standard schema
 			r.Router.NewRoute().Path("/api/v1.0/feedback").Methods("OPTIONS", "POST").HandlerFunc(r.wrapMetrics(metric.FeedbackHttpServiceName, r.feedback))
 		case api.ProtocolV2, api.ProtocolKFServing:
-			r.Router.NewRoute().Path("/v2/models/{"+ModelHttpPathVariable+"}/infer").Methods("OPTIONS", "POST").HandlerFunc(r.wrapMetrics(metric.PredictionHttpServiceName, r.predictions))
-			r.Router.NewRoute().Path("/v2/models/infer").Methods("OPTIONS", "POST").HandlerFunc(r.wrapMetrics(metric.PredictionHttpServiceName, r.predictions)) // Nonstandard path - Seldon extension
+			r.Router.NewRoute().Path("/v2/models/{"+ModelHttpPathVariable+"}/infer").Methods("OPTIONS", "POST").HandlerFunc(r.wrapMetrics(metric.PredictionHttpServiceName, predictions))
+			r.Router.NewRoute().Path("/v2/models/infer").Methods("OPTIONS", "POST").HandlerFunc(r.wrapMetrics(metric.PredictionHttpServiceName, predictions)) // Nonstandard path - Seldon extension
 			r.Router.NewRoute().Path("/v2/models/{"+ModelHttpPathVariable+"}/ready").Methods("GET", "OPTIONS").HandlerFunc(r.wrapMetrics(metric.StatusHttpServiceName, r.status))
 			r.Router.NewRoute().Path("/v2/models/{"+ModelHttpPathVariable+"}").Methods("GET", "OPTIONS").HandlerFunc(r.wrapMetrics(metric.MetadataHttpServiceName, r.metadata))
 			// Health
@@ -194,8 +228,11 @@ func (r *SeldonRestApi) Initialise() {
 }
 
 func (r *SeldonRestApi) checkReady(w http.ResponseWriter, req *http.Request) {
-	err := predictor.Ready(r.Protocol, &r.predictor.Graph, r.fullHealthCheck)
-	if err != nil {
+	if util.IsDraining() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	if err := predictor.CheckReady(r.Protocol, r.predictorStore.Get(), r.fullHealthCheck); err != nil {
 		r.Log.Error(err, "Ready check failed")
 		w.WriteHeader(http.StatusServiceUnavailable)
 	} else {
@@ -207,29 +244,35 @@ func (r *SeldonRestApi) alive(w http.ResponseWriter, req *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
-func setupTracing(ctx context.Context, req *http.Request, spanName string) (context.Context, opentracing.Span) {
+// setupTracing starts the server span of the request with the active tracer, returning the function to finish it.
+func setupTracing(ctx context.Context, req *http.Request, spanName string) (context.Context, func()) {
+	if tracing.OpenTelemetryEnabled() {
+		ctx, serverSpan := tracing.StartServerSpan(ctx, spanName, req.Header)
+		return ctx, func() { serverSpan.End() }
+	}
+	if !opentracing.IsGlobalTracerRegistered() {
+		return ctx, func() {}
+	}
 	tracer := opentracing.GlobalTracer()
 	spanCtx, _ := tracer.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(req.Header))
 	serverSpan := tracer.StartSpan(spanName, ext.RPCServerOption(spanCtx))
 	ctx = opentracing.ContextWithSpan(ctx, serverSpan)
-	return ctx, serverSpan
+	return ctx, serverSpan.Finish
 }
 
 func (r *SeldonRestApi) metadata(w http.ResponseWriter, req *http.Request) {
 	ctx := req.Context()
 
 	// Apply tracing if active
-	if opentracing.IsGlobalTracerRegistered() {
-		var serverSpan opentracing.Span
-		ctx, serverSpan = setupTracing(ctx, req, TracingMetadataName)
-		defer serverSpan.Finish()
-	}
+	var finishSpan func()
+	ctx, finishSpan = setupTracing(ctx, req, TracingMetadataName)
+	defer finishSpan()
 
 	vars := mux.Vars(req)
 	modelName := vars[ModelHttpPathVariable]
 
 	seldonPredictorProcess := predictor.NewPredictorProcess(ctx, r.Client, logf.Log.WithName(LoggingRestClientName), r.ServerUrl, r.Namespace, req.Header, modelName)
-	resPayload, err := seldonPredictorProcess.Metadata(&r.predictor.Graph, modelName, nil)
+	resPayload, err := seldonPredictorProcess.Metadata(&r.predictorStore.Get().Graph, modelName, nil)
 	if err != nil {
 		r.respondWithError(w, resPayload, err)
 		return
@@ -241,17 +284,15 @@ func (r *SeldonRestApi) status(w http.ResponseWriter, req *http.Request) {
 	ctx := req.Context()
 
 	// Apply tracing if active
-	if opentracing.IsGlobalTracerRegistered() {
-		var serverSpan opentracing.Span
-		ctx, serverSpan = setupTracing(ctx, req, TracingStatusName)
-		defer serverSpan.Finish()
-	}
+	var finishSpan func()
+	ctx, finishSpan = setupTracing(ctx, req, TracingStatusName)
+	defer finishSpan()
 
 	vars := mux.Vars(req)
 	modelName := vars[ModelHttpPathVariable]
 
 	seldonPredictorProcess := predictor.NewPredictorProcess(ctx, r.Client, logf.Log.WithName(LoggingRestClientName), r.ServerUrl, r.Namespace, req.Header, modelName)
-	resPayload, err := seldonPredictorProcess.Status(&r.predictor.Graph, modelName, nil)
+	resPayload, err := seldonPredictorProcess.Status(&r.predictorStore.Get().Graph, modelName, nil)
 	if err != nil {
 		r.respondWithError(w, resPayload, err)
 		return
@@ -264,11 +305,9 @@ func (r *SeldonRestApi) feedback(w http.ResponseWriter, req *http.Request) {
 	ctx = context.WithValue(ctx, payload.SeldonPUIDHeader, req.Header.Get(payload.SeldonPUIDHeader))
 
 	// Apply tracing if active
-	if opentracing.IsGlobalTracerRegistered() {
-		var serverSpan opentracing.Span
-		ctx, serverSpan = setupTracing(ctx, req, TracingStatusName)
-		defer serverSpan.Finish()
-	}
+	var finishSpan func()
+	ctx, finishSpan = setupTracing(ctx, req, TracingStatusName)
+	defer finishSpan()
 
 	bodyBytes, err := ioutil.ReadAll(req.Body)
 	if err != nil {
@@ -283,7 +322,7 @@ func (r *SeldonRestApi) feedback(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	resPayload, err := seldonPredictorProcess.Feedback(&r.predictor.Graph, reqPayload)
+	resPayload, err := seldonPredictorProcess.Feedback(&r.predictorStore.Get().Graph, reqPayload)
 	if err != nil {
 		r.respondWithError(w, resPayload, err)
 		return
@@ -299,11 +338,9 @@ func (r *SeldonRestApi) predictions(w http.ResponseWriter, req *http.Request) {
 	ctx = context.WithValue(ctx, payload.SeldonPUIDHeader, req.Header.Get(payload.SeldonPUIDHeader))
 
 	// Apply tracing if active
-	if opentracing.IsGlobalTracerRegistered() {
-		var serverSpan opentracing.Span
-		ctx, serverSpan = setupTracing(ctx, req, TracingPredictionsName)
-		defer serverSpan.Finish()
-	}
+	var finishSpan func()
+	ctx, finishSpan = setupTracing(ctx, req, TracingPredictionsName)
+	defer finishSpan()
 
 	bodyBytes, err := ioutil.ReadAll(req.Body)
 	if err != nil {
@@ -322,7 +359,7 @@ func (r *SeldonRestApi) predictions(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	resPayload, err := seldonPredictorProcess.Predict(&r.predictor.Graph, reqPayload)
+	resPayload, err := seldonPredictorProcess.Predict(&r.predictorStore.Get().Graph, reqPayload)
 	if err != nil {
 		r.respondWithError(w, resPayload, err)
 		return
@@ -336,15 +373,13 @@ func (r *SeldonRestApi) graphMetadata(w http.ResponseWriter, req *http.Request)
 	ctx := req.Context()
 
 	// Apply tracing if active
-	if opentracing.IsGlobalTracerRegistered() {
-		var serverSpan opentracing.Span
-		ctx, serverSpan = setupTracing(ctx, req, TracingMetadataName)
-		defer serverSpan.Finish()
-	}
+	var finishSpan func()
+	ctx, finishSpan = setupTracing(ctx, req, TracingMetadataName)
+	defer finishSpan()
 
 	seldonPredictorProcess := predictor.NewPredictorProcess(ctx, r.Client, logf.Log.WithName(LoggingRestClientName), r.ServerUrl, r.Namespace, req.Header, "")
 
-	graphMetadata, err := seldonPredictorProcess.GraphMetadata(r.predictor)
+	graphMetadata, err := seldonPredictorProcess.GraphMetadata(r.predictorStore.Get())
 
 	if err != nil {
 		r.respondWithError(w, nil, err)
@@ -1,13 +1,17 @@
 package rest
 
 import (
+	"encoding/json"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	guuid "github.com/google/uuid"
 	. "github.com/onsi/gomega"
@@ -16,6 +20,9 @@ import (
 	"github.com/seldonio/seldon-core/executor/api/metric"
 	"github.com/seldonio/seldon-core/executor/api/payload"
 	"github.com/seldonio/seldon-core/executor/api/test"
+	"github.com/seldonio/seldon-core/executor/api/util"
+	"github.com/seldonio/seldon-core/executor/predictor"
+	"github.com/seldonio/seldon-core/executor/record"
 	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
 )
 
@@ -67,6 +74,54 @@ func TestSimpleModel(t *testing.T) {
 	g.Expect(res.Code).To(Equal(200))
 }
 
+func TestRecordPredictions(t *testing.T) {
+	t.Logf("Started")
+	g := NewGomegaWithT(t)
+
+	model := v1.MODEL
+	p := v1.PredictorSpec{
+		Name: "p",
+		Graph: v1.PredictiveUnit{
+			Type: &model,
+			Endpoint: &v1.Endpoint{
+				ServiceHost: "foo",
+				ServicePort: 9000,
+				Type:        v1.REST,
+			},
+		},
+	}
+
+	dir, err := ioutil.TempDir("", "record")
+	g.Expect(err).To(BeNil())
+	defer os.RemoveAll(dir)
+	recorder, err := record.NewRecorder(filepath.Join(dir, "recording.jsonl"), 1, nil, 0)
+	g.Expect(err).To(BeNil())
+	defer recorder.Close()
+
+	url, _ := url.Parse("http://localhost")
+	r := NewServerRestApi(&p, &test.SeldonMessageTestClient{}, false, url, "default", api.ProtocolSeldon, "test", "/metrics", true)
+	r.SetRecorder(recorder)
+	r.Initialise()
+	var data = ` {"data":{"ndarray":[1.1,2.0]}}`
+
+	req, _ := http.NewRequest("POST", "/api/v1.0/predictions", strings.NewReader(data))
+	req.Header = map[string][]string{"Content-Type": []string{"application/json"}}
+	res := httptest.NewRecorder()
+	r.Router.ServeHTTP(res, req)
+	g.Expect(res.Code).To(Equal(200))
+
+	f, err := os.Open(filepath.Join(dir, "recording.jsonl"))
+	g.Expect(err).To(BeNil())
+	defer f.Close()
+	exchanges, err := record.ReadExchanges(f)
+	g.Expect(err).To(BeNil())
+	g.Expect(exchanges).To(HaveLen(1))
+	g.Expect(exchanges[0].Path).To(Equal("/api/v1.0/predictions"))
+	g.Expect(exchanges[0].Puid).ToNot(BeEmpty())
+	g.Expect(string(exchanges[0].RequestBody())).To(Equal(strings.TrimSpace(data)))
+	g.Expect(string(exchanges[0].ResponseBody())).To(Equal(strings.TrimSpace(res.Body.String())))
+}
+
 func TestCloudeventHeaderIsSet(t *testing.T) {
 	t.Logf("Started")
 	g := NewGomegaWithT(t)
@@ -708,3 +763,82 @@ func TestTensorflowModelBadModelName(t *testing.T) {
 	r.Router.ServeHTTP(res, req)
 	g.Expect(res.Code).To(Equal(200))
 }
+
+func TestReadyWhileDraining(t *testing.T) {
+	t.Logf("Started")
+	g := NewGomegaWithT(t)
+
+	model := v1.MODEL
+	p := v1.PredictorSpec{
+		Name: "p",
+		Graph: v1.PredictiveUnit{
+			Type: &model,
+		},
+	}
+	url, _ := url.Parse("http://localhost")
+	r := NewServerRestApi(&p, &test.SeldonMessageTestClient{}, false, url, "default", api.ProtocolV2, "test", "/metrics", true)
+	r.Initialise()
+
+	req, _ := http.NewRequest("GET", "/v2/health/ready", nil)
+	res := httptest.NewRecorder()
+	r.Router.ServeHTTP(res, req)
+	g.Expect(res.Code).To(Equal(http.StatusOK))
+
+	util.SetDraining(true)
+	defer util.SetDraining(false)
+
+	res = httptest.NewRecorder()
+	r.Router.ServeHTTP(res, req)
+	g.Expect(res.Code).To(Equal(http.StatusServiceUnavailable))
+
+	req, _ = http.NewRequest("GET", "/live", nil)
+	res = httptest.NewRecorder()
+	r.Router.ServeHTTP(res, req)
+	g.Expect(res.Code).To(Equal(http.StatusOK))
+}
+
+func TestReadyFromProber(t *testing.T) {
+	t.Logf("Started")
+	g := NewGomegaWithT(t)
+
+	model := v1.MODEL
+	p := v1.PredictorSpec{
+		Name: "p",
+		Graph: v1.PredictiveUnit{
+			Name:     "model",
+			Type:     &model,
+			Endpoint: &v1.Endpoint{ServiceHost: "127.0.0.1", ServicePort: 1, Type: v1.REST},
+		},
+	}
+	prober := predictor.NewReadinessProber(predictor.NewPredictorStore(&p), api.ProtocolSeldon, true, time.Minute, 100*time.Millisecond, nil)
+	predictor.EnableReadinessProber(prober)
+	defer predictor.EnableReadinessProber(nil)
+
+	url, _ := url.Parse("http://localhost")
+	r := NewServerRestApi(&p, &test.SeldonMessageTestClient{}, false, url, "default", api.ProtocolSeldon, "test", "/metrics", true)
+	r.Initialise()
+
+	// Not ready until the nodes are probed
+	req, _ := http.NewRequest("GET", "/ready", nil)
+	res := httptest.NewRecorder()
+	r.Router.ServeHTTP(res, req)
+	g.Expect(res.Code).To(Equal(http.StatusServiceUnavailable))
+
+	prober.Probe()
+	req, _ = http.NewRequest("GET", "/ready/nodes", nil)
+	res = httptest.NewRecorder()
+	r.Router.ServeHTTP(res, req)
+	g.Expect(res.Code).To(Equal(http.StatusServiceUnavailable))
+	var status predictor.ReadinessStatus
+	g.Expect(json.Unmarshal(res.Body.Bytes(), &status)).To(BeNil())
+	g.Expect(status.Nodes).To(HaveLen(1))
+	g.Expect(status.Nodes[0].Name).To(Equal("model"))
+	g.Expect(status.Nodes[0].LastError).ToNot(BeEmpty())
+
+	prober.OptionalNodes["model"] = true
+	prober.Probe()
+	req, _ = http.NewRequest("GET", "/ready", nil)
+	res = httptest.NewRecorder()
+	r.Router.ServeHTTP(res, req)
+	g.Expect(res.Code).To(Equal(http.StatusOK))
+}
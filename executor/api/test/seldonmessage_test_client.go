@@ -105,6 +105,9 @@ func (s SeldonMessageTestClient) Route(ctx context.Context, modelName string, ho
 }
 
 func (s SeldonMessageTestClient) Combine(ctx context.Context, modelName string, host string, port int32, msgs []payload.SeldonPayload, meta map[string][]string) (payload.SeldonPayload, error) {
+	if s.ErrMethod != nil && *s.ErrMethod == v1.AGGREGATE {
+		return s.ErrPayload, s.Err
+	}
 	return msgs[0], nil
 }
 
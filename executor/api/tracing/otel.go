@@ -0,0 +1,207 @@
+package tracing
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	ENV_OTEL_SERVICE_NAME           = "OTEL_SERVICE_NAME"
+	ENV_OTEL_EXPORTER_OTLP_PROTOCOL = "OTEL_EXPORTER_OTLP_PROTOCOL"
+
+	OtlpProtocolGrpc         = "grpc"
+	OtlpProtocolHttpProtobuf = "http/protobuf"
+
+	tracerName         = "github.com/seldonio/seldon-core/executor"
+	serviceNameKey     = "service.name"
+	otelShutdownPeriod = 10 * time.Second
+)
+
+var otelEnabled = false
+
+// OpenTelemetryEnabled returns whether spans are created with OpenTelemetry rather than opentracing.
+func OpenTelemetryEnabled() bool {
+	return otelEnabled
+}
+
+type otelCloser struct {
+	provider *sdktrace.TracerProvider
+}
+
+// Close flushes the spans not yet exported and stops the exporter.
+func (c otelCloser) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), otelShutdownPeriod)
+	defer cancel()
+	return c.provider.Shutdown(ctx)
+}
+
+func newOtlpExporter() (*otlptrace.Exporter, error) {
+	protocol := os.Getenv(ENV_OTEL_EXPORTER_OTLP_PROTOCOL)
+	// The endpoint, headers, TLS and timeout are read by the exporters from the standard OTEL_EXPORTER_OTLP variables
+	if protocol == OtlpProtocolHttpProtobuf {
+		return otlptracehttp.New(context.Background())
+	}
+	return otlptracegrpc.New(context.Background())
+}
+
+// initOpenTelemetry sets the global tracer provider to one exporting spans with OTLP and propagates the W3C
+// traceparent and baggage headers. The sampler is set by OTEL_TRACES_SAMPLER and OTEL_TRACES_SAMPLER_ARG.
+func initOpenTelemetry() (io.Closer, error) {
+	exporter, err := newOtlpExporter()
+	if err != nil {
+		return nil, err
+	}
+	res := resource.Default()
+	if os.Getenv(ENV_OTEL_SERVICE_NAME) == "" {
+		res, err = resource.Merge(res, resource.NewSchemaless(attribute.String(serviceNameKey, "executor")))
+		if err != nil {
+			return nil, err
+		}
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	SetTracerProvider(provider)
+	return otelCloser{provider: provider}, nil
+}
+
+// SetTracerProvider creates spans with OpenTelemetry from the provider, or with opentracing again if it is nil.
+func SetTracerProvider(provider trace.TracerProvider) {
+	if provider == nil {
+		otelEnabled = false
+		return
+	}
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	otelEnabled = true
+}
+
+func tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// StartSpan starts a span of the executor's work, such as a graph node, as a child of any span in ctx.
+func StartSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// StartServerSpan starts the span of a request received by the executor, continuing the trace in its headers.
+func StartServerSpan(ctx context.Context, name string, header http.Header) (context.Context, trace.Span) {
+	ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(header))
+	return tracer().Start(ctx, name, trace.WithSpanKind(trace.SpanKindServer))
+}
+
+// StartClientSpan starts the span of a request sent by the executor and adds its trace context to the headers.
+func StartClientSpan(ctx context.Context, name string, header http.Header) (context.Context, trace.Span) {
+	ctx, span := tracer().Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient))
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(header))
+	return ctx, span
+}
+
+// StartConsumerSpan starts the span of processing a message received, continuing the trace in its headers.
+func StartConsumerSpan(ctx context.Context, name string, carrier propagation.TextMapCarrier) (context.Context, trace.Span) {
+	ctx = otel.GetTextMapPropagator().Extract(ctx, carrier)
+	return tracer().Start(ctx, name, trace.WithSpanKind(trace.SpanKindConsumer))
+}
+
+// StartProducerSpan starts the span of sending a message and adds its trace context to the message headers.
+func StartProducerSpan(ctx context.Context, name string, carrier propagation.TextMapCarrier) (context.Context, trace.Span) {
+	ctx, span := tracer().Start(ctx, name, trace.WithSpanKind(trace.SpanKindProducer))
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	return ctx, span
+}
+
+// Inject adds the trace context of ctx to the carrier.
+func Inject(ctx context.Context, carrier propagation.TextMapCarrier) {
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+}
+
+// EndSpan records err, if any, on the span and ends it.
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// metadataCarrier adapts grpc metadata to the propagators.
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	values := metadata.MD(c).Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+func (c metadataCarrier) Set(key string, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func grpcSpanName(fullMethod string) string {
+	return strings.TrimPrefix(fullMethod, "/")
+}
+
+// UnaryServerInterceptor starts a span for each grpc request, continuing the trace in its metadata.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, ok := metadata.FromIncomingContext(ctx)
+		if !ok {
+			md = metadata.MD{}
+		}
+		ctx = otel.GetTextMapPropagator().Extract(ctx, metadataCarrier(md))
+		ctx, span := tracer().Start(ctx, grpcSpanName(info.FullMethod), trace.WithSpanKind(trace.SpanKindServer))
+		resp, err := handler(ctx, req)
+		span.SetAttributes(attribute.String("rpc.grpc.status_code", status.Code(err).String()))
+		EndSpan(span, err)
+		return resp, err
+	}
+}
+
+// UnaryClientInterceptor starts a span for each grpc call and adds its trace context to the outgoing metadata.
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, span := tracer().Start(ctx, grpcSpanName(method), trace.WithSpanKind(trace.SpanKindClient))
+		md, ok := metadata.FromOutgoingContext(ctx)
+		if ok {
+			md = md.Copy()
+		} else {
+			md = metadata.MD{}
+		}
+		otel.GetTextMapPropagator().Inject(ctx, metadataCarrier(md))
+		ctx = metadata.NewOutgoingContext(ctx, md)
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		span.SetAttributes(attribute.String("rpc.grpc.status_code", status.Code(err).String()))
+		EndSpan(span, err)
+		return err
+	}
+}
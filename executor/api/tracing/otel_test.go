@@ -0,0 +1,77 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func enableTestTracing(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { SetTracerProvider(nil) })
+	return recorder
+}
+
+func TestOpenTelemetryHeaderPropagation(t *testing.T) {
+	g := NewGomegaWithT(t)
+	recorder := enableTestTracing(t)
+	g.Expect(OpenTelemetryEnabled()).To(BeTrue())
+
+	header := http.Header{}
+	clientCtx, clientSpan := StartClientSpan(context.Background(), "predict", header)
+	g.Expect(header.Get("traceparent")).ToNot(BeEmpty())
+
+	_, serverSpan := StartServerSpan(context.Background(), "predictions", header)
+	EndSpan(serverSpan, nil)
+	EndSpan(clientSpan, errors.New("failed"))
+
+	spans := recorder.Ended()
+	g.Expect(spans).To(HaveLen(2))
+	clientSpanContext := trace.SpanContextFromContext(clientCtx)
+	g.Expect(spans[0].Name()).To(Equal("predictions"))
+	g.Expect(spans[0].SpanKind()).To(Equal(trace.SpanKindServer))
+	g.Expect(spans[0].Parent().SpanID()).To(Equal(clientSpanContext.SpanID()))
+	g.Expect(spans[0].SpanContext().TraceID()).To(Equal(clientSpanContext.TraceID()))
+	g.Expect(spans[1].Status().Code).To(Equal(codes.Error))
+}
+
+func TestOpenTelemetryGrpcInterceptors(t *testing.T) {
+	g := NewGomegaWithT(t)
+	recorder := enableTestTracing(t)
+
+	var serverSpanContext trace.SpanContext
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		serverSpanContext = trace.SpanContextFromContext(ctx)
+		return req, nil
+	}
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		g.Expect(md.Get("traceparent")).To(HaveLen(1))
+		// Hand the metadata to the server as grpc would
+		serverCtx := metadata.NewIncomingContext(context.Background(), md)
+		_, err := UnaryServerInterceptor()(serverCtx, req, &grpc.UnaryServerInfo{FullMethod: "/seldon.protos.Model/Predict"}, handler)
+		return err
+	}
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "seldon-puid", "1")
+	err := UnaryClientInterceptor()(ctx, "/seldon.protos.Model/Predict", nil, nil, nil, invoker)
+	g.Expect(err).To(BeNil())
+
+	spans := recorder.Ended()
+	g.Expect(spans).To(HaveLen(2))
+	g.Expect(spans[0].Name()).To(Equal("seldon.protos.Model/Predict"))
+	g.Expect(spans[0].SpanKind()).To(Equal(trace.SpanKindServer))
+	g.Expect(spans[1].SpanKind()).To(Equal(trace.SpanKindClient))
+	g.Expect(serverSpanContext.TraceID()).To(Equal(spans[1].SpanContext().TraceID()))
+	g.Expect(spans[0].Parent().SpanID()).To(Equal(spans[1].SpanContext().SpanID()))
+}
@@ -9,7 +9,22 @@ import (
 	"github.com/uber/jaeger-client-go/zipkin"
 )
 
+const (
+	ENV_TRACING_BACKEND = "TRACING_BACKEND"
+
+	BackendOpenTracing   = "opentracing"
+	BackendOpenTelemetry = "opentelemetry"
+	BackendOtel          = "otel"
+)
+
+// InitTracing initialises the tracer chosen by TRACING_BACKEND: opentelemetry, exporting with OTLP, or by default
+// opentracing with the jaeger client.
 func InitTracing() (io.Closer, error) {
+	switch os.Getenv(ENV_TRACING_BACKEND) {
+	case BackendOpenTelemetry, BackendOtel:
+		return initOpenTelemetry()
+	}
+
 	//Initialise tracing
 	cfg, err := jaegercfg.FromEnv()
 	if err != nil {
@@ -0,0 +1,141 @@
+package util
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/go-logr/logr"
+)
+
+// CertWatcher serves the listener certificate, and optionally the CA used to verify client certificates,
+// reloading both whenever the files in the watched directory change. Kubernetes secret mounts are updated
+// by swapping a symlink, so the directory rather than the individual files is watched.
+type CertWatcher struct {
+	sync.RWMutex
+	certPath     string
+	keyPath      string
+	clientCAPath string
+	cert         *tls.Certificate
+	clientCAs    *x509.CertPool
+	watcher      *fsnotify.Watcher
+	onReload     []func()
+	Log          logr.Logger
+}
+
+// NewCertWatcher loads the certificate and key and watches dir for changes. If clientCAPath is not empty
+// client certificates are required and verified against the CA bundle it contains.
+func NewCertWatcher(dir string, certPath string, keyPath string, clientCAPath string, logger logr.Logger) (*CertWatcher, error) {
+	cw := &CertWatcher{
+		certPath:     certPath,
+		keyPath:      keyPath,
+		clientCAPath: clientCAPath,
+		Log:          logger.WithName("CertWatcher"),
+	}
+	if err := cw.load(); err != nil {
+		return nil, err
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := watcher.Add(dir); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+	cw.watcher = watcher
+	return cw, nil
+}
+
+func (cw *CertWatcher) load() error {
+	cert, err := tls.LoadX509KeyPair(cw.certPath, cw.keyPath)
+	if err != nil {
+		return err
+	}
+	var clientCAs *x509.CertPool
+	if cw.clientCAPath != "" {
+		caBytes, err := ioutil.ReadFile(cw.clientCAPath)
+		if err != nil {
+			return err
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(caBytes) {
+			return fmt.Errorf("no certificates found in client CA bundle %s", cw.clientCAPath)
+		}
+	}
+	cw.Lock()
+	defer cw.Unlock()
+	cw.cert = &cert
+	cw.clientCAs = clientCAs
+	return nil
+}
+
+// Start reloads the certificates on every change to the watched directory until stop is closed.
+// A failed reload keeps the previous certificates so a partially written secret never drops traffic.
+func (cw *CertWatcher) Start(stop <-chan struct{}) {
+	defer cw.watcher.Close()
+	for {
+		select {
+		case <-stop:
+			return
+		case event, ok := <-cw.watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op == fsnotify.Chmod {
+				continue
+			}
+			if err := cw.load(); err != nil {
+				cw.Log.Error(err, "Failed to reload certificates, keeping previous", "event", event.String())
+			} else {
+				cw.Log.Info("Reloaded certificates", "event", event.String())
+				for _, f := range cw.onReload {
+					f()
+				}
+			}
+		case err, ok := <-cw.watcher.Errors:
+			if !ok {
+				return
+			}
+			cw.Log.Error(err, "Certificate watch error")
+		}
+	}
+}
+
+// OnReload calls f after each successful reload. It must be called before Start.
+func (cw *CertWatcher) OnReload(f func()) {
+	cw.onReload = append(cw.onReload, f)
+}
+
+// GetCertificate returns the current certificate for use in tls.Config.
+func (cw *CertWatcher) GetCertificate(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cw.RLock()
+	defer cw.RUnlock()
+	return cw.cert, nil
+}
+
+func (cw *CertWatcher) getConfigForClient(_ *tls.ClientHelloInfo) (*tls.Config, error) {
+	cw.RLock()
+	defer cw.RUnlock()
+	return &tls.Config{
+		GetCertificate: cw.GetCertificate,
+		ClientCAs:      cw.clientCAs,
+		ClientAuth:     tls.RequireAndVerifyClientCert,
+		MinVersion:     tls.VersionTLS12,
+	}, nil
+}
+
+// TLSConfig returns a server TLS config backed by the watched certificates.
+func (cw *CertWatcher) TLSConfig() *tls.Config {
+	tlsConfig := &tls.Config{
+		GetCertificate: cw.GetCertificate,
+		MinVersion:     tls.VersionTLS12,
+	}
+	if cw.clientCAPath != "" {
+		tlsConfig.GetConfigForClient = cw.getConfigForClient
+	}
+	return tlsConfig
+}
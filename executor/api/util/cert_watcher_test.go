@@ -0,0 +1,63 @@
+package util
+
+import (
+	"bytes"
+	"crypto/tls"
+	"io/ioutil"
+	"os"
+	"path"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func TestCertWatcherReload(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	dir, err := ioutil.TempDir("", "seldon-certs")
+	g.Expect(err).To(BeNil())
+	defer os.RemoveAll(dir)
+	writeTestCerts(g, dir)
+
+	cw, err := NewCertWatcher(dir, path.Join(dir, DefaultCertFileName), path.Join(dir, DefaultKeyFileName), "", logf.Log)
+	g.Expect(err).To(BeNil())
+	var reloads int32
+	cw.OnReload(func() { atomic.AddInt32(&reloads, 1) })
+	stop := make(chan struct{})
+	defer close(stop)
+	go cw.Start(stop)
+
+	cert, err := cw.GetCertificate(nil)
+	g.Expect(err).To(BeNil())
+	g.Expect(cw.TLSConfig().GetConfigForClient).To(BeNil())
+
+	writeTestCerts(g, dir)
+	g.Eventually(func() bool {
+		reloaded, _ := cw.GetCertificate(nil)
+		return !bytes.Equal(reloaded.Certificate[0], cert.Certificate[0])
+	}, 5*time.Second, 50*time.Millisecond).Should(BeTrue())
+	g.Eventually(func() int32 { return atomic.LoadInt32(&reloads) }, 5*time.Second, 50*time.Millisecond).Should(BeNumerically(">", 0))
+}
+
+func TestCertWatcherClientVerification(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	dir, err := ioutil.TempDir("", "seldon-certs")
+	g.Expect(err).To(BeNil())
+	defer os.RemoveAll(dir)
+	writeTestCerts(g, dir)
+
+	cw, err := NewCertWatcher(dir, path.Join(dir, DefaultCertFileName), path.Join(dir, DefaultKeyFileName), path.Join(dir, DefaultCACertFileName), logf.Log)
+	g.Expect(err).To(BeNil())
+
+	tlsConfig, err := cw.TLSConfig().GetConfigForClient(nil)
+	g.Expect(err).To(BeNil())
+	g.Expect(tlsConfig.ClientAuth).To(Equal(tls.RequireAndVerifyClientCert))
+	g.Expect(tlsConfig.ClientCAs).ToNot(BeNil())
+
+	_, err = NewCertWatcher(dir, path.Join(dir, DefaultCertFileName), path.Join(dir, DefaultKeyFileName), path.Join(dir, "missing.crt"), logf.Log)
+	g.Expect(err).ToNot(BeNil())
+}
@@ -0,0 +1,20 @@
+package util
+
+import "sync/atomic"
+
+var draining int32
+
+// SetDraining marks the executor as shutting down. While draining, readiness checks report not ready
+// so load balancers stop routing new requests and in-flight requests can complete.
+func SetDraining(isDraining bool) {
+	var val int32
+	if isDraining {
+		val = 1
+	}
+	atomic.StoreInt32(&draining, val)
+}
+
+// IsDraining returns true if the executor is shutting down.
+func IsDraining() bool {
+	return atomic.LoadInt32(&draining) == 1
+}
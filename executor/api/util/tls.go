@@ -0,0 +1,172 @@
+package util
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"sync"
+
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+)
+
+const (
+	CertMountPathEnvVar = "SELDON_CERT_MOUNT_PATH"
+
+	DefaultCACertFileName = "ca.crt"
+	DefaultCertFileName   = "tls.crt"
+	DefaultKeyFileName    = "tls.key"
+)
+
+func valueOrDefault(value string, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+type clientTLSKey struct {
+	clientTLS     v1.ClientTLS
+	certMountPath string
+}
+
+// clientTLSCache holds the last client TLS config built so probes and clients don't read the certificates
+// from disk on every call. Its certificates are read again by ReloadClientTLSConfig when the mounted
+// certificates change, which long-lived transports using the config pick up on their next handshake.
+var clientTLSCache struct {
+	sync.Mutex
+	key       clientTLSKey
+	certs     *clientCerts
+	tlsConfig *tls.Config
+}
+
+// GetClientTLSConfig returns the TLS config for calls to the predictor's graph nodes or nil if
+// client TLS is not enabled. Certificates are read from the mounted cert secret on the first call
+// and again on each ReloadClientTLSConfig.
+func GetClientTLSConfig(predictor *v1.PredictorSpec) (*tls.Config, error) {
+	if !v1.IsClientTLSEnabled(predictor) {
+		return nil, nil
+	}
+	certMountPath := GetEnv(CertMountPathEnvVar, "")
+	if certMountPath == "" {
+		return nil, fmt.Errorf("client TLS enabled but %s is not set", CertMountPathEnvVar)
+	}
+	key := clientTLSKey{clientTLS: *predictor.SSL.ClientTLS, certMountPath: certMountPath}
+	clientTLSCache.Lock()
+	defer clientTLSCache.Unlock()
+	if clientTLSCache.tlsConfig != nil && clientTLSCache.key == key {
+		return clientTLSCache.tlsConfig, nil
+	}
+	certs := &clientCerts{clientTLS: key.clientTLS, certMountPath: certMountPath}
+	if err := certs.load(); err != nil {
+		return nil, err
+	}
+	clientTLSCache.key = key
+	clientTLSCache.certs = certs
+	clientTLSCache.tlsConfig = certs.tlsConfig()
+	return clientTLSCache.tlsConfig, nil
+}
+
+// ReloadClientTLSConfig reads the certificates of the cached client TLS config again. If they can't be read
+// the config keeps the certificates it had.
+func ReloadClientTLSConfig() error {
+	clientTLSCache.Lock()
+	defer clientTLSCache.Unlock()
+	if clientTLSCache.certs == nil {
+		return nil
+	}
+	return clientTLSCache.certs.load()
+}
+
+// clientCerts holds the CA pool and client certificate of a client TLS config, read from the files in the
+// cert mount path.
+type clientCerts struct {
+	sync.RWMutex
+	clientTLS     v1.ClientTLS
+	certMountPath string
+	caPool        *x509.CertPool
+	cert          *tls.Certificate
+}
+
+func (c *clientCerts) load() error {
+	caPath := path.Join(c.certMountPath, valueOrDefault(c.clientTLS.CaFileName, DefaultCACertFileName))
+	caBytes, err := ioutil.ReadFile(caPath)
+	if err != nil {
+		return fmt.Errorf("failed to read CA bundle %s: %w", caPath, err)
+	}
+	caPool := x509.NewCertPool()
+	if !caPool.AppendCertsFromPEM(caBytes) {
+		return fmt.Errorf("no certificates found in CA bundle %s", caPath)
+	}
+
+	var cert *tls.Certificate
+	switch c.clientTLS.Mode {
+	case v1.ClientTLSSimple:
+	case v1.ClientTLSMutual:
+		certPath := path.Join(c.certMountPath, valueOrDefault(c.clientTLS.CertFileName, DefaultCertFileName))
+		keyPath := path.Join(c.certMountPath, valueOrDefault(c.clientTLS.KeyFileName, DefaultKeyFileName))
+		keyPair, err := tls.LoadX509KeyPair(certPath, keyPath)
+		if err != nil {
+			return fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		cert = &keyPair
+	default:
+		return fmt.Errorf("unknown client TLS mode %s", c.clientTLS.Mode)
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	c.caPool, c.cert = caPool, cert
+	return nil
+}
+
+// tlsConfig returns a config presenting and verifying with the certificates current at each handshake.
+// Verification is done by verifyConnection as the config's RootCAs can't be replaced once it is in use.
+func (c *clientCerts) tlsConfig() *tls.Config {
+	tlsConfig := &tls.Config{
+		ServerName:         c.clientTLS.ServerName,
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: true,
+		VerifyConnection:   c.verifyConnection,
+	}
+	if c.clientTLS.Mode == v1.ClientTLSMutual {
+		tlsConfig.GetClientCertificate = c.clientCertificate
+	}
+	return tlsConfig
+}
+
+func (c *clientCerts) verifyConnection(cs tls.ConnectionState) error {
+	if len(cs.PeerCertificates) == 0 {
+		return fmt.Errorf("server presented no certificate")
+	}
+	c.RLock()
+	caPool := c.caPool
+	c.RUnlock()
+	intermediates := x509.NewCertPool()
+	for _, cert := range cs.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := cs.PeerCertificates[0].Verify(x509.VerifyOptions{
+		Roots:         caPool,
+		DNSName:       cs.ServerName,
+		Intermediates: intermediates,
+	})
+	return err
+}
+
+func (c *clientCerts) clientCertificate(_ *tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	c.RLock()
+	defer c.RUnlock()
+	return c.cert, nil
+}
+
+// NewClientTLSConfig creates a TLS config verifying servers against the CA bundle in certMountPath and,
+// for mtls, presenting the client certificate found alongside it.
+func NewClientTLSConfig(clientTLS *v1.ClientTLS, certMountPath string) (*tls.Config, error) {
+	certs := &clientCerts{clientTLS: *clientTLS, certMountPath: certMountPath}
+	if err := certs.load(); err != nil {
+		return nil, err
+	}
+	return certs.tlsConfig(), nil
+}
@@ -0,0 +1,182 @@
+package util
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+)
+
+func writeTestCerts(g *WithT, dir string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	g.Expect(err).To(BeNil())
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "seldon-test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		DNSNames:              []string{"seldon-test"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	g.Expect(err).To(BeNil())
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	g.Expect(err).To(BeNil())
+
+	certPem := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPem := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+	g.Expect(ioutil.WriteFile(path.Join(dir, DefaultCACertFileName), certPem, 0600)).To(BeNil())
+	g.Expect(ioutil.WriteFile(path.Join(dir, DefaultCertFileName), certPem, 0600)).To(BeNil())
+	g.Expect(ioutil.WriteFile(path.Join(dir, DefaultKeyFileName), keyPem, 0600)).To(BeNil())
+}
+
+func TestGetClientTLSConfigDisabled(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	tlsConfig, err := GetClientTLSConfig(&v1.PredictorSpec{})
+	g.Expect(err).To(BeNil())
+	g.Expect(tlsConfig).To(BeNil())
+
+	tlsConfig, err = GetClientTLSConfig(&v1.PredictorSpec{SSL: &v1.SSL{ClientTLS: &v1.ClientTLS{Mode: v1.ClientTLSDisabled}}})
+	g.Expect(err).To(BeNil())
+	g.Expect(tlsConfig).To(BeNil())
+}
+
+func TestGetClientTLSConfigNoMountPath(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	os.Unsetenv(CertMountPathEnvVar)
+	_, err := GetClientTLSConfig(&v1.PredictorSpec{SSL: &v1.SSL{CertSecretName: "certs", ClientTLS: &v1.ClientTLS{Mode: v1.ClientTLSSimple}}})
+	g.Expect(err).ToNot(BeNil())
+}
+
+func TestNewClientTLSConfig(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	dir, err := ioutil.TempDir("", "seldon-tls")
+	g.Expect(err).To(BeNil())
+	defer os.RemoveAll(dir)
+	writeTestCerts(g, dir)
+
+	tlsConfig, err := NewClientTLSConfig(&v1.ClientTLS{Mode: v1.ClientTLSSimple, ServerName: "seldon-test"}, dir)
+	g.Expect(err).To(BeNil())
+	g.Expect(tlsConfig.VerifyConnection).ToNot(BeNil())
+	g.Expect(tlsConfig.ServerName).To(Equal("seldon-test"))
+	g.Expect(tlsConfig.GetClientCertificate).To(BeNil())
+
+	tlsConfig, err = NewClientTLSConfig(&v1.ClientTLS{Mode: v1.ClientTLSMutual}, dir)
+	g.Expect(err).To(BeNil())
+	cert, err := tlsConfig.GetClientCertificate(nil)
+	g.Expect(err).To(BeNil())
+	g.Expect(cert.Certificate).To(HaveLen(1))
+
+	_, err = NewClientTLSConfig(&v1.ClientTLS{Mode: v1.ClientTLSMutual, KeyFileName: "missing.key"}, dir)
+	g.Expect(err).ToNot(BeNil())
+
+	_, err = NewClientTLSConfig(&v1.ClientTLS{Mode: v1.ClientTLSSimple, CaFileName: "missing.crt"}, dir)
+	g.Expect(err).ToNot(BeNil())
+
+	_, err = NewClientTLSConfig(&v1.ClientTLS{Mode: "other"}, dir)
+	g.Expect(err).ToNot(BeNil())
+}
+
+func TestGetClientTLSConfigCached(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	dir, err := ioutil.TempDir("", "seldon-tls")
+	g.Expect(err).To(BeNil())
+	defer os.RemoveAll(dir)
+	writeTestCerts(g, dir)
+	os.Setenv(CertMountPathEnvVar, dir)
+	defer os.Unsetenv(CertMountPathEnvVar)
+	defer func() { clientTLSCache.certs, clientTLSCache.tlsConfig = nil, nil }()
+
+	spec := &v1.PredictorSpec{SSL: &v1.SSL{CertSecretName: "certs", ClientTLS: &v1.ClientTLS{Mode: v1.ClientTLSSimple}}}
+	tlsConfig, err := GetClientTLSConfig(spec)
+	g.Expect(err).To(BeNil())
+
+	// Served from the cache even once the files are gone
+	g.Expect(os.Remove(path.Join(dir, DefaultCACertFileName))).To(BeNil())
+	cached, err := GetClientTLSConfig(spec)
+	g.Expect(err).To(BeNil())
+	g.Expect(cached).To(BeIdenticalTo(tlsConfig))
+
+	// A failed reload keeps the cached config
+	g.Expect(ReloadClientTLSConfig()).ToNot(Succeed())
+	cached, err = GetClientTLSConfig(spec)
+	g.Expect(err).To(BeNil())
+	g.Expect(cached).To(BeIdenticalTo(tlsConfig))
+
+	writeTestCerts(g, dir)
+	g.Expect(ReloadClientTLSConfig()).To(Succeed())
+	reloaded, err := GetClientTLSConfig(spec)
+	g.Expect(err).To(BeNil())
+	g.Expect(reloaded).To(BeIdenticalTo(tlsConfig))
+}
+
+func TestClientTLSConfigRotation(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	dir, err := ioutil.TempDir("", "seldon-tls")
+	g.Expect(err).To(BeNil())
+	defer os.RemoveAll(dir)
+	os.Setenv(CertMountPathEnvVar, dir)
+	defer os.Unsetenv(CertMountPathEnvVar)
+	defer func() { clientTLSCache.certs, clientTLSCache.tlsConfig = nil, nil }()
+
+	// Each server presents, and only accepts clients presenting, the certificates written at its start
+	startServer := func() *httptest.Server {
+		cert, err := tls.LoadX509KeyPair(path.Join(dir, DefaultCertFileName), path.Join(dir, DefaultKeyFileName))
+		g.Expect(err).To(BeNil())
+		caBytes, err := ioutil.ReadFile(path.Join(dir, DefaultCACertFileName))
+		g.Expect(err).To(BeNil())
+		clientCAs := x509.NewCertPool()
+		g.Expect(clientCAs.AppendCertsFromPEM(caBytes)).To(BeTrue())
+		server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		server.TLS = &tls.Config{Certificates: []tls.Certificate{cert}, ClientCAs: clientCAs, ClientAuth: tls.RequireAndVerifyClientCert}
+		server.StartTLS()
+		return server
+	}
+
+	writeTestCerts(g, dir)
+	oldServer := startServer()
+	defer oldServer.Close()
+
+	spec := &v1.PredictorSpec{SSL: &v1.SSL{CertSecretName: "certs", ClientTLS: &v1.ClientTLS{Mode: v1.ClientTLSMutual, ServerName: "seldon-test"}}}
+	tlsConfig, err := GetClientTLSConfig(spec)
+	g.Expect(err).To(BeNil())
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig, DisableKeepAlives: true}}
+	res, err := client.Get(oldServer.URL)
+	g.Expect(err).To(BeNil())
+	res.Body.Close()
+
+	writeTestCerts(g, dir)
+	newServer := startServer()
+	defer newServer.Close()
+	_, err = client.Get(newServer.URL)
+	g.Expect(err).ToNot(BeNil())
+
+	// The same transport handshakes with the rotated certificates once they are reloaded
+	g.Expect(ReloadClientTLSConfig()).To(Succeed())
+	res, err = client.Get(newServer.URL)
+	g.Expect(err).To(BeNil())
+	res.Body.Close()
+	_, err = client.Get(oldServer.URL)
+	g.Expect(err).ToNot(BeNil())
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/golang/protobuf/jsonpb"
 	"github.com/seldonio/seldon-core/executor/api/grpc/seldon/proto"
@@ -141,3 +142,15 @@ func GetEnvAsBool(key string, fallback bool) bool {
 
 	return fallback
 }
+
+// Get an environment variable given by key as a duration such as "5s" or return the fallback.
+func GetEnvAsDuration(key string, fallback time.Duration) time.Duration {
+	if raw, ok := os.LookupEnv(key); ok {
+		val, err := time.ParseDuration(raw)
+		if err == nil {
+			return val
+		}
+	}
+
+	return fallback
+}
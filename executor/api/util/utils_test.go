@@ -3,6 +3,7 @@ package util
 import (
 	"os"
 	"testing"
+	"time"
 
 	"github.com/golang/protobuf/jsonpb"
 	. "github.com/onsi/gomega"
@@ -101,6 +102,20 @@ func TestGetEnvAsBool(t *testing.T) {
 	}
 }
 
+func TestGetEnvAsDuration(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	os.Unsetenv("TEST_DURATION")
+	g.Expect(GetEnvAsDuration("TEST_DURATION", time.Second)).To(Equal(time.Second))
+
+	os.Setenv("TEST_DURATION", "250ms")
+	defer os.Unsetenv("TEST_DURATION")
+	g.Expect(GetEnvAsDuration("TEST_DURATION", time.Second)).To(Equal(250 * time.Millisecond))
+
+	os.Setenv("TEST_DURATION", "soon")
+	g.Expect(GetEnvAsDuration("TEST_DURATION", time.Second)).To(Equal(time.Second))
+}
+
 func TestInjectRouteSeldonProto(t *testing.T) {
 	g := NewGomegaWithT(t)
 
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"crypto/tls"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
@@ -18,17 +19,22 @@ import (
 	"time"
 
 	"strconv"
+	"strings"
 
 	"github.com/go-logr/logr"
 	"github.com/seldonio/seldon-core/executor/api"
+	"github.com/seldonio/seldon-core/executor/api/admin"
 	seldonclient "github.com/seldonio/seldon-core/executor/api/client"
 	"github.com/seldonio/seldon-core/executor/api/grpc"
+	"github.com/seldonio/seldon-core/executor/api/grpc/health"
 	"github.com/seldonio/seldon-core/executor/api/grpc/kfserving"
 	kfproto "github.com/seldonio/seldon-core/executor/api/grpc/kfserving/inference"
 	"github.com/seldonio/seldon-core/executor/api/grpc/seldon"
 	"github.com/seldonio/seldon-core/executor/api/grpc/seldon/proto"
 	"github.com/seldonio/seldon-core/executor/api/grpc/tensorflow"
 	"github.com/seldonio/seldon-core/executor/api/kafka"
+	"github.com/seldonio/seldon-core/executor/api/metric"
+	"github.com/seldonio/seldon-core/executor/api/nats"
 	"github.com/seldonio/seldon-core/executor/api/rest"
 	"github.com/seldonio/seldon-core/executor/api/tracing"
 	"github.com/seldonio/seldon-core/executor/api/util"
@@ -36,25 +42,41 @@ import (
 	loghandler "github.com/seldonio/seldon-core/executor/logger"
 	predictor2 "github.com/seldonio/seldon-core/executor/predictor"
 	"github.com/seldonio/seldon-core/executor/proto/tensorflow/serving"
+	"github.com/seldonio/seldon-core/executor/record"
+	"github.com/seldonio/seldon-core/executor/stats"
 	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
 	"go.uber.org/automaxprocs/maxprocs"
 	"go.uber.org/zap"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/reflection"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	zapf "sigs.k8s.io/controller-runtime/pkg/log/zap"
 )
 
 const (
-	logLevelEnvVar        = "SELDON_LOG_LEVEL"
-	logLevelDefault       = "INFO"
-	debugEnvVar           = "SELDON_DEBUG"
-	certMountPathEnvVar   = "SELDON_CERT_MOUNT_PATH"
-	certFileEnvVar        = "SELDON_CERT_FILE_NAME"
-	certKeyFileNameEnvVar = "SELDON_CERT_KEY_FILE_NAME"
+	logLevelEnvVar          = "SELDON_LOG_LEVEL"
+	logLevelDefault         = "INFO"
+	debugEnvVar             = "SELDON_DEBUG"
+	certMountPathEnvVar     = "SELDON_CERT_MOUNT_PATH"
+	certFileEnvVar          = "SELDON_CERT_FILE_NAME"
+	certKeyFileNameEnvVar   = "SELDON_CERT_KEY_FILE_NAME"
+	certClientCAFileEnvVar  = "SELDON_CERT_CLIENT_CA_FILE_NAME"
+	certVerifyClientEnvVar  = "SELDON_CERT_VERIFY_CLIENT"
+	modelMetricsEnvVar      = "SELDON_MODEL_METRICS"
+	stripModelMetricsEnvVar = "SELDON_STRIP_MODEL_METRICS"
+	featureStatsEnvVar      = "SELDON_FEATURE_STATS"
+	featureProfileEnvVar    = "SELDON_FEATURE_STATS_REFERENCE"
+	featureWindowEnvVar     = "SELDON_FEATURE_STATS_WINDOW"
+	probeIntervalEnvVar     = "SELDON_READINESS_PROBE_INTERVAL"
+	probeTimeoutEnvVar      = "SELDON_READINESS_PROBE_TIMEOUT"
+	optionalNodesEnvVar     = "SELDON_READINESS_OPTIONAL_NODES"
+	adminTokenEnvVar        = "SELDON_ADMIN_TOKEN"
+	recordFileEnvVar        = "SELDON_RECORD_FILE"
+	recordRedactEnvVar      = "SELDON_RECORD_REDACT"
 )
 
 var (
-	serverType = flag.String("server_type", "rpc", "Server type: rpc or kafka")
+	serverType = flag.String("server_type", "rpc", "Server type: rpc, kafka or nats")
 
 	debugDefault = false
 
@@ -79,11 +101,93 @@ var (
 	kafkaTopicOut     = flag.String("kafka_output_topic", "", "The kafka output topic")
 	kafkaFullGraph    = flag.Bool("kafka_full_graph", false, "Use kafka for internal graph processing")
 	kafkaWorkers      = flag.Int("kafka_workers", 4, "Number of kafka workers")
-	kafkaAutoCommit   = flag.Bool("kafka_auto_commit", true, "Use auto committing in the kafka consumer")
+	kafkaAutoCommit   = flag.Bool("kafka_auto_commit", true, "Use auto committing in the kafka consumer. If false offsets are committed once outputs are delivered (at-least-once)")
+	kafkaDeadLetter   = flag.String("kafka_dead_letter_topic", "", "The kafka topic failed requests are published to")
+	kafkaMaxRetries   = flag.Int("kafka_max_retries", 0, "Number of times a failed kafka request is retried")
+	kafkaRetryBackoff = flag.Duration("kafka_retry_backoff", time.Second, "Backoff before the first retry of a failed kafka request, doubled on each attempt")
+	kafkaErrorReplies = flag.Bool("kafka_error_replies", false, "Publish an error payload to the output topic for failed kafka requests")
+	kafkaHeaders      = flag.String("kafka_header_passthrough", "", "Comma separated input headers copied to kafka outputs or * for all")
+	kafkaKeyField     = flag.String("kafka_output_key_field", "", "Dotted path of the response field used as the kafka output key")
+	natsUrl           = flag.String("nats_url", "", "The nats server url")
+	natsSubjectIn     = flag.String("nats_input_subject", "", "The nats input subject")
+	natsSubjectOut    = flag.String("nats_output_subject", "", "The nats output subject. It must be captured by a JetStream stream")
+	natsStream        = flag.String("nats_stream", "", "The JetStream stream created for the input and output subjects if it doesn't exist")
+	natsWorkers       = flag.Int("nats_workers", 4, "Number of nats workers")
+	natsMaxDeliver    = flag.Int("nats_max_deliver", nats.DefaultMaxDeliver, "Deliveries of a failing nats request before it is dead-lettered. 0 for unlimited")
+	natsDeadLetter    = flag.String("nats_dead_letter_subject", "", "The nats subject failed requests are published to. Without one they are dropped")
 	logKafkaBroker    = flag.String("log_kafka_broker", "", "The kafka log broker")
 	logKafkaTopic     = flag.String("log_kafka_topic", "", "The kafka log topic")
+	logMaxRetries     = flag.Int("log_max_retries", loghandler.DefaultMaxRetries, "Number of retries of a log request which fails to send")
+	logRetryBackoff   = flag.Duration("log_retry_backoff", loghandler.DefaultRetryBackoff, "Delay before the first retry of a log request, doubled on each further attempt")
+	logSpillDir       = flag.String("log_spill_dir", "", "Directory log requests are written to when they can't be sent or buffered, to be sent once the endpoint recovers")
+	logSpillMaxBytes  = flag.Int64("log_spill_max_bytes", loghandler.DefaultSpillMaxBytes, "Maximum size of the log spill directory")
+	logBatchSize      = flag.Int("log_batch_size", loghandler.DefaultBatchSize, "Maximum number of log requests sent together as a cloudevents batch, kafka produce batch or sink write. 1 sends each on its own")
+	logBatchLinger    = flag.Duration("log_batch_linger", loghandler.DefaultBatchLinger, "Time to wait for a log batch to fill before sending it")
+	logSink           = flag.String("log_sink", "", "Store logs in a sink instead of sending them to the logger url or kafka: file, stdout or s3")
+	logSinkDir        = flag.String("log_sink_dir", "", "Directory the file log sink writes JSON lines files to")
+	logSinkFileBytes  = flag.Int64("log_sink_max_file_bytes", loghandler.DefaultSinkMaxFileBytes, "Size at which the file log sink starts a new file")
+	logSinkMaxFiles   = flag.Int("log_sink_max_files", loghandler.DefaultSinkMaxFiles, "Number of files the file log sink keeps. 0 keeps every file")
+	logS3Endpoint     = flag.String("log_s3_endpoint", "", "URL of the S3 compatible store the s3 log sink writes to. Defaults to AWS S3")
+	logS3Bucket       = flag.String("log_s3_bucket", "", "Bucket the s3 log sink writes to")
+	logS3Prefix       = flag.String("log_s3_prefix", "", "Key prefix of the objects written by the s3 log sink")
+	logS3Region       = flag.String("log_s3_region", "", "Region of the s3 log sink bucket")
 	fullHealthChecks  = flag.Bool("full_health_checks", false, "Full health checks via chosen protocol API")
-	debug             = flag.Bool(
+	predictorReload   = flag.Bool("predictor_reload", false, "Reload the predictor graph when the file given by --file or the SeldonDeployment changes. Watching the SeldonDeployment needs the pod service account bound to the seldon-executor-reload-role ClusterRole")
+	modelMetrics      = flag.Bool(
+		"model_metrics",
+		util.GetEnvAsBool(modelMetricsEnvVar, false),
+		"Expose the custom metrics returned by models in meta.metrics or v2 parameters as prometheus metrics prefixed with "+metric.CustomMetricPrefix,
+	)
+	stripModelMetrics = flag.Bool(
+		"strip_model_metrics",
+		util.GetEnvAsBool(stripModelMetricsEnvVar, false),
+		"Remove the custom metrics returned by models from the response",
+	)
+	featureStats = flag.Bool(
+		"feature_stats",
+		util.GetEnvAsBool(featureStatsEnvVar, false),
+		"Keep summaries of the input features of each model, exposed as prometheus metrics and at /stats/features",
+	)
+	featureProfile = flag.String(
+		"feature_stats_reference",
+		util.GetEnv(featureProfileEnvVar, ""),
+		"JSON file of summaries saved from /stats/features which feature drift is measured against",
+	)
+	featureWindow = flag.Duration(
+		"feature_stats_window",
+		util.GetEnvAsDuration(featureWindowEnvVar, stats.DefaultWindow),
+		"Length of the windows of feature values summarised, which cover the current and previous window. 0 summarises all values",
+	)
+	probeInterval = flag.Duration(
+		"readiness_probe_interval",
+		util.GetEnvAsDuration(probeIntervalEnvVar, predictor2.DefaultProbeInterval),
+		"Interval the graph nodes are health checked in the background, with /ready answered from the latest results. 0 checks the nodes on each /ready call",
+	)
+	probeTimeout = flag.Duration(
+		"readiness_probe_timeout",
+		util.GetEnvAsDuration(probeTimeoutEnvVar, predictor2.DefaultProbeTimeout),
+		"Timeout of the background health check of each graph node",
+	)
+	optionalNodes = flag.String(
+		"readiness_optional_nodes",
+		util.GetEnv(optionalNodesEnvVar, ""),
+		"Comma separated graph nodes whose failed health checks leave the executor ready but degraded",
+	)
+	recordFile = flag.String(
+		"record_file",
+		util.GetEnv(recordFileEnvVar, ""),
+		"File the prediction requests and responses are appended to for the replay command",
+	)
+	recordRate     = flag.Float64("record_rate", 1, "Fraction of prediction requests recorded, sampled by PUID")
+	recordMaxBytes = flag.Int64("record_max_bytes", 1<<30, "Size at which the recording stops. 0 doesn't limit it")
+	recordRedact   = flag.String(
+		"record_redact",
+		util.GetEnv(recordRedactEnvVar, ""),
+		`JSON list of redaction rules applied to recorded requests and responses, e.g. [{"tensor":"ssn","action":"drop"}]. Exchanges with bodies other than JSON aren't recorded when it is set`,
+	)
+	adminPort    = flag.Int("admin_port", 0, "Port of the admin listener serving the log level, configuration, graph and pprof endpoints. 0 disables it")
+	adminAddress = flag.String("admin_address", "127.0.0.1", "Address the admin listener binds to. The default only accepts connections from within the pod, e.g. through kubectl port-forward")
+	debug        = flag.Bool(
 		"debug",
 		util.GetEnvAsBool(debugEnvVar, debugDefault),
 		"Enable debug mode. Logs will be sampled and less structured.",
@@ -94,22 +198,29 @@ var (
 		"Log level.",
 	)
 
-	certMountPath   = util.GetEnv(certMountPathEnvVar, "")
-	certFileName    = util.GetEnv(certFileEnvVar, "tls.crt")
-	certKeyFileName = util.GetEnv(certFileEnvVar, "tls.key")
+	certMountPath        = util.GetEnv(certMountPathEnvVar, "")
+	certFileName         = util.GetEnv(certFileEnvVar, "tls.crt")
+	certKeyFileName      = util.GetEnv(certKeyFileNameEnvVar, "tls.key")
+	certClientCAFileName = util.GetEnv(certClientCAFileEnvVar, "ca.crt")
+	certVerifyClient     = util.GetEnvAsBool(certVerifyClientEnvVar, false)
+	adminToken           = util.GetEnv(adminTokenEnvVar, "")
 )
 
 func getServerUrl(hostname string, port int) (*url.URL, error) {
 	return url.Parse(fmt.Sprintf("http://%s:%d/", hostname, port))
 }
 
-func runHttpServer(wg *sync.WaitGroup, shutdown chan bool, lis net.Listener, logger logr.Logger, predictor *v1.PredictorSpec, client seldonclient.SeldonApiClient, port int, probesOnly bool, serverUrl *url.URL, namespace string, protocol string, deploymentName string, prometheusPath string, fullHealthChecks bool) {
+func runHttpServer(wg *sync.WaitGroup, shutdown chan bool, lis net.Listener, logger logr.Logger, predictorStore *predictor2.PredictorStore, client seldonclient.SeldonApiClient, port int, probesOnly bool, serverUrl *url.URL, namespace string, protocol string, deploymentName string, prometheusPath string, fullHealthChecks bool, recorder *record.Recorder) {
 	wg.Add(1)
 	defer wg.Done()
 	defer lis.Close()
 
 	// Create REST API
-	seldonRest := rest.NewServerRestApi(predictor, client, probesOnly, serverUrl, namespace, protocol, deploymentName, prometheusPath, fullHealthChecks)
+	seldonRest := rest.NewServerRestApi(predictorStore.Get(), client, probesOnly, serverUrl, namespace, protocol, deploymentName, prometheusPath, fullHealthChecks)
+	seldonRest.SetPredictorStore(predictorStore)
+	if recorder != nil {
+		seldonRest.SetRecorder(recorder)
+	}
 	seldonRest.Initialise()
 	srv := seldonRest.CreateHttpServer(port)
 
@@ -136,10 +247,11 @@ func runHttpServer(wg *sync.WaitGroup, shutdown chan bool, lis net.Listener, log
 	logger.Info("http server shutdown")
 }
 
-func runGrpcServer(wg *sync.WaitGroup, shutdown chan bool, lis net.Listener, logger logr.Logger, predictor *v1.PredictorSpec, client seldonclient.SeldonApiClient, serverUrl *url.URL, namespace string, protocol string, deploymentName string, annotations map[string]string) {
+func runGrpcServer(wg *sync.WaitGroup, shutdown chan bool, lis net.Listener, logger logr.Logger, predictorStore *predictor2.PredictorStore, client seldonclient.SeldonApiClient, serverUrl *url.URL, namespace string, protocol string, deploymentName string, annotations map[string]string, fullHealthChecks bool) {
 	wg.Add(1)
 	defer wg.Done()
 	defer lis.Close()
+	predictor := predictorStore.Get()
 	grpcServer, err := grpc.CreateGrpcServer(predictor, deploymentName, annotations, logger)
 	if err != nil {
 		log.Fatalf("Failed to create gRPC server: %v", err)
@@ -147,17 +259,26 @@ func runGrpcServer(wg *sync.WaitGroup, shutdown chan bool, lis net.Listener, log
 	switch protocol {
 	case api.ProtocolSeldon:
 		seldonGrpcServer := seldon.NewGrpcSeldonServer(predictor, client, serverUrl, namespace)
+		seldonGrpcServer.SetPredictorStore(predictorStore)
 		proto.RegisterSeldonServer(grpcServer, seldonGrpcServer)
 		// Register reflection service on gRPC server.
 		reflection.Register(grpcServer)
 	case api.ProtocolTensorflow:
 		tensorflowGrpcServer := tensorflow.NewGrpcTensorflowServer(predictor, client, serverUrl, namespace)
+		tensorflowGrpcServer.SetPredictorStore(predictorStore)
 		serving.RegisterPredictionServiceServer(grpcServer, tensorflowGrpcServer)
 		serving.RegisterModelServiceServer(grpcServer, tensorflowGrpcServer)
 	case api.ProtocolV2, api.ProtocolKFServing:
-		kfservingGrpcServer := kfserving.NewGrpcKFServingServer(predictor, client, serverUrl, namespace)
+		kfservingGrpcServer := kfserving.NewGrpcKFServingServer(predictor, client, serverUrl, namespace, fullHealthChecks)
+		kfservingGrpcServer.SetPredictorStore(predictorStore)
 		kfproto.RegisterGRPCInferenceServiceServer(grpcServer, kfservingGrpcServer)
 	}
+	// The health service answers for the whole server and for each service registered above.
+	services := []string{healthpb.Health_ServiceDesc.ServiceName}
+	for service := range grpcServer.GetServiceInfo() {
+		services = append(services, service)
+	}
+	healthpb.RegisterHealthServer(grpcServer, health.NewGrpcHealthServer(predictorStore, protocol, fullHealthChecks, services))
 
 	go func() {
 		logger.Info("gRPC server started")
@@ -171,6 +292,34 @@ func runGrpcServer(wg *sync.WaitGroup, shutdown chan bool, lis net.Listener, log
 	logger.Info("gRPC server shutdown")
 }
 
+func runAdminServer(wg *sync.WaitGroup, shutdown chan bool, address string, logger logr.Logger, predictorStore *predictor2.PredictorStore, logLevel zap.AtomicLevel, annotations map[string]string) {
+	wg.Add(1)
+	defer wg.Done()
+
+	settings := make(map[string]string)
+	flag.VisitAll(func(f *flag.Flag) {
+		settings[f.Name] = f.Value.String()
+	})
+	adminServer := admin.NewAdminServer(predictorStore, logLevel, annotations, settings, adminToken)
+	adminServer.Initialise()
+	srv := adminServer.CreateHttpServer(address)
+
+	var isShutdown bool
+	go func() {
+		if err := srv.ListenAndServe(); err != nil {
+			if !(isShutdown && errors.Is(err, http.ErrServerClosed)) {
+				logger.Error(err, "admin server error")
+			}
+		}
+	}()
+
+	isShutdown = <-shutdown
+	if err := srv.Shutdown(context.Background()); err != nil {
+		logger.Error(err, "admin server shutdown error")
+	}
+	logger.Info("admin server shutdown")
+}
+
 func waitForShutdown(logger logr.Logger, wg *sync.WaitGroup, chs ...chan bool) {
 	// wait for and then signal servers have exited
 	done := make(chan struct{})
@@ -188,6 +337,8 @@ func waitForShutdown(logger logr.Logger, wg *sync.WaitGroup, chs ...chan bool) {
 	// Block until we receive our signal.
 	sig := <-c
 	logger.Info("shutdown signal received", "signal", sig, "shutdown_delay", *delay)
+	// Report not ready during the delay so no new requests are routed to us.
+	util.SetDraining(true)
 	time.Sleep(*delay) // shutdown_delay
 
 	// Create a deadline to wait for graceful shutdown.
@@ -208,7 +359,8 @@ func waitForShutdown(logger logr.Logger, wg *sync.WaitGroup, chs ...chan bool) {
 	}
 }
 
-func setupLogger() {
+// setupLogger sets the logger with the level given by --log_level, returning the level so it can be changed at runtime.
+func setupLogger() zap.AtomicLevel {
 	level := zap.InfoLevel
 	switch *logLevel {
 	case "DEBUG":
@@ -232,6 +384,7 @@ func setupLogger() {
 	)
 
 	logf.SetLogger(logger)
+	return atomicLevel
 }
 
 func main() {
@@ -297,6 +450,45 @@ func main() {
 			}
 		}
 
+		// Dead-letter topic and retries
+		if *kafkaDeadLetter == "" {
+			*kafkaDeadLetter = os.Getenv(kafka.ENV_KAFKA_DEAD_LETTER_TOPIC)
+		}
+		kafkaMaxRetriesFromEnv := os.Getenv(kafka.ENV_KAFKA_MAX_RETRIES)
+		if kafkaMaxRetriesFromEnv != "" {
+			kafkaMaxRetriesFromEnvInt, err := strconv.Atoi(kafkaMaxRetriesFromEnv)
+			if err != nil {
+				log.Fatalf("Failed to parse %s %s", kafka.ENV_KAFKA_MAX_RETRIES, kafkaMaxRetriesFromEnv)
+			} else {
+				*kafkaMaxRetries = kafkaMaxRetriesFromEnvInt
+			}
+		}
+		kafkaRetryBackoffFromEnv := os.Getenv(kafka.ENV_KAFKA_RETRY_BACKOFF)
+		if kafkaRetryBackoffFromEnv != "" {
+			kafkaRetryBackoffFromEnvDuration, err := time.ParseDuration(kafkaRetryBackoffFromEnv)
+			if err != nil {
+				log.Fatalf("Failed to parse %s %s", kafka.ENV_KAFKA_RETRY_BACKOFF, kafkaRetryBackoffFromEnv)
+			} else {
+				*kafkaRetryBackoff = kafkaRetryBackoffFromEnvDuration
+			}
+		}
+		kafkaErrorRepliesFromEnv := os.Getenv(kafka.ENV_KAFKA_ERROR_REPLIES)
+		if kafkaErrorRepliesFromEnv != "" {
+			kafkaErrorRepliesFromEnvBool, err := strconv.ParseBool(kafkaErrorRepliesFromEnv)
+			if err != nil {
+				log.Fatalf("Failed to parse %s %s", kafka.ENV_KAFKA_ERROR_REPLIES, kafkaErrorRepliesFromEnv)
+			} else {
+				*kafkaErrorReplies = kafkaErrorRepliesFromEnvBool
+			}
+		}
+
+		if *kafkaHeaders == "" {
+			*kafkaHeaders = os.Getenv(kafka.ENV_KAFKA_HEADER_PASSTHROUGH)
+		}
+		if *kafkaKeyField == "" {
+			*kafkaKeyField = os.Getenv(kafka.ENV_KAFKA_OUTPUT_KEY_FIELD)
+		}
+
 		//Kafka workers
 		kafkaWorkersFromEnv := os.Getenv(kafka.ENV_KAFKA_WORKERS)
 		if kafkaWorkersFromEnv != "" {
@@ -309,6 +501,51 @@ func main() {
 		}
 	}
 
+	if *serverType == "nats" {
+		if *natsUrl == "" {
+			*natsUrl = os.Getenv(nats.ENV_NATS_URL)
+			if *natsUrl == "" {
+				log.Fatal("Required argument nats_url missing")
+			}
+		}
+		if *natsSubjectIn == "" {
+			*natsSubjectIn = os.Getenv(nats.ENV_NATS_INPUT_SUBJECT)
+			if *natsSubjectIn == "" {
+				log.Fatal("Required argument nats_input_subject missing")
+			}
+		}
+		if *natsSubjectOut == "" {
+			*natsSubjectOut = os.Getenv(nats.ENV_NATS_OUTPUT_SUBJECT)
+			if *natsSubjectOut == "" {
+				log.Fatal("Required argument nats_output_subject missing")
+			}
+		}
+		if *natsStream == "" {
+			*natsStream = os.Getenv(nats.ENV_NATS_STREAM)
+		}
+		natsWorkersFromEnv := os.Getenv(nats.ENV_NATS_WORKERS)
+		if natsWorkersFromEnv != "" {
+			natsWorkersFromEnvInt, err := strconv.Atoi(natsWorkersFromEnv)
+			if err != nil {
+				log.Fatalf("Failed to parse %s %s", nats.ENV_NATS_WORKERS, natsWorkersFromEnv)
+			} else {
+				*natsWorkers = natsWorkersFromEnvInt
+			}
+		}
+		natsMaxDeliverFromEnv := os.Getenv(nats.ENV_NATS_MAX_DELIVER)
+		if natsMaxDeliverFromEnv != "" {
+			natsMaxDeliverFromEnvInt, err := strconv.Atoi(natsMaxDeliverFromEnv)
+			if err != nil {
+				log.Fatalf("Failed to parse %s %s", nats.ENV_NATS_MAX_DELIVER, natsMaxDeliverFromEnv)
+			} else {
+				*natsMaxDeliver = natsMaxDeliverFromEnvInt
+			}
+		}
+		if *natsDeadLetter == "" {
+			*natsDeadLetter = os.Getenv(nats.ENV_NATS_DEAD_LETTER)
+		}
+	}
+
 	if !(*transport == "rest" || *transport == "grpc") {
 		log.Fatal("Only rest and grpc supported")
 	}
@@ -318,7 +555,7 @@ func main() {
 		log.Fatal("Failed to create server url from", *hostname, *httpPort)
 	}
 
-	setupLogger()
+	logLevelSetting := setupLogger()
 	logger := logf.Log.WithName("entrypoint")
 
 	logger.Info("Full health checks ", "value", fullHealthChecks)
@@ -349,6 +586,14 @@ func main() {
 		os.Exit(-1)
 
 	}
+	if err := predictor2.ValidatePredictor(predictor); err != nil {
+		logger.Error(err, "Invalid predictor")
+		os.Exit(-1)
+	}
+	predictorStore := predictor2.NewPredictorStore(predictor)
+	if *predictorReload {
+		startPredictorReload(predictorStore, logger)
+	}
 
 	// Ensure standard OpenAPI seldon API file has this deployment's values
 	err = rest.EmbedSeldonDeploymentValuesInSwaggerFile(*namespace, *sdepName)
@@ -361,8 +606,63 @@ func main() {
 		logger.Error(err, "Failed to load annotations")
 	}
 
+	logSinkStore, err := loghandler.NewSink(loghandler.SinkConfig{
+		Type:         *logSink,
+		Dir:          *logSinkDir,
+		MaxFileBytes: *logSinkFileBytes,
+		MaxFiles:     *logSinkMaxFiles,
+		S3: loghandler.S3Config{
+			Endpoint: *logS3Endpoint,
+			Bucket:   *logS3Bucket,
+			Prefix:   *logS3Prefix,
+			Region:   *logS3Region,
+		},
+	})
+	if err != nil {
+		log.Fatal("Failed to create log sink", err)
+	}
+	if logSinkStore != nil {
+		defer logSinkStore.Close()
+	}
+
+	if *modelMetrics {
+		predictor2.EnableModelMetrics(*sdepName, *predictorName, *stripModelMetrics)
+	}
+	predictor2.EnableNodeMetrics(*sdepName, *predictorName)
+
+	if *featureStats {
+		var profile *stats.Snapshot
+		if *featureProfile != "" {
+			profile, err = stats.LoadProfile(*featureProfile)
+			if err != nil {
+				log.Fatalf("Failed to load feature stats reference: %v", err)
+			}
+		}
+		predictor2.EnableFeatureStats(stats.NewCollector(*sdepName, *predictorName, profile, *featureWindow))
+	}
+
+	if *probeInterval > 0 {
+		var optional []string
+		if *optionalNodes != "" {
+			optional = strings.Split(*optionalNodes, ",")
+		}
+		prober := predictor2.NewReadinessProber(predictorStore, *protocol, *fullHealthChecks, *probeInterval, *probeTimeout, optional)
+		proberStop := make(chan struct{})
+		defer close(proberStop)
+		prober.Start(proberStop)
+		predictor2.EnableReadinessProber(prober)
+	}
+
 	//Start Logger Dispacther
-	err = loghandler.StartDispatcher(*logWorkers, *logWorkBufferSize, *logWriteTimeoutMs, logger, *sdepName, *namespace, *predictorName, *logKafkaBroker, *logKafkaTopic, *protocol)
+	err = loghandler.StartDispatcher(*logWorkers, *logWorkBufferSize, *logWriteTimeoutMs, logger, *sdepName, *namespace, *predictorName, *logKafkaBroker, *logKafkaTopic, *protocol, loghandler.DeliveryConfig{
+		MaxRetries:    *logMaxRetries,
+		RetryBackoff:  *logRetryBackoff,
+		SpillDir:      *logSpillDir,
+		SpillMaxBytes: *logSpillMaxBytes,
+		BatchSize:     *logBatchSize,
+		BatchLinger:   *logBatchLinger,
+		Sink:          logSinkStore,
+	})
 	if err != nil {
 		log.Fatal("Failed to start log dispatcher", err)
 	}
@@ -374,13 +674,21 @@ func main() {
 	}
 	defer closer.Close()
 
+	wg := sync.WaitGroup{}
+	var stops []chan bool
 	if *serverType == "kafka" {
 		logger.Info("Starting kafka server")
-		kafkaServer, err := kafka.NewKafkaServer(*kafkaFullGraph, *kafkaWorkers, *sdepName, *namespace, *protocol, *transport, annotations, serverUrl, predictor, *kafkaBroker, *kafkaTopicIn, *kafkaTopicOut, logger, *fullHealthChecks, *kafkaAutoCommit)
+		kafkaServer, err := kafka.NewKafkaServer(*kafkaFullGraph, *kafkaWorkers, *sdepName, *namespace, *protocol, *transport, annotations, serverUrl, predictor, *kafkaBroker, *kafkaTopicIn, *kafkaTopicOut, logger, *fullHealthChecks, *kafkaAutoCommit,
+			kafka.DeadLetterConfig{Topic: *kafkaDeadLetter, MaxRetries: *kafkaMaxRetries, RetryBackoff: *kafkaRetryBackoff, ErrorReplies: *kafkaErrorReplies},
+			kafka.OutputConfig{Headers: kafka.ParseHeaderPassthrough(*kafkaHeaders), KeyField: *kafkaKeyField})
 		if err != nil {
 			log.Fatalf("Failed to create kafka server: %v", err)
 		}
+		kafkaServer.SetPredictorStore(predictorStore)
+		// The kafka server drains its workers on the shutdown signal so wait for it to exit.
+		wg.Add(1)
 		go func() {
+			defer wg.Done()
 			err = kafkaServer.Serve()
 			if err != nil {
 				log.Fatal("Failed to serve kafka", err)
@@ -388,6 +696,26 @@ func main() {
 		}()
 	}
 
+	if *serverType == "nats" {
+		logger.Info("Starting nats server")
+		natsServer, err := nats.NewNatsServer(*natsWorkers, *sdepName, *namespace, *protocol, *transport, annotations, serverUrl, predictor, *natsUrl, *natsStream, *natsSubjectIn, *natsSubjectOut, *natsMaxDeliver, *natsDeadLetter, logger, *fullHealthChecks)
+		if err != nil {
+			log.Fatalf("Failed to create nats server: %v", err)
+		}
+		natsServer.SetPredictorStore(predictorStore)
+		// The nats server finishes in-flight requests on the shutdown signal so wait for it to exit.
+		natsStop := make(chan bool, 1)
+		stops = append(stops, natsStop)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err = natsServer.Serve(natsStop)
+			if err != nil {
+				log.Fatal("Failed to serve nats", err)
+			}
+		}()
+	}
+
 	clientRest, err := rest.NewJSONRestClient(*protocol, *sdepName, predictor, annotations)
 	if err != nil {
 		log.Fatalf("Failed to create http client: %v", err)
@@ -405,30 +733,88 @@ func main() {
 		log.Fatalf("Failed to create grpc client. Unknown protocol %s: %v", *protocol, err)
 	}
 
-	wg := sync.WaitGroup{}
+	var recorder *record.Recorder
+	if *recordFile != "" {
+		var rules []v1.RedactionRule
+		if *recordRedact != "" {
+			if err := json.Unmarshal([]byte(*recordRedact), &rules); err != nil {
+				log.Fatalf("Failed to parse record redaction rules: %v", err)
+			}
+		}
+		recorder, err = record.NewRecorder(*recordFile, *recordRate, rules, *recordMaxBytes)
+		if err != nil {
+			log.Fatalf("Failed to create recorder: %v", err)
+		}
+		defer recorder.Close()
+		logger.Info("Recording prediction requests", "file", *recordFile, "rate", *recordRate)
+	}
+
+	tlsConfig := createServerTLSConfig(logger)
+
 	logger.Info("Running http server ", "port", *httpPort)
 	httpStop := make(chan bool, 1)
-	go runHttpServer(&wg, httpStop, createListener(*httpPort, logger), logger, predictor, clientRest, *httpPort, false, serverUrl, *namespace, *protocol, *sdepName, *prometheusPath, *fullHealthChecks)
+	go runHttpServer(&wg, httpStop, createListener(*httpPort, tlsConfig, logger), logger, predictorStore, clientRest, *httpPort, false, serverUrl, *namespace, *protocol, *sdepName, *prometheusPath, *fullHealthChecks, recorder)
 
 	logger.Info("Running grpc server ", "port", *grpcPort)
 	grpcStop := make(chan bool, 1)
-	go runGrpcServer(&wg, grpcStop, createListener(*grpcPort, logger), logger, predictor, clientGrpc, serverUrl, *namespace, *protocol, *sdepName, annotations)
-	waitForShutdown(logger, &wg, httpStop, grpcStop)
+	go runGrpcServer(&wg, grpcStop, createListener(*grpcPort, tlsConfig, logger), logger, predictorStore, clientGrpc, serverUrl, *namespace, *protocol, *sdepName, annotations, *fullHealthChecks)
+	stops = append(stops, httpStop, grpcStop)
+	if *adminPort > 0 {
+		adminAddr := net.JoinHostPort(*adminAddress, strconv.Itoa(*adminPort))
+		logger.Info("Running admin server ", "address", adminAddr)
+		adminStop := make(chan bool, 1)
+		go runAdminServer(&wg, adminStop, adminAddr, logger, predictorStore, logLevelSetting, annotations)
+		stops = append(stops, adminStop)
+	}
+	waitForShutdown(logger, &wg, stops...)
+}
+
+// startPredictorReload watches the predictor file if one was given and otherwise the SeldonDeployment,
+// swapping reloaded graphs into predictorStore.
+func startPredictorReload(predictorStore *predictor2.PredictorStore, logger logr.Logger) {
+	go func() {
+		var err error
+		if *filename != "" {
+			err = predictor2.WatchPredictorFile(predictorStore, *predictorName, *filename, make(chan struct{}), logger)
+		} else {
+			err = predictor2.WatchSeldonDeployment(predictorStore, *predictorName, *sdepName, *namespace, *configPath, make(chan struct{}), logger)
+		}
+		if err != nil {
+			logger.Error(err, "Predictor reload stopped")
+		}
+	}()
+}
+
+// createServerTLSConfig returns the listener TLS config or nil if no certificates are mounted.
+// Certificates are reloaded when the mounted secret changes.
+func createServerTLSConfig(logger logr.Logger) *tls.Config {
+	if len(certMountPath) == 0 {
+		return nil
+	}
+	clientCAPath := ""
+	if certVerifyClient {
+		clientCAPath = path.Join(certMountPath, certClientCAFileName)
+	}
+	certWatcher, err := util.NewCertWatcher(certMountPath, path.Join(certMountPath, certFileName), path.Join(certMountPath, certKeyFileName), clientCAPath, logger)
+	if err != nil {
+		log.Fatalf("Error certificate could not be loaded: %v", err)
+	}
+	certWatcher.OnReload(func() {
+		if err := util.ReloadClientTLSConfig(); err != nil {
+			logger.Error(err, "Failed to reload client TLS certificates, keeping the current ones")
+		}
+	})
+	go certWatcher.Start(make(chan struct{}))
+	return certWatcher.TLSConfig()
 }
 
-func createListener(port int, logger logr.Logger) net.Listener {
+func createListener(port int, tlsConfig *tls.Config, logger logr.Logger) net.Listener {
 	// Create a listener at the desired port.
 	var lis net.Listener
 	var err error
-	if len(certMountPath) > 0 {
-		logger.Info("Creating TLS listener", "port", port)
-		certPath := path.Join(certMountPath, certFileName)
-		keyPath := path.Join(certMountPath, certKeyFileName)
-		cert, err := tls.LoadX509KeyPair(certPath, keyPath)
-		if err != nil {
-			log.Fatalf("Error certificate could not be found: %v", err)
-		}
-		lis, err = tls.Listen("tcp", fmt.Sprintf(":%d", port), &tls.Config{Certificates: []tls.Certificate{cert}})
+	if tlsConfig != nil {
+		logger.Info("Creating TLS listener", "port", port, "verifyClient", certVerifyClient)
+		lis, err = tls.Listen("tcp", fmt.Sprintf(":%d", port), tlsConfig)
 		if err != nil {
 			log.Fatalf("failed to create listener: %v", err)
 		}
@@ -2,8 +2,11 @@ package main
 
 import (
 	"flag"
+	"fmt"
+	"net/http"
 	"os"
 
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/seldonio/seldon-core/executor/api"
 	"github.com/seldonio/seldon-core/executor/api/kafka"
 	"github.com/seldonio/seldon-core/executor/api/rest"
@@ -16,16 +19,18 @@ import (
 )
 
 var (
-	configPath    = flag.String("config", "", "Path to kubconfig")
-	modelName     = flag.String("model_name", "", "Name of the model inside the predictor")
-	predictorName = flag.String("predictor", "", "Name of the predictor inside the SeldonDeployment")
-	sdepName      = flag.String("sdep", "", "Seldon deployment name")
-	namespace     = flag.String("namespace", "default", "Namespace")
-	hostname      = flag.String("hostname", "localhost", "The hostname of client service")
-	httpPort      = flag.Int("http_port", 9000, "Port of the client service")
-	protocol      = flag.String("protocol", "seldon", "The payload protocol")
-	filename      = flag.String("file", "", "Load graph from file")
-	broker        = flag.String("broker", "", "The kafka broker as host:port")
+	configPath     = flag.String("config", "", "Path to kubconfig")
+	modelName      = flag.String("model_name", "", "Name of the model inside the predictor")
+	predictorName  = flag.String("predictor", "", "Name of the predictor inside the SeldonDeployment")
+	sdepName       = flag.String("sdep", "", "Seldon deployment name")
+	namespace      = flag.String("namespace", "default", "Namespace")
+	hostname       = flag.String("hostname", "localhost", "The hostname of client service")
+	httpPort       = flag.Int("http_port", 9000, "Port of the client service")
+	protocol       = flag.String("protocol", "seldon", "The payload protocol")
+	filename       = flag.String("file", "", "Load graph from file")
+	broker         = flag.String("broker", "", "The kafka broker as host:port")
+	metricsPort    = flag.Int("metrics_port", 0, "Port to serve prometheus metrics on. 0 disables the metrics server")
+	prometheusPath = flag.String("prometheus_path", "/metrics", "The prometheus metrics path")
 )
 
 func main() {
@@ -74,6 +79,17 @@ func main() {
 		logger.Error(err, "failed to set GOMAXPROCS")
 	}
 
+	if *metricsPort > 0 {
+		go func() {
+			mux := http.NewServeMux()
+			mux.Handle(*prometheusPath, promhttp.Handler())
+			logger.Info("Serving metrics", "port", *metricsPort, "path", *prometheusPath)
+			if err := http.ListenAndServe(fmt.Sprintf(":%d", *metricsPort), mux); err != nil {
+				logger.Error(err, "Metrics server failed")
+			}
+		}()
+	}
+
 	kafkaProxy := kafka.NewKafkaProxy(client, *modelName, *predictorName, *sdepName, *namespace, *broker, *hostname, int32(*httpPort), logger)
 
 	err = kafkaProxy.Consume()
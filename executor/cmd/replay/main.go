@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/seldonio/seldon-core/executor/record"
+)
+
+var (
+	file         = flag.String("file", "", "Recording written by the executor with --record_file")
+	target       = flag.String("target", "", "Base URL of the executor the requests are sent to, e.g. http://localhost:8000")
+	rate         = flag.Float64("rate", 0, "Requests sent per second. 0 sends them as fast as the workers allow")
+	concurrency  = flag.Int("concurrency", record.DefaultReplayConcurrency, "Number of requests in flight at once")
+	timeout      = flag.Duration("timeout", record.DefaultReplayTimeout, "Timeout of each request")
+	absTolerance = flag.Float64("abs_tolerance", 0, "Absolute difference allowed between recorded and replayed numbers")
+	relTolerance = flag.Float64("rel_tolerance", 1e-6, "Difference allowed between recorded and replayed numbers relative to the larger")
+	ignore       = flag.String("ignore", strings.Join(record.DefaultIgnorePaths, ","), "Comma separated response fields not compared, e.g. $.meta.requestPath")
+	maxResults   = flag.Int("max_results", 20, "Number of mismatched requests printed")
+	reportFile   = flag.String("report", "", "File the full report is written to as JSON")
+)
+
+func main() {
+	flag.Parse()
+
+	if *file == "" {
+		log.Fatal("Required argument file missing")
+	}
+	if *target == "" {
+		log.Fatal("Required argument target missing")
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		log.Fatalf("Failed to open recording: %v", err)
+	}
+	exchanges, err := record.ReadExchanges(f)
+	f.Close()
+	if err != nil {
+		log.Fatalf("Failed to read recording: %v", err)
+	}
+
+	var ignorePaths []string
+	if *ignore != "" {
+		ignorePaths = strings.Split(*ignore, ",")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+	report := record.Replay(ctx, exchanges, record.ReplayConfig{
+		Target:      *target,
+		Rate:        *rate,
+		Concurrency: *concurrency,
+		Timeout:     *timeout,
+		Compare: record.CompareOptions{
+			AbsTolerance: *absTolerance,
+			RelTolerance: *relTolerance,
+			IgnorePaths:  ignorePaths,
+		},
+	})
+	report.Write(os.Stdout, *maxResults)
+
+	if *reportFile != "" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			log.Fatalf("Failed to create report: %v", err)
+		}
+		if err := os.WriteFile(*reportFile, data, 0644); err != nil {
+			log.Fatalf("Failed to write report: %v", err)
+		}
+	}
+	if !report.Passed() {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,154 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go"
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+// collectBatch returns first along with any requests which arrive before the batch is full or lingers too long.
+func (w *Worker) collectBatch(first LogRequest) []LogRequest {
+	batch := []LogRequest{first}
+	if w.Delivery.BatchSize <= 1 {
+		return batch
+	}
+	timer := time.NewTimer(w.Delivery.BatchLinger)
+	defer timer.Stop()
+	for len(batch) < w.Delivery.BatchSize {
+		select {
+		case work := <-w.Work:
+			batch = append(batch, work)
+		case <-timer.C:
+			return batch
+		}
+	}
+	return batch
+}
+
+// sendBatch sends the requests, returning those which failed along with the last error.
+func (w *Worker) sendBatch(batch []LogRequest) ([]LogRequest, error) {
+	if w.Delivery.Sink != nil {
+		return w.writeToSink(batch)
+	}
+	if w.KafkaTopic != "" {
+		return w.sendKafkaEvents(batch)
+	}
+	if len(batch) == 1 {
+		if err := w.sendCloudEvent(batch[0]); err != nil {
+			return batch, err
+		}
+		return nil, nil
+	}
+
+	// Requests can go to different loggers so a batch is posted to each
+	var urls []string
+	byUrl := make(map[string][]LogRequest)
+	for _, logReq := range batch {
+		target := logReq.Url.String()
+		if _, ok := byUrl[target]; !ok {
+			urls = append(urls, target)
+		}
+		byUrl[target] = append(byUrl[target], logReq)
+	}
+	var failed []LogRequest
+	var lastErr error
+	for _, target := range urls {
+		if err := w.sendCloudEventBatch(target, byUrl[target]); err != nil {
+			failed = append(failed, byUrl[target]...)
+			lastErr = err
+		}
+	}
+	return failed, lastErr
+}
+
+// sendCloudEventBatch posts the requests to target as a structured mode cloudevents batch.
+func (w *Worker) sendCloudEventBatch(target string, batch []LogRequest) error {
+	events := make([]cloudevents.Event, 0, len(batch))
+	for _, logReq := range batch {
+		event, err := w.createCloudEvent(logReq)
+		if err != nil {
+			return err
+		}
+		// Binary data is base64 encoded in structured mode, JSON can be embedded as is
+		if event.DataMediaType() == cloudevents.ApplicationJSON {
+			event.DataBinary = false
+		}
+		event.SetTime(time.Now())
+		events = append(events, event)
+	}
+	body, err := json.Marshal(events)
+	if err != nil {
+		return fmt.Errorf("while encoding cloudevents batch: %s", err)
+	}
+
+	req, err := http.NewRequestWithContext(w.CeCtx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("while creating cloudevents batch request: %s", err)
+	}
+	req.Header.Set("Content-Type", cloudevents.ApplicationCloudEventsBatchJSON)
+	res, err := w.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("while sending cloudevents batch: %s", err)
+	}
+	defer res.Body.Close()
+	// Drain the body so the connection is reused
+	io.Copy(ioutil.Discard, res.Body)
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("while sending cloudevents batch: %s", res.Status)
+	}
+	return nil
+}
+
+// sendKafkaEvents produces the requests together, so they are batched by the producer, and waits for their delivery.
+func (w *Worker) sendKafkaEvents(batch []LogRequest) ([]LogRequest, error) {
+	deliveryChan := make(chan kafka.Event, len(batch))
+	var failed []LogRequest
+	var lastErr error
+	pending := make(map[int]bool, len(batch))
+	for i, logReq := range batch {
+		msg, err := w.createKafkaMessage(logReq)
+		if err == nil {
+			msg.Opaque = i
+			err = w.Producer.Produce(msg, deliveryChan)
+		}
+		if err != nil {
+			failed = append(failed, logReq)
+			lastErr = fmt.Errorf("while producing kafka log: %s", err)
+			continue
+		}
+		pending[i] = true
+	}
+
+	timer := time.NewTimer(kafkaDeliveryTimeout)
+	defer timer.Stop()
+	for len(pending) > 0 {
+		select {
+		case ev := <-deliveryChan:
+			m, ok := ev.(*kafka.Message)
+			if !ok {
+				continue
+			}
+			i := m.Opaque.(int)
+			delete(pending, i)
+			if m.TopicPartition.Error != nil {
+				failed = append(failed, batch[i])
+				lastErr = fmt.Errorf("while delivering kafka log: %s", m.TopicPartition.Error)
+			}
+		case <-timer.C:
+			// Anything not yet reported is retried, which may duplicate it if it is delivered later
+			for i := range pending {
+				failed = append(failed, batch[i])
+			}
+			return failed, errors.New("timed out waiting for kafka log delivery")
+		}
+	}
+	return failed, lastErr
+}
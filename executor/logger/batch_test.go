@@ -0,0 +1,130 @@
+package logger
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go"
+	. "github.com/onsi/gomega"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+type testBatchEndpoint struct {
+	sync.Mutex
+	contentTypes []string
+	batches      [][]map[string]interface{}
+}
+
+func (e *testBatchEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.Lock()
+	defer e.Unlock()
+	e.contentTypes = append(e.contentTypes, r.Header.Get("Content-Type"))
+	body, _ := ioutil.ReadAll(r.Body)
+	var batch []map[string]interface{}
+	if err := json.Unmarshal(body, &batch); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	e.batches = append(e.batches, batch)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func TestCollectBatch(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	worker, err := NewWorker(1, make(chan LogRequest, 10), logf.Log.WithName("test"), "dep", "default", "p", "", "", "seldon", DeliveryConfig{BatchSize: 3, BatchLinger: 50 * time.Millisecond})
+	g.Expect(err).To(BeNil())
+	logUrl, err := url.Parse("http://localhost")
+	g.Expect(err).To(BeNil())
+	data := []byte(`{"data":{"ndarray":[[1.0]]}}`)
+	for _, id := range []string{"2", "3", "4"} {
+		worker.Work <- LogRequest{Url: logUrl, Bytes: &data, ContentType: "application/json", ReqType: InferenceRequest, Id: id}
+	}
+	batch := worker.collectBatch(LogRequest{Url: logUrl, Bytes: &data, ContentType: "application/json", ReqType: InferenceRequest, Id: "1"})
+	g.Expect(batch).To(HaveLen(3))
+	g.Expect(batch[0].Id).To(Equal("1"))
+
+	// A batch which doesn't fill is sent after the linger time
+	batch = worker.collectBatch(LogRequest{Url: logUrl, Bytes: &data, ContentType: "application/json", ReqType: InferenceRequest, Id: "5"})
+	g.Expect(batch).To(HaveLen(2))
+	g.Expect(batch[1].Id).To(Equal("4"))
+}
+
+func TestSendCloudEventBatch(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	endpoint := &testBatchEndpoint{}
+	server := httptest.NewServer(endpoint)
+	defer server.Close()
+
+	worker, err := NewWorker(1, make(chan LogRequest), logf.Log.WithName("test"), "dep", "default", "p", "", "", "seldon", DeliveryConfig{BatchSize: 10})
+	g.Expect(err).To(BeNil())
+	logUrl, err := url.Parse(server.URL)
+	g.Expect(err).To(BeNil())
+	sourceUri, err := url.Parse("http://localhost:8000")
+	g.Expect(err).To(BeNil())
+	data := []byte(`{"data":{"ndarray":[[1.0]]}}`)
+	failed, err := worker.sendBatch([]LogRequest{
+		{Url: logUrl, Bytes: &data, ContentType: "application/json", ReqType: InferenceRequest, Id: "1", SourceUri: sourceUri, ModelId: "model"},
+		{Url: logUrl, Bytes: &data, ContentType: "application/json", ReqType: InferenceRequest, Id: "2", SourceUri: sourceUri, ModelId: "model"},
+	})
+	g.Expect(err).To(BeNil())
+	g.Expect(failed).To(BeEmpty())
+	g.Expect(endpoint.contentTypes).To(Equal([]string{cloudevents.ApplicationCloudEventsBatchJSON}))
+	g.Expect(endpoint.batches).To(HaveLen(1))
+	g.Expect(endpoint.batches[0]).To(HaveLen(2))
+	event := endpoint.batches[0][1]
+	g.Expect(event["id"]).To(Equal("2"))
+	g.Expect(event["type"]).To(Equal(CEInferenceRequest))
+	g.Expect(event[ModelIdAttr]).To(Equal("model"))
+	g.Expect(event["data"]).To(HaveKey("data"))
+}
+
+func TestSendCloudEventBatchFailure(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	endpoint := &testLoggerEndpoint{failures: 1}
+	server := httptest.NewServer(endpoint)
+	defer server.Close()
+
+	worker, err := NewWorker(1, make(chan LogRequest), logf.Log.WithName("test"), "dep", "default", "p", "", "", "seldon", DeliveryConfig{BatchSize: 10})
+	g.Expect(err).To(BeNil())
+	logUrl, err := url.Parse(server.URL)
+	g.Expect(err).To(BeNil())
+	sourceUri, err := url.Parse("http://localhost:8000")
+	g.Expect(err).To(BeNil())
+	data := []byte(`{"data":{"ndarray":[[1.0]]}}`)
+	batch := []LogRequest{
+		{Url: logUrl, Bytes: &data, ContentType: "application/json", ReqType: InferenceRequest, Id: "1", SourceUri: sourceUri, ModelId: "model"},
+		{Url: logUrl, Bytes: &data, ContentType: "application/json", ReqType: InferenceRequest, Id: "2", SourceUri: sourceUri, ModelId: "model"},
+	}
+	failed, err := worker.sendBatch(batch)
+	g.Expect(err).ToNot(BeNil())
+	g.Expect(failed).To(Equal(batch))
+}
+
+func TestCloudEventClientReused(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	endpoint := &testLoggerEndpoint{}
+	server := httptest.NewServer(endpoint)
+	defer server.Close()
+
+	worker, err := NewWorker(1, make(chan LogRequest), logf.Log.WithName("test"), "dep", "default", "p", "", "", "seldon", DeliveryConfig{})
+	g.Expect(err).To(BeNil())
+	logUrl, err := url.Parse(server.URL)
+	g.Expect(err).To(BeNil())
+	sourceUri, err := url.Parse("http://localhost:8000")
+	g.Expect(err).To(BeNil())
+	data := []byte(`{"data":{"ndarray":[[1.0]]}}`)
+	worker.deliver([]LogRequest{{Url: logUrl, Bytes: &data, ContentType: "application/json", ReqType: InferenceRequest, Id: "1", SourceUri: sourceUri, ModelId: "model"}})
+	worker.deliver([]LogRequest{{Url: logUrl, Bytes: &data, ContentType: "application/json", ReqType: InferenceRequest, Id: "2", SourceUri: sourceUri, ModelId: "model"}})
+	g.Expect(endpoint.received()).To(Equal([]string{"1", "2"}))
+	g.Expect(worker.ceClients).To(HaveLen(1))
+}
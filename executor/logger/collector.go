@@ -2,7 +2,10 @@ package logger
 
 import (
 	"errors"
+	"fmt"
 	"time"
+
+	"github.com/seldonio/seldon-core/executor/api/metric"
 )
 
 const (
@@ -16,6 +19,9 @@ var (
 	workQueue = make(chan LogRequest, DefaultWorkQueueSize)
 	// writeTimeoutMilliseconds is the timeout for waiting for work to be written to the queue. If 0, will not wait if buffer is full.
 	writeTimeoutMilliseconds = DefaultWriteTimeoutMilliseconds
+	// spill holds requests on disk when the buffer is full or delivery fails. If nil, such requests are dropped.
+	spill   *spillQueue
+	metrics = metric.NewLoggerMetrics("", "")
 )
 
 func QueueLogRequest(req LogRequest) error {
@@ -23,8 +29,19 @@ func QueueLogRequest(req LogRequest) error {
 	defer timer.Stop()
 	select {
 	case workQueue <- req:
+		metrics.EventQueued()
 		return nil
 	case <-timer.C:
+		if spill != nil {
+			err := spill.write(req)
+			if err == nil {
+				metrics.EventSpilled()
+				return nil
+			}
+			metrics.EventDropped()
+			return fmt.Errorf("buffer is full and failed to spill log request: %w", err)
+		}
+		metrics.EventDropped()
 		return errors.New("timed out waiting to queue log request: buffer is full")
 	}
 }
@@ -2,16 +2,56 @@ package logger
 
 import (
 	"os"
+	"time"
 
 	"github.com/go-logr/logr"
+	"github.com/seldonio/seldon-core/executor/api/metric"
 )
 
 const (
 	ENV_LOGGER_KAFKA_BROKER = "LOGGER_KAFKA_BROKER"
 	ENV_LOGGER_KAFKA_TOPIC  = "LOGGER_KAFKA_TOPIC"
+	ENV_LOGGER_SPILL_DIR    = "LOGGER_SPILL_DIR"
+
+	DefaultMaxRetries    = 3
+	DefaultRetryBackoff  = 500 * time.Millisecond
+	DefaultSpillMaxBytes = 1 << 30
+	DefaultBatchSize     = 1
+	DefaultBatchLinger   = 100 * time.Millisecond
+
+	maxRetryBackoff = 30 * time.Second
 )
 
-func StartDispatcher(nworkers int, logBufferSize int, writeTimeoutMs int, log logr.Logger, sdepName string, namespace string, predictorName string, kafkaBroker string, kafkaTopic string, protocol string) error {
+// DeliveryConfig controls where log requests are sent and how those which fail to send are retried and spilled.
+type DeliveryConfig struct {
+	// Sink stores log requests in place of the cloudevents endpoint or kafka. Nil sends them there.
+	Sink Sink
+	// MaxRetries is the number of times a failed send is retried before the request is spilled or dropped.
+	MaxRetries int
+	// RetryBackoff is the delay before the first retry, doubled on each further attempt.
+	RetryBackoff time.Duration
+	// SpillDir is the directory undeliverable requests are written to. Empty disables spilling.
+	SpillDir string
+	// SpillMaxBytes bounds the size of the spill directory. Requests which would exceed it are dropped.
+	SpillMaxBytes int64
+	// BatchSize is the maximum number of requests sent together. 1 or less sends each request on its own.
+	BatchSize int
+	// BatchLinger is how long to wait for a batch to fill before sending it.
+	BatchLinger time.Duration
+}
+
+func (d DeliveryConfig) backoff(attempt int) time.Duration {
+	backoff := d.RetryBackoff
+	for i := 1; i < attempt && backoff < maxRetryBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxRetryBackoff {
+		return maxRetryBackoff
+	}
+	return backoff
+}
+
+func StartDispatcher(nworkers int, logBufferSize int, writeTimeoutMs int, log logr.Logger, sdepName string, namespace string, predictorName string, kafkaBroker string, kafkaTopic string, protocol string, delivery DeliveryConfig) error {
 	if kafkaBroker == "" {
 		kafkaBroker = os.Getenv(ENV_LOGGER_KAFKA_BROKER)
 	}
@@ -24,15 +64,36 @@ func StartDispatcher(nworkers int, logBufferSize int, writeTimeoutMs int, log lo
 		}
 	}
 
+	if delivery.SpillDir == "" {
+		delivery.SpillDir = os.Getenv(ENV_LOGGER_SPILL_DIR)
+	}
+
+	metrics = metric.NewLoggerMetrics(sdepName, predictorName)
 	workQueue = make(chan LogRequest, logBufferSize)
 	writeTimeoutMilliseconds = writeTimeoutMs
+	spill = nil
+	if delivery.SpillDir != "" {
+		var err error
+		spill, err = newSpillQueue(delivery.SpillDir, delivery.SpillMaxBytes)
+		if err != nil {
+			return err
+		}
+		log.Info("Spilling undelivered log requests to disk", "dir", delivery.SpillDir, "maxBytes", delivery.SpillMaxBytes)
+		replayer, err := NewWorker(0, workQueue, log, sdepName, namespace, predictorName, kafkaBroker, kafkaTopic, protocol, delivery)
+		if err != nil {
+			return err
+		}
+		// The replayer runs for the life of the process, as do the workers
+		go spill.replay(replayer.send, nil, log)
+	}
 	// Now, create all of our workers.
 	for i := 0; i < nworkers; i++ {
 		log.Info("Starting", "worker", i+1)
-		worker, err := NewWorker(i+1, workQueue, log, sdepName, namespace, predictorName, kafkaBroker, kafkaTopic, protocol)
+		worker, err := NewWorker(i+1, workQueue, log, sdepName, namespace, predictorName, kafkaBroker, kafkaTopic, protocol, delivery)
 		if err != nil {
 			return err
 		}
+		worker.Spill = spill
 		worker.Start()
 	}
 
@@ -0,0 +1,118 @@
+package logger
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	sinkFilePrefix = "payloads-"
+	sinkFileSuffix = ".jsonl"
+)
+
+// fileSink appends records as JSON lines to files in a directory, such as a mounted volume, starting a new file
+// when the current one reaches its maximum size and removing the oldest beyond the number to keep.
+type fileSink struct {
+	sync.Mutex
+	dir      string
+	maxBytes int64
+	maxFiles int
+	file     *os.File
+	size     int64
+	seq      uint64
+}
+
+func NewFileSink(dir string, maxBytes int64, maxFiles int) (Sink, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("file logger sink requires a directory")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &fileSink{dir: dir, maxBytes: maxBytes, maxFiles: maxFiles}, nil
+}
+
+func (s *fileSink) Write(records []Record) error {
+	lines, err := encodeRecords(records)
+	if err != nil {
+		return err
+	}
+	s.Lock()
+	defer s.Unlock()
+	if s.file == nil || (s.maxBytes > 0 && s.size > 0 && s.size+int64(len(lines)) > s.maxBytes) {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+	if _, err := s.file.Write(lines); err != nil {
+		// Remove any part of the batch that was written so its retry doesn't leave a torn line or duplicates.
+		// If the file can't be truncated later records start a new one.
+		if terr := s.file.Truncate(s.size); terr != nil {
+			s.file.Close()
+			s.file = nil
+		}
+		return err
+	}
+	s.size += int64(len(lines))
+	return nil
+}
+
+// rotate closes the current file, opens a new one and prunes the oldest files.
+func (s *fileSink) rotate() error {
+	if s.file != nil {
+		if err := s.file.Close(); err != nil {
+			return err
+		}
+		s.file = nil
+	}
+	s.seq++
+	// Names sort in the order the files were created
+	name := fmt.Sprintf("%s%s-%06d%s", sinkFilePrefix, time.Now().UTC().Format("20060102T150405.000000000"), s.seq, sinkFileSuffix)
+	file, err := os.OpenFile(filepath.Join(s.dir, name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	s.file, s.size = file, 0
+	return s.prune()
+}
+
+func (s *fileSink) prune() error {
+	if s.maxFiles <= 0 {
+		return nil
+	}
+	entries, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasPrefix(entry.Name(), sinkFilePrefix) && strings.HasSuffix(entry.Name(), sinkFileSuffix) {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	for len(names) > s.maxFiles {
+		if err := os.Remove(filepath.Join(s.dir, names[0])); err != nil {
+			return err
+		}
+		names = names[1:]
+	}
+	return nil
+}
+
+func (s *fileSink) Close() error {
+	s.Lock()
+	defer s.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
@@ -26,7 +26,7 @@ var processedChan = make(chan bool, 100)
 func BenchmarkLoggerMemoryUsage(b *testing.B) {
 	serverPort := startSlowLogListener()
 
-	err := StartDispatcher(5, DefaultWorkQueueSize, DefaultWriteTimeoutMilliseconds, logf.Log.WithName("test"), "test-name", "test-namespace", "test-predictor", "", "", api.ProtocolSeldon)
+	err := StartDispatcher(5, DefaultWorkQueueSize, DefaultWriteTimeoutMilliseconds, logf.Log.WithName("test"), "test-name", "test-namespace", "test-predictor", "", "", api.ProtocolSeldon, DeliveryConfig{})
 	if err != nil {
 		b.Fatal(err)
 	}
@@ -0,0 +1,279 @@
+package logger
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+)
+
+const (
+	ENV_LOGGER_REDACTION_SALT = "LOGGER_REDACTION_SALT"
+
+	redactionMask   = "****"
+	v2BytesDatatype = "BYTES"
+)
+
+// redactionSalt is prepended to values before they are hashed so common identifiers can't be recovered from a lookup table.
+var redactionSalt = os.Getenv(ENV_LOGGER_REDACTION_SALT)
+
+// CheckRedactionRules fails when rules hash values without a redaction salt, as unsalted hashes of common
+// identifiers such as emails can be reversed with a lookup table.
+func CheckRedactionRules(rules []v1.RedactionRule) error {
+	if redactionSalt != "" {
+		return nil
+	}
+	for _, rule := range rules {
+		if rule.Action == v1.RedactHash {
+			return fmt.Errorf("hash redaction requires %s to be set", ENV_LOGGER_REDACTION_SALT)
+		}
+	}
+	return nil
+}
+
+func hashValue(value interface{}) string {
+	s, ok := value.(string)
+	if !ok {
+		b, _ := json.Marshal(value)
+		s = string(b)
+	}
+	sum := sha256.Sum256([]byte(redactionSalt + s))
+	return hex.EncodeToString(sum[:])
+}
+
+func redactValue(value interface{}, action v1.RedactionAction) interface{} {
+	if action == v1.RedactHash {
+		return hashValue(value)
+	}
+	return redactionMask
+}
+
+// redactElements redacts each scalar of a possibly nested array, keeping its shape.
+func redactElements(value interface{}, action v1.RedactionAction) interface{} {
+	if arr, ok := value.([]interface{}); ok {
+		for i := range arr {
+			arr[i] = redactElements(arr[i], action)
+		}
+		return arr
+	}
+	return redactValue(value, action)
+}
+
+// applyPath redacts the members of node selected by tokens and returns the updated node.
+func applyPath(node interface{}, tokens []v1.RedactionPathToken, action v1.RedactionAction) interface{} {
+	token := tokens[0]
+	last := len(tokens) == 1
+	apply := func(child interface{}) interface{} {
+		if last {
+			return redactValue(child, action)
+		}
+		return applyPath(child, tokens[1:], action)
+	}
+
+	switch n := node.(type) {
+	case map[string]interface{}:
+		if token.Index == v1.RedactionPathWildcard {
+			for k, v := range n {
+				if last && action == v1.RedactDrop {
+					delete(n, k)
+				} else {
+					n[k] = apply(v)
+				}
+			}
+		} else if v, ok := n[token.Key]; ok && token.Index == v1.RedactionPathKey {
+			if last && action == v1.RedactDrop {
+				delete(n, token.Key)
+			} else {
+				n[token.Key] = apply(v)
+			}
+		}
+	case []interface{}:
+		if token.Index == v1.RedactionPathWildcard {
+			if last && action == v1.RedactDrop {
+				return []interface{}{}
+			}
+			for i := range n {
+				n[i] = apply(n[i])
+			}
+		} else if token.Index >= 0 && token.Index < len(n) {
+			if last {
+				return redactIndex(n, token.Index, action)
+			}
+			n[token.Index] = apply(n[token.Index])
+		}
+	}
+	return node
+}
+
+func redactPath(doc interface{}, path string, action v1.RedactionAction) (interface{}, error) {
+	tokens, err := v1.ParseRedactionPath(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		if action == v1.RedactDrop {
+			return nil, nil
+		}
+		return redactValue(doc, action), nil
+	}
+	return applyPath(doc, tokens, action), nil
+}
+
+func redactIndex(values []interface{}, i int, action v1.RedactionAction) []interface{} {
+	if i >= len(values) {
+		return values
+	}
+	if action == v1.RedactDrop {
+		return append(values[:i], values[i+1:]...)
+	}
+	values[i] = redactValue(values[i], action)
+	return values
+}
+
+// redactColumn redacts column i of the rows of a seldon ndarray or tensor, removing it if dropped.
+func redactColumn(data map[string]interface{}, i int, action v1.RedactionAction) {
+	if ndarray, ok := data["ndarray"].([]interface{}); ok && len(ndarray) > 0 {
+		if _, nested := ndarray[0].([]interface{}); nested {
+			for r, row := range ndarray {
+				if values, ok := row.([]interface{}); ok {
+					ndarray[r] = redactIndex(values, i, action)
+				}
+			}
+		} else {
+			// A single row of values, one per name
+			data["ndarray"] = redactIndex(ndarray, i, action)
+		}
+	}
+	if tensor, ok := data["tensor"].(map[string]interface{}); ok {
+		shape, _ := tensor["shape"].([]interface{})
+		values, _ := tensor["values"].([]interface{})
+		if len(shape) == 0 {
+			return
+		}
+		cols, err := strconv.Atoi(fmt.Sprint(shape[len(shape)-1]))
+		if err != nil || cols <= i {
+			return
+		}
+		var kept []interface{}
+		for j := range values {
+			if j%cols != i {
+				kept = append(kept, values[j])
+			} else if action != v1.RedactDrop {
+				values[j] = redactValue(values[j], action)
+				kept = append(kept, values[j])
+			}
+		}
+		tensor["values"] = kept
+		if action == v1.RedactDrop {
+			shape[len(shape)-1] = json.Number(strconv.Itoa(cols - 1))
+		}
+	}
+}
+
+// redactSeldonTensor redacts the columns of the seldon data whose names match.
+func redactSeldonTensor(doc map[string]interface{}, name string, action v1.RedactionAction) {
+	data, ok := doc["data"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	names, _ := data["names"].([]interface{})
+	for i := len(names) - 1; i >= 0; i-- {
+		if names[i] != name {
+			continue
+		}
+		redactColumn(data, i, action)
+		if action == v1.RedactDrop {
+			names = append(names[:i], names[i+1:]...)
+		}
+	}
+	if names != nil {
+		data["names"] = names
+	}
+}
+
+// redactV2Tensors redacts the v2 inputs or outputs with the given name. Raw contents, which are in the same
+// order as the tensors when the payload was protobuf, are redacted along with them.
+func redactV2Tensors(doc map[string]interface{}, tensorsKey string, rawKeys []string, name string, action v1.RedactionAction) {
+	tensors, ok := doc[tensorsKey].([]interface{})
+	if !ok {
+		return
+	}
+	rawKey := ""
+	var raw []interface{}
+	for _, key := range rawKeys {
+		if r, ok := doc[key].([]interface{}); ok {
+			rawKey, raw = key, r
+		}
+	}
+	for i := len(tensors) - 1; i >= 0; i-- {
+		tensor, ok := tensors[i].(map[string]interface{})
+		if !ok || tensor["name"] != name {
+			continue
+		}
+		if action == v1.RedactDrop {
+			tensors = append(tensors[:i], tensors[i+1:]...)
+			if i < len(raw) {
+				raw = append(raw[:i], raw[i+1:]...)
+			}
+			continue
+		}
+		if data, ok := tensor["data"]; ok {
+			tensor["data"] = redactElements(data, action)
+		}
+		if contents, ok := tensor["contents"].(map[string]interface{}); ok {
+			for k, v := range contents {
+				contents[k] = redactElements(v, action)
+			}
+		}
+		tensor["datatype"] = v2BytesDatatype
+		if i < len(raw) {
+			raw[i] = redactValue(raw[i], action)
+		}
+	}
+	doc[tensorsKey] = tensors
+	if rawKey != "" {
+		doc[rawKey] = raw
+	}
+}
+
+func redactTensor(doc interface{}, name string, action v1.RedactionAction) interface{} {
+	m, ok := doc.(map[string]interface{})
+	if !ok {
+		return doc
+	}
+	redactSeldonTensor(m, name, action)
+	redactV2Tensors(m, "inputs", []string{"raw_input_contents", "rawInputContents"}, name, action)
+	redactV2Tensors(m, "outputs", []string{"raw_output_contents", "rawOutputContents"}, name, action)
+	return m
+}
+
+// RedactJSON applies the redaction rules in order to a JSON payload: seldon JSON, v2 JSON or the JSON form of a
+// protobuf message. Fields selected by path and tensors selected by name are dropped, hashed or masked.
+func RedactJSON(data []byte, rules []v1.RedactionRule) ([]byte, error) {
+	if err := CheckRedactionRules(rules); err != nil {
+		return nil, err
+	}
+	var doc interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	// Keep numbers as they were sent so unredacted values are logged unchanged
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("redaction requires a JSON payload: %w", err)
+	}
+	for _, rule := range rules {
+		if rule.Path != "" {
+			var err error
+			if doc, err = redactPath(doc, rule.Path, rule.Action); err != nil {
+				return nil, err
+			}
+		} else if rule.Tensor != "" {
+			doc = redactTensor(doc, rule.Tensor, rule.Action)
+		}
+	}
+	return json.Marshal(doc)
+}
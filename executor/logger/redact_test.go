@@ -0,0 +1,108 @@
+package logger
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+)
+
+func redactToMap(g *GomegaWithT, data string, rules []v1.RedactionRule) map[string]interface{} {
+	redacted, err := RedactJSON([]byte(data), rules)
+	g.Expect(err).To(BeNil())
+	var doc map[string]interface{}
+	g.Expect(json.Unmarshal(redacted, &doc)).To(BeNil())
+	return doc
+}
+
+func TestRedactJSONPath(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	redactionSalt = "salt"
+	defer func() { redactionSalt = "" }()
+	data := `{"jsonData":{"customer":{"id":"c123","email":"a@b.com"},"items":[{"sku":"x","price":1.5},{"sku":"y","price":2}]}}`
+
+	doc := redactToMap(g, data, []v1.RedactionRule{
+		{Path: "$.jsonData.customer.id", Action: v1.RedactHash},
+		{Path: "$.jsonData.customer.email", Action: v1.RedactDrop},
+		{Path: "$.jsonData.items[*].sku", Action: v1.RedactMask},
+	})
+	jsonData := doc["jsonData"].(map[string]interface{})
+	customer := jsonData["customer"].(map[string]interface{})
+	g.Expect(customer["id"]).To(Equal(hashValue("c123")))
+	g.Expect(customer["id"]).ToNot(Equal(hashValue("c124")))
+	g.Expect(customer).ToNot(HaveKey("email"))
+	items := jsonData["items"].([]interface{})
+	g.Expect(items[0]).To(Equal(map[string]interface{}{"sku": redactionMask, "price": 1.5}))
+	g.Expect(items[1]).To(Equal(map[string]interface{}{"sku": redactionMask, "price": 2.0}))
+
+	// Hashes depend on the salt
+	hashed := hashValue("c123")
+	redactionSalt = "other"
+	g.Expect(hashValue("c123")).ToNot(Equal(hashed))
+
+	// Values aren't hashed without a salt
+	redactionSalt = ""
+	_, err := RedactJSON([]byte(data), []v1.RedactionRule{{Path: "$.jsonData.customer.id", Action: v1.RedactHash}})
+	g.Expect(err).ToNot(BeNil())
+	_, err = RedactJSON([]byte(data), []v1.RedactionRule{{Path: "$.jsonData.customer.id", Action: v1.RedactMask}})
+	g.Expect(err).To(BeNil())
+}
+
+func TestRedactSeldonTensor(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	data := `{"data":{"names":["id","age","email"],"ndarray":[["c1",30,"a@b.com"],["c2",40,"c@d.com"]]}}`
+	doc := redactToMap(g, data, []v1.RedactionRule{
+		{Tensor: "email", Action: v1.RedactDrop},
+		{Tensor: "id", Action: v1.RedactMask},
+	})
+	g.Expect(doc["data"]).To(Equal(map[string]interface{}{
+		"names":   []interface{}{"id", "age"},
+		"ndarray": []interface{}{[]interface{}{redactionMask, 30.0}, []interface{}{redactionMask, 40.0}},
+	}))
+
+	data = `{"data":{"names":["a","b"],"tensor":{"shape":[2,2],"values":[1,2,3,4]}}}`
+	doc = redactToMap(g, data, []v1.RedactionRule{{Tensor: "a", Action: v1.RedactDrop}})
+	g.Expect(doc["data"]).To(Equal(map[string]interface{}{
+		"names":  []interface{}{"b"},
+		"tensor": map[string]interface{}{"shape": []interface{}{2.0, 1.0}, "values": []interface{}{2.0, 4.0}},
+	}))
+}
+
+func TestRedactV2Tensor(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	redactionSalt = "salt"
+	defer func() { redactionSalt = "" }()
+
+	data := `{"inputs":[{"name":"email","datatype":"BYTES","shape":[2],"data":["a@b.com","c@d.com"]},{"name":"x","datatype":"FP32","shape":[2],"data":[1,2]}]}`
+	doc := redactToMap(g, data, []v1.RedactionRule{{Tensor: "email", Action: v1.RedactHash}})
+	inputs := doc["inputs"].([]interface{})
+	g.Expect(inputs[0].(map[string]interface{})["data"]).To(Equal([]interface{}{hashValue("a@b.com"), hashValue("c@d.com")}))
+	g.Expect(inputs[1].(map[string]interface{})["data"]).To(Equal([]interface{}{1.0, 2.0}))
+
+	// The JSON form of a protobuf request, with raw contents in the same order as the inputs
+	data = `{"inputs":[{"name":"email","datatype":"BYTES","shape":[1]},{"name":"x","datatype":"FP32","shape":[1],"contents":{"fp32_contents":[1.5]}}],"raw_input_contents":["YUBiLmNvbQ==","AADAPw=="]}`
+	doc = redactToMap(g, data, []v1.RedactionRule{
+		{Tensor: "email", Action: v1.RedactDrop},
+		{Tensor: "x", Action: v1.RedactMask},
+	})
+	inputs = doc["inputs"].([]interface{})
+	g.Expect(inputs).To(HaveLen(1))
+	g.Expect(inputs[0]).To(Equal(map[string]interface{}{
+		"name":     "x",
+		"datatype": v2BytesDatatype,
+		"shape":    []interface{}{1.0},
+		"contents": map[string]interface{}{"fp32_contents": []interface{}{redactionMask}},
+	}))
+	g.Expect(doc["raw_input_contents"]).To(Equal([]interface{}{redactionMask}))
+}
+
+func TestRedactJSONRequiresJSON(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	_, err := RedactJSON([]byte("not json"), []v1.RedactionRule{{Tensor: "a", Action: v1.RedactDrop}})
+	g.Expect(err).ToNot(BeNil())
+}
@@ -0,0 +1,146 @@
+package logger
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	guuid "github.com/google/uuid"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+const (
+	DefaultS3Region = "us-east-1"
+
+	s3Timeout         = 60 * time.Second
+	s3IAMTimeout      = 5 * time.Second
+	s3ObjectExtension = ".jsonl.gz"
+)
+
+// S3Config locates the bucket the s3 sink writes to. Any S3 compatible store, such as MinIO, can be used.
+type S3Config struct {
+	// Endpoint is the URL of the store, e.g. http://minio.minio-system:9000. Defaults to AWS S3 in the region.
+	Endpoint string
+	Bucket   string
+	// Prefix is prepended to the key of every object.
+	Prefix    string
+	Region    string
+	AccessKey string
+	SecretKey string
+	// SessionToken is needed for temporary credentials.
+	SessionToken string
+}
+
+// s3Sink writes each batch of records as a gzipped JSON lines object. Objects are partitioned by deployment,
+// predictor, date and hour so datasets can be built from a time range. Larger batches, set with the batch size
+// and linger, give fewer and larger objects.
+type s3Sink struct {
+	config S3Config
+	client *minio.Client
+}
+
+// s3Credentials returns the credentials set in the config or else the first found in the AWS environment
+// variables, the shared credentials file or an IAM role, which includes the web identity of a service account.
+func s3Credentials(config S3Config) *credentials.Credentials {
+	if config.AccessKey != "" {
+		return credentials.NewStaticV4(config.AccessKey, config.SecretKey, config.SessionToken)
+	}
+	return credentials.NewChainCredentials([]credentials.Provider{
+		&credentials.EnvAWS{},
+		&credentials.FileAWSCredentials{},
+		&credentials.IAM{Client: &http.Client{Timeout: s3IAMTimeout}},
+	})
+}
+
+// NewS3Sink creates a sink for the bucket. It fails if no credentials are found, rather than writing anonymously.
+func NewS3Sink(config S3Config) (Sink, error) {
+	if config.Bucket == "" {
+		return nil, fmt.Errorf("s3 logger sink requires a bucket")
+	}
+	if config.Region == "" {
+		config.Region = DefaultS3Region
+	}
+	if config.Endpoint == "" {
+		config.Endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", config.Region)
+	}
+	config.Endpoint = strings.TrimSuffix(config.Endpoint, "/")
+	endpoint, err := url.Parse(config.Endpoint)
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid s3 logger sink endpoint %s", config.Endpoint)
+	}
+
+	creds := s3Credentials(config)
+	value, err := creds.Get()
+	if err != nil {
+		return nil, fmt.Errorf("while getting s3 logger sink credentials: %s", err)
+	}
+	if value.AccessKeyID == "" || value.SecretAccessKey == "" {
+		return nil, fmt.Errorf("s3 logger sink found no credentials")
+	}
+	client, err := minio.New(endpoint.Host, &minio.Options{
+		Creds:  creds,
+		Secure: endpoint.Scheme == "https",
+		Region: config.Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("while creating s3 client: %s", err)
+	}
+	return &s3Sink{
+		config: config,
+		client: client,
+	}, nil
+}
+
+// objectKey returns the partitioned key of a new object holding the records.
+func (s *s3Sink) objectKey(records []Record, now time.Time) string {
+	name := fmt.Sprintf("%s-%s%s", now.Format("20060102T150405"), guuid.New().String(), s3ObjectExtension)
+	return path.Join(
+		s.config.Prefix,
+		"deployment="+records[0].InferenceServiceName,
+		"predictor="+records[0].Endpoint,
+		"date="+now.Format("2006-01-02"),
+		"hour="+now.Format("15"),
+		name,
+	)
+}
+
+func (s *s3Sink) Write(records []Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+	lines, err := encodeRecords(records)
+	if err != nil {
+		return err
+	}
+	var body bytes.Buffer
+	zw := gzip.NewWriter(&body)
+	if _, err := zw.Write(lines); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s3Timeout)
+	defer cancel()
+	key := s.objectKey(records, time.Now().UTC())
+	_, err = s.client.PutObject(ctx, s.config.Bucket, key, bytes.NewReader(body.Bytes()), int64(body.Len()), minio.PutObjectOptions{
+		ContentType:     "application/x-ndjson",
+		ContentEncoding: "gzip",
+	})
+	if err != nil {
+		return fmt.Errorf("while writing s3 log object: %s", err)
+	}
+	return nil
+}
+
+func (s *s3Sink) Close() error {
+	return nil
+}
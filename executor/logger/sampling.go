@@ -0,0 +1,21 @@
+package logger
+
+import (
+	"hash/fnv"
+)
+
+const samplingBuckets = 1000000
+
+// Sampled returns whether the call with the given PUID is in a sample of the given rate. The choice depends only on
+// the PUID so every node, and every executor a call passes through, makes the same decision.
+func Sampled(puid string, rate float64) bool {
+	if rate >= 1 {
+		return true
+	}
+	if rate <= 0 {
+		return false
+	}
+	h := fnv.New64a()
+	h.Write([]byte(puid))
+	return float64(h.Sum64()%samplingBuckets) < rate*samplingBuckets
+}
@@ -0,0 +1,28 @@
+package logger
+
+import (
+	"testing"
+
+	guuid "github.com/google/uuid"
+	. "github.com/onsi/gomega"
+)
+
+func TestSampled(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	g.Expect(Sampled("puid", 1)).To(BeTrue())
+	g.Expect(Sampled("puid", 0)).To(BeFalse())
+
+	sampled := 0
+	for i := 0; i < 10000; i++ {
+		puid := guuid.New().String()
+		if Sampled(puid, 0.1) {
+			sampled++
+			// The same PUID is always in the sample
+			g.Expect(Sampled(puid, 0.1)).To(BeTrue())
+			// and in any larger one
+			g.Expect(Sampled(puid, 0.5)).To(BeTrue())
+		}
+	}
+	g.Expect(sampled).To(BeNumerically("~", 1000, 150))
+}
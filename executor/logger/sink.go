@@ -0,0 +1,184 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/seldonio/seldon-core/executor/api/payload"
+)
+
+const (
+	ENV_LOGGER_SINK        = "LOGGER_SINK"
+	ENV_LOGGER_SINK_DIR    = "LOGGER_SINK_DIR"
+	ENV_LOGGER_S3_ENDPOINT = "LOGGER_S3_ENDPOINT"
+	ENV_LOGGER_S3_BUCKET   = "LOGGER_S3_BUCKET"
+	ENV_LOGGER_S3_PREFIX   = "LOGGER_S3_PREFIX"
+	ENV_LOGGER_S3_REGION   = "LOGGER_S3_REGION"
+
+	SinkFile   = "file"
+	SinkStdout = "stdout"
+	SinkS3     = "s3"
+
+	DefaultSinkMaxFileBytes = 100 << 20
+	DefaultSinkMaxFiles     = 10
+)
+
+// Record is a logged payload in the JSON form of a structured cloudevent, so the attributes sent as cloudevents
+// headers or kafka headers are fields of each record. JSON payloads are embedded as is, others are base64 encoded.
+type Record struct {
+	SpecVersion          string          `json:"specversion"`
+	Id                   string          `json:"id"`
+	Type                 string          `json:"type"`
+	Source               string          `json:"source"`
+	Time                 time.Time       `json:"time"`
+	DataContentType      string          `json:"datacontenttype"`
+	RequestId            string          `json:"requestid"`
+	ModelId              string          `json:"modelid"`
+	InferenceServiceName string          `json:"inferenceservicename"`
+	Namespace            string          `json:"namespace"`
+	Endpoint             string          `json:"endpoint"`
+	Protocol             string          `json:"protocol"`
+	Data                 json.RawMessage `json:"data,omitempty"`
+	DataBase64           []byte          `json:"data_base64,omitempty"`
+}
+
+// Sink stores log records instead of sending them to a cloudevents endpoint or kafka. A sink is shared by the
+// workers so must be safe for concurrent use. Write either stores all the records or fails, so they can be retried.
+type Sink interface {
+	Write(records []Record) error
+	Close() error
+}
+
+// SinkConfig selects and configures a sink.
+type SinkConfig struct {
+	// Type is one of file, stdout or s3. Empty sends logs to the cloudevents endpoint or kafka.
+	Type string
+	// Dir is the directory the file sink writes to.
+	Dir string
+	// MaxFileBytes is the size at which the file sink starts a new file.
+	MaxFileBytes int64
+	// MaxFiles is the number of files the file sink keeps, removing the oldest. 0 keeps every file.
+	MaxFiles int
+	// S3 is the object store the s3 sink writes to.
+	S3 S3Config
+}
+
+func getEnvIfEmpty(value string, envName string) string {
+	if value == "" {
+		return os.Getenv(envName)
+	}
+	return value
+}
+
+// NewSink creates the sink for the config, falling back to the environment for unset fields. It returns nil if no
+// sink is configured.
+func NewSink(config SinkConfig) (Sink, error) {
+	config.Type = getEnvIfEmpty(config.Type, ENV_LOGGER_SINK)
+	config.Dir = getEnvIfEmpty(config.Dir, ENV_LOGGER_SINK_DIR)
+	config.S3.Endpoint = getEnvIfEmpty(config.S3.Endpoint, ENV_LOGGER_S3_ENDPOINT)
+	config.S3.Bucket = getEnvIfEmpty(config.S3.Bucket, ENV_LOGGER_S3_BUCKET)
+	config.S3.Prefix = getEnvIfEmpty(config.S3.Prefix, ENV_LOGGER_S3_PREFIX)
+	config.S3.Region = getEnvIfEmpty(config.S3.Region, ENV_LOGGER_S3_REGION)
+	switch config.Type {
+	case "":
+		return nil, nil
+	case SinkFile:
+		return NewFileSink(config.Dir, config.MaxFileBytes, config.MaxFiles)
+	case SinkStdout:
+		return NewStdoutSink(os.Stdout), nil
+	case SinkS3:
+		return NewS3Sink(config.S3)
+	default:
+		return nil, fmt.Errorf("unknown logger sink %s", config.Type)
+	}
+}
+
+func (w *Worker) createRecord(logReq LogRequest) (Record, error) {
+	data, err := payload.DecompressBytes(*logReq.Bytes, logReq.ContentEncoding)
+	if err != nil {
+		return Record{}, fmt.Errorf("while creating log record: %s", err)
+	}
+	reqType, err := getCEType(logReq)
+	if err != nil {
+		return Record{}, err
+	}
+	record := Record{
+		SpecVersion:          "1.0",
+		Id:                   logReq.Id,
+		Type:                 reqType,
+		Source:               urlString(logReq.SourceUri),
+		Time:                 time.Now().UTC(),
+		DataContentType:      logReq.ContentType,
+		RequestId:            logReq.RequestId,
+		ModelId:              logReq.ModelId,
+		InferenceServiceName: w.SdepName,
+		Namespace:            w.Namespace,
+		Endpoint:             w.PredictorName,
+		Protocol:             w.PayloadProtocol,
+	}
+	if strings.Contains(logReq.ContentType, "json") && json.Valid(data) {
+		record.Data = data
+	} else {
+		record.DataBase64 = data
+	}
+	return record, nil
+}
+
+// writeToSink writes the batch to the configured sink. The sink stores the whole batch or none of it.
+func (w *Worker) writeToSink(batch []LogRequest) ([]LogRequest, error) {
+	records := make([]Record, 0, len(batch))
+	for _, logReq := range batch {
+		record, err := w.createRecord(logReq)
+		if err != nil {
+			return batch, err
+		}
+		records = append(records, record)
+	}
+	if err := w.Delivery.Sink.Write(records); err != nil {
+		return batch, err
+	}
+	return nil, nil
+}
+
+// encodeRecords returns the records as JSON lines.
+func encodeRecords(records []Record) ([]byte, error) {
+	var lines []byte
+	for _, record := range records {
+		line, err := json.Marshal(record)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(append(lines, line...), '\n')
+	}
+	return lines, nil
+}
+
+// stdoutSink writes records as JSON lines for a log collector such as fluentd or vector to pick up.
+type stdoutSink struct {
+	sync.Mutex
+	out io.Writer
+}
+
+func NewStdoutSink(out io.Writer) Sink {
+	return &stdoutSink{out: out}
+}
+
+func (s *stdoutSink) Write(records []Record) error {
+	lines, err := encodeRecords(records)
+	if err != nil {
+		return err
+	}
+	s.Lock()
+	defer s.Unlock()
+	_, err = s.out.Write(lines)
+	return err
+}
+
+func (s *stdoutSink) Close() error {
+	return nil
+}
@@ -0,0 +1,260 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func decodeRecords(g *GomegaWithT, data []byte) []Record {
+	var records []Record
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		var record Record
+		g.Expect(json.Unmarshal(scanner.Bytes(), &record)).To(Succeed())
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestWorkerWritesToSink(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	var out bytes.Buffer
+	worker, err := NewWorker(1, make(chan LogRequest), logf.Log.WithName("test"), "dep", "default", "p", "", "", "seldon", DeliveryConfig{Sink: NewStdoutSink(&out)})
+	g.Expect(err).To(BeNil())
+	sourceUri, err := url.Parse("http://localhost:8000")
+	g.Expect(err).To(BeNil())
+	jsonData := []byte(`{"data":{"ndarray":[[1.0]]}}`)
+	data := []byte{0x0a, 0x01, 0xff}
+	worker.deliver([]LogRequest{
+		{Bytes: &jsonData, ContentType: "application/json", ReqType: InferenceRequest, Id: "1", SourceUri: sourceUri, ModelId: "model", RequestId: "puid"},
+		{Bytes: &data, ContentType: "application/protobuf", ReqType: InferenceResponse, Id: "2", SourceUri: sourceUri, ModelId: "model", RequestId: "puid"},
+	})
+
+	records := decodeRecords(g, out.Bytes())
+	g.Expect(records).To(HaveLen(2))
+	g.Expect(records[0].Id).To(Equal("1"))
+	g.Expect(records[0].Type).To(Equal(CEInferenceRequest))
+	g.Expect(records[0].Source).To(Equal("http://localhost:8000"))
+	g.Expect(records[0].RequestId).To(Equal("puid"))
+	g.Expect(records[0].ModelId).To(Equal("model"))
+	g.Expect(records[0].InferenceServiceName).To(Equal("dep"))
+	g.Expect(records[0].Namespace).To(Equal("default"))
+	g.Expect(records[0].Endpoint).To(Equal("p"))
+	g.Expect(records[0].Protocol).To(Equal("seldon"))
+	g.Expect(string(records[0].Data)).To(MatchJSON(`{"data":{"ndarray":[[1.0]]}}`))
+	g.Expect(records[1].Type).To(Equal(CEInferenceResponse))
+	g.Expect(records[1].Data).To(BeNil())
+	g.Expect(records[1].DataBase64).To(Equal(data))
+}
+
+type failingSink struct {
+	sync.Mutex
+	failures int
+	written  int
+}
+
+func (s *failingSink) Write(records []Record) error {
+	s.Lock()
+	defer s.Unlock()
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("unavailable")
+	}
+	s.written += len(records)
+	return nil
+}
+
+func (s *failingSink) Close() error {
+	return nil
+}
+
+func TestWorkerRetriesSinkWrites(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	sink := &failingSink{failures: 2}
+	worker, err := NewWorker(1, make(chan LogRequest), logf.Log.WithName("test"), "dep", "default", "p", "", "", "seldon", DeliveryConfig{Sink: sink, MaxRetries: 2, RetryBackoff: time.Millisecond})
+	g.Expect(err).To(BeNil())
+	data := []byte(`{"data":{"ndarray":[[1.0]]}}`)
+	worker.deliver([]LogRequest{{Bytes: &data, ContentType: "application/json", ReqType: InferenceRequest, Id: "1"}, {Bytes: &data, ContentType: "application/json", ReqType: InferenceRequest, Id: "2"}})
+	g.Expect(sink.written).To(Equal(2))
+}
+
+func TestFileSinkRotates(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	dir, err := ioutil.TempDir("", "logger-sink")
+	g.Expect(err).To(BeNil())
+	defer os.RemoveAll(dir)
+
+	sink, err := NewFileSink(dir, 300, 2)
+	g.Expect(err).To(BeNil())
+	worker, err := NewWorker(1, make(chan LogRequest), logf.Log.WithName("test"), "dep", "default", "p", "", "", "seldon", DeliveryConfig{Sink: sink})
+	g.Expect(err).To(BeNil())
+	data := []byte(`{"data":{"ndarray":[[1.0]]}}`)
+	for _, id := range []string{"1", "2", "3", "4"} {
+		worker.deliver([]LogRequest{{Bytes: &data, ContentType: "application/json", ReqType: InferenceRequest, Id: id}})
+	}
+	g.Expect(sink.Close()).To(Succeed())
+
+	// Each record is over half the maximum size so has a file of its own and only the newest two are kept
+	files, err := filepath.Glob(filepath.Join(dir, sinkFilePrefix+"*"+sinkFileSuffix))
+	g.Expect(err).To(BeNil())
+	g.Expect(files).To(HaveLen(2))
+	var ids []string
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		g.Expect(err).To(BeNil())
+		for _, record := range decodeRecords(g, data) {
+			ids = append(ids, record.Id)
+		}
+	}
+	g.Expect(ids).To(Equal([]string{"3", "4"}))
+}
+
+func TestFileSinkRemovesPartialWrites(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	dir := t.TempDir()
+	sink, err := NewFileSink(dir, 0, 0)
+	g.Expect(err).To(BeNil())
+	defer sink.Close()
+	g.Expect(sink.Write([]Record{{Id: "1"}})).To(Succeed())
+	files, err := filepath.Glob(filepath.Join(dir, sinkFilePrefix+"*"+sinkFileSuffix))
+	g.Expect(err).To(BeNil())
+	g.Expect(files).To(HaveLen(1))
+	info, err := os.Stat(files[0])
+	g.Expect(err).To(BeNil())
+
+	// Limit the file size so only part of the next batch is written
+	var limit syscall.Rlimit
+	g.Expect(syscall.Getrlimit(syscall.RLIMIT_FSIZE, &limit)).To(Succeed())
+	signal.Ignore(syscall.SIGXFSZ)
+	defer signal.Reset(syscall.SIGXFSZ)
+	partial := limit
+	partial.Cur = uint64(info.Size()) + 10
+	g.Expect(syscall.Setrlimit(syscall.RLIMIT_FSIZE, &partial)).To(Succeed())
+	err = sink.Write([]Record{{Id: "2"}, {Id: "3"}})
+	g.Expect(syscall.Setrlimit(syscall.RLIMIT_FSIZE, &limit)).To(Succeed())
+	g.Expect(err).ToNot(BeNil())
+
+	g.Expect(sink.Write([]Record{{Id: "2"}, {Id: "3"}})).To(Succeed())
+	data, err := ioutil.ReadFile(files[0])
+	g.Expect(err).To(BeNil())
+	var ids []string
+	for _, record := range decodeRecords(g, data) {
+		ids = append(ids, record.Id)
+	}
+	g.Expect(ids).To(Equal([]string{"1", "2", "3"}))
+}
+
+func TestS3SinkPutsPartitionedObjects(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	type put struct {
+		path   string
+		auth   string
+		header http.Header
+		body   []byte
+	}
+	puts := make(chan put, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		g.Expect(r.Method).To(Equal(http.MethodPut))
+		// Over plain http the object is sent in signed chunks, each a hex size line followed by the data
+		var body []byte
+		chunks := bufio.NewReader(r.Body)
+		for {
+			line, err := chunks.ReadString('\n')
+			g.Expect(err).To(BeNil())
+			size, err := strconv.ParseInt(strings.SplitN(line, ";", 2)[0], 16, 64)
+			g.Expect(err).To(BeNil())
+			chunk := make([]byte, size+2)
+			_, err = io.ReadFull(chunks, chunk)
+			g.Expect(err).To(BeNil())
+			if size == 0 {
+				break
+			}
+			body = append(body, chunk[:size]...)
+		}
+		puts <- put{path: r.URL.Path, auth: r.Header.Get("Authorization"), header: r.Header, body: body}
+	}))
+	defer server.Close()
+
+	sink, err := NewS3Sink(S3Config{Endpoint: server.URL, Bucket: "logs", Prefix: "payloads", AccessKey: "minio", SecretKey: "minio123"})
+	g.Expect(err).To(BeNil())
+	worker, err := NewWorker(1, make(chan LogRequest), logf.Log.WithName("test"), "dep", "default", "p", "", "", "seldon", DeliveryConfig{Sink: sink})
+	g.Expect(err).To(BeNil())
+	data := []byte(`{"data":{"ndarray":[[1.0]]}}`)
+	worker.deliver([]LogRequest{{Bytes: &data, ContentType: "application/json", ReqType: InferenceRequest, Id: "1"}, {Bytes: &data, ContentType: "application/json", ReqType: InferenceRequest, Id: "2"}})
+
+	var p put
+	g.Eventually(puts).Should(Receive(&p))
+	now := time.Now().UTC()
+	g.Expect(p.path).To(HavePrefix("/logs/payloads/deployment=dep/predictor=p/date=" + now.Format("2006-01-02") + "/hour="))
+	g.Expect(p.path).To(HaveSuffix(s3ObjectExtension))
+	g.Expect(p.auth).To(HavePrefix("AWS4-HMAC-SHA256 Credential=minio/" + now.Format("20060102") + "/" + DefaultS3Region + "/s3/aws4_request"))
+	g.Expect(p.header.Get("Content-Encoding")).To(Equal("gzip"))
+
+	zr, err := gzip.NewReader(bytes.NewReader(p.body))
+	g.Expect(err).To(BeNil())
+	lines, err := ioutil.ReadAll(zr)
+	g.Expect(err).To(BeNil())
+	records := decodeRecords(g, lines)
+	g.Expect(records).To(HaveLen(2))
+	g.Expect(records[1].Id).To(Equal("2"))
+}
+
+func TestS3SinkRequiresCredentials(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	// No keys in the environment or a credentials file and no role from the container credentials endpoint
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(t.TempDir(), "credentials"))
+	t.Setenv("AWS_CONTAINER_CREDENTIALS_FULL_URI", server.URL)
+
+	_, err := NewS3Sink(S3Config{Endpoint: server.URL, Bucket: "logs"})
+	g.Expect(err).ToNot(BeNil())
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "minio")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "minio123")
+	_, err = NewS3Sink(S3Config{Endpoint: server.URL, Bucket: "logs"})
+	g.Expect(err).To(BeNil())
+}
+
+func TestNewSink(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	sink, err := NewSink(SinkConfig{})
+	g.Expect(err).To(BeNil())
+	g.Expect(sink).To(BeNil())
+
+	_, err = NewSink(SinkConfig{Type: "unknown"})
+	g.Expect(err).ToNot(BeNil())
+
+	_, err = NewSink(SinkConfig{Type: SinkS3})
+	g.Expect(err).ToNot(BeNil())
+	g.Expect(strings.Contains(err.Error(), "bucket")).To(BeTrue())
+}
@@ -0,0 +1,222 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+const (
+	spillFileSuffix     = ".json"
+	spillReplayInterval = 5 * time.Second
+)
+
+// spilledRequest is the on-disk form of a LogRequest.
+type spilledRequest struct {
+	Url             string         `json:"url"`
+	Bytes           []byte         `json:"bytes"`
+	ContentType     string         `json:"contentType"`
+	ContentEncoding string         `json:"contentEncoding"`
+	ReqType         LogRequestType `json:"reqType"`
+	Id              string         `json:"id"`
+	SourceUri       string         `json:"sourceUri"`
+	ModelId         string         `json:"modelId"`
+	RequestId       string         `json:"requestId"`
+}
+
+func urlString(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	return u.String()
+}
+
+func parseUrl(s string) (*url.URL, error) {
+	if s == "" {
+		return nil, nil
+	}
+	return url.Parse(s)
+}
+
+// spillQueue is a size bounded queue of log requests on disk, one file per request, which holds events
+// that could not be delivered until the logging endpoint is available again.
+type spillQueue struct {
+	sync.Mutex
+	dir      string
+	maxBytes int64
+	size     int64
+	seq      uint64
+}
+
+// newSpillQueue opens the spill queue in dir, picking up any requests spilled by a previous run.
+func newSpillQueue(dir string, maxBytes int64) (*spillQueue, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	s := &spillQueue{dir: dir, maxBytes: maxBytes}
+	names, err := s.files()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		if info, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			s.size += info.Size()
+		}
+	}
+	metrics.SetSpillBytes(s.size)
+	return s, nil
+}
+
+// write persists the request, failing if the queue would grow beyond its maximum size.
+func (s *spillQueue) write(req LogRequest) error {
+	spilled := spilledRequest{
+		Url:             urlString(req.Url),
+		ContentType:     req.ContentType,
+		ContentEncoding: req.ContentEncoding,
+		ReqType:         req.ReqType,
+		Id:              req.Id,
+		SourceUri:       urlString(req.SourceUri),
+		ModelId:         req.ModelId,
+		RequestId:       req.RequestId,
+	}
+	if req.Bytes != nil {
+		spilled.Bytes = *req.Bytes
+	}
+	data, err := json.Marshal(spilled)
+	if err != nil {
+		return err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	if s.maxBytes > 0 && s.size+int64(len(data)) > s.maxBytes {
+		return fmt.Errorf("spill queue is full: %d of %d bytes used", s.size, s.maxBytes)
+	}
+	s.seq++
+	// Names sort in the order the requests were spilled
+	name := fmt.Sprintf("%020d-%010d%s", time.Now().UnixNano(), s.seq, spillFileSuffix)
+	tmp := filepath.Join(s.dir, name+".tmp")
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	// Rename so a crash never leaves a partially written request in the queue
+	if err := os.Rename(tmp, filepath.Join(s.dir, name)); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	s.size += int64(len(data))
+	metrics.SetSpillBytes(s.size)
+	return nil
+}
+
+// files returns the names of the spilled requests, oldest first.
+func (s *spillQueue) files() ([]string, error) {
+	entries, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), spillFileSuffix) {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (s *spillQueue) read(name string) (LogRequest, error) {
+	data, err := ioutil.ReadFile(filepath.Join(s.dir, name))
+	if err != nil {
+		return LogRequest{}, err
+	}
+	var spilled spilledRequest
+	if err := json.Unmarshal(data, &spilled); err != nil {
+		return LogRequest{}, err
+	}
+	logUrl, err := parseUrl(spilled.Url)
+	if err != nil {
+		return LogRequest{}, err
+	}
+	sourceUri, err := parseUrl(spilled.SourceUri)
+	if err != nil {
+		return LogRequest{}, err
+	}
+	return LogRequest{
+		Url:             logUrl,
+		Bytes:           &spilled.Bytes,
+		ContentType:     spilled.ContentType,
+		ContentEncoding: spilled.ContentEncoding,
+		ReqType:         spilled.ReqType,
+		Id:              spilled.Id,
+		SourceUri:       sourceUri,
+		ModelId:         spilled.ModelId,
+		RequestId:       spilled.RequestId,
+	}, nil
+}
+
+func (s *spillQueue) remove(name string) error {
+	path := filepath.Join(s.dir, name)
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+	s.Lock()
+	defer s.Unlock()
+	s.size -= info.Size()
+	metrics.SetSpillBytes(s.size)
+	return nil
+}
+
+// drain sends the spilled requests in order, stopping at the first failure so the rest are kept for the next attempt.
+func (s *spillQueue) drain(send func(LogRequest) error, log logr.Logger) {
+	names, err := s.files()
+	if err != nil {
+		log.Error(err, "Failed to list spilled log requests", "dir", s.dir)
+		return
+	}
+	for _, name := range names {
+		req, err := s.read(name)
+		if err != nil {
+			// An unreadable request can never be sent so is dropped rather than blocking the queue
+			log.Error(err, "Dropping unreadable spilled log request", "file", name)
+			metrics.EventDropped()
+		} else if err := send(req); err != nil {
+			log.Info("Logger endpoint still unavailable, keeping spilled log requests", "error", err.Error())
+			return
+		} else {
+			metrics.EventSent()
+		}
+		if err := s.remove(name); err != nil {
+			log.Error(err, "Failed to remove spilled log request", "file", name)
+			return
+		}
+	}
+}
+
+// replay periodically sends spilled requests until stop is closed.
+func (s *spillQueue) replay(send func(LogRequest) error, stop <-chan struct{}, log logr.Logger) {
+	ticker := time.NewTicker(spillReplayInterval)
+	defer ticker.Stop()
+	for {
+		s.drain(send, log)
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package logger
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+type testLoggerEndpoint struct {
+	sync.Mutex
+	failures int
+	ids      []string
+}
+
+func (e *testLoggerEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.Lock()
+	defer e.Unlock()
+	if e.failures > 0 {
+		e.failures--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	e.ids = append(e.ids, r.Header.Get(CloudEventsIdHeader))
+	w.WriteHeader(http.StatusOK)
+}
+
+func (e *testLoggerEndpoint) received() []string {
+	e.Lock()
+	defer e.Unlock()
+	return append([]string{}, e.ids...)
+}
+
+func TestDeliveryBackoff(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	delivery := DeliveryConfig{RetryBackoff: time.Second}
+	g.Expect(delivery.backoff(1)).To(Equal(time.Second))
+	g.Expect(delivery.backoff(3)).To(Equal(4 * time.Second))
+	g.Expect(delivery.backoff(10)).To(Equal(maxRetryBackoff))
+}
+
+func TestWorkerRetriesFailedSend(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	endpoint := &testLoggerEndpoint{failures: 2}
+	server := httptest.NewServer(endpoint)
+	defer server.Close()
+
+	worker, err := NewWorker(1, make(chan LogRequest), logf.Log.WithName("test"), "dep", "default", "p", "", "", "seldon", DeliveryConfig{MaxRetries: 3, RetryBackoff: time.Millisecond})
+	g.Expect(err).To(BeNil())
+	logUrl, err := url.Parse(server.URL)
+	g.Expect(err).To(BeNil())
+	sourceUri, err := url.Parse("http://localhost:8000")
+	g.Expect(err).To(BeNil())
+	data := []byte(`{"data":{"ndarray":[[1.0]]}}`)
+	worker.deliver([]LogRequest{{Url: logUrl, Bytes: &data, ContentType: "application/json", ReqType: InferenceRequest, Id: "1", SourceUri: sourceUri, ModelId: "model"}})
+	g.Expect(endpoint.received()).To(Equal([]string{"1"}))
+}
+
+func TestWorkerSpillsAndReplays(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	dir, err := ioutil.TempDir("", "seldon-logger")
+	g.Expect(err).To(BeNil())
+	defer os.RemoveAll(dir)
+	endpoint := &testLoggerEndpoint{failures: 4}
+	server := httptest.NewServer(endpoint)
+	defer server.Close()
+
+	worker, err := NewWorker(1, make(chan LogRequest), logf.Log.WithName("test"), "dep", "default", "p", "", "", "seldon", DeliveryConfig{MaxRetries: 1, RetryBackoff: time.Millisecond})
+	g.Expect(err).To(BeNil())
+	worker.Spill, err = newSpillQueue(dir, DefaultSpillMaxBytes)
+	g.Expect(err).To(BeNil())
+	logUrl, err := url.Parse(server.URL)
+	g.Expect(err).To(BeNil())
+	sourceUri, err := url.Parse("http://localhost:8000")
+	g.Expect(err).To(BeNil())
+	data := []byte(`{"data":{"ndarray":[[1.0]]}}`)
+	worker.deliver([]LogRequest{{Url: logUrl, Bytes: &data, ContentType: "application/json", ReqType: InferenceRequest, Id: "1", SourceUri: sourceUri, ModelId: "model"}})
+	worker.deliver([]LogRequest{{Url: logUrl, Bytes: &data, ContentType: "application/json", ReqType: InferenceRequest, Id: "2", SourceUri: sourceUri, ModelId: "model"}})
+	g.Expect(endpoint.received()).To(BeEmpty())
+	names, err := worker.Spill.files()
+	g.Expect(err).To(BeNil())
+	g.Expect(names).To(HaveLen(2))
+
+	// A new queue over the same directory picks up the spilled requests, as after a restart
+	spill, err := newSpillQueue(dir, DefaultSpillMaxBytes)
+	g.Expect(err).To(BeNil())
+	g.Expect(spill.size).To(Equal(worker.Spill.size))
+	spill.drain(worker.send, logf.Log)
+	g.Expect(endpoint.received()).To(Equal([]string{"1", "2"}))
+	names, err = spill.files()
+	g.Expect(err).To(BeNil())
+	g.Expect(names).To(BeEmpty())
+	g.Expect(spill.size).To(BeZero())
+}
+
+func TestSpillQueueMaxBytes(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	dir, err := ioutil.TempDir("", "seldon-logger")
+	g.Expect(err).To(BeNil())
+	defer os.RemoveAll(dir)
+
+	spill, err := newSpillQueue(dir, 100)
+	g.Expect(err).To(BeNil())
+	logUrl, err := url.Parse("http://localhost")
+	g.Expect(err).To(BeNil())
+	data := []byte(`{"data":{"ndarray":[[1.0]]}}`)
+	err = spill.write(LogRequest{Url: logUrl, Bytes: &data, ContentType: "application/json", ReqType: InferenceRequest, Id: "1"})
+	g.Expect(err).ToNot(BeNil())
+	names, err := spill.files()
+	g.Expect(err).To(BeNil())
+	g.Expect(names).To(BeEmpty())
+}
+
+func TestSpillQueueRoundTrip(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	dir, err := ioutil.TempDir("", "seldon-logger")
+	g.Expect(err).To(BeNil())
+	defer os.RemoveAll(dir)
+
+	spill, err := newSpillQueue(dir, DefaultSpillMaxBytes)
+	g.Expect(err).To(BeNil())
+	logUrl, err := url.Parse("http://localhost:2222/log")
+	g.Expect(err).To(BeNil())
+	sourceUri, err := url.Parse("http://localhost:8000")
+	g.Expect(err).To(BeNil())
+	data := []byte(`{"data":{"ndarray":[[1.0]]}}`)
+	req := LogRequest{
+		Url:         logUrl,
+		Bytes:       &data,
+		ContentType: "application/json",
+		ReqType:     InferenceRequest,
+		Id:          "1",
+		SourceUri:   sourceUri,
+		ModelId:     "model",
+		RequestId:   "puid",
+	}
+	g.Expect(spill.write(req)).To(BeNil())
+	names, err := spill.files()
+	g.Expect(err).To(BeNil())
+	g.Expect(names).To(HaveLen(1))
+	read, err := spill.read(names[0])
+	g.Expect(err).To(BeNil())
+	g.Expect(read).To(Equal(req))
+}
@@ -28,6 +28,8 @@ const (
 	ProtocolAttr             = "protocol"
 	KafkaTypeHeader          = "type"
 	KafkaContentTypeHeader   = "content-type"
+
+	kafkaDeliveryTimeout = 60 * time.Second
 )
 
 // NewWorker creates, and returns a new Worker object. Its only argument
@@ -43,6 +45,7 @@ func NewWorker(
 	kafkaBroker string,
 	kafkaTopic string,
 	protocol string,
+	delivery DeliveryConfig,
 ) (*Worker, error) {
 
 	var producer *kafka.Producer
@@ -50,6 +53,14 @@ func NewWorker(
 	if kafkaBroker != "" {
 		log.Info("Creating producer", "broker", kafkaBroker, "topic", kafkaTopic)
 		producerConfig := util.GetKafkaProducerConfig(kafkaBroker)
+		// Delivery reports are needed to know when to retry. They are read from a channel per message.
+		if err := producerConfig.SetKey("go.delivery.reports", true); err != nil {
+			return nil, err
+		}
+		if delivery.BatchSize > 1 {
+			producerConfig.SetKey("batch.num.messages", delivery.BatchSize)
+			producerConfig.SetKey("linger.ms", int(delivery.BatchLinger/time.Millisecond))
+		}
 		producer, err = kafka.NewProducer(producerConfig)
 		if err != nil {
 			return nil, err
@@ -73,6 +84,8 @@ func NewWorker(
 		KafkaTopic:      kafkaTopic,
 		Producer:        producer,
 		PayloadProtocol: protocol,
+		Delivery:        delivery,
+		ceClients:       make(map[string]cloudevents.Client),
 	}, nil
 }
 
@@ -90,6 +103,9 @@ type Worker struct {
 	KafkaTopic      string
 	Producer        *kafka.Producer
 	PayloadProtocol string
+	Delivery        DeliveryConfig
+	Spill           *spillQueue
+	ceClients       map[string]cloudevents.Client
 }
 
 func getCEType(logReq LogRequest) (string, error) {
@@ -105,16 +121,16 @@ func getCEType(logReq LogRequest) (string, error) {
 	}
 }
 
-func (w *Worker) sendKafkaEvent(logReq LogRequest) error {
+func (w *Worker) createKafkaMessage(logReq LogRequest) (*kafka.Message, error) {
 
 	data, err := payload.DecompressBytes(*logReq.Bytes, logReq.ContentEncoding)
 	if err != nil {
-		return fmt.Errorf("while creating kafka transport: %s", err)
+		return nil, fmt.Errorf("while creating kafka transport: %s", err)
 	}
 
 	reqType, err := getCEType(logReq)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	kafkaHeaders := []kafka.Header{
@@ -127,21 +143,20 @@ func (w *Worker) sendKafkaEvent(logReq LogRequest) error {
 		{Key: EndpointAttr, Value: []byte(w.PredictorName)},
 		{Key: ProtocolAttr, Value: []byte(w.PayloadProtocol)},
 	}
-	w.Log.Info("kafkaHeaders is", "kafkaHeaders", kafkaHeaders)
-	err = w.Producer.Produce(&kafka.Message{
+	w.Log.V(1).Info("kafkaHeaders is", "kafkaHeaders", kafkaHeaders)
+	return &kafka.Message{
 		TopicPartition: kafka.TopicPartition{Topic: &w.KafkaTopic, Partition: kafka.PartitionAny},
 		Value:          data,
 		Headers:        kafkaHeaders,
-	}, nil)
-	if err != nil {
-		w.Log.Error(err, "Failed to produce response")
-		return err
-	}
+	}, nil
+}
 
-	return nil
+func (w *Worker) sendKafkaEvent(logReq LogRequest) error {
+	_, err := w.sendKafkaEvents([]LogRequest{logReq})
+	return err
 }
 
-func (w *Worker) sendCloudEvent(logReq LogRequest) error {
+func (w *Worker) createCloudEvent(logReq LogRequest) (cloudevents.Event, error) {
 
 	// This temporary fix related to the fact that Triton server responses
 	// are now gzipped compressed. Until we introduce support for gzip
@@ -149,29 +164,15 @@ func (w *Worker) sendCloudEvent(logReq LogRequest) error {
 	// header in the CloudEvent messages this can serve as temporary solution.
 	data, err := payload.DecompressBytes(*logReq.Bytes, logReq.ContentEncoding)
 	if err != nil {
-		return fmt.Errorf("while creating http transport: %s", err)
+		return cloudevents.Event{}, fmt.Errorf("while creating http transport: %s", err)
 	}
 
-	t, err := cloudevents.NewHTTPTransport(
-		cloudevents.WithTarget(logReq.Url.String()),
-		cloudevents.WithEncoding(cloudevents.HTTPBinaryV1),
-	)
-
-	if err != nil {
-		return fmt.Errorf("while creating http transport: %s", err)
-	}
-	c, err := cloudevents.NewClient(t,
-		cloudevents.WithTimeNow(),
-	)
-	if err != nil {
-		return fmt.Errorf("while creating new cloudevents client: %s", err)
-	}
 	event := cloudevents.NewEvent(cloudevents.VersionV1)
 	event.SetID(logReq.Id)
 	if refType, err := getCEType(logReq); err == nil {
 		event.SetType(refType)
 	} else {
-		return err
+		return cloudevents.Event{}, err
 	}
 
 	event.SetExtension(ModelIdAttr, logReq.ModelId)
@@ -185,17 +186,101 @@ func (w *Worker) sendCloudEvent(logReq LogRequest) error {
 	event.SetSource(logReq.SourceUri.String())
 	event.SetDataContentType(logReq.ContentType)
 	if err := event.SetData(data); err != nil {
-		return fmt.Errorf("while setting cloudevents data: %s", err)
+		return cloudevents.Event{}, fmt.Errorf("while setting cloudevents data: %s", err)
 	}
+	return event, nil
+}
 
-	//fmt.Printf("%+v\n", event)
+// ceClient returns the cloudevents client for target, reusing the client and transport created for earlier requests.
+func (w *Worker) ceClient(target string) (cloudevents.Client, error) {
+	if c, ok := w.ceClients[target]; ok {
+		return c, nil
+	}
+	t, err := cloudevents.NewHTTPTransport(
+		cloudevents.WithTarget(target),
+		cloudevents.WithEncoding(cloudevents.HTTPBinaryV1),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("while creating http transport: %s", err)
+	}
+	c, err := cloudevents.NewClient(t,
+		cloudevents.WithTimeNow(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("while creating new cloudevents client: %s", err)
+	}
+	w.ceClients[target] = c
+	return c, nil
+}
 
+func (w *Worker) sendCloudEvent(logReq LogRequest) error {
+	event, err := w.createCloudEvent(logReq)
+	if err != nil {
+		return err
+	}
+	c, err := w.ceClient(logReq.Url.String())
+	if err != nil {
+		return err
+	}
 	if _, _, err := c.Send(w.CeCtx, event); err != nil {
 		return fmt.Errorf("while sending event: %s", err)
 	}
 	return nil
 }
 
+func (w *Worker) send(logReq LogRequest) error {
+	_, err := w.sendBatch([]LogRequest{logReq})
+	return err
+}
+
+// deliver sends the requests, retrying failures with exponential backoff up to the configured number of times.
+// Requests which still fail are spilled to disk to be sent once the endpoint recovers, or dropped.
+func (w *Worker) deliver(batch []LogRequest) {
+	pending := batch
+	var err error
+	for attempt := 1; ; attempt++ {
+		var failed []LogRequest
+		failed, err = w.sendBatch(pending)
+		for i := len(failed); i < len(pending); i++ {
+			metrics.EventSent()
+		}
+		pending = failed
+		if len(pending) == 0 {
+			return
+		}
+		if attempt > w.Delivery.MaxRetries {
+			break
+		}
+		backoff := w.Delivery.backoff(attempt)
+		w.Log.Info("Retrying log requests", "count", len(pending), "attempt", attempt, "backoff", backoff.String(), "error", err.Error())
+		for range pending {
+			metrics.EventRetried()
+		}
+		time.Sleep(backoff)
+	}
+	for _, logReq := range pending {
+		w.spillOrDrop(logReq, err)
+	}
+}
+
+func (w *Worker) spillOrDrop(logReq LogRequest, err error) {
+	if w.Spill != nil {
+		serr := w.Spill.write(logReq)
+		if serr == nil {
+			w.Log.Info("Spilled undelivered log request", "id", logReq.Id, "error", err.Error())
+			metrics.EventSpilled()
+			return
+		}
+		w.Log.Error(serr, "Failed to spill log request", "id", logReq.Id)
+	}
+	metrics.EventDropped()
+	if w.KafkaTopic != "" {
+		w.Log.Error(err, "Failed to send kafka log", "Topic", w.KafkaTopic)
+	} else {
+		w.Log.Error(err, "Failed to send cloudevent log", "URL", logReq.Url.String())
+	}
+}
+
 // This function "starts" the worker by starting a goroutine, that is
 // an infinite "for-select" loop.
 func (w *Worker) Start() {
@@ -203,17 +288,8 @@ func (w *Worker) Start() {
 		for {
 			select {
 			case work := <-w.Work:
-				// Receive a work request.
-
-				if w.KafkaTopic != "" {
-					if err := w.sendKafkaEvent(work); err != nil {
-						w.Log.Error(err, "Failed to send kafka log", "Topic", w.KafkaTopic)
-					}
-				} else {
-					if err := w.sendCloudEvent(work); err != nil {
-						w.Log.Error(err, "Failed to send cloudevent log", "URL", work.Url.String())
-					}
-				}
+				// Receive a work request and any more which arrive while the batch lingers.
+				w.deliver(w.collectBatch(work))
 
 			case <-w.QuitChan:
 				// We have been asked to stop.
@@ -0,0 +1,105 @@
+package predictor
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	payloadLogger "github.com/seldonio/seldon-core/executor/logger"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+)
+
+// callLog tracks a call to a node so its request and response are logged according to the node's logger.
+type callLog struct {
+	node    *v1.PredictiveUnit
+	request payload.SeldonPayload
+	puid    string
+	sampled bool
+	start   time.Time
+}
+
+func logsRequests(logger *v1.Logger) bool {
+	return logger.Mode == v1.LogRequest || logger.Mode == v1.LogAll
+}
+
+func logsResponses(logger *v1.Logger) bool {
+	return logger.Mode == v1.LogResponse || logger.Mode == v1.LogAll
+}
+
+// isSampled returns whether the logger's sample includes the call. Without a valid rate every call is logged.
+func isSampled(logger *v1.Logger, puid string) bool {
+	if logger.Sampling == nil || logger.Sampling.Rate == "" {
+		return true
+	}
+	rate, err := strconv.ParseFloat(logger.Sampling.Rate, 64)
+	if err != nil {
+		return true
+	}
+	return payloadLogger.Sampled(puid, rate)
+}
+
+// startCallLog logs the request if the call is sampled. Otherwise the request is kept so it can still be logged
+// if the call fails or is slow. It returns nil if the node has no logger.
+func (p *PredictorProcess) startCallLog(node *v1.PredictiveUnit, msg payload.SeldonPayload, puid string) (*callLog, error) {
+	if node.Logger == nil {
+		return nil, nil
+	}
+	c := &callLog{
+		node:    node,
+		request: msg,
+		puid:    puid,
+		sampled: isSampled(node.Logger, puid),
+		start:   time.Now(),
+	}
+	if c.sampled && logsRequests(node.Logger) {
+		if err := p.logPayload(node.Name, node.Logger, payloadLogger.InferenceRequest, msg, puid); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// finishCallLog logs the response of the call, or the error it failed with if the logger logs errors. Calls which
+// weren't sampled are logged in full if they failed or were slow and the logger asks for those to be kept.
+func (p *PredictorProcess) finishCallLog(c *callLog, res payload.SeldonPayload, callErr error) error {
+	if c == nil {
+		return nil
+	}
+	logger := c.node.Logger
+	sampling := logger.Sampling
+	logError := callErr != nil && sampling != nil && sampling.Errors
+	if !c.sampled {
+		slow := sampling != nil && sampling.LatencyThresholdMs > 0 &&
+			time.Since(c.start) > time.Duration(sampling.LatencyThresholdMs)*time.Millisecond
+		if !logError && !slow {
+			return nil
+		}
+		if logsRequests(logger) {
+			if err := p.logPayload(c.node.Name, logger, payloadLogger.InferenceRequest, c.request, c.puid); err != nil {
+				return err
+			}
+		}
+	}
+	if !logsResponses(logger) {
+		return nil
+	}
+	if callErr != nil {
+		if logError {
+			// Not every client can build an error payload, the full graph kafka client among them
+			return p.logPayload(c.node.Name, logger, payloadLogger.InferenceResponse, payload.NewJSONErrorPayload(callErr), c.puid)
+		}
+		return nil
+	}
+	if res != nil {
+		return p.logPayload(c.node.Name, logger, payloadLogger.InferenceResponse, res, c.puid)
+	}
+	return nil
+}
+
+// failCallLog finishes the call log of a call which failed with callErr. Errors logging the call are only reported
+// as the caller returns callErr.
+func (p *PredictorProcess) failCallLog(c *callLog, callErr error) {
+	if err := p.finishCallLog(c, nil, callErr); err != nil {
+		p.Log.Error(err, "Failed to log failed call", "node", c.node.Name, "PUID", c.puid)
+	}
+}
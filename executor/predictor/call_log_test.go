@@ -0,0 +1,109 @@
+package predictor
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/seldonio/seldon-core/executor/api"
+	"github.com/seldonio/seldon-core/executor/logger"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func createLoggedGraph(url string, sampling *v1.LoggerSampling) *v1.PredictiveUnit {
+	model := v1.MODEL
+	return &v1.PredictiveUnit{
+		Name: "foo",
+		Type: &model,
+		Endpoint: &v1.Endpoint{
+			ServiceHost: "foo",
+			ServicePort: 9000,
+			Type:        v1.REST,
+		},
+		Logger: &v1.Logger{
+			Mode:     v1.LogAll,
+			Url:      &url,
+			Sampling: sampling,
+		},
+	}
+}
+
+func startTestLogEndpoint() (*httptest.Server, chan string) {
+	types := make(chan string, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		types <- r.Header.Get(logger.CloudEventsTypeHeader)
+	}))
+	logger.StartDispatcher(1, logger.DefaultWorkQueueSize, logger.DefaultWriteTimeoutMilliseconds, logf.Log.WithName("test"), "", "", "", "", "", api.ProtocolSeldon, logger.DeliveryConfig{})
+	return server, types
+}
+
+// receiveLogTypes waits for n logged events, which can arrive in any order, and returns their types.
+func receiveLogTypes(g *GomegaWithT, types chan string, n int) []string {
+	var received []string
+	for i := 0; i < n; i++ {
+		var ceType string
+		g.Eventually(types).Should(Receive(&ceType))
+		received = append(received, ceType)
+	}
+	return received
+}
+
+func TestUnsampledCallsNotLogged(t *testing.T) {
+	g := NewGomegaWithT(t)
+	server, types := startTestLogEndpoint()
+	defer server.Close()
+
+	graph := createLoggedGraph(server.URL, &v1.LoggerSampling{Rate: "0", Errors: true, LatencyThresholdMs: 60000})
+	_, err := createPredictorProcess(t).Predict(graph, createPredictPayload(g))
+	g.Expect(err).To(BeNil())
+	g.Consistently(types, 200*time.Millisecond).ShouldNot(Receive())
+
+	graph = createLoggedGraph(server.URL, &v1.LoggerSampling{Rate: "1"})
+	_, err = createPredictorProcess(t).Predict(graph, createPredictPayload(g))
+	g.Expect(err).To(BeNil())
+	g.Expect(receiveLogTypes(g, types, 2)).To(ConsistOf(logger.CEInferenceRequest, logger.CEInferenceResponse))
+}
+
+func TestUnsampledErrorsLogged(t *testing.T) {
+	g := NewGomegaWithT(t)
+	server, types := startTestLogEndpoint()
+	defer server.Close()
+
+	graph := createLoggedGraph(server.URL, &v1.LoggerSampling{Rate: "0", Errors: true})
+	method := v1.TRANSFORM_INPUT
+	_, err := createPredictorProcessWithError(t, &method, errors.New("model failed"), nil).Predict(graph, createPredictPayload(g))
+	g.Expect(err).ToNot(BeNil())
+	g.Expect(receiveLogTypes(g, types, 2)).To(ConsistOf(logger.CEInferenceRequest, logger.CEInferenceResponse))
+
+	// Without the errors rule failed calls outside the sample aren't logged
+	graph = createLoggedGraph(server.URL, &v1.LoggerSampling{Rate: "0"})
+	_, err = createPredictorProcessWithError(t, &method, errors.New("model failed"), nil).Predict(graph, createPredictPayload(g))
+	g.Expect(err).ToNot(BeNil())
+	g.Consistently(types, 200*time.Millisecond).ShouldNot(Receive())
+}
+
+func TestErrorLoggedWithoutClientPayload(t *testing.T) {
+	g := NewGomegaWithT(t)
+	bodies := make(chan string, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(logger.CloudEventsTypeHeader) == logger.CEInferenceResponse {
+			body, _ := io.ReadAll(r.Body)
+			bodies <- string(body)
+		}
+	}))
+	defer server.Close()
+	logger.StartDispatcher(1, logger.DefaultWorkQueueSize, logger.DefaultWriteTimeoutMilliseconds, logf.Log.WithName("test"), "", "", "", "", "", api.ProtocolSeldon, logger.DeliveryConfig{})
+
+	graph := createLoggedGraph(server.URL, &v1.LoggerSampling{Rate: "0", Errors: true})
+	method := v1.TRANSFORM_INPUT
+	_, err := createPredictorProcessWithError(t, &method, errors.New("model failed"), nil).Predict(graph, createPredictPayload(g))
+	g.Expect(err).ToNot(BeNil())
+	var body string
+	g.Eventually(bodies).Should(Receive(&body))
+	g.Expect(body).To(MatchJSON(`{"error":"model failed"}`))
+}
@@ -0,0 +1,30 @@
+package predictor
+
+import (
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	"github.com/seldonio/seldon-core/executor/stats"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+)
+
+var featureStats *stats.Collector
+
+// EnableFeatureStats summarises the input features of the requests sent to each model with the collector.
+func EnableFeatureStats(collector *stats.Collector) {
+	featureStats = collector
+}
+
+// FeatureStats returns the collector of feature summaries, or nil if they are not kept.
+func FeatureStats() *stats.Collector {
+	return featureStats
+}
+
+// recordFeatureStats adds the features of a request to the node's summaries. Requests whose features can't be read
+// are still sent so the failure is only logged.
+func (p *PredictorProcess) recordFeatureStats(node *v1.PredictiveUnit, msg payload.SeldonPayload) {
+	if featureStats == nil {
+		return
+	}
+	if err := featureStats.Observe(node.Name, msg); err != nil {
+		p.Log.Error(err, "Failed to record feature stats", "node", node.Name)
+	}
+}
@@ -0,0 +1,29 @@
+package predictor
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/seldonio/seldon-core/executor/stats"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+)
+
+func TestFeatureStatsRecordsModelInputs(t *testing.T) {
+	g := NewGomegaWithT(t)
+	EnableFeatureStats(stats.NewCollector("dep", "p", nil, stats.DefaultWindow))
+	defer EnableFeatureStats(nil)
+
+	model := v1.MODEL
+	graph := &v1.PredictiveUnit{
+		Name:     "model",
+		Type:     &model,
+		Endpoint: &v1.Endpoint{ServiceHost: "foo", ServicePort: 9000, Type: v1.REST},
+	}
+	_, err := createPredictorProcess(t).Predict(graph, createPredictPayload(g))
+	g.Expect(err).Should(BeNil())
+
+	features := FeatureStats().Snapshot().Models["model"].Features
+	g.Expect(features).To(HaveLen(2))
+	g.Expect(features["0"].Numeric.Mean).To(BeNumerically("~", 1.1, 1e-9))
+	g.Expect(features["1"].Numeric.Mean).To(BeNumerically("~", 2.0, 1e-9))
+}
@@ -0,0 +1,151 @@
+package predictor
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/seldonio/seldon-core/executor/api/grpc/kfserving/inference"
+	"github.com/seldonio/seldon-core/executor/api/grpc/seldon/proto"
+	"github.com/seldonio/seldon-core/executor/api/metric"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+)
+
+// modelMetricsKey is the field of meta in a seldon response, or of parameters in a v2 response, holding the metrics.
+const modelMetricsKey = "metrics"
+
+var (
+	modelMetrics      *metric.ModelMetrics
+	stripModelMetrics bool
+)
+
+// EnableModelMetrics exposes the custom metrics models return as prometheus metrics of the deployment and
+// predictor. If strip is set the metrics are removed from the response returned to the caller.
+func EnableModelMetrics(deploymentName string, predictorName string, strip bool) {
+	modelMetrics = metric.NewModelMetrics(deploymentName, predictorName)
+	stripModelMetrics = strip
+}
+
+type jsonModelMetrics struct {
+	Meta struct {
+		Metrics []metric.CustomMetric `json:"metrics"`
+	} `json:"meta"`
+	Parameters struct {
+		Metrics json.RawMessage `json:"metrics"`
+	} `json:"parameters"`
+}
+
+// parseV2Metrics parses the metrics parameter of a v2 response, which is either a list of metrics or, as
+// parameters of the protobuf protocol are scalars, a string holding the list.
+func parseV2Metrics(data []byte) ([]metric.CustomMetric, error) {
+	var metrics []metric.CustomMetric
+	var encoded string
+	if err := json.Unmarshal(data, &encoded); err == nil {
+		data = []byte(encoded)
+	}
+	if err := json.Unmarshal(data, &metrics); err != nil {
+		return nil, err
+	}
+	return metrics, nil
+}
+
+// getModelMetrics returns the custom metrics in a seldon or v2 response, in either JSON or protobuf form.
+func getModelMetrics(msg payload.SeldonPayload) ([]metric.CustomMetric, error) {
+	switch res := msg.GetPayload().(type) {
+	case *proto.SeldonMessage:
+		var metrics []metric.CustomMetric
+		for _, m := range res.GetMeta().GetMetrics() {
+			metrics = append(metrics, metric.CustomMetric{Key: m.Key, Type: m.Type.String(), Value: float64(m.Value), Tags: m.Tags})
+		}
+		return metrics, nil
+	case *inference.ModelInferResponse:
+		if param, ok := res.GetParameters()[modelMetricsKey]; ok {
+			return parseV2Metrics([]byte(param.GetStringParam()))
+		}
+		return nil, nil
+	case []byte:
+		if !strings.Contains(msg.GetContentType(), "json") {
+			return nil, nil
+		}
+		data, err := payload.DecompressBytes(res, msg.GetContentEncoding())
+		if err != nil {
+			return nil, err
+		}
+		// Skip decoding responses which can't hold metrics
+		if !bytes.Contains(data, []byte(`"`+modelMetricsKey+`"`)) {
+			return nil, nil
+		}
+		var parsed jsonModelMetrics
+		if err := json.Unmarshal(data, &parsed); err != nil {
+			return nil, err
+		}
+		metrics := parsed.Meta.Metrics
+		if len(parsed.Parameters.Metrics) > 0 {
+			v2Metrics, err := parseV2Metrics(parsed.Parameters.Metrics)
+			if err != nil {
+				return nil, err
+			}
+			metrics = append(metrics, v2Metrics...)
+		}
+		return metrics, nil
+	}
+	return nil, nil
+}
+
+// recordModelMetrics updates the prometheus metrics with those returned by the node. A response whose metrics can't
+// be recorded is still returned so the failure is only logged.
+func (p *PredictorProcess) recordModelMetrics(node *v1.PredictiveUnit, msg payload.SeldonPayload) {
+	if modelMetrics == nil || msg == nil {
+		return
+	}
+	metrics, err := getModelMetrics(msg)
+	if err == nil && len(metrics) > 0 {
+		err = modelMetrics.Record(node.Name, metrics)
+	}
+	if err != nil {
+		p.Log.Error(err, "Failed to record model metrics", "node", node.Name)
+	}
+}
+
+// removeModelMetrics returns the response without the custom metrics of the models.
+func removeModelMetrics(msg payload.SeldonPayload) (payload.SeldonPayload, error) {
+	switch res := msg.GetPayload().(type) {
+	case *proto.SeldonMessage:
+		if res.GetMeta() != nil {
+			res.Meta.Metrics = nil
+		}
+	case *inference.ModelInferResponse:
+		delete(res.Parameters, modelMetricsKey)
+	case []byte:
+		// Compressed responses are returned as they are
+		if !strings.Contains(msg.GetContentType(), "json") || msg.GetContentEncoding() != "" ||
+			!bytes.Contains(res, []byte(`"`+modelMetricsKey+`"`)) {
+			return msg, nil
+		}
+		var doc map[string]interface{}
+		dec := json.NewDecoder(bytes.NewReader(res))
+		dec.UseNumber()
+		if err := dec.Decode(&doc); err != nil {
+			return nil, err
+		}
+		removed := false
+		for _, key := range []string{"meta", "parameters"} {
+			if fields, ok := doc[key].(map[string]interface{}); ok {
+				if _, ok := fields[modelMetricsKey]; ok {
+					delete(fields, modelMetricsKey)
+					removed = true
+				}
+			}
+		}
+		if !removed {
+			return msg, nil
+		}
+		data, err := json.Marshal(doc)
+		if err != nil {
+			return nil, err
+		}
+		return &payload.BytesPayload{Msg: data, ContentType: msg.GetContentType()}, nil
+	}
+	return msg, nil
+}
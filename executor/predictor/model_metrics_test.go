@@ -0,0 +1,106 @@
+package predictor
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/seldonio/seldon-core/executor/api/grpc/kfserving/inference"
+	"github.com/seldonio/seldon-core/executor/api/grpc/seldon/proto"
+	"github.com/seldonio/seldon-core/executor/api/metric"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+)
+
+func TestGetModelMetrics(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	seldonJson := &payload.BytesPayload{
+		Msg:         []byte(`{"data":{"ndarray":[1]},"meta":{"metrics":[{"key":"requests","type":"COUNTER","value":1,"tags":{"class":"a"}}]}}`),
+		ContentType: payload.APPLICATION_TYPE_JSON,
+	}
+	metrics, err := getModelMetrics(seldonJson)
+	g.Expect(err).To(BeNil())
+	g.Expect(metrics).To(Equal([]metric.CustomMetric{{Key: "requests", Type: "COUNTER", Value: 1, Tags: map[string]string{"class": "a"}}}))
+
+	v2Json := &payload.BytesPayload{
+		Msg:         []byte(`{"outputs":[],"parameters":{"metrics":[{"key":"confidence","type":"GAUGE","value":0.5}]}}`),
+		ContentType: payload.APPLICATION_TYPE_JSON,
+	}
+	metrics, err = getModelMetrics(v2Json)
+	g.Expect(err).To(BeNil())
+	g.Expect(metrics).To(Equal([]metric.CustomMetric{{Key: "confidence", Type: "GAUGE", Value: 0.5}}))
+
+	seldonProto := &payload.ProtoPayload{Msg: &proto.SeldonMessage{Meta: &proto.Meta{Metrics: []*proto.Metric{{Key: "lookup", Type: proto.Metric_TIMER, Value: 20}}}}}
+	metrics, err = getModelMetrics(seldonProto)
+	g.Expect(err).To(BeNil())
+	g.Expect(metrics).To(Equal([]metric.CustomMetric{{Key: "lookup", Type: "TIMER", Value: 20}}))
+
+	v2Proto := &payload.ProtoPayload{Msg: &inference.ModelInferResponse{Parameters: map[string]*inference.InferParameter{
+		modelMetricsKey: {ParameterChoice: &inference.InferParameter_StringParam{StringParam: `[{"key":"confidence","type":"GAUGE","value":0.5}]`}},
+	}}}
+	metrics, err = getModelMetrics(v2Proto)
+	g.Expect(err).To(BeNil())
+	g.Expect(metrics).To(Equal([]metric.CustomMetric{{Key: "confidence", Type: "GAUGE", Value: 0.5}}))
+
+	metrics, err = getModelMetrics(&payload.BytesPayload{Msg: []byte(`{"data":{"ndarray":[1]}}`), ContentType: payload.APPLICATION_TYPE_JSON})
+	g.Expect(err).To(BeNil())
+	g.Expect(metrics).To(BeEmpty())
+}
+
+func TestRemoveModelMetrics(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	res, err := removeModelMetrics(&payload.BytesPayload{
+		Msg:         []byte(`{"data":{"ndarray":[1]},"meta":{"puid":"1","metrics":[{"key":"requests","value":1}]}}`),
+		ContentType: payload.APPLICATION_TYPE_JSON,
+	})
+	g.Expect(err).To(BeNil())
+	data, err := res.GetBytes()
+	g.Expect(err).To(BeNil())
+	g.Expect(data).To(MatchJSON(`{"data":{"ndarray":[1]},"meta":{"puid":"1"}}`))
+
+	v2Proto := &inference.ModelInferResponse{Parameters: map[string]*inference.InferParameter{
+		modelMetricsKey: {ParameterChoice: &inference.InferParameter_StringParam{StringParam: `[]`}},
+	}}
+	_, err = removeModelMetrics(&payload.ProtoPayload{Msg: v2Proto})
+	g.Expect(err).To(BeNil())
+	g.Expect(v2Proto.Parameters).To(BeEmpty())
+}
+
+func TestPredictRecordsModelMetrics(t *testing.T) {
+	g := NewGomegaWithT(t)
+	EnableModelMetrics("dep", "p1", true)
+	defer func() { modelMetrics, stripModelMetrics = nil, false }()
+
+	model := v1.MODEL
+	graph := &v1.PredictiveUnit{
+		Name: "classifier",
+		Type: &model,
+		Endpoint: &v1.Endpoint{
+			ServiceHost: "foo",
+			ServicePort: 9000,
+			Type:        v1.REST,
+		},
+	}
+	// The test client returns the request so the metrics are returned by the model
+	req := &payload.BytesPayload{
+		Msg:         []byte(`{"data":{"ndarray":[1]},"meta":{"metrics":[{"key":"classifier_hits","type":"GAUGE","value":7}]}}`),
+		ContentType: payload.APPLICATION_TYPE_JSON,
+	}
+	res, err := createPredictorProcess(t).Predict(graph, req)
+	g.Expect(err).To(BeNil())
+	data, err := res.GetBytes()
+	g.Expect(err).To(BeNil())
+	g.Expect(data).To(MatchJSON(`{"data":{"ndarray":[1]},"meta":{}}`))
+
+	families, err := prometheus.DefaultGatherer.Gather()
+	g.Expect(err).To(BeNil())
+	var value float64
+	for _, family := range families {
+		if family.GetName() == "seldon_model_classifier_hits" {
+			value = family.GetMetric()[0].GetGauge().GetValue()
+		}
+	}
+	g.Expect(value).To(Equal(7.0))
+}
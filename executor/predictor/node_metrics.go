@@ -0,0 +1,72 @@
+package predictor
+
+import (
+	protoV1 "github.com/golang/protobuf/proto"
+	"github.com/seldonio/seldon-core/executor/api/metric"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+)
+
+var nodeMetrics *metric.NodeMetrics
+
+// EnableNodeMetrics exposes the payload sizes, failures and in-flight calls of each graph node and the decisions of
+// routers as prometheus metrics of the deployment and predictor.
+func EnableNodeMetrics(deploymentName string, predictorName string) {
+	nodeMetrics = metric.NewNodeMetrics(deploymentName, predictorName)
+}
+
+// payloadSize returns the size in bytes of the payload, or false if it can't be serialised. Protobuf messages are
+// sized without being serialised.
+func payloadSize(msg payload.SeldonPayload) (int, bool) {
+	if msg == nil {
+		return 0, false
+	}
+	switch m := msg.GetPayload().(type) {
+	case []byte:
+		return len(m), true
+	case protoV1.Message:
+		return protoV1.Size(m), true
+	}
+	data, err := msg.GetBytes()
+	if err != nil {
+		return 0, false
+	}
+	return len(data), true
+}
+
+// totalPayloadSize returns the size of all the payloads, or -1 if any is unknown.
+func totalPayloadSize(msgs ...payload.SeldonPayload) int {
+	total := 0
+	for _, msg := range msgs {
+		size, ok := payloadSize(msg)
+		if !ok {
+			return -1
+		}
+		total += size
+	}
+	return total
+}
+
+func nodeCallStarted(node *v1.PredictiveUnit, msgs ...payload.SeldonPayload) {
+	if nodeMetrics != nil {
+		nodeMetrics.CallStarted(node.Name, totalPayloadSize(msgs...))
+	}
+}
+
+// nodeCallFinished records the end of a call to the node. The response is nil for calls, such as routing, which
+// don't return a payload.
+func nodeCallFinished(node *v1.PredictiveUnit, msg payload.SeldonPayload, err error) {
+	if nodeMetrics != nil {
+		size := -1
+		if msg != nil {
+			size = totalPayloadSize(msg)
+		}
+		nodeMetrics.CallFinished(node.Name, size, err)
+	}
+}
+
+func routeChosen(node *v1.PredictiveUnit, route int) {
+	if nodeMetrics != nil {
+		nodeMetrics.RouteChosen(node.Name, route)
+	}
+}
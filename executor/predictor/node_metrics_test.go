@@ -0,0 +1,57 @@
+package predictor
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/seldonio/seldon-core/executor/api/metric"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+)
+
+func TestNodeMetricsRecordsRoutes(t *testing.T) {
+	g := NewGomegaWithT(t)
+	EnableNodeMetrics("dep-routes", "p")
+	defer func() { nodeMetrics = nil }()
+
+	model := v1.MODEL
+	router := v1.ROUTER
+	graph := &v1.PredictiveUnit{
+		Name:     "router",
+		Type:     &router,
+		Endpoint: &v1.Endpoint{ServiceHost: "foo", ServicePort: 9000, Type: v1.REST},
+		Children: []v1.PredictiveUnit{
+			{Name: "a", Type: &model, Endpoint: &v1.Endpoint{ServiceHost: "foo2", ServicePort: 9001, Type: v1.REST}},
+			{Name: "b", Type: &model, Endpoint: &v1.Endpoint{ServiceHost: "foo3", ServicePort: 9002, Type: v1.REST}},
+		},
+	}
+
+	_, err := createPredictorProcessWithRoute(t, 1).Predict(graph, createPredictPayload(g))
+	g.Expect(err).Should(BeNil())
+
+	g.Expect(testutil.ToFloat64(nodeMetrics.RouterDecisions.WithLabelValues("dep-routes", "p", "router", "1"))).To(Equal(1.0))
+	g.Expect(testutil.ToFloat64(nodeMetrics.InFlight.WithLabelValues("dep-routes", "p", "router"))).To(Equal(0.0))
+	g.Expect(testutil.ToFloat64(nodeMetrics.InFlight.WithLabelValues("dep-routes", "p", "b"))).To(Equal(0.0))
+}
+
+func TestNodeMetricsRecordsFailures(t *testing.T) {
+	g := NewGomegaWithT(t)
+	EnableNodeMetrics("dep-failures", "p")
+	defer func() { nodeMetrics = nil }()
+
+	model := v1.MODEL
+	graph := &v1.PredictiveUnit{
+		Name:     "model",
+		Type:     &model,
+		Endpoint: &v1.Endpoint{ServiceHost: "foo", ServicePort: 9000, Type: v1.REST},
+	}
+	errMethod := v1.TRANSFORM_INPUT
+	errPayload := &payload.BytesPayload{Msg: []byte(`{"status":"failed"}`), ContentType: "xyz"}
+	_, err := createPredictorProcessWithError(t, &errMethod, errors.New("failed"), errPayload).Predict(graph, createPredictPayload(g))
+	g.Expect(err).ShouldNot(BeNil())
+
+	g.Expect(testutil.ToFloat64(nodeMetrics.Failures.WithLabelValues("dep-failures", "p", "model", metric.ErrorClassOther))).To(Equal(1.0))
+	g.Expect(testutil.ToFloat64(nodeMetrics.InFlight.WithLabelValues("dep-failures", "p", "model"))).To(Equal(0.0))
+}
@@ -8,11 +8,15 @@ import (
 	"sync"
 
 	"github.com/go-logr/logr"
+	"github.com/golang/protobuf/jsonpb"
+	protoV1 "github.com/golang/protobuf/proto"
 	guuid "github.com/google/uuid"
 	"github.com/seldonio/seldon-core/executor/api/client"
 	"github.com/seldonio/seldon-core/executor/api/grpc/seldon/proto"
 	"github.com/seldonio/seldon-core/executor/api/payload"
+	"github.com/seldonio/seldon-core/executor/api/tracing"
 	"github.com/seldonio/seldon-core/executor/api/util"
+	"go.opentelemetry.io/otel/trace"
 
 	payloadLogger "github.com/seldonio/seldon-core/executor/logger"
 	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
@@ -49,6 +53,8 @@ type PredictorProcess struct {
 	Routing           map[string]int32
 	RoutingMutex      *sync.RWMutex
 	ModelNameOverride string
+	// FailedNode is the name of the first graph node to fail during Predict
+	FailedNode string
 }
 
 func NewPredictorProcess(context context.Context, client client.SeldonApiClient, log logr.Logger, serverUrl *url.URL, namespace string, meta map[string][]string, modelNameOverride string) PredictorProcess {
@@ -92,7 +98,7 @@ func (p *PredictorProcess) getModelName(node *v1.PredictiveUnit) string {
 	return modelName
 }
 
-func (p *PredictorProcess) transformInput(node *v1.PredictiveUnit, msg payload.SeldonPayload, puid string) (tmsg payload.SeldonPayload, err error) {
+func (p *PredictorProcess) transformInput(ctx context.Context, node *v1.PredictiveUnit, msg payload.SeldonPayload, puid string) (tmsg payload.SeldonPayload, err error) {
 	callModel := false
 	callTransformInput := false
 	if (*node).Type != nil {
@@ -110,36 +116,37 @@ func (p *PredictorProcess) transformInput(node *v1.PredictiveUnit, msg payload.S
 	modelName := p.getModelName(node)
 
 	if callModel || callTransformInput {
-		msg, err := p.Client.Chain(p.Ctx, modelName, msg)
+		msg, err := p.Client.Chain(ctx, modelName, msg)
 		if err != nil {
 			return nil, err
 		}
 
 		//Log Request
-		if node.Logger != nil && (node.Logger.Mode == v1.LogRequest || node.Logger.Mode == v1.LogAll) {
-			err := p.logPayload(node.Name, node.Logger, payloadLogger.InferenceRequest, msg, puid)
-			if err != nil {
-				return nil, err
-			}
+		callLog, err := p.startCallLog(node, msg, puid)
+		if err != nil {
+			return nil, err
 		}
 
 		p.RoutingMutex.Lock()
 		p.Routing[node.Name] = -1
 		p.RoutingMutex.Unlock()
 
+		if callModel {
+			p.recordFeatureStats(node, msg)
+		}
+		nodeCallStarted(node, msg)
 		if callTransformInput {
-			tmsg, err = p.Client.TransformInput(p.Ctx, modelName, node.Endpoint.ServiceHost, p.getPort(node), msg, p.Meta.Meta)
+			tmsg, err = p.Client.TransformInput(ctx, modelName, node.Endpoint.ServiceHost, p.getPort(node), msg, p.Meta.Meta)
 		} else {
-			tmsg, err = p.Client.Predict(p.Ctx, modelName, node.Endpoint.ServiceHost, p.getPort(node), msg, p.Meta.Meta)
+			tmsg, err = p.Client.Predict(ctx, modelName, node.Endpoint.ServiceHost, p.getPort(node), msg, p.Meta.Meta)
 		}
-		if tmsg != nil && err == nil {
-			// Log Response
-			if node.Logger != nil && (node.Logger.Mode == v1.LogResponse || node.Logger.Mode == v1.LogAll) {
-				err := p.logPayload(node.Name, node.Logger, payloadLogger.InferenceResponse, tmsg, puid)
-				if err != nil {
-					return nil, err
-				}
-			}
+		nodeCallFinished(node, tmsg, err)
+		if err == nil {
+			p.recordModelMetrics(node, tmsg)
+		}
+		// Log Response
+		if err := p.finishCallLog(callLog, tmsg, err); err != nil {
+			return nil, err
 		}
 		return tmsg, err
 	} else {
@@ -147,7 +154,7 @@ func (p *PredictorProcess) transformInput(node *v1.PredictiveUnit, msg payload.S
 	}
 }
 
-func (p *PredictorProcess) transformOutput(node *v1.PredictiveUnit, msg payload.SeldonPayload, puid string) (payload.SeldonPayload, error) {
+func (p *PredictorProcess) transformOutput(ctx context.Context, node *v1.PredictiveUnit, msg payload.SeldonPayload, puid string) (payload.SeldonPayload, error) {
 	callClient := false
 	if (*node).Type != nil {
 		switch *node.Type {
@@ -162,28 +169,26 @@ func (p *PredictorProcess) transformOutput(node *v1.PredictiveUnit, msg payload.
 	modelName := p.getModelName(node)
 
 	if callClient {
-		msg, err := p.Client.Chain(p.Ctx, modelName, msg)
+		msg, err := p.Client.Chain(ctx, modelName, msg)
 		if err != nil {
 			return nil, err
 		}
 
 		//Log Request
-		if node.Logger != nil && (node.Logger.Mode == v1.LogRequest || node.Logger.Mode == v1.LogAll) {
-			err := p.logPayload(node.Name, node.Logger, payloadLogger.InferenceRequest, msg, puid)
-			if err != nil {
-				return nil, err
-			}
+		callLog, err := p.startCallLog(node, msg, puid)
+		if err != nil {
+			return nil, err
 		}
 
-		tmsg, err := p.Client.TransformOutput(p.Ctx, modelName, node.Endpoint.ServiceHost, p.getPort(node), msg, p.Meta.Meta)
-		if tmsg != nil && err == nil {
-			// Log Response
-			if node.Logger != nil && (node.Logger.Mode == v1.LogResponse || node.Logger.Mode == v1.LogAll) {
-				err := p.logPayload(node.Name, node.Logger, payloadLogger.InferenceResponse, tmsg, puid)
-				if err != nil {
-					return nil, err
-				}
-			}
+		nodeCallStarted(node, msg)
+		tmsg, err := p.Client.TransformOutput(ctx, modelName, node.Endpoint.ServiceHost, p.getPort(node), msg, p.Meta.Meta)
+		nodeCallFinished(node, tmsg, err)
+		if err == nil {
+			p.recordModelMetrics(node, tmsg)
+		}
+		// Log Response
+		if err := p.finishCallLog(callLog, tmsg, err); err != nil {
+			return nil, err
 		}
 		return tmsg, err
 	} else {
@@ -207,7 +212,10 @@ func (p *PredictorProcess) feedback(node *v1.PredictiveUnit, msg payload.SeldonP
 	modelName := p.getModelName(node)
 
 	if callClient {
-		return p.Client.Feedback(p.Ctx, modelName, node.Endpoint.ServiceHost, p.getPort(node), msg, p.Meta.Meta)
+		nodeCallStarted(node, msg)
+		res, err := p.Client.Feedback(p.Ctx, modelName, node.Endpoint.ServiceHost, p.getPort(node), msg, p.Meta.Meta)
+		nodeCallFinished(node, res, err)
+		return res, err
 	} else {
 		return msg, nil
 	}
@@ -222,7 +230,7 @@ func (p *PredictorProcess) routeFeedback(node *v1.PredictiveUnit, msg payload.Se
 	}
 }
 
-func (p *PredictorProcess) route(node *v1.PredictiveUnit, msg payload.SeldonPayload) (int, error) {
+func (p *PredictorProcess) route(ctx context.Context, node *v1.PredictiveUnit, msg payload.SeldonPayload) (int, error) {
 	callClient := false
 	if (*node).Type != nil {
 		switch *node.Type {
@@ -236,16 +244,24 @@ func (p *PredictorProcess) route(node *v1.PredictiveUnit, msg payload.SeldonPayl
 
 	modelName := p.getModelName(node)
 
+	var route int
+	var err error
 	if callClient {
-		return p.Client.Route(p.Ctx, modelName, node.Endpoint.ServiceHost, p.getPort(node), msg, p.Meta.Meta)
+		nodeCallStarted(node, msg)
+		route, err = p.Client.Route(ctx, modelName, node.Endpoint.ServiceHost, p.getPort(node), msg, p.Meta.Meta)
+		nodeCallFinished(node, nil, err)
 	} else if node.Implementation != nil && *node.Implementation == v1.RANDOM_ABTEST {
-		return p.abTestRouter(node)
+		route, err = p.abTestRouter(node)
 	} else {
 		return -1, nil
 	}
+	if err == nil {
+		routeChosen(node, route)
+	}
+	return route, err
 }
 
-func (p *PredictorProcess) aggregate(node *v1.PredictiveUnit, cmsg []payload.SeldonPayload, msg payload.SeldonPayload, puid string) (payload.SeldonPayload, error) {
+func (p *PredictorProcess) aggregate(ctx context.Context, node *v1.PredictiveUnit, cmsg []payload.SeldonPayload, msg payload.SeldonPayload, puid string) (payload.SeldonPayload, error) {
 	callClient := false
 	if (*node).Type != nil {
 		switch *node.Type {
@@ -261,24 +277,22 @@ func (p *PredictorProcess) aggregate(node *v1.PredictiveUnit, cmsg []payload.Sel
 
 	if callClient {
 		//Log Request
-		if node.Logger != nil && (node.Logger.Mode == v1.LogRequest || node.Logger.Mode == v1.LogAll) {
-			err := p.logPayload(node.Name, node.Logger, payloadLogger.InferenceRequest, msg, puid)
-			if err != nil {
-				return nil, err
-			}
+		callLog, err := p.startCallLog(node, msg, puid)
+		if err != nil {
+			return nil, err
 		}
 		p.RoutingMutex.Lock()
 		p.Routing[node.Name] = -1
 		p.RoutingMutex.Unlock()
-		tmsg, err := p.Client.Combine(p.Ctx, modelName, node.Endpoint.ServiceHost, p.getPort(node), cmsg, p.Meta.Meta)
-		if tmsg != nil && err == nil {
-			// Log Response
-			if node.Logger != nil && (node.Logger.Mode == v1.LogResponse || node.Logger.Mode == v1.LogAll) {
-				err := p.logPayload(node.Name, node.Logger, payloadLogger.InferenceResponse, tmsg, puid)
-				if err != nil {
-					return nil, err
-				}
-			}
+		nodeCallStarted(node, cmsg...)
+		tmsg, err := p.Client.Combine(ctx, modelName, node.Endpoint.ServiceHost, p.getPort(node), cmsg, p.Meta.Meta)
+		nodeCallFinished(node, tmsg, err)
+		if err == nil {
+			p.recordModelMetrics(node, tmsg)
+		}
+		// Log Response
+		if err := p.finishCallLog(callLog, tmsg, err); err != nil {
+			return nil, err
 		}
 		return tmsg, err
 	} else {
@@ -286,17 +300,16 @@ func (p *PredictorProcess) aggregate(node *v1.PredictiveUnit, cmsg []payload.Sel
 	}
 }
 
-func (p *PredictorProcess) predictChildren(node *v1.PredictiveUnit, msg payload.SeldonPayload, puid string) (payload.SeldonPayload, error) {
+func (p *PredictorProcess) predictChildren(ctx context.Context, node *v1.PredictiveUnit, msg payload.SeldonPayload, puid string) (payload.SeldonPayload, error) {
 	if node.Children != nil && len(node.Children) > 0 {
 		//Log Request
-		if node.Logger != nil && (node.Logger.Mode == v1.LogRequest || node.Logger.Mode == v1.LogAll) {
-			err := p.logPayload(node.Name, node.Logger, payloadLogger.InferenceRequest, msg, puid)
-			if err != nil {
-				return nil, err
-			}
+		callLog, err := p.startCallLog(node, msg, puid)
+		if err != nil {
+			return nil, err
 		}
-		route, err := p.route(node, msg)
+		route, err := p.route(ctx, node, msg)
 		if err != nil {
+			p.failCallLog(callLog, err)
 			return nil, err
 		}
 		var cmsgs []payload.SeldonPayload
@@ -307,7 +320,7 @@ func (p *PredictorProcess) predictChildren(node *v1.PredictiveUnit, msg payload.
 			for i, nodeChild := range node.Children {
 				wg.Add(1)
 				go func(i int, nodeChild v1.PredictiveUnit, msg payload.SeldonPayload) {
-					cmsgs[i], errs[i] = p.Predict(&nodeChild, msg)
+					cmsgs[i], errs[i] = p.predictNode(ctx, &nodeChild, msg)
 					wg.Done()
 				}(i, nodeChild, msg)
 			}
@@ -317,6 +330,7 @@ func (p *PredictorProcess) predictChildren(node *v1.PredictiveUnit, msg payload.
 			p.RoutingMutex.Unlock()
 			for i, err := range errs {
 				if err != nil {
+					p.failCallLog(callLog, err)
 					return cmsgs[i], err
 				}
 			}
@@ -328,23 +342,19 @@ func (p *PredictorProcess) predictChildren(node *v1.PredictiveUnit, msg payload.
 			return msg, nil
 		} else { // Calls SeldonApiClient.Predict.
 			cmsgs = make([]payload.SeldonPayload, 1)
-			cmsgs[0], err = p.Predict(&node.Children[route], msg)
+			cmsgs[0], err = p.predictNode(ctx, &node.Children[route], msg)
 			p.RoutingMutex.Lock()
 			p.Routing[node.Name] = int32(route)
 			p.RoutingMutex.Unlock()
 			if err != nil {
+				p.failCallLog(callLog, err)
 				return cmsgs[0], err
 			}
 		}
-		amsg, err := p.aggregate(node, cmsgs, msg, puid)
-		if amsg != nil && err == nil {
-			// Log Response
-			if node.Logger != nil && (node.Logger.Mode == v1.LogResponse || node.Logger.Mode == v1.LogAll) {
-				err := p.logPayload(node.Name, node.Logger, payloadLogger.InferenceResponse, amsg, puid)
-				if err != nil {
-					return nil, err
-				}
-			}
+		amsg, err := p.aggregate(ctx, node, cmsgs, msg, puid)
+		// Log Response
+		if err := p.finishCallLog(callLog, amsg, err); err != nil {
+			return nil, err
 		}
 		return amsg, err
 	} else {
@@ -411,6 +421,16 @@ func (p *PredictorProcess) logPayload(nodeName string, logger *v1.Logger, reqTyp
 	if err != nil {
 		return err
 	}
+	contentType, contentEncoding := msg.GetContentType(), msg.GetContentEncoding()
+	if len(logger.Redact) > 0 {
+		data, err = redactPayload(msg, data, logger.Redact)
+		if err != nil {
+			// The payload may hold the fields the rules protect so it isn't logged at all
+			p.Log.Error(err, "Failed to redact payload, skipped logging", "PUID", puid, "node", nodeName)
+			return nil
+		}
+		contentType, contentEncoding = payload.APPLICATION_TYPE_JSON, ""
+	}
 	logUrl, err := p.getLogUrl(logger)
 	if err != nil {
 		return err
@@ -419,8 +439,8 @@ func (p *PredictorProcess) logPayload(nodeName string, logger *v1.Logger, reqTyp
 		err := payloadLogger.QueueLogRequest(payloadLogger.LogRequest{
 			Url:             logUrl,
 			Bytes:           &data,
-			ContentType:     msg.GetContentType(),
-			ContentEncoding: msg.GetContentEncoding(),
+			ContentType:     contentType,
+			ContentEncoding: contentEncoding,
 			ReqType:         reqType,
 			Id:              guuid.New().String(),
 			SourceUri:       p.ServerUrl,
@@ -434,6 +454,41 @@ func (p *PredictorProcess) logPayload(nodeName string, logger *v1.Logger, reqTyp
 	return nil
 }
 
+// CheckRedaction fails if the logger of any node in the graph has redaction rules that can't be applied.
+func CheckRedaction(node *v1.PredictiveUnit) error {
+	if node.Logger != nil {
+		if err := payloadLogger.CheckRedactionRules(node.Logger.Redact); err != nil {
+			return fmt.Errorf("node %s: %w", node.Name, err)
+		}
+	}
+	for i := range node.Children {
+		if err := CheckRedaction(&node.Children[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// redactPayload applies the logger's redaction rules to the payload, returning it as JSON. Protobuf payloads are
+// converted to their JSON form first.
+func redactPayload(msg payload.SeldonPayload, data []byte, rules []v1.RedactionRule) ([]byte, error) {
+	if pm, ok := msg.GetPayload().(protoV1.Message); ok {
+		ma := jsonpb.Marshaler{OrigName: true}
+		js, err := ma.MarshalToString(pm)
+		if err != nil {
+			return nil, err
+		}
+		data = []byte(js)
+	} else {
+		var err error
+		data, err = payload.DecompressBytes(data, msg.GetContentEncoding())
+		if err != nil {
+			return nil, err
+		}
+	}
+	return payloadLogger.RedactJSON(data, rules)
+}
+
 func (p *PredictorProcess) getPUIDHeader() (string, error) {
 	// Check request ID is not nil
 	if puid, ok := p.Ctx.Value(payload.SeldonPUIDHeader).(string); ok {
@@ -442,22 +497,53 @@ func (p *PredictorProcess) getPUIDHeader() (string, error) {
 	return "", fmt.Errorf(NilPUIDError)
 }
 
+func (p *PredictorProcess) recordFailedNode(node *v1.PredictiveUnit) {
+	p.RoutingMutex.Lock()
+	defer p.RoutingMutex.Unlock()
+	if p.FailedNode == "" {
+		p.FailedNode = node.Name
+	}
+}
+
 func (p *PredictorProcess) Predict(node *v1.PredictiveUnit, msg payload.SeldonPayload) (payload.SeldonPayload, error) {
+	response, err := p.predictNode(p.Ctx, node, msg)
+	if err == nil && stripModelMetrics {
+		return removeModelMetrics(response)
+	}
+	return response, err
+}
+
+// predictNode runs the prediction of the node and its children, within a span of the node when tracing with
+// OpenTelemetry. The context is passed down the graph so the spans of children nest in that of their parent.
+func (p *PredictorProcess) predictNode(ctx context.Context, node *v1.PredictiveUnit, msg payload.SeldonPayload) (response payload.SeldonPayload, err error) {
 	puid, err := p.getPUIDHeader()
 	if err != nil {
 		return nil, err
 	}
 
-	tmsg, err := p.transformInput(node, msg, puid)
+	if tracing.OpenTelemetryEnabled() {
+		var span trace.Span
+		ctx, span = startNodeSpan(ctx, node, msg)
+		defer func() {
+			p.endNodeSpan(span, node, response, err)
+		}()
+	}
+
+	tmsg, err := p.transformInput(ctx, node, msg, puid)
 	if err != nil {
+		p.recordFailedNode(node)
 		return tmsg, err
 	}
-	cmsg, err := p.predictChildren(node, tmsg, puid)
+	cmsg, err := p.predictChildren(ctx, node, tmsg, puid)
 	if err != nil {
+		p.recordFailedNode(node)
 		return cmsg, err
 	}
 
-	response, err := p.transformOutput(node, cmsg, puid)
+	response, err = p.transformOutput(ctx, node, cmsg, puid)
+	if err != nil {
+		p.recordFailedNode(node)
+	}
 
 	if envEnableRoutingInjection {
 		if routeResponse, err := util.InsertRouteToSeldonPredictPayload(response, &p.Routing); err == nil {
@@ -503,11 +589,11 @@ func (p *PredictorProcess) GraphMetadata(spec *v1.PredictorSpec) (*GraphMetadata
 
 func (p *PredictorProcess) Feedback(node *v1.PredictiveUnit, msg payload.SeldonPayload) (payload.SeldonPayload, error) {
 
-	if node.Logger != nil && (node.Logger.Mode == v1.LogResponse || node.Logger.Mode == v1.LogAll) {
+	if node.Logger != nil && logsResponses(node.Logger) {
 		puid, puiderr := p.getPUIDHeader()
 		if puiderr != nil {
 			p.Log.Error(puiderr, "Error retrieving uuid for feedback and could not send feedback")
-		} else {
+		} else if isSampled(node.Logger, puid) {
 			err := p.logPayload(node.Name, node.Logger, payloadLogger.InferenceFeedback, msg, puid)
 			if err != nil {
 				return nil, err
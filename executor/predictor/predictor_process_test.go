@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -463,7 +464,7 @@ func TestModelWithLogRequests(t *testing.T) {
 
 	logf.SetLogger(zap.New())
 	log := logf.Log.WithName("entrypoint")
-	logger.StartDispatcher(1, logger.DefaultWorkQueueSize, logger.DefaultWriteTimeoutMilliseconds, log, "", "", "", "", "", api.ProtocolSeldon)
+	logger.StartDispatcher(1, logger.DefaultWorkQueueSize, logger.DefaultWriteTimeoutMilliseconds, log, "", "", "", "", "", api.ProtocolSeldon, logger.DeliveryConfig{})
 
 	model := v1.MODEL
 	graph := &v1.PredictiveUnit{
@@ -489,6 +490,45 @@ func TestModelWithLogRequests(t *testing.T) {
 	g.Expect(logMessagesReceived).To(Equal(1))
 }
 
+func TestModelWithRedactedLogRequests(t *testing.T) {
+	g := NewGomegaWithT(t)
+	modelName := "foo"
+	bodies := make(chan []byte, 1)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		g.Expect(r.Header.Get(contentTypeHeaderName)).To(Equal(payload.APPLICATION_TYPE_JSON))
+		body, err := ioutil.ReadAll(r.Body)
+		g.Expect(err).To(BeNil())
+		bodies <- body
+	})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	log := logf.Log.WithName("entrypoint")
+	logger.StartDispatcher(1, logger.DefaultWorkQueueSize, logger.DefaultWriteTimeoutMilliseconds, log, "", "", "", "", "", api.ProtocolSeldon, logger.DeliveryConfig{})
+
+	model := v1.MODEL
+	graph := &v1.PredictiveUnit{
+		Name: modelName,
+		Type: &model,
+		Endpoint: &v1.Endpoint{
+			ServiceHost: "foo",
+			ServicePort: 9000,
+			Type:        v1.REST,
+		},
+		Logger: &v1.Logger{
+			Mode:   v1.LogRequest,
+			Url:    &server.URL,
+			Redact: []v1.RedactionRule{{Path: "$.data.ndarray[0]", Action: v1.RedactMask}},
+		},
+	}
+
+	_, err := createPredictorProcess(t).Predict(graph, createPredictPayload(g))
+	g.Expect(err).Should(BeNil())
+	var body []byte
+	g.Eventually(bodies).Should(Receive(&body))
+	g.Expect(body).To(MatchJSON(`{"data":{"ndarray":["****",2]}}`))
+}
+
 func TestModelWithLogRequestsAtDefaultedUrl(t *testing.T) {
 	t.Logf("Started")
 	g := NewGomegaWithT(t)
@@ -512,7 +552,7 @@ func TestModelWithLogRequestsAtDefaultedUrl(t *testing.T) {
 
 	logf.SetLogger(zap.New())
 	log := logf.Log.WithName("entrypoint")
-	logger.StartDispatcher(1, logger.DefaultWorkQueueSize, logger.DefaultWriteTimeoutMilliseconds, log, "", "", "", "", "", api.ProtocolSeldon)
+	logger.StartDispatcher(1, logger.DefaultWorkQueueSize, logger.DefaultWriteTimeoutMilliseconds, log, "", "", "", "", "", api.ProtocolSeldon, logger.DeliveryConfig{})
 
 	model := v1.MODEL
 	graph := &v1.PredictiveUnit{
@@ -555,7 +595,7 @@ func TestModelWithLogResponses(t *testing.T) {
 
 	logf.SetLogger(zap.New())
 	log := logf.Log.WithName("entrypoint")
-	logger.StartDispatcher(1, logger.DefaultWorkQueueSize, logger.DefaultWriteTimeoutMilliseconds, log, "", "", "", "", "", api.ProtocolSeldon)
+	logger.StartDispatcher(1, logger.DefaultWorkQueueSize, logger.DefaultWriteTimeoutMilliseconds, log, "", "", "", "", "", api.ProtocolSeldon, logger.DeliveryConfig{})
 
 	model := v1.MODEL
 	graph := &v1.PredictiveUnit{
@@ -609,7 +649,7 @@ func TestModelRequestSkipsLogging(t *testing.T) {
 
 	logf.SetLogger(zap.New())
 	log := logf.Log.WithName("entrypoint")
-	logger.StartDispatcher(1, logger.DefaultWorkQueueSize, logger.DefaultWriteTimeoutMilliseconds, log, "", "", "", "", "", api.ProtocolSeldon)
+	logger.StartDispatcher(1, logger.DefaultWorkQueueSize, logger.DefaultWriteTimeoutMilliseconds, log, "", "", "", "", "", api.ProtocolSeldon, logger.DeliveryConfig{})
 
 	model := v1.MODEL
 	graph := &v1.PredictiveUnit{
@@ -659,7 +699,7 @@ func TestModelWithLogRequestsForRouter(t *testing.T) {
 
 	logf.SetLogger(zap.New())
 	log := logf.Log.WithName("entrypoint")
-	logger.StartDispatcher(1, logger.DefaultWorkQueueSize, logger.DefaultWriteTimeoutMilliseconds, log, "", "", "", "", "", api.ProtocolSeldon)
+	logger.StartDispatcher(1, logger.DefaultWorkQueueSize, logger.DefaultWriteTimeoutMilliseconds, log, "", "", "", "", "", api.ProtocolSeldon, logger.DeliveryConfig{})
 
 	router := v1.ROUTER
 	model := v1.MODEL
@@ -696,3 +736,30 @@ func TestModelWithLogRequestsForRouter(t *testing.T) {
 	g.Eventually(func() bool { return logged }).Should(Equal(true))
 	g.Expect(logMessagesReceived).To(Equal(2))
 }
+
+func TestModelErrorRecordsFailedNode(t *testing.T) {
+	g := NewGomegaWithT(t)
+	model := v1.MODEL
+	router := v1.SIMPLE_ROUTER
+	graph := &v1.PredictiveUnit{
+		Name:           "router",
+		Implementation: &router,
+		Children: []v1.PredictiveUnit{
+			{
+				Name: "child",
+				Type: &model,
+				Endpoint: &v1.Endpoint{
+					ServiceHost: "foo",
+					ServicePort: 9000,
+					Type:        v1.REST,
+				},
+			},
+		},
+	}
+
+	errMethod := v1.TRANSFORM_INPUT
+	pp := createPredictorProcessWithError(t, &errMethod, errors.New("something bad happened"), nil)
+	_, err := pp.Predict(graph, createPredictPayload(g))
+	g.Expect(err).ShouldNot(BeNil())
+	g.Expect(pp.FailedNode).To(Equal("child"))
+}
@@ -0,0 +1,237 @@
+package predictor
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/seldonio/seldon-core/executor/api/util"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	DefaultProbeInterval = 5 * time.Second
+	DefaultProbeTimeout  = 2 * time.Second
+)
+
+// NodeHealth is the result of the latest health check of a graph node.
+type NodeHealth struct {
+	Name  string `json:"name"`
+	Ready bool   `json:"ready"`
+	// Optional nodes leave the graph ready but degraded when they fail.
+	Optional    bool       `json:"optional,omitempty"`
+	LastError   string     `json:"lastError,omitempty"`
+	LastCheck   time.Time  `json:"lastCheck"`
+	LastSuccess *time.Time `json:"lastSuccess,omitempty"`
+}
+
+// ReadinessStatus is the readiness of the graph as last probed along with the health of each node.
+type ReadinessStatus struct {
+	Ready    bool         `json:"ready"`
+	Degraded bool         `json:"degraded"`
+	Error    string       `json:"error,omitempty"`
+	Nodes    []NodeHealth `json:"nodes"`
+}
+
+// ReadinessProber checks every node of the graph in the background, each with its own timeout, and caches the
+// results so readiness probes are answered without waiting on the models.
+type ReadinessProber struct {
+	sync.RWMutex
+	predictorStore  *PredictorStore
+	Log             logr.Logger
+	Protocol        string
+	FullHealthCheck bool
+	Interval        time.Duration
+	Timeout         time.Duration
+	OptionalNodes   map[string]bool
+	health          *healthClient
+	probed          bool
+	err             error
+	nodes           map[string]*NodeHealth
+}
+
+func NewReadinessProber(predictorStore *PredictorStore, protocol string, fullHealthCheck bool, interval time.Duration, timeout time.Duration, optionalNodes []string) *ReadinessProber {
+	optional := make(map[string]bool, len(optionalNodes))
+	for _, name := range optionalNodes {
+		optional[name] = true
+	}
+	return &ReadinessProber{
+		predictorStore:  predictorStore,
+		Log:             logf.Log.WithName("ReadinessProber"),
+		Protocol:        protocol,
+		FullHealthCheck: fullHealthCheck,
+		Interval:        interval,
+		Timeout:         timeout,
+		OptionalNodes:   optional,
+		health:          newHealthClient(),
+		nodes:           make(map[string]*NodeHealth),
+	}
+}
+
+// Start probes the graph now and then on every interval until stop is closed, when the prober's connections
+// are closed.
+func (p *ReadinessProber) Start(stop <-chan struct{}) {
+	go func() {
+		ticker := time.NewTicker(p.Interval)
+		defer ticker.Stop()
+		for {
+			p.Probe()
+			select {
+			case <-stop:
+				p.health.close()
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Probe checks the nodes of the current graph concurrently and stores the results. Nodes removed from the graph
+// by a reload are dropped along with their connections.
+func (p *ReadinessProber) Probe() {
+	spec := p.predictorStore.Get()
+	var nodes []*v1.PredictiveUnit
+	walkEndpoints(&spec.Graph, func(node *v1.PredictiveUnit) error {
+		nodes = append(nodes, node)
+		return nil
+	})
+
+	var tlsConfig *tls.Config
+	var err error
+	if p.FullHealthCheck && !knownHealthProtocol(p.Protocol) {
+		err = fmt.Errorf("Unknown protocol for health check: %s", p.Protocol)
+	} else {
+		tlsConfig, err = util.GetClientTLSConfig(spec)
+	}
+
+	errs := make([]error, len(nodes))
+	if err == nil {
+		wg := sync.WaitGroup{}
+		for i, node := range nodes {
+			wg.Add(1)
+			go func(i int, node *v1.PredictiveUnit) {
+				defer wg.Done()
+				errs[i] = readyNode(p.Protocol, node, p.FullHealthCheck, tlsConfig, p.health, p.Timeout)
+			}(i, node)
+		}
+		wg.Wait()
+		p.health.retainGrpc(&spec.Graph, tlsConfig)
+	}
+	now := time.Now()
+
+	p.Lock()
+	defer p.Unlock()
+	results := make(map[string]*NodeHealth, len(nodes))
+	for i, node := range nodes {
+		health := &NodeHealth{Name: node.Name, Optional: p.OptionalNodes[node.Name], LastCheck: now}
+		if previous, ok := p.nodes[node.Name]; ok {
+			health.LastSuccess = previous.LastSuccess
+		}
+		nodeErr := errs[i]
+		if nodeErr == nil {
+			nodeErr = err
+		}
+		if nodeErr == nil {
+			health.Ready = true
+			health.LastSuccess = &now
+		} else {
+			health.LastError = nodeErr.Error()
+		}
+		if previous, ok := p.nodes[node.Name]; (!ok && !health.Ready) || (ok && previous.Ready != health.Ready) {
+			p.Log.Info("Node health changed", "node", node.Name, "ready", health.Ready, "error", health.LastError)
+		}
+		results[node.Name] = health
+	}
+	p.nodes = results
+	p.err = err
+	p.probed = true
+}
+
+// Ready returns nil if the graph was ready when last probed. Failing optional nodes don't make it unready.
+func (p *ReadinessProber) Ready() error {
+	p.RLock()
+	defer p.RUnlock()
+	if !p.probed {
+		return fmt.Errorf("graph not probed yet")
+	}
+	if p.err != nil {
+		return p.err
+	}
+	var failed []string
+	for name, health := range p.nodes {
+		if !health.Ready && !health.Optional {
+			failed = append(failed, fmt.Sprintf("%s: %s", name, health.LastError))
+		}
+	}
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		return fmt.Errorf("nodes not ready: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// Status returns the readiness of the graph and the health of each node, ordered by name.
+func (p *ReadinessProber) Status() ReadinessStatus {
+	err := p.Ready()
+	p.RLock()
+	defer p.RUnlock()
+	status := ReadinessStatus{Ready: err == nil, Nodes: make([]NodeHealth, 0, len(p.nodes))}
+	if err != nil {
+		status.Error = err.Error()
+	}
+	for _, health := range p.nodes {
+		status.Nodes = append(status.Nodes, *health)
+		if !health.Ready && health.Optional {
+			status.Degraded = true
+		}
+	}
+	sort.Slice(status.Nodes, func(i, j int) bool { return status.Nodes[i].Name < status.Nodes[j].Name })
+	return status
+}
+
+// ServeHTTP returns the status as JSON with a 503 status code if the graph isn't ready.
+func (p *ReadinessProber) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	status := p.Status()
+	data, err := json.Marshal(status)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if !status.Ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	w.Write(data)
+}
+
+var readinessProber *ReadinessProber
+
+// EnableReadinessProber answers readiness checks made with CheckReady from the prober's cached results.
+func EnableReadinessProber(prober *ReadinessProber) {
+	readinessProber = prober
+}
+
+// Prober returns the readiness prober, or nil if readiness is checked on each call.
+func Prober() *ReadinessProber {
+	return readinessProber
+}
+
+// CheckReady answers from the readiness prober if one is enabled with the same kind of health checks, and otherwise
+// checks the graph of the predictor with Ready.
+func CheckReady(protocol string, spec *v1.PredictorSpec, fullHealthCheck bool) error {
+	if readinessProber != nil && readinessProber.FullHealthCheck == fullHealthCheck {
+		return readinessProber.Ready()
+	}
+	tlsConfig, err := util.GetClientTLSConfig(spec)
+	if err != nil {
+		return err
+	}
+	return Ready(protocol, &spec.Graph, fullHealthCheck, tlsConfig)
+}
@@ -0,0 +1,177 @@
+package predictor
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/seldonio/seldon-core/executor/api"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+)
+
+func restModel(t *testing.T, name string, handler http.HandlerFunc) v1.PredictiveUnit {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	host, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+	portNum, _ := strconv.Atoi(port)
+	model := v1.MODEL
+	return v1.PredictiveUnit{
+		Name:     name,
+		Type:     &model,
+		Endpoint: &v1.Endpoint{ServiceHost: host, ServicePort: int32(portNum), Type: v1.REST},
+	}
+}
+
+func TestProberCachesNodeHealth(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	healthy := restModel(t, "healthy", func(w http.ResponseWriter, r *http.Request) {})
+	failing := restModel(t, "failing", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	combiner := v1.COMBINER
+	graph := v1.PredictiveUnit{Name: "combiner", Type: &combiner, Children: []v1.PredictiveUnit{healthy, failing}}
+
+	prober := NewReadinessProber(NewPredictorStore(&v1.PredictorSpec{Name: "p", Graph: graph}), api.ProtocolSeldon, true, time.Minute, 100*time.Millisecond, nil)
+	g.Expect(prober.Ready()).ToNot(BeNil())
+
+	prober.Probe()
+	err := prober.Ready()
+	g.Expect(err).ToNot(BeNil())
+	g.Expect(err.Error()).To(ContainSubstring("failing"))
+
+	status := prober.Status()
+	g.Expect(status.Ready).To(BeFalse())
+	g.Expect(status.Degraded).To(BeFalse())
+	g.Expect(status.Nodes).To(HaveLen(2))
+	g.Expect(status.Nodes[0].Name).To(Equal("failing"))
+	g.Expect(status.Nodes[0].Ready).To(BeFalse())
+	g.Expect(status.Nodes[0].LastError).ToNot(BeEmpty())
+	g.Expect(status.Nodes[0].LastSuccess).To(BeNil())
+	g.Expect(status.Nodes[1].Name).To(Equal("healthy"))
+	g.Expect(status.Nodes[1].Ready).To(BeTrue())
+	g.Expect(status.Nodes[1].LastSuccess).ToNot(BeNil())
+}
+
+func TestProberOptionalNodes(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	healthy := restModel(t, "healthy", func(w http.ResponseWriter, r *http.Request) {})
+	failing := restModel(t, "shadow", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	combiner := v1.COMBINER
+	graph := v1.PredictiveUnit{Name: "combiner", Type: &combiner, Children: []v1.PredictiveUnit{healthy, failing}}
+
+	prober := NewReadinessProber(NewPredictorStore(&v1.PredictorSpec{Name: "p", Graph: graph}), api.ProtocolSeldon, true, time.Minute, 100*time.Millisecond, []string{"shadow"})
+	prober.Probe()
+	g.Expect(prober.Ready()).To(BeNil())
+	status := prober.Status()
+	g.Expect(status.Ready).To(BeTrue())
+	g.Expect(status.Degraded).To(BeTrue())
+	g.Expect(status.Nodes[1].Optional).To(BeTrue())
+}
+
+func TestProberTimesOutHungNodes(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	release := make(chan struct{})
+	defer close(release)
+	hung := restModel(t, "hung", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	})
+
+	prober := NewReadinessProber(NewPredictorStore(&v1.PredictorSpec{Name: "p", Graph: hung}), api.ProtocolSeldon, true, time.Minute, 100*time.Millisecond, nil)
+	start := time.Now()
+	prober.Probe()
+	g.Expect(time.Since(start)).To(BeNumerically("<", 2*time.Second))
+	g.Expect(prober.Ready()).ToNot(BeNil())
+	g.Expect(prober.Status().Nodes[0].LastError).ToNot(BeEmpty())
+}
+
+func TestProberReusesConnections(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	var conns int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(make([]byte, 64*1024))
+	}))
+	server.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	addr := server.Listener.Addr().(*net.TCPAddr)
+	model := v1.MODEL
+	node := v1.PredictiveUnit{Name: "model", Type: &model, Endpoint: &v1.Endpoint{ServiceHost: addr.IP.String(), ServicePort: int32(addr.Port), Type: v1.REST}}
+	prober := NewReadinessProber(NewPredictorStore(&v1.PredictorSpec{Name: "p", Graph: node}), api.ProtocolSeldon, true, time.Minute, 100*time.Millisecond, nil)
+	for i := 0; i < 5; i++ {
+		prober.Probe()
+		g.Expect(prober.Ready()).To(BeNil())
+	}
+	g.Expect(atomic.LoadInt32(&conns)).To(Equal(int32(1)))
+	g.Expect(prober.health).ToNot(BeIdenticalTo(defaultHealthClient))
+}
+
+func TestProberKeepsLastSuccess(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	var failing int32
+	node := restModel(t, "model", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&failing) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+	prober := NewReadinessProber(NewPredictorStore(&v1.PredictorSpec{Name: "p", Graph: node}), api.ProtocolSeldon, true, time.Minute, 100*time.Millisecond, nil)
+	prober.Probe()
+	lastSuccess := prober.Status().Nodes[0].LastSuccess
+	g.Expect(lastSuccess).ToNot(BeNil())
+
+	atomic.StoreInt32(&failing, 1)
+	prober.Probe()
+	health := prober.Status().Nodes[0]
+	g.Expect(health.Ready).To(BeFalse())
+	g.Expect(health.LastSuccess).To(Equal(lastSuccess))
+	g.Expect(health.LastCheck.After(*lastSuccess)).To(BeTrue())
+}
+
+func TestCheckReadyUsesProber(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	model := v1.MODEL
+	unreachable := &v1.PredictorSpec{Name: "p", Graph: v1.PredictiveUnit{
+		Name:     "model",
+		Type:     &model,
+		Endpoint: &v1.Endpoint{ServiceHost: "127.0.0.1", ServicePort: 1, Type: v1.REST},
+	}}
+	g.Expect(CheckReady(api.ProtocolSeldon, unreachable, true)).ToNot(BeNil())
+
+	healthy := restModel(t, "model", func(w http.ResponseWriter, r *http.Request) {})
+	prober := NewReadinessProber(NewPredictorStore(&v1.PredictorSpec{Name: "p", Graph: healthy}), api.ProtocolSeldon, true, time.Minute, 100*time.Millisecond, nil)
+	prober.Probe()
+	EnableReadinessProber(prober)
+	defer EnableReadinessProber(nil)
+	g.Expect(CheckReady(api.ProtocolSeldon, unreachable, true)).To(BeNil())
+	// The prober only answers checks of the kind it makes
+	g.Expect(CheckReady(api.ProtocolSeldon, unreachable, false)).ToNot(BeNil())
+
+	res := httptest.NewRecorder()
+	prober.ServeHTTP(res, httptest.NewRequest("GET", "/ready/nodes", nil))
+	g.Expect(res.Code).To(Equal(http.StatusOK))
+	var status ReadinessStatus
+	g.Expect(json.Unmarshal(res.Body.Bytes(), &status)).To(BeNil())
+	g.Expect(status.Ready).To(BeTrue())
+	g.Expect(status.Nodes).To(HaveLen(1))
+}
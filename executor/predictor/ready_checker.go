@@ -1,74 +1,277 @@
 package predictor
 
 import (
+	"context"
+	"crypto/tls"
 	"fmt"
-	"github.com/seldonio/seldon-core/executor/api"
-	"github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	"io"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"net/url"
 	"strconv"
+	"sync"
+	"time"
+
+	"github.com/seldonio/seldon-core/executor/api"
+	"github.com/seldonio/seldon-core/executor/api/grpc/kfserving/inference"
+	"github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
 )
 
-func Ready(protocol string, node *v1.PredictiveUnit, fullHealthCheck bool) error {
+// DefaultReadyTimeout bounds the health check of each node so a hung model can't hang the readiness probe.
+const DefaultReadyTimeout = 5 * time.Second
+
+// Ready checks the graph nodes can be reached. A non-nil tlsConfig is used for health calls to nodes serving TLS.
+// Nodes with a gRPC endpoint are asked with the gRPC health checking protocol, or ServerReady for the V2 protocol.
+// With full health checks the others are asked with the HTTP health path of the protocol, otherwise only their
+// connection is checked.
+func Ready(protocol string, node *v1.PredictiveUnit, fullHealthCheck bool, tlsConfig *tls.Config) error {
+	if fullHealthCheck && !knownHealthProtocol(protocol) {
+		return fmt.Errorf("Unknown protocol for health check: %s", protocol)
+	}
+	defer defaultHealthClient.retainGrpc(node, tlsConfig)
+	return walkEndpoints(node, func(node *v1.PredictiveUnit) error {
+		return readyNode(protocol, node, fullHealthCheck, tlsConfig, defaultHealthClient, DefaultReadyTimeout)
+	})
+}
+
+func knownHealthProtocol(protocol string) bool {
+	switch protocol {
+	case api.ProtocolSeldon, api.ProtocolTensorflow, api.ProtocolV2, api.ProtocolKFServing:
+		return true
+	default:
+		return false
+	}
+}
+
+func hasEndpoint(node *v1.PredictiveUnit) bool {
+	return node.Endpoint != nil && node.Endpoint.ServiceHost != "" && node.Endpoint.ServicePort > 0
+}
+
+// walkEndpoints calls check on the nodes with an endpoint, children first, stopping at the first error.
+func walkEndpoints(node *v1.PredictiveUnit, check func(node *v1.PredictiveUnit) error) error {
+	for i := range node.Children {
+		if err := walkEndpoints(&node.Children[i], check); err != nil {
+			return err
+		}
+	}
+	if hasEndpoint(node) {
+		return check(node)
+	}
+	return nil
+}
+
+// readyNode checks a single node with an endpoint, making HTTP health calls with health and giving up after timeout.
+func readyNode(protocol string, node *v1.PredictiveUnit, fullHealthCheck bool, tlsConfig *tls.Config, health *healthClient, timeout time.Duration) error {
+	if node.Endpoint.Type == v1.GRPC {
+		return readyGrpcNode(protocol, node, tlsConfig, health, timeout)
+	}
 	if !fullHealthCheck {
-		return ReadyTCP(node)
+		return readyTCPNode(node, timeout)
 	}
 	switch protocol {
 	case api.ProtocolSeldon:
-		return ReadyHealth(node, "/api/v1.0/health/status")
+		return readyHealthNode(node, "/api/v1.0/health/status", tlsConfig, health, timeout)
 	case api.ProtocolTensorflow:
-		return ReadyTCP(node)
+		return readyTCPNode(node, timeout)
 	case api.ProtocolV2, api.ProtocolKFServing:
-		return ReadyHealth(node, "/v2/health/ready")
+		return readyHealthNode(node, "/v2/health/ready", tlsConfig, health, timeout)
 	default:
 		return fmt.Errorf("Unknown protocol for health check: %s", protocol)
 	}
 }
 
 func ReadyTCP(node *v1.PredictiveUnit) error {
-	for _, child := range node.Children {
-		err := ReadyTCP(&child)
-		if err != nil {
-			return err
+	return walkEndpoints(node, func(node *v1.PredictiveUnit) error {
+		return readyTCPNode(node, DefaultReadyTimeout)
+	})
+}
+
+func readyTCPNode(node *v1.PredictiveUnit, timeout time.Duration) error {
+	c, err := net.DialTimeout("tcp", net.JoinHostPort(node.Endpoint.ServiceHost, strconv.Itoa(int(node.Endpoint.ServicePort))), timeout)
+	if err != nil {
+		return err
+	}
+	return c.Close()
+}
+
+func ReadyHealth(node *v1.PredictiveUnit, healthPath string, tlsConfig *tls.Config) error {
+	return walkEndpoints(node, func(node *v1.PredictiveUnit) error {
+		return readyHealthNode(node, healthPath, tlsConfig, defaultHealthClient, DefaultReadyTimeout)
+	})
+}
+
+// healthClient keeps one http client per client TLS config and one gRPC connection per endpoint and TLS config
+// for health calls so probes reuse their connections. When the TLS config is replaced the previous transport's
+// idle connections are closed.
+type healthClient struct {
+	sync.Mutex
+	plain     *http.Client
+	tlsConfig *tls.Config
+	tls       *http.Client
+	conns     map[grpcConnKey]*grpc.ClientConn
+}
+
+type grpcConnKey struct {
+	address   string
+	tlsConfig *tls.Config
+}
+
+func newHealthClient() *healthClient {
+	return &healthClient{
+		plain: &http.Client{Transport: http.DefaultTransport.(*http.Transport).Clone()},
+		conns: make(map[grpcConnKey]*grpc.ClientConn),
+	}
+}
+
+var defaultHealthClient = newHealthClient()
+
+func (h *healthClient) client(tlsConfig *tls.Config) *http.Client {
+	if tlsConfig == nil {
+		return h.plain
+	}
+	h.Lock()
+	defer h.Unlock()
+	if h.tls == nil || h.tlsConfig != tlsConfig {
+		if h.tls != nil {
+			h.tls.CloseIdleConnections()
 		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = tlsConfig
+		h.tlsConfig = tlsConfig
+		h.tls = &http.Client{Transport: transport}
 	}
-	if node.Endpoint != nil && node.Endpoint.ServiceHost != "" && node.Endpoint.ServicePort > 0 {
-		c, err := net.Dial("tcp", fmt.Sprintf("%s:%d", node.Endpoint.ServiceHost, node.Endpoint.ServicePort))
-		if err != nil {
-			return err
-		} else {
-			err = c.Close()
-			return nil
+	return h.tls
+}
+
+// grpcConn returns the connection to address, dialing it on first use.
+func (h *healthClient) grpcConn(address string, tlsConfig *tls.Config) (*grpc.ClientConn, error) {
+	h.Lock()
+	defer h.Unlock()
+	key := grpcConnKey{address: address, tlsConfig: tlsConfig}
+	if conn, ok := h.conns[key]; ok {
+		// Reconnect now rather than after the backoff of a model which was down
+		if conn.GetState() == connectivity.TransientFailure {
+			conn.ResetConnectBackoff()
+		}
+		return conn, nil
+	}
+	transportCredentials := grpc.WithInsecure()
+	if tlsConfig != nil {
+		transportCredentials = grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig))
+	}
+	conn, err := grpc.Dial(address, transportCredentials)
+	if err != nil {
+		return nil, err
+	}
+	h.conns[key] = conn
+	return conn, nil
+}
+
+// retainGrpc closes the gRPC connections other than those to the endpoints of graph with tlsConfig, such as those
+// to nodes removed by a reload.
+func (h *healthClient) retainGrpc(graph *v1.PredictiveUnit, tlsConfig *tls.Config) {
+	keep := make(map[grpcConnKey]bool)
+	walkEndpoints(graph, func(node *v1.PredictiveUnit) error {
+		if node.Endpoint.Type == v1.GRPC {
+			keep[grpcConnKey{address: endpointAddress(node), tlsConfig: tlsConfig}] = true
 		}
-	} else {
 		return nil
+	})
+	h.Lock()
+	defer h.Unlock()
+	for key, conn := range h.conns {
+		if !keep[key] {
+			conn.Close()
+			delete(h.conns, key)
+		}
 	}
 }
 
-func ReadyHealth(node *v1.PredictiveUnit, healthPath string) error {
-	for _, child := range node.Children {
-		err := ReadyHealth(&child, healthPath)
+// close closes the gRPC connections and the idle http connections.
+func (h *healthClient) close() {
+	h.Lock()
+	defer h.Unlock()
+	for key, conn := range h.conns {
+		conn.Close()
+		delete(h.conns, key)
+	}
+	h.plain.CloseIdleConnections()
+	if h.tls != nil {
+		h.tls.CloseIdleConnections()
+	}
+}
+
+func endpointAddress(node *v1.PredictiveUnit) string {
+	return net.JoinHostPort(node.Endpoint.ServiceHost, strconv.Itoa(int(node.Endpoint.ServicePort)))
+}
+
+func readyHealthNode(node *v1.PredictiveUnit, healthPath string, tlsConfig *tls.Config, health *healthClient, timeout time.Duration) error {
+	scheme := "http"
+	if tlsConfig != nil {
+		scheme = "https"
+	}
+	urlHealth := &url.URL{
+		Scheme: scheme,
+		Host:   net.JoinHostPort(node.Endpoint.ServiceHost, strconv.Itoa(int(node.Endpoint.ServicePort))),
+		Path:   healthPath,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, urlHealth.String(), nil)
+	if err != nil {
+		return err
+	}
+	res, err := health.client(tlsConfig).Do(req)
+	if err != nil {
+		return err
+	}
+	// The body is read to the end so the connection can be reused
+	io.Copy(ioutil.Discard, res.Body)
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("Bad status from %s:%d", node.Endpoint.ServiceHost, node.Endpoint.ServicePort)
+	}
+	return nil
+}
+
+// readyGrpcNode checks a gRPC endpoint with ServerReady for the V2 protocol and the gRPC health checking protocol
+// otherwise. Models not implementing the health service are ready once they answer.
+func readyGrpcNode(protocol string, node *v1.PredictiveUnit, tlsConfig *tls.Config, health *healthClient, timeout time.Duration) error {
+	address := endpointAddress(node)
+	conn, err := health.grpcConn(address, tlsConfig)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	switch protocol {
+	case api.ProtocolV2, api.ProtocolKFServing:
+		res, err := inference.NewGRPCInferenceServiceClient(conn).ServerReady(ctx, &inference.ServerReadyRequest{})
 		if err != nil {
 			return err
 		}
-	}
-	if node.Endpoint != nil && node.Endpoint.ServiceHost != "" && node.Endpoint.ServicePort > 0 {
-		urlHealth := &url.URL{
-			Scheme: "http",
-			Host:   net.JoinHostPort(node.Endpoint.ServiceHost, strconv.Itoa(int(node.Endpoint.ServicePort))),
-			Path:   healthPath,
+		if !res.GetReady() {
+			return fmt.Errorf("Server not ready at %s", address)
+		}
+	default:
+		res, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+		if status.Code(err) == codes.Unimplemented {
+			return nil
 		}
-		res, err := http.Get(urlHealth.String())
 		if err != nil {
 			return err
-		} else {
-			if res.StatusCode != http.StatusOK {
-				return fmt.Errorf("Bad status from %s:%d", node.Endpoint.ServiceHost, node.Endpoint.ServicePort)
-			}
-			return nil
 		}
-	} else {
-		return nil
+		if res.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+			return fmt.Errorf("Bad health status %s from %s", res.GetStatus(), address)
+		}
 	}
+	return nil
 }
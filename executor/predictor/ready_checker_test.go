@@ -0,0 +1,151 @@
+package predictor
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/seldonio/seldon-core/executor/api"
+	"github.com/seldonio/seldon-core/executor/api/grpc/kfserving/inference"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+type readyInferenceServer struct {
+	inference.UnimplementedGRPCInferenceServiceServer
+	ready bool
+}
+
+func (s *readyInferenceServer) ServerReady(ctx context.Context, request *inference.ServerReadyRequest) (*inference.ServerReadyResponse, error) {
+	return &inference.ServerReadyResponse{Ready: s.ready}, nil
+}
+
+func startGrpcModel(t *testing.T, register func(*grpc.Server)) *v1.PredictiveUnit {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer()
+	register(server)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	model := v1.MODEL
+	addr := lis.Addr().(*net.TCPAddr)
+	return &v1.PredictiveUnit{
+		Name:     "model",
+		Type:     &model,
+		Endpoint: &v1.Endpoint{ServiceHost: addr.IP.String(), ServicePort: int32(addr.Port), Type: v1.GRPC},
+	}
+}
+
+func TestReadyGrpcHealthCheck(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	healthServer := health.NewServer()
+	node := startGrpcModel(t, func(s *grpc.Server) { healthpb.RegisterHealthServer(s, healthServer) })
+
+	g.Expect(Ready(api.ProtocolSeldon, node, true, nil)).To(BeNil())
+
+	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	g.Expect(Ready(api.ProtocolSeldon, node, true, nil)).ToNot(BeNil())
+	g.Expect(Ready(api.ProtocolTensorflow, node, true, nil)).ToNot(BeNil())
+	// The health service is asked without full health checks too
+	g.Expect(Ready(api.ProtocolSeldon, node, false, nil)).ToNot(BeNil())
+}
+
+func TestReadyGrpcWithoutHealthService(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	node := startGrpcModel(t, func(s *grpc.Server) {})
+	g.Expect(Ready(api.ProtocolSeldon, node, true, nil)).To(BeNil())
+
+	node.Endpoint.ServicePort = 1
+	g.Expect(Ready(api.ProtocolSeldon, node, true, nil)).ToNot(BeNil())
+}
+
+func TestReadyGrpcServerReady(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	server := &readyInferenceServer{ready: true}
+	child := startGrpcModel(t, func(s *grpc.Server) { inference.RegisterGRPCInferenceServiceServer(s, server) })
+	model := v1.MODEL
+	graph := &v1.PredictiveUnit{Name: "parent", Type: &model, Children: []v1.PredictiveUnit{*child}}
+
+	g.Expect(Ready(api.ProtocolV2, graph, true, nil)).To(BeNil())
+	g.Expect(Ready(api.ProtocolV2, graph, false, nil)).To(BeNil())
+
+	server.ready = false
+	g.Expect(Ready(api.ProtocolV2, graph, true, nil)).ToNot(BeNil())
+	g.Expect(Ready(api.ProtocolV2, graph, false, nil)).ToNot(BeNil())
+}
+
+func TestReadyGrpcReusesConnection(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	healthServer := health.NewServer()
+	node := startGrpcModel(t, func(s *grpc.Server) { healthpb.RegisterHealthServer(s, healthServer) })
+	h := newHealthClient()
+
+	g.Expect(readyNode(api.ProtocolSeldon, node, true, nil, h, DefaultReadyTimeout)).To(BeNil())
+	g.Expect(h.conns).To(HaveLen(1))
+	key := grpcConnKey{address: endpointAddress(node)}
+	conn := h.conns[key]
+	g.Expect(readyNode(api.ProtocolSeldon, node, true, nil, h, DefaultReadyTimeout)).To(BeNil())
+	g.Expect(h.conns).To(HaveKeyWithValue(key, BeIdenticalTo(conn)))
+
+	// Connections to endpoints still in the graph are kept
+	h.retainGrpc(node, nil)
+	g.Expect(h.conns).To(HaveKeyWithValue(key, BeIdenticalTo(conn)))
+
+	// and those to nodes removed by a reload are closed
+	model := v1.MODEL
+	h.retainGrpc(&v1.PredictiveUnit{Name: "other", Type: &model}, nil)
+	g.Expect(h.conns).To(BeEmpty())
+	g.Expect(conn.GetState()).To(Equal(connectivity.Shutdown))
+
+	g.Expect(readyNode(api.ProtocolSeldon, node, true, nil, h, DefaultReadyTimeout)).To(BeNil())
+	conn = h.conns[key]
+	h.close()
+	g.Expect(h.conns).To(BeEmpty())
+	g.Expect(conn.GetState()).To(Equal(connectivity.Shutdown))
+}
+
+func TestReadyHealthReusesClient(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	var conns int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	addr := server.Listener.Addr().(*net.TCPAddr)
+	model := v1.MODEL
+	node := &v1.PredictiveUnit{Name: "model", Type: &model, Endpoint: &v1.Endpoint{ServiceHost: addr.IP.String(), ServicePort: int32(addr.Port), Type: v1.REST}}
+	for i := 0; i < 5; i++ {
+		g.Expect(Ready(api.ProtocolSeldon, node, true, nil)).To(BeNil())
+	}
+	g.Expect(atomic.LoadInt32(&conns)).To(Equal(int32(1)))
+
+	h := newHealthClient()
+	first := &tls.Config{}
+	g.Expect(h.client(nil)).To(BeIdenticalTo(h.plain))
+	client := h.client(first)
+	g.Expect(h.client(first)).To(BeIdenticalTo(client))
+	g.Expect(h.client(&tls.Config{})).ToNot(BeIdenticalTo(client))
+}
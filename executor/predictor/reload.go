@@ -0,0 +1,161 @@
+package predictor
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/go-logr/logr"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	"github.com/seldonio/seldon-core/operator/client/machinelearning.seldon.io/v1/clientset/versioned"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const watchRetryInterval = 5 * time.Second
+
+// ValidatePredictor checks the predictor's graph as the webhook would and that the executor can apply its
+// loggers' redaction rules.
+func ValidatePredictor(predictor *v1.PredictorSpec) error {
+	if err := predictor.ValidateGraph(); err != nil {
+		return err
+	}
+	return CheckRedaction(&predictor.Graph)
+}
+
+// ReloadPredictor swaps predictor into the store. Endpoints missing from the new graph are copied from the
+// nodes of the same name in the current graph, as the executor can only route to containers that already exist.
+// Invalid predictors are rejected, keeping the current one.
+func ReloadPredictor(store *PredictorStore, predictor *v1.PredictorSpec, log logr.Logger) error {
+	current := store.Get()
+	if current != nil && predictor.Name != current.Name {
+		return fmt.Errorf("predictor name changed from %s to %s", current.Name, predictor.Name)
+	}
+	if err := ValidatePredictor(predictor); err != nil {
+		return fmt.Errorf("invalid predictor %s: %w", predictor.Name, err)
+	}
+	if current != nil {
+		mergeEndpoints(&predictor.Graph, &current.Graph)
+	}
+	store.Set(predictor)
+	log.Info("Reloaded predictor", "predictor", predictor.Name)
+	return nil
+}
+
+func mergeEndpoints(node *v1.PredictiveUnit, current *v1.PredictiveUnit) {
+	if node.Endpoint == nil || node.Endpoint.ServiceHost == "" {
+		if existing := v1.GetPredictiveUnit(current, node.Name); existing != nil && existing.Endpoint != nil {
+			endpoint := *existing.Endpoint
+			node.Endpoint = &endpoint
+		}
+	}
+	for i := range node.Children {
+		mergeEndpoints(&node.Children[i], current)
+	}
+}
+
+// WatchPredictorFile reloads the predictor from filename whenever it changes until stop is closed.
+func WatchPredictorFile(store *PredictorStore, predictorName string, filename string, stop <-chan struct{}, log logr.Logger) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+	// Watch the directory as editors and configmap mounts replace the file rather than writing to it
+	if err := watcher.Add(filepath.Dir(filename)); err != nil {
+		return err
+	}
+	log.Info("Watching predictor file", "file", filename)
+	for {
+		select {
+		case <-stop:
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if event.Op == fsnotify.Chmod {
+				continue
+			}
+			predictor, err := getPredictorFromFile(predictorName, filename)
+			if err != nil {
+				log.Error(err, "Failed to load predictor, keeping current graph", "file", filename)
+				continue
+			}
+			if err := ReloadPredictor(store, predictor, log); err != nil {
+				log.Error(err, "Failed to reload predictor")
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			log.Error(err, "Predictor file watch error")
+		}
+	}
+}
+
+// WatchSeldonDeployment reloads the predictor from the live SeldonDeployment until stop is closed.
+// An empty configPath uses the in-cluster config.
+func WatchSeldonDeployment(store *PredictorStore, predictorName string, sdepName string, namespace string, configPath string, stop <-chan struct{}, log logr.Logger) error {
+	config, err := clientcmd.BuildConfigFromFlags("", configPath)
+	if err != nil {
+		return err
+	}
+	clientset, err := versioned.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-stop
+		cancel()
+	}()
+
+	log.Info("Watching SeldonDeployment", "name", sdepName, "namespace", namespace)
+	for {
+		w, err := clientset.MachinelearningV1().SeldonDeployments(namespace).Watch(ctx, metav1.ListOptions{
+			FieldSelector: "metadata.name=" + sdepName,
+		})
+		if err != nil {
+			log.Error(err, "Failed to watch SeldonDeployment", "name", sdepName)
+		} else {
+			for event := range w.ResultChan() {
+				if event.Type != watch.Added && event.Type != watch.Modified {
+					continue
+				}
+				sdep, ok := event.Object.(*v1.SeldonDeployment)
+				if !ok {
+					continue
+				}
+				predictor := findPredictor(sdep, predictorName)
+				if predictor == nil {
+					log.Info("Predictor not found in SeldonDeployment, keeping current graph", "predictor", predictorName)
+					continue
+				}
+				if err := ReloadPredictor(store, predictor, log); err != nil {
+					log.Error(err, "Failed to reload predictor")
+				}
+			}
+			w.Stop()
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(watchRetryInterval):
+		}
+	}
+}
+
+func findPredictor(sdep *v1.SeldonDeployment, predictorName string) *v1.PredictorSpec {
+	for i := range sdep.Spec.Predictors {
+		if sdep.Spec.Predictors[i].Name == predictorName {
+			predictor := sdep.Spec.Predictors[i].DeepCopy()
+			return predictor
+		}
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package predictor
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const testSdepYaml = `apiVersion: machinelearning.seldon.io/v1
+kind: SeldonDeployment
+metadata:
+  name: mymodel
+spec:
+  predictors:
+  - name: p1
+    graph:
+      name: model
+      type: MODEL
+      parameters:
+      - name: threshold
+        type: FLOAT
+        value: "%s"
+`
+
+func TestReloadPredictorMergesEndpoints(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	model := v1.MODEL
+	store := NewPredictorStore(&v1.PredictorSpec{
+		Name: "p1",
+		Graph: v1.PredictiveUnit{
+			Name: "model",
+			Type: &model,
+			Endpoint: &v1.Endpoint{
+				ServiceHost: "model-host",
+				ServicePort: 9000,
+			},
+		},
+	})
+	previous := store.Get()
+
+	err := ReloadPredictor(store, &v1.PredictorSpec{
+		Name: "p1",
+		Graph: v1.PredictiveUnit{
+			Name:       "model",
+			Type:       &model,
+			Parameters: []v1.Parameter{{Name: "threshold", Type: v1.FLOAT, Value: "0.5"}},
+		},
+	}, logf.Log)
+	g.Expect(err).To(BeNil())
+	g.Expect(store.Get().Graph.Endpoint.ServiceHost).To(Equal("model-host"))
+	g.Expect(store.Get().Graph.Parameters).To(HaveLen(1))
+	// Requests which took a snapshot keep the graph they started with
+	g.Expect(previous.Graph.Parameters).To(BeEmpty())
+
+	err = ReloadPredictor(store, &v1.PredictorSpec{Name: "p2"}, logf.Log)
+	g.Expect(err).ToNot(BeNil())
+	g.Expect(store.Get().Name).To(Equal("p1"))
+}
+
+func TestReloadPredictorRejectsInvalid(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	model := v1.MODEL
+	store := NewPredictorStore(&v1.PredictorSpec{
+		Name: "p1",
+		Graph: v1.PredictiveUnit{
+			Name: "model",
+			Type: &model,
+			Endpoint: &v1.Endpoint{
+				ServiceHost: "model-host",
+				ServicePort: 9000,
+			},
+		},
+	})
+	current := store.Get()
+
+	err := ReloadPredictor(store, &v1.PredictorSpec{
+		Name: "p1",
+		Graph: v1.PredictiveUnit{
+			Name:   "model",
+			Type:   &model,
+			Logger: &v1.Logger{Mode: v1.LogAll, Sampling: &v1.LoggerSampling{Rate: "2"}},
+		},
+	}, logf.Log)
+	g.Expect(err).ToNot(BeNil())
+	g.Expect(store.Get()).To(BeIdenticalTo(current))
+
+	err = ReloadPredictor(store, &v1.PredictorSpec{
+		Name: "p1",
+		Graph: v1.PredictiveUnit{
+			Name:   "model",
+			Type:   &model,
+			Logger: &v1.Logger{Mode: v1.LogAll, Redact: []v1.RedactionRule{{Path: "$.jsonData.ssn", Action: v1.RedactHash}}},
+		},
+	}, logf.Log)
+	g.Expect(err).ToNot(BeNil())
+	g.Expect(store.Get()).To(BeIdenticalTo(current))
+
+	err = ReloadPredictor(store, &v1.PredictorSpec{
+		Name: "p1",
+		Graph: v1.PredictiveUnit{
+			Name:     "model",
+			Type:     &model,
+			Children: []v1.PredictiveUnit{{Name: "model", Type: &model}},
+		},
+	}, logf.Log)
+	g.Expect(err).ToNot(BeNil())
+	g.Expect(store.Get()).To(BeIdenticalTo(current))
+}
+
+func TestWatchPredictorFile(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	dir, err := ioutil.TempDir("", "seldon-predictor")
+	g.Expect(err).To(BeNil())
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "model.yaml")
+	g.Expect(ioutil.WriteFile(filename, []byte(fmt.Sprintf(testSdepYaml, "0.1")), 0600)).To(BeNil())
+
+	predictor, err := GetPredictor("p1", filename, "mymodel", "default", nil)
+	g.Expect(err).To(BeNil())
+	store := NewPredictorStore(predictor)
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go WatchPredictorFile(store, "p1", filename, stop, logf.Log)
+
+	g.Eventually(func() string {
+		// Rewrite until the watcher has started and picked the change up
+		ioutil.WriteFile(filename, []byte(fmt.Sprintf(testSdepYaml, "0.9")), 0600)
+		return store.Get().Graph.Parameters[0].Value
+	}, 5*time.Second, 100*time.Millisecond).Should(Equal("0.9"))
+}
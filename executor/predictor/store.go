@@ -0,0 +1,28 @@
+package predictor
+
+import (
+	"sync/atomic"
+
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+)
+
+// PredictorStore holds the PredictorSpec being served. The spec can be swapped while serving:
+// callers take a snapshot with Get at the start of a request so in-flight requests finish on the graph
+// they started with.
+type PredictorStore struct {
+	value atomic.Value
+}
+
+func NewPredictorStore(predictor *v1.PredictorSpec) *PredictorStore {
+	store := &PredictorStore{}
+	store.Set(predictor)
+	return store
+}
+
+func (s *PredictorStore) Get() *v1.PredictorSpec {
+	return s.value.Load().(*v1.PredictorSpec)
+}
+
+func (s *PredictorStore) Set(predictor *v1.PredictorSpec) {
+	s.value.Store(predictor)
+}
@@ -0,0 +1,58 @@
+package predictor
+
+import (
+	"context"
+
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	"github.com/seldonio/seldon-core/executor/api/tracing"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Attributes of the span of a graph node.
+const (
+	AttributeNodeName           = "seldon.node.name"
+	AttributeNodeType           = "seldon.node.type"
+	AttributeNodeImplementation = "seldon.node.implementation"
+	AttributeNodeRoute          = "seldon.node.route"
+	AttributeRequestSize        = "seldon.request.size"
+	AttributeResponseSize       = "seldon.response.size"
+)
+
+// startNodeSpan starts the span of a graph node. The payload is only serialised to find its size when the span is
+// sampled.
+func startNodeSpan(ctx context.Context, node *v1.PredictiveUnit, msg payload.SeldonPayload) (context.Context, trace.Span) {
+	attrs := []attribute.KeyValue{attribute.String(AttributeNodeName, node.Name)}
+	if node.Type != nil {
+		attrs = append(attrs, attribute.String(AttributeNodeType, string(*node.Type)))
+	}
+	if node.Implementation != nil {
+		attrs = append(attrs, attribute.String(AttributeNodeImplementation, string(*node.Implementation)))
+	}
+	ctx, span := tracing.StartSpan(ctx, node.Name, attrs...)
+	if span.IsRecording() {
+		if size, ok := payloadSize(msg); ok {
+			span.SetAttributes(attribute.Int(AttributeRequestSize, size))
+		}
+	}
+	return ctx, span
+}
+
+// endNodeSpan adds the route taken by the node and the size of its response to the span and ends it.
+func (p *PredictorProcess) endNodeSpan(span trace.Span, node *v1.PredictiveUnit, msg payload.SeldonPayload, err error) {
+	if span.IsRecording() {
+		p.RoutingMutex.RLock()
+		route, ok := p.Routing[node.Name]
+		p.RoutingMutex.RUnlock()
+		if ok {
+			span.SetAttributes(attribute.Int64(AttributeNodeRoute, int64(route)))
+		}
+		if err == nil {
+			if size, ok := payloadSize(msg); ok {
+				span.SetAttributes(attribute.Int(AttributeResponseSize, size))
+			}
+		}
+	}
+	tracing.EndSpan(span, err)
+}
@@ -0,0 +1,77 @@
+package predictor
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/seldonio/seldon-core/executor/api/tracing"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func spanAttributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func TestPredictNodeSpans(t *testing.T) {
+	g := NewGomegaWithT(t)
+	recorder := tracetest.NewSpanRecorder()
+	tracing.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer tracing.SetTracerProvider(nil)
+
+	model := v1.MODEL
+	router := v1.ROUTER
+	graph := &v1.PredictiveUnit{
+		Name: "router",
+		Type: &router,
+		Endpoint: &v1.Endpoint{
+			ServiceHost: "foo",
+			ServicePort: 9000,
+			Type:        v1.REST,
+		},
+		Children: []v1.PredictiveUnit{
+			{
+				Name: "a",
+				Type: &model,
+				Endpoint: &v1.Endpoint{
+					ServiceHost: "foo2",
+					ServicePort: 9001,
+					Type:        v1.REST,
+				},
+			},
+			{
+				Name: "b",
+				Type: &model,
+				Endpoint: &v1.Endpoint{
+					ServiceHost: "foo3",
+					ServicePort: 9002,
+					Type:        v1.REST,
+				},
+			},
+		},
+	}
+
+	_, err := createPredictorProcessWithRoute(t, 1).Predict(graph, createPredictPayload(g))
+	g.Expect(err).Should(BeNil())
+
+	spans := recorder.Ended()
+	g.Expect(spans).To(HaveLen(2))
+	child, parent := spans[0], spans[1]
+	g.Expect(child.Name()).To(Equal("b"))
+	g.Expect(parent.Name()).To(Equal("router"))
+	g.Expect(child.Parent().SpanID()).To(Equal(parent.SpanContext().SpanID()))
+
+	attrs := spanAttributes(parent)
+	g.Expect(attrs[AttributeNodeName].AsString()).To(Equal("router"))
+	g.Expect(attrs[AttributeNodeType].AsString()).To(Equal(string(v1.ROUTER)))
+	g.Expect(attrs[AttributeNodeRoute].AsInt64()).To(Equal(int64(1)))
+	g.Expect(attrs[AttributeRequestSize].AsInt64()).To(BeNumerically(">", 0))
+	g.Expect(attrs[AttributeResponseSize].AsInt64()).To(BeNumerically(">", 0))
+	g.Expect(spanAttributes(child)[AttributeNodeType].AsString()).To(Equal(string(v1.MODEL)))
+}
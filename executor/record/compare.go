@@ -0,0 +1,121 @@
+package record
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// DefaultIgnorePaths are response fields expected to change between deployments: the images of the graph
+// containers, the v2 model version and the v2 response id, which is generated if the request has none.
+var DefaultIgnorePaths = []string{"$.meta.requestPath", "$.model_version", "$.id"}
+
+// CompareOptions sets how replayed responses are compared with the recorded ones. Numbers match if they differ by
+// at most AbsTolerance + RelTolerance * the larger magnitude.
+type CompareOptions struct {
+	AbsTolerance float64
+	RelTolerance float64
+	// IgnorePaths are fields, e.g. $.meta.requestPath, not compared along with everything below them.
+	IgnorePaths []string
+}
+
+// Mismatch is a field of a replayed response which differs from the recorded response.
+type Mismatch struct {
+	Path     string `json:"path"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+}
+
+func (o CompareOptions) ignored(path string) bool {
+	for _, ignore := range o.IgnorePaths {
+		if path == ignore || strings.HasPrefix(path, ignore+".") || strings.HasPrefix(path, ignore+"[") {
+			return true
+		}
+	}
+	return false
+}
+
+func (o CompareOptions) numbersMatch(expected float64, actual float64) bool {
+	if expected == actual {
+		return true
+	}
+	tolerance := o.AbsTolerance + o.RelTolerance*math.Max(math.Abs(expected), math.Abs(actual))
+	return math.Abs(expected-actual) <= tolerance
+}
+
+func describe(v interface{}) string {
+	if v == nil {
+		return "null"
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// Compare returns the differences between the expected and actual response bodies. JSON bodies are compared field
+// by field and any others byte for byte.
+func Compare(expected []byte, actual []byte, opts CompareOptions) []Mismatch {
+	var expectedDoc, actualDoc interface{}
+	if json.Unmarshal(expected, &expectedDoc) != nil || json.Unmarshal(actual, &actualDoc) != nil {
+		if bytes.Equal(expected, actual) {
+			return nil
+		}
+		return []Mismatch{{Path: "$", Expected: string(expected), Actual: string(actual)}}
+	}
+	var mismatches []Mismatch
+	compareValues("$", expectedDoc, actualDoc, opts, &mismatches)
+	return mismatches
+}
+
+func compareValues(path string, expected interface{}, actual interface{}, opts CompareOptions, mismatches *[]Mismatch) {
+	if opts.ignored(path) {
+		return
+	}
+	mismatch := func() {
+		*mismatches = append(*mismatches, Mismatch{Path: path, Expected: describe(expected), Actual: describe(actual)})
+	}
+	switch e := expected.(type) {
+	case map[string]interface{}:
+		a, ok := actual.(map[string]interface{})
+		if !ok {
+			mismatch()
+			return
+		}
+		keys := make([]string, 0, len(e)+len(a))
+		for key := range e {
+			keys = append(keys, key)
+		}
+		for key := range a {
+			if _, ok := e[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			compareValues(path+"."+key, e[key], a[key], opts, mismatches)
+		}
+	case []interface{}:
+		a, ok := actual.([]interface{})
+		if !ok || len(a) != len(e) {
+			mismatch()
+			return
+		}
+		for i := range e {
+			compareValues(fmt.Sprintf("%s[%d]", path, i), e[i], a[i], opts, mismatches)
+		}
+	case float64:
+		a, ok := actual.(float64)
+		if !ok || !opts.numbersMatch(e, a) {
+			mismatch()
+		}
+	default:
+		if expected != actual {
+			mismatch()
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package record
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestCompareJSON(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	expected := []byte(`{"data":{"names":["a","b"],"ndarray":[[0.1,0.9]]},"meta":{"puid":"1","requestPath":{"model":"image:1"}}}`)
+	actual := []byte(`{"data":{"names":["a","b"],"ndarray":[[0.1000001,0.9]]},"meta":{"puid":"1","requestPath":{"model":"image:2"}}}`)
+	opts := CompareOptions{RelTolerance: 1e-5, IgnorePaths: DefaultIgnorePaths}
+	g.Expect(Compare(expected, actual, opts)).To(BeEmpty())
+
+	opts.RelTolerance = 0
+	mismatches := Compare(expected, actual, opts)
+	g.Expect(mismatches).To(Equal([]Mismatch{{Path: "$.data.ndarray[0][0]", Expected: "0.1", Actual: "0.1000001"}}))
+
+	opts.AbsTolerance = 1e-3
+	g.Expect(Compare(expected, actual, opts)).To(BeEmpty())
+}
+
+func TestCompareStructure(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	opts := CompareOptions{}
+	mismatches := Compare([]byte(`{"a":[1,2],"b":"x","c":true}`), []byte(`{"a":[1],"b":"y","d":1}`), opts)
+	g.Expect(mismatches).To(Equal([]Mismatch{
+		{Path: "$.a", Expected: "[1,2]", Actual: "[1]"},
+		{Path: "$.b", Expected: `"x"`, Actual: `"y"`},
+		{Path: "$.c", Expected: "true", Actual: "null"},
+		{Path: "$.d", Expected: "null", Actual: "1"},
+	}))
+
+	g.Expect(Compare([]byte("binary"), []byte("binary"), opts)).To(BeEmpty())
+	g.Expect(Compare([]byte("binary"), []byte(`{"a":1}`), opts)).To(HaveLen(1))
+}
@@ -0,0 +1,103 @@
+package record
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// maxLineBytes bounds the size of a recorded exchange when reading a recording.
+const maxLineBytes = 64 * 1024 * 1024
+
+// Exchange is a request received by the executor and the response it returned, written as one JSON line of a
+// recording. JSON bodies are kept as JSON so recordings can be read and edited, other bodies as base64. Gzipped
+// bodies are kept decompressed, with the request compressed again when it is replayed.
+type Exchange struct {
+	Time            time.Time       `json:"time"`
+	Puid            string          `json:"puid,omitempty"`
+	Method          string          `json:"method"`
+	Path            string          `json:"path"`
+	ContentType     string          `json:"contentType,omitempty"`
+	ContentEncoding string          `json:"contentEncoding,omitempty"`
+	Request         json.RawMessage `json:"request,omitempty"`
+	RequestBinary   []byte          `json:"requestBinary,omitempty"`
+	Status          int             `json:"status"`
+	Response        json.RawMessage `json:"response,omitempty"`
+	ResponseBinary  []byte          `json:"responseBinary,omitempty"`
+	LatencyMs       float64         `json:"latencyMs"`
+}
+
+func splitBody(body []byte) (json.RawMessage, []byte) {
+	if len(body) == 0 {
+		return nil, nil
+	}
+	if json.Valid(body) {
+		return json.RawMessage(body), nil
+	}
+	return nil, body
+}
+
+func (e *Exchange) SetRequestBody(body []byte) {
+	e.Request, e.RequestBinary = splitBody(body)
+}
+
+func (e *Exchange) SetResponseBody(body []byte) {
+	e.Response, e.ResponseBinary = splitBody(body)
+}
+
+func (e *Exchange) RequestBody() []byte {
+	if e.Request != nil {
+		return e.Request
+	}
+	return e.RequestBinary
+}
+
+func (e *Exchange) ResponseBody() []byte {
+	if e.Response != nil {
+		return e.Response
+	}
+	return e.ResponseBinary
+}
+
+// encodeBody compresses a request body again if it was gzipped when recorded.
+func encodeBody(body []byte, contentEncoding string) ([]byte, error) {
+	if contentEncoding != "gzip" {
+		return body, nil
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(body); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ReadExchanges reads the exchanges of a recording.
+func ReadExchanges(r io.Reader) ([]*Exchange, error) {
+	var exchanges []*Exchange
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineBytes)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		exchange := &Exchange{}
+		if err := json.Unmarshal(scanner.Bytes(), exchange); err != nil {
+			return nil, fmt.Errorf("while reading recorded exchange on line %d: %w", line, err)
+		}
+		exchanges = append(exchanges, exchange)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return exchanges, nil
+}
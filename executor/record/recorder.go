@@ -0,0 +1,183 @@
+package record
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	payloadLogger "github.com/seldonio/seldon-core/executor/logger"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Recorder appends a sample of the requests received by the executor and their responses to a file, in the
+// format read by the replay command.
+type Recorder struct {
+	sync.Mutex
+	Log logr.Logger
+	// Rate is the fraction of requests recorded, chosen by PUID as payload logging samples them.
+	Rate float64
+	// Rules redact the JSON requests and responses before they are written. Redacted requests are replayed
+	// with the redacted values. Exchanges with other bodies aren't recorded when there are rules as they can't
+	// be redacted.
+	Rules []v1.RedactionRule
+	// MaxBytes stops the recording once the file reaches it. 0 doesn't limit the file.
+	MaxBytes int64
+	file     *os.File
+	written  int64
+}
+
+func NewRecorder(path string, rate float64, rules []v1.RedactionRule, maxBytes int64) (*Recorder, error) {
+	if err := payloadLogger.CheckRedactionRules(rules); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &Recorder{
+		Log:      logf.Log.WithName("Recorder"),
+		Rate:     rate,
+		Rules:    rules,
+		MaxBytes: maxBytes,
+		file:     file,
+		written:  info.Size(),
+	}, nil
+}
+
+func (r *Recorder) redact(body json.RawMessage, binary []byte) (json.RawMessage, error) {
+	if len(r.Rules) == 0 {
+		return body, nil
+	}
+	if binary != nil {
+		return nil, fmt.Errorf("can't redact a body that isn't JSON")
+	}
+	if body == nil {
+		return nil, nil
+	}
+	return payloadLogger.RedactJSON(body, r.Rules)
+}
+
+// Record writes the exchange to the recording after redacting it.
+func (r *Recorder) Record(exchange *Exchange) error {
+	var err error
+	if exchange.Request, err = r.redact(exchange.Request, exchange.RequestBinary); err != nil {
+		return err
+	}
+	if exchange.Response, err = r.redact(exchange.Response, exchange.ResponseBinary); err != nil {
+		return err
+	}
+	line, err := json.Marshal(exchange)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	r.Lock()
+	defer r.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	if r.MaxBytes > 0 && r.written+int64(len(line)) > r.MaxBytes {
+		r.Log.Info("Recording reached its maximum size so stopping", "maxBytes", r.MaxBytes)
+		err = r.file.Close()
+		r.file = nil
+		return err
+	}
+	n, err := r.file.Write(line)
+	r.written += int64(n)
+	return err
+}
+
+func (r *Recorder) Close() error {
+	r.Lock()
+	defer r.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+// responseRecorder keeps a copy of the status and body written to the client.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *responseRecorder) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+// recordBodies records the exchange with its bodies decompressed so they can be redacted.
+func (r *Recorder) recordBodies(exchange *Exchange, request []byte, response []byte, responseEncoding string) error {
+	request, err := payload.DecompressBytes(request, exchange.ContentEncoding)
+	if err != nil {
+		return err
+	}
+	response, err = payload.DecompressBytes(response, responseEncoding)
+	if err != nil {
+		return err
+	}
+	exchange.SetRequestBody(request)
+	exchange.SetResponseBody(response)
+	return r.Record(exchange)
+}
+
+// Wrap records the sampled requests handled by next along with their responses.
+func (r *Recorder) Wrap(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		puid := req.Header.Get(payload.SeldonPUIDHeader)
+		if !payloadLogger.Sampled(puid, r.Rate) {
+			next(w, req)
+			return
+		}
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			next(w, req)
+			return
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		start := time.Now()
+		res := &responseRecorder{ResponseWriter: w}
+		next(res, req)
+
+		exchange := &Exchange{
+			Time:            start.UTC(),
+			Puid:            puid,
+			Method:          req.Method,
+			Path:            req.URL.RequestURI(),
+			ContentType:     req.Header.Get("Content-Type"),
+			ContentEncoding: req.Header.Get("Content-Encoding"),
+			Status:          res.status,
+			LatencyMs:       float64(time.Since(start).Microseconds()) / 1000,
+		}
+		if err := r.recordBodies(exchange, body, res.body.Bytes(), res.Header().Get("Content-Encoding")); err != nil {
+			r.Log.Error(err, "Failed to record request", "puid", puid)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package record
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+	v1 "github.com/seldonio/seldon-core/operator/apis/machinelearning.seldon.io/v1"
+)
+
+func echoHandler(w http.ResponseWriter, req *http.Request) {
+	body, _ := ioutil.ReadAll(req.Body)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+func sendRequest(handler http.HandlerFunc, puid string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/api/v1.0/predictions?x=1", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(payload.SeldonPUIDHeader, puid)
+	res := httptest.NewRecorder()
+	handler(res, req)
+	return res
+}
+
+func readRecording(g *WithT, path string) []*Exchange {
+	f, err := os.Open(path)
+	g.Expect(err).To(BeNil())
+	defer f.Close()
+	exchanges, err := ReadExchanges(f)
+	g.Expect(err).To(BeNil())
+	return exchanges
+}
+
+func TestRecorderWrap(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	path := filepath.Join(t.TempDir(), "recording.jsonl")
+	recorder, err := NewRecorder(path, 1, []v1.RedactionRule{{Path: "$.jsonData.ssn", Action: v1.RedactDrop}}, 0)
+	g.Expect(err).To(BeNil())
+	defer recorder.Close()
+	handler := recorder.Wrap(echoHandler)
+
+	res := sendRequest(handler, "p1", `{"jsonData":{"ssn":"123","age":30}}`)
+	g.Expect(res.Code).To(Equal(http.StatusOK))
+	g.Expect(res.Body.String()).To(Equal(`{"jsonData":{"ssn":"123","age":30}}`))
+
+	exchanges := readRecording(g, path)
+	g.Expect(exchanges).To(HaveLen(1))
+	exchange := exchanges[0]
+	g.Expect(exchange.Puid).To(Equal("p1"))
+	g.Expect(exchange.Method).To(Equal("POST"))
+	g.Expect(exchange.Path).To(Equal("/api/v1.0/predictions?x=1"))
+	g.Expect(exchange.ContentType).To(Equal("application/json"))
+	g.Expect(exchange.Status).To(Equal(http.StatusOK))
+	g.Expect(string(exchange.RequestBody())).To(Equal(`{"jsonData":{"age":30}}`))
+	g.Expect(string(exchange.ResponseBody())).To(Equal(`{"jsonData":{"age":30}}`))
+	g.Expect(exchange.LatencyMs).To(BeNumerically(">", 0))
+}
+
+func TestRecorderBinaryBodies(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	path := filepath.Join(t.TempDir(), "recording.jsonl")
+	recorder, err := NewRecorder(path, 1, nil, 0)
+	g.Expect(err).To(BeNil())
+	defer recorder.Close()
+	sendRequest(recorder.Wrap(echoHandler), "p1", "not json")
+
+	exchanges := readRecording(g, path)
+	g.Expect(exchanges).To(HaveLen(1))
+	g.Expect(exchanges[0].Request).To(BeNil())
+	g.Expect(string(exchanges[0].RequestBody())).To(Equal("not json"))
+	g.Expect(string(exchanges[0].ResponseBody())).To(Equal("not json"))
+
+	// Bodies that can't be redacted aren't recorded when there are rules
+	redactedPath := filepath.Join(t.TempDir(), "recording.jsonl")
+	redacted, err := NewRecorder(redactedPath, 1, []v1.RedactionRule{{Path: "$.jsonData.ssn", Action: v1.RedactDrop}}, 0)
+	g.Expect(err).To(BeNil())
+	defer redacted.Close()
+	res := sendRequest(redacted.Wrap(echoHandler), "p1", "not json")
+	g.Expect(res.Body.String()).To(Equal("not json"))
+	g.Expect(readRecording(g, redactedPath)).To(BeEmpty())
+}
+
+func TestRecorderGzipBodies(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	path := filepath.Join(t.TempDir(), "recording.jsonl")
+	recorder, err := NewRecorder(path, 1, []v1.RedactionRule{{Path: "$.jsonData.ssn", Action: v1.RedactDrop}}, 0)
+	g.Expect(err).To(BeNil())
+	defer recorder.Close()
+	// Echoes the compressed request body back compressed
+	handler := recorder.Wrap(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(body)
+	})
+
+	var body bytes.Buffer
+	zw := gzip.NewWriter(&body)
+	zw.Write([]byte(`{"jsonData":{"ssn":"123","age":30}}`))
+	g.Expect(zw.Close()).To(Succeed())
+	req := httptest.NewRequest("POST", "/api/v1.0/predictions", &body)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+	handler(httptest.NewRecorder(), req)
+
+	exchanges := readRecording(g, path)
+	g.Expect(exchanges).To(HaveLen(1))
+	g.Expect(exchanges[0].ContentEncoding).To(Equal("gzip"))
+	g.Expect(string(exchanges[0].Request)).To(Equal(`{"jsonData":{"age":30}}`))
+	g.Expect(string(exchanges[0].Response)).To(Equal(`{"jsonData":{"age":30}}`))
+}
+
+func TestRecorderSamplingAndLimit(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	path := filepath.Join(t.TempDir(), "recording.jsonl")
+	recorder, err := NewRecorder(path, 0, nil, 0)
+	g.Expect(err).To(BeNil())
+	defer recorder.Close()
+	sendRequest(recorder.Wrap(echoHandler), "p1", `{}`)
+	g.Expect(readRecording(g, path)).To(BeEmpty())
+
+	path = filepath.Join(t.TempDir(), "recording.jsonl")
+	recorder, err = NewRecorder(path, 1, nil, 300)
+	g.Expect(err).To(BeNil())
+	defer recorder.Close()
+	handler := recorder.Wrap(echoHandler)
+	for i := 0; i < 10; i++ {
+		res := sendRequest(handler, "p1", `{"data":{"ndarray":[1,2,3]}}`)
+		g.Expect(res.Code).To(Equal(http.StatusOK))
+	}
+	exchanges := readRecording(g, path)
+	g.Expect(len(exchanges)).To(BeNumerically(">", 0))
+	g.Expect(len(exchanges)).To(BeNumerically("<", 10))
+	info, err := os.Stat(path)
+	g.Expect(err).To(BeNil())
+	g.Expect(info.Size()).To(BeNumerically("<=", 300))
+}
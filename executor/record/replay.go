@@ -0,0 +1,239 @@
+package record
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/seldonio/seldon-core/executor/api/payload"
+)
+
+const (
+	DefaultReplayConcurrency = 4
+	DefaultReplayTimeout     = 30 * time.Second
+)
+
+// ReplayConfig sets where and how fast recorded requests are sent.
+type ReplayConfig struct {
+	// Target is the base URL of the executor, e.g. http://localhost:8000, the recorded paths are sent to.
+	Target string
+	// Rate is the number of requests sent per second. 0 sends them as fast as the workers allow.
+	Rate        float64
+	Concurrency int
+	Timeout     time.Duration
+	Compare     CompareOptions
+}
+
+// Result is the outcome of replaying one exchange.
+type Result struct {
+	Puid           string     `json:"puid,omitempty"`
+	Path           string     `json:"path"`
+	Status         int        `json:"status"`
+	RecordedStatus int        `json:"recordedStatus"`
+	LatencyMs      float64    `json:"latencyMs"`
+	RecordedMs     float64    `json:"recordedLatencyMs"`
+	Error          string     `json:"error,omitempty"`
+	Mismatches     []Mismatch `json:"mismatches,omitempty"`
+}
+
+func (r *Result) Matched() bool {
+	return r.Error == "" && r.Status == r.RecordedStatus && len(r.Mismatches) == 0
+}
+
+// LatencySummary summarises the latencies of a set of requests in milliseconds.
+type LatencySummary struct {
+	Mean float64 `json:"mean"`
+	P50  float64 `json:"p50"`
+	P95  float64 `json:"p95"`
+	P99  float64 `json:"p99"`
+}
+
+func summariseLatencies(latencies []float64) LatencySummary {
+	if len(latencies) == 0 {
+		return LatencySummary{}
+	}
+	sorted := append([]float64{}, latencies...)
+	sort.Float64s(sorted)
+	sum := 0.0
+	for _, l := range sorted {
+		sum += l
+	}
+	percentile := func(p float64) float64 {
+		return sorted[int(math.Ceil(p*float64(len(sorted))))-1]
+	}
+	return LatencySummary{Mean: sum / float64(len(sorted)), P50: percentile(0.5), P95: percentile(0.95), P99: percentile(0.99)}
+}
+
+// Report compares the replayed responses and latencies with the recorded ones. Results only holds the exchanges
+// which didn't match.
+type Report struct {
+	Total      int            `json:"total"`
+	Matched    int            `json:"matched"`
+	Mismatched int            `json:"mismatched"`
+	Failed     int            `json:"failed"`
+	Recorded   LatencySummary `json:"recordedLatencyMs"`
+	Replayed   LatencySummary `json:"replayedLatencyMs"`
+	Results    []Result       `json:"results,omitempty"`
+}
+
+// Passed returns whether every replayed response matched its recording.
+func (r *Report) Passed() bool {
+	return r.Matched == r.Total
+}
+
+// Write prints the report, listing at most maxResults exchanges which didn't match.
+func (r *Report) Write(w io.Writer, maxResults int) {
+	fmt.Fprintf(w, "Replayed %d requests: %d matched, %d mismatched, %d failed\n", r.Total, r.Matched, r.Mismatched, r.Failed)
+	fmt.Fprintf(w, "Latency ms   %10s %10s %10s %10s\n", "mean", "p50", "p95", "p99")
+	fmt.Fprintf(w, "  recorded   %10.2f %10.2f %10.2f %10.2f\n", r.Recorded.Mean, r.Recorded.P50, r.Recorded.P95, r.Recorded.P99)
+	fmt.Fprintf(w, "  replayed   %10.2f %10.2f %10.2f %10.2f\n", r.Replayed.Mean, r.Replayed.P50, r.Replayed.P95, r.Replayed.P99)
+	fmt.Fprintf(w, "  delta      %+10.2f %+10.2f %+10.2f %+10.2f\n", r.Replayed.Mean-r.Recorded.Mean, r.Replayed.P50-r.Recorded.P50, r.Replayed.P95-r.Recorded.P95, r.Replayed.P99-r.Recorded.P99)
+	for i, result := range r.Results {
+		if i == maxResults {
+			fmt.Fprintf(w, "... %d more\n", len(r.Results)-maxResults)
+			break
+		}
+		fmt.Fprintf(w, "%s %s puid=%s latency=%.2fms (recorded %.2fms)\n", result.Path, describeStatus(result), result.Puid, result.LatencyMs, result.RecordedMs)
+		for _, m := range result.Mismatches {
+			fmt.Fprintf(w, "  %s: expected %s, got %s\n", m.Path, m.Expected, m.Actual)
+		}
+	}
+}
+
+func describeStatus(result Result) string {
+	switch {
+	case result.Error != "":
+		return "failed: " + result.Error
+	case result.Status != result.RecordedStatus:
+		return fmt.Sprintf("status %d, recorded %d", result.Status, result.RecordedStatus)
+	default:
+		return "mismatched"
+	}
+}
+
+// Replay sends the exchanges to the target and compares the responses with the recorded ones.
+func Replay(ctx context.Context, exchanges []*Exchange, config ReplayConfig) *Report {
+	concurrency := config.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultReplayConcurrency
+	}
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = DefaultReplayTimeout
+	}
+	client := &http.Client{Timeout: timeout}
+
+	var tick <-chan time.Time
+	if config.Rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / config.Rate))
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	results := make([]Result, len(exchanges))
+	jobs := make(chan int)
+	wg := sync.WaitGroup{}
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = replayExchange(ctx, client, config, exchanges[i])
+			}
+		}()
+	}
+	sent := 0
+send:
+	for i := range exchanges {
+		if tick != nil && i > 0 {
+			select {
+			case <-ctx.Done():
+				break send
+			case <-tick:
+			}
+		}
+		select {
+		case <-ctx.Done():
+			break send
+		case jobs <- i:
+			sent++
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	report := &Report{Total: sent}
+	var recorded, replayed []float64
+	for _, result := range results[:sent] {
+		switch {
+		case result.Error != "":
+			report.Failed++
+		case result.Matched():
+			report.Matched++
+		default:
+			report.Mismatched++
+		}
+		if !result.Matched() {
+			report.Results = append(report.Results, result)
+		}
+		if result.Error == "" {
+			recorded = append(recorded, result.RecordedMs)
+			replayed = append(replayed, result.LatencyMs)
+		}
+	}
+	report.Recorded = summariseLatencies(recorded)
+	report.Replayed = summariseLatencies(replayed)
+	return report
+}
+
+func replayExchange(ctx context.Context, client *http.Client, config ReplayConfig, exchange *Exchange) Result {
+	result := Result{Puid: exchange.Puid, Path: exchange.Path, RecordedStatus: exchange.Status, RecordedMs: exchange.LatencyMs}
+	url := strings.TrimSuffix(config.Target, "/") + exchange.Path
+	requestBody, err := encodeBody(exchange.RequestBody(), exchange.ContentEncoding)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	req, err := http.NewRequestWithContext(ctx, exchange.Method, url, bytes.NewReader(requestBody))
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if exchange.ContentType != "" {
+		req.Header.Set("Content-Type", exchange.ContentType)
+	}
+	if exchange.ContentEncoding != "" {
+		req.Header.Set("Content-Encoding", exchange.ContentEncoding)
+	}
+	// Sending the recorded PUID keeps it the same in the response
+	if exchange.Puid != "" {
+		req.Header.Set(payload.SeldonPUIDHeader, exchange.Puid)
+	}
+	start := time.Now()
+	res, err := client.Do(req)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	result.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+	if err == nil {
+		body, err = payload.DecompressBytes(body, res.Header.Get("Content-Encoding"))
+	}
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Status = res.StatusCode
+	result.Mismatches = Compare(exchange.ResponseBody(), body, config.Compare)
+	return result
+}
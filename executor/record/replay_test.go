@@ -0,0 +1,147 @@
+package record
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+)
+
+// scoringServer doubles the values sent to it and adds offset, replying with the PUID it was sent.
+func scoringServer(offset float64) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/api/v1.0/predictions" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var body struct {
+			Data struct {
+				Ndarray []float64 `json:"ndarray"`
+			} `json:"data"`
+		}
+		data, _ := ioutil.ReadAll(req.Body)
+		json.Unmarshal(data, &body)
+		for i, v := range body.Data.Ndarray {
+			body.Data.Ndarray[i] = 2*v + offset
+		}
+		res, _ := json.Marshal(map[string]interface{}{
+			"data": body.Data,
+			"meta": map[string]interface{}{"puid": req.Header.Get(payload.SeldonPUIDHeader)},
+		})
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(res)
+	}))
+}
+
+func recordedExchange(puid string, path string, request string, response string) *Exchange {
+	exchange := &Exchange{Puid: puid, Method: "POST", Path: path, ContentType: "application/json", Status: http.StatusOK, LatencyMs: 1}
+	exchange.SetRequestBody([]byte(request))
+	exchange.SetResponseBody([]byte(response))
+	return exchange
+}
+
+func testExchanges() []*Exchange {
+	return []*Exchange{
+		recordedExchange("a", "/api/v1.0/predictions", `{"data":{"ndarray":[1,2]}}`, `{"data":{"ndarray":[2,4]},"meta":{"puid":"a"}}`),
+		recordedExchange("b", "/api/v1.0/predictions", `{"data":{"ndarray":[0.5]}}`, `{"data":{"ndarray":[1]},"meta":{"puid":"b"}}`),
+	}
+}
+
+func TestReplayMatches(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	server := scoringServer(0)
+	defer server.Close()
+
+	report := Replay(context.Background(), testExchanges(), ReplayConfig{Target: server.URL})
+	g.Expect(report.Total).To(Equal(2))
+	g.Expect(report.Matched).To(Equal(2))
+	g.Expect(report.Passed()).To(BeTrue())
+	g.Expect(report.Results).To(BeEmpty())
+	g.Expect(report.Recorded.Mean).To(Equal(1.0))
+	g.Expect(report.Replayed.P99).To(BeNumerically(">", 0))
+}
+
+func TestReplayTolerance(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	server := scoringServer(1e-4)
+	defer server.Close()
+
+	report := Replay(context.Background(), testExchanges(), ReplayConfig{Target: server.URL})
+	g.Expect(report.Mismatched).To(Equal(2))
+	g.Expect(report.Results[0].Mismatches[0].Path).To(Equal("$.data.ndarray[0]"))
+
+	report = Replay(context.Background(), testExchanges(), ReplayConfig{Target: server.URL, Compare: CompareOptions{AbsTolerance: 1e-3}})
+	g.Expect(report.Passed()).To(BeTrue())
+
+	var out bytes.Buffer
+	report.Write(&out, 10)
+	g.Expect(out.String()).To(ContainSubstring("Replayed 2 requests: 2 matched, 0 mismatched, 0 failed"))
+}
+
+func TestReplayStatusAndFailures(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	server := scoringServer(0)
+	exchanges := append(testExchanges(), recordedExchange("c", "/unknown", `{}`, `{}`))
+	report := Replay(context.Background(), exchanges, ReplayConfig{Target: server.URL})
+	g.Expect(report.Matched).To(Equal(2))
+	g.Expect(report.Mismatched).To(Equal(1))
+	g.Expect(report.Results[0].Status).To(Equal(http.StatusNotFound))
+
+	var out bytes.Buffer
+	report.Write(&out, 10)
+	g.Expect(out.String()).To(ContainSubstring("/unknown status 404, recorded 200"))
+
+	server.Close()
+	report = Replay(context.Background(), testExchanges(), ReplayConfig{Target: server.URL, Timeout: time.Second})
+	g.Expect(report.Failed).To(Equal(2))
+	g.Expect(report.Passed()).To(BeFalse())
+}
+
+func TestReplayRate(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	server := scoringServer(0)
+	defer server.Close()
+
+	var exchanges []*Exchange
+	for i := 0; i < 5; i++ {
+		exchanges = append(exchanges, testExchanges()[0])
+	}
+	start := time.Now()
+	report := Replay(context.Background(), exchanges, ReplayConfig{Target: server.URL, Rate: 20})
+	g.Expect(report.Passed()).To(BeTrue())
+	// Four intervals of 50ms separate the five requests
+	g.Expect(time.Since(start)).To(BeNumerically(">=", 200*time.Millisecond))
+}
+
+func TestReplayGzipRequests(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	bodies := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		zr, err := gzip.NewReader(req.Body)
+		g.Expect(err).To(BeNil())
+		body, err := ioutil.ReadAll(zr)
+		g.Expect(err).To(BeNil())
+		bodies <- req.Header.Get("Content-Encoding") + " " + string(body)
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	exchange := recordedExchange("a", "/api/v1.0/predictions", `{"data":{"ndarray":[1]}}`, `{"data":{"ndarray":[1]}}`)
+	exchange.ContentEncoding = "gzip"
+	report := Replay(context.Background(), []*Exchange{exchange}, ReplayConfig{Target: server.URL})
+	g.Expect(report.Passed()).To(BeTrue())
+	g.Expect(<-bodies).To(Equal(`gzip {"data":{"ndarray":[1]}}`))
+}
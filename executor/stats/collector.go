@@ -0,0 +1,294 @@
+package stats
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/seldonio/seldon-core/executor/api/metric"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+)
+
+// DefaultWindow is how long the values of a feature are summarised before they start to be dropped.
+const DefaultWindow = time.Hour
+
+// Collector keeps streaming summaries of the input features of each model in the graph. The summaries are exposed as
+// prometheus metrics and as JSON, with drift scores if a reference profile is given. They cover the values of the
+// current window and the one before it, so older values drop out when a window ends.
+type Collector struct {
+	sync.RWMutex
+	DeploymentName string
+	PredictorName  string
+	// Reference is the profile drift is measured against, or nil to not measure drift.
+	Reference *Snapshot
+	// Window is the length of each window of summarised values, or 0 to summarise all values.
+	Window time.Duration
+	models map[string]*modelStats
+	now    func() time.Time
+
+	observations *prometheus.Desc
+	mean         *prometheus.Desc
+	stddev       *prometheus.Desc
+	min          *prometheus.Desc
+	max          *prometheus.Desc
+	quantile     *prometheus.Desc
+	category     *prometheus.Desc
+	drift        *prometheus.Desc
+}
+
+// modelStats holds the summaries of the features of a model for the current and previous windows. Each model is
+// locked on its own so requests to different models don't wait on each other.
+type modelStats struct {
+	sync.Mutex
+	start    time.Time
+	current  map[string]*featureStats
+	previous map[string]*featureStats
+}
+
+// rotate starts a new window if the current one has ended, dropping the previous window's values.
+func (m *modelStats) rotate(now time.Time, window time.Duration) {
+	if window <= 0 || now.Sub(m.start) < window {
+		return
+	}
+	if now.Sub(m.start) < 2*window {
+		m.previous = m.current
+		m.start = m.start.Add(window)
+	} else {
+		m.previous = nil
+		m.start = now
+	}
+	m.current = make(map[string]*featureStats)
+}
+
+// feature returns the current summaries of a feature, or nil if the model has too many features.
+func (m *modelStats) feature(name string) *featureStats {
+	s, ok := m.current[name]
+	if !ok {
+		if len(m.current) >= maxFeatures {
+			return nil
+		}
+		s = &featureStats{}
+		m.current[name] = s
+	}
+	return s
+}
+
+// merged returns the summaries of each feature over both windows.
+func (m *modelStats) merged() map[string]*featureStats {
+	merged := make(map[string]*featureStats, len(m.current))
+	for _, window := range []map[string]*featureStats{m.previous, m.current} {
+		for name, s := range window {
+			if _, ok := merged[name]; !ok {
+				merged[name] = &featureStats{}
+			}
+			merged[name].merge(s)
+		}
+	}
+	return merged
+}
+
+// NewCollector creates a collector and registers it with prometheus, replacing any collector created before.
+func NewCollector(deploymentName string, predictorName string, reference *Snapshot, window time.Duration) *Collector {
+	labelNames := []string{metric.DeploymentNameMetric, metric.PredictorNameMetric, metric.ModelNameMetric, metric.FeatureMetric}
+	desc := func(name string, help string, extraLabels ...string) *prometheus.Desc {
+		return prometheus.NewDesc(name, help, append(append([]string{}, labelNames...), extraLabels...), nil)
+	}
+	c := &Collector{
+		DeploymentName: deploymentName,
+		PredictorName:  predictorName,
+		Reference:      reference,
+		Window:         window,
+		models:         make(map[string]*modelStats),
+		now:            time.Now,
+		observations:   desc(metric.FeatureObservationsMetricName, "The number of values of an input feature in the summaries"),
+		mean:           desc(metric.FeatureMeanMetricName, "The mean of a numeric input feature"),
+		stddev:         desc(metric.FeatureStddevMetricName, "The standard deviation of a numeric input feature"),
+		min:            desc(metric.FeatureMinMetricName, "The minimum of a numeric input feature"),
+		max:            desc(metric.FeatureMaxMetricName, "The maximum of a numeric input feature"),
+		quantile:       desc(metric.FeatureQuantileMetricName, "The estimated quantiles of a numeric input feature", metric.QuantileMetric),
+		category:       desc(metric.FeatureCategoryMetricName, "The fraction of values of a categorical input feature in each category", metric.CategoryMetric),
+		drift:          desc(metric.FeatureDriftMetricName, "The drift of an input feature from the reference profile", metric.DriftMethodMetric),
+	}
+	if err := prometheus.Register(c); err != nil {
+		if e, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			prometheus.Unregister(e.ExistingCollector)
+			prometheus.Register(c)
+		}
+	}
+	return c
+}
+
+// LoadProfile reads a reference profile saved from the JSON summaries of a collector.
+func LoadProfile(path string) (*Snapshot, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("while reading feature profile: %w", err)
+	}
+	var profile Snapshot
+	if err := json.Unmarshal(data, &profile); err != nil {
+		return nil, fmt.Errorf("while parsing feature profile %s: %w", path, err)
+	}
+	return &profile, nil
+}
+
+// Observe adds the features of a request sent to the model to its summaries.
+func (c *Collector) Observe(modelName string, msg payload.SeldonPayload) error {
+	f, err := extractFeatures(msg)
+	if err != nil {
+		return err
+	}
+	if len(f.numeric) == 0 && len(f.categorical) == 0 {
+		return nil
+	}
+	model := c.model(modelName)
+	model.Lock()
+	defer model.Unlock()
+	model.rotate(c.now(), c.Window)
+	for name, values := range f.numeric {
+		s := model.feature(name)
+		if s == nil {
+			continue
+		}
+		if s.numeric == nil {
+			s.numeric = newNumericStats()
+		}
+		for _, v := range values {
+			s.numeric.add(v)
+		}
+	}
+	for name, values := range f.categorical {
+		s := model.feature(name)
+		if s == nil {
+			continue
+		}
+		if s.categorical == nil {
+			s.categorical = newCategoricalStats()
+		}
+		for _, v := range values {
+			s.categorical.add(v)
+		}
+	}
+	return nil
+}
+
+// model returns the summaries of a model, adding them if it has none.
+func (c *Collector) model(modelName string) *modelStats {
+	c.RLock()
+	model, ok := c.models[modelName]
+	c.RUnlock()
+	if ok {
+		return model
+	}
+	c.Lock()
+	defer c.Unlock()
+	if model, ok = c.models[modelName]; !ok {
+		model = &modelStats{start: c.now(), current: make(map[string]*featureStats)}
+		c.models[modelName] = model
+	}
+	return model
+}
+
+func (c *Collector) referenceFor(modelName string, featureName string) *FeatureSummary {
+	if c.Reference == nil {
+		return nil
+	}
+	if model, ok := c.Reference.Models[modelName]; ok && model != nil {
+		return model.Features[featureName]
+	}
+	return nil
+}
+
+// Snapshot returns the current summaries of the features of each model.
+func (c *Collector) Snapshot() *Snapshot {
+	c.RLock()
+	models := make(map[string]*modelStats, len(c.models))
+	for modelName, model := range c.models {
+		models[modelName] = model
+	}
+	c.RUnlock()
+	snapshot := &Snapshot{Models: make(map[string]*ModelSummary, len(models))}
+	for modelName, model := range models {
+		model.Lock()
+		model.rotate(c.now(), c.Window)
+		features := model.merged()
+		model.Unlock()
+		summaries := &ModelSummary{Features: make(map[string]*FeatureSummary, len(features))}
+		for featureName, s := range features {
+			summary := s.summary()
+			if reference := c.referenceFor(modelName, featureName); reference != nil {
+				drift := make(map[string]float64)
+				if s.numeric != nil {
+					if psi, ks, ok := numericDrift(reference.Numeric, s.numeric); ok {
+						drift[DriftPSI], drift[DriftKS] = psi, ks
+					}
+				}
+				if s.categorical != nil {
+					if psi, ok := categoricalDrift(reference.Categorical, s.categorical); ok {
+						// A feature with values of both kinds is scored by its larger shift
+						drift[DriftPSI] = math.Max(drift[DriftPSI], psi)
+					}
+				}
+				if len(drift) > 0 {
+					summary.Drift = drift
+				}
+			}
+			summaries.Features[featureName] = summary
+		}
+		snapshot.Models[modelName] = summaries
+	}
+	return snapshot
+}
+
+// ServeHTTP returns the snapshot of the summaries as JSON.
+func (c *Collector) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	data, err := json.Marshal(c.Snapshot())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{c.observations, c.mean, c.stddev, c.min, c.max, c.quantile, c.category, c.drift} {
+		ch <- desc
+	}
+}
+
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	for modelName, model := range c.Snapshot().Models {
+		for featureName, summary := range model.Features {
+			labels := []string{c.DeploymentName, c.PredictorName, modelName, featureName}
+			gauge := func(desc *prometheus.Desc, value float64, extraLabels ...string) {
+				ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, append(append([]string{}, labels...), extraLabels...)...)
+			}
+			var count int64
+			if n := summary.Numeric; n != nil && n.Count > 0 {
+				count += n.Count
+				gauge(c.mean, n.Mean)
+				gauge(c.stddev, math.Sqrt(n.Variance))
+				gauge(c.min, n.Min)
+				gauge(c.max, n.Max)
+				for q, v := range n.Quantiles {
+					gauge(c.quantile, v, q)
+				}
+			}
+			if cat := summary.Categorical; cat != nil && cat.Count > 0 {
+				count += cat.Count
+				for category, n := range cat.Counts {
+					gauge(c.category, float64(n)/float64(cat.Count), category)
+				}
+			}
+			for method, score := range summary.Drift {
+				gauge(c.drift, score, method)
+			}
+			ch <- prometheus.MustNewConstMetric(c.observations, prometheus.GaugeValue, float64(count), labels...)
+		}
+	}
+}
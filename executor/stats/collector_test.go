@@ -0,0 +1,145 @@
+package stats
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+)
+
+func ndarrayPayload(rows []string) payload.SeldonPayload {
+	return &payload.BytesPayload{
+		Msg:         []byte(`{"data":{"names":["x","colour"],"ndarray":[` + strings.Join(rows, ",") + `]}}`),
+		ContentType: payload.APPLICATION_TYPE_JSON,
+	}
+}
+
+func observeSample(g *WithT, c *Collector, r *rand.Rand, shift float64, colours []string) {
+	for i := 0; i < 20; i++ {
+		rows := make([]string, 50)
+		for j := range rows {
+			rows[j] = fmt.Sprintf(`[%f,"%s"]`, r.NormFloat64()+shift, colours[r.Intn(len(colours))])
+		}
+		g.Expect(c.Observe("model", ndarrayPayload(rows))).To(BeNil())
+	}
+}
+
+func TestCollectorSnapshot(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	c := NewCollector("dep", "pred", nil, DefaultWindow)
+	rows := []string{`[1,"red"]`, `[2,"blue"]`, `[3,"red"]`}
+	g.Expect(c.Observe("model", ndarrayPayload(rows))).To(BeNil())
+
+	snapshot := c.Snapshot()
+	x := snapshot.Models["model"].Features["x"]
+	g.Expect(x.Numeric.Count).To(Equal(int64(3)))
+	g.Expect(x.Numeric.Mean).To(BeNumerically("~", 2, 1e-9))
+	g.Expect(x.Numeric.Variance).To(BeNumerically("~", 1, 1e-9))
+	g.Expect(x.Numeric.Min).To(Equal(1.0))
+	g.Expect(x.Numeric.Max).To(Equal(3.0))
+	g.Expect(x.Numeric.Quantiles["0.5"]).To(BeNumerically("~", 2, 0.05))
+	g.Expect(x.Drift).To(BeNil())
+
+	colour := snapshot.Models["model"].Features["colour"]
+	g.Expect(colour.Categorical.Count).To(Equal(int64(3)))
+	g.Expect(colour.Categorical.Counts).To(Equal(map[string]int64{"red": 2, "blue": 1}))
+
+	g.Expect(testutil.CollectAndCount(c)).To(BeNumerically(">", 0))
+}
+
+func TestCollectorWindow(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	c := NewCollector("dep", "pred", nil, time.Minute)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+	g.Expect(c.Observe("model", ndarrayPayload([]string{`[1,"red"]`, `[3,"red"]`}))).To(BeNil())
+
+	// The previous window is summarised with the current one
+	now = now.Add(time.Minute)
+	g.Expect(c.Observe("model", ndarrayPayload([]string{`[5,"blue"]`, `[7,"blue"]`}))).To(BeNil())
+	features := c.Snapshot().Models["model"].Features
+	g.Expect(features["x"].Numeric.Count).To(Equal(int64(4)))
+	g.Expect(features["x"].Numeric.Mean).To(BeNumerically("~", 4, 1e-9))
+	g.Expect(features["x"].Numeric.Variance).To(BeNumerically("~", 20.0/3, 1e-9))
+	g.Expect(features["x"].Numeric.Min).To(Equal(1.0))
+	g.Expect(features["colour"].Categorical.Counts).To(Equal(map[string]int64{"red": 2, "blue": 2}))
+
+	// Then dropped when the next window starts
+	now = now.Add(time.Minute)
+	features = c.Snapshot().Models["model"].Features
+	g.Expect(features["x"].Numeric.Count).To(Equal(int64(2)))
+	g.Expect(features["x"].Numeric.Min).To(Equal(5.0))
+	g.Expect(features["colour"].Categorical.Counts).To(Equal(map[string]int64{"blue": 2}))
+
+	now = now.Add(2 * time.Minute)
+	g.Expect(c.Snapshot().Models["model"].Features).To(BeEmpty())
+}
+
+func TestCollectorDrift(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	r := rand.New(rand.NewSource(1))
+	colours := []string{"red", "green", "blue"}
+	reference := NewCollector("dep", "pred", nil, DefaultWindow)
+	observeSample(g, reference, r, 0, colours)
+	profile := reference.Snapshot()
+
+	same := NewCollector("dep", "pred", profile, DefaultWindow)
+	observeSample(g, same, r, 0, colours)
+	drift := same.Snapshot().Models["model"]
+	g.Expect(drift.Features["x"].Drift[DriftPSI]).To(BeNumerically("<", 0.05))
+	g.Expect(drift.Features["x"].Drift[DriftKS]).To(BeNumerically("<", 0.1))
+	g.Expect(drift.Features["colour"].Drift[DriftPSI]).To(BeNumerically("<", 0.05))
+
+	shifted := NewCollector("dep", "pred", profile, DefaultWindow)
+	observeSample(g, shifted, r, 1, []string{"red", "yellow"})
+	drift = shifted.Snapshot().Models["model"]
+	g.Expect(drift.Features["x"].Drift[DriftPSI]).To(BeNumerically(">", 0.2))
+	g.Expect(drift.Features["x"].Drift[DriftKS]).To(BeNumerically(">", 0.3))
+	g.Expect(drift.Features["colour"].Drift[DriftPSI]).To(BeNumerically(">", 0.2))
+}
+
+func TestLoadProfile(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	c := NewCollector("dep", "pred", nil, DefaultWindow)
+	g.Expect(c.Observe("model", ndarrayPayload([]string{`[1,"red"]`, `[2,"blue"]`}))).To(BeNil())
+
+	server := httptest.NewServer(c)
+	defer server.Close()
+	res, err := http.Get(server.URL)
+	g.Expect(err).To(BeNil())
+	defer res.Body.Close()
+	g.Expect(res.Header.Get("Content-Type")).To(Equal("application/json"))
+	data, err := ioutil.ReadAll(res.Body)
+	g.Expect(err).To(BeNil())
+
+	dir, err := ioutil.TempDir("", "profile")
+	g.Expect(err).To(BeNil())
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "profile.json")
+	g.Expect(ioutil.WriteFile(path, data, 0644)).To(BeNil())
+
+	profile, err := LoadProfile(path)
+	g.Expect(err).To(BeNil())
+	var expected Snapshot
+	g.Expect(json.Unmarshal(data, &expected)).To(BeNil())
+	g.Expect(profile).To(Equal(&expected))
+	g.Expect(profile.Models["model"].Features["x"].Numeric.Count).To(Equal(int64(2)))
+
+	_, err = LoadProfile(filepath.Join(dir, "missing.json"))
+	g.Expect(err).ToNot(BeNil())
+}
@@ -0,0 +1,70 @@
+package stats
+
+import (
+	"math"
+)
+
+const (
+	DriftPSI = "psi"
+	DriftKS  = "ks"
+
+	// psiMinFraction replaces empty bins so the PSI stays finite.
+	psiMinFraction = 1e-4
+)
+
+func psiTerm(expected float64, actual float64) float64 {
+	expected = math.Max(expected, psiMinFraction)
+	actual = math.Max(actual, psiMinFraction)
+	return (actual - expected) * math.Log(actual/expected)
+}
+
+// numericDrift returns the population stability index and Kolmogorov-Smirnov statistic of the values against the
+// reference. Bins are bounded by the deciles of the reference so each holds a tenth of it, except where deciles
+// are equal and their bins are merged. It returns false if the reference lacks the deciles.
+func numericDrift(reference *NumericSummary, s *numericStats) (float64, float64, bool) {
+	if reference == nil || reference.Count == 0 || s.count == 0 {
+		return 0, 0, false
+	}
+	var edges, levels []float64
+	for _, q := range DriftQuantiles {
+		edge, ok := reference.Quantiles[quantileKey(q)]
+		if !ok {
+			return 0, 0, false
+		}
+		if n := len(edges); n > 0 && edge <= edges[n-1] {
+			// The reference has at least this fraction of values up to the edge
+			levels[n-1] = q
+			continue
+		}
+		edges = append(edges, edge)
+		levels = append(levels, q)
+	}
+	cdf := s.sketch.CDF(edges)
+
+	psi, ks := 0.0, 0.0
+	prevLevel, prevCDF := 0.0, 0.0
+	for i := range edges {
+		psi += psiTerm(levels[i]-prevLevel, cdf[i]-prevCDF)
+		ks = math.Max(ks, math.Abs(cdf[i]-levels[i]))
+		prevLevel, prevCDF = levels[i], cdf[i]
+	}
+	psi += psiTerm(1-prevLevel, 1-prevCDF)
+	return psi, ks, true
+}
+
+// categoricalDrift returns the population stability index of the category frequencies against the reference.
+func categoricalDrift(reference *CategoricalSummary, s *categoricalStats) (float64, bool) {
+	if reference == nil || reference.Count == 0 || s.count == 0 {
+		return 0, false
+	}
+	psi := 0.0
+	for category, count := range reference.Counts {
+		psi += psiTerm(float64(count)/float64(reference.Count), float64(s.counts[category])/float64(s.count))
+	}
+	for category, count := range s.counts {
+		if _, ok := reference.Counts[category]; !ok {
+			psi += psiTerm(0, float64(count)/float64(s.count))
+		}
+	}
+	return psi, true
+}
@@ -0,0 +1,323 @@
+package stats
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	_struct "github.com/golang/protobuf/ptypes/struct"
+	"github.com/seldonio/seldon-core/executor/api/grpc/kfserving/inference"
+	"github.com/seldonio/seldon-core/executor/api/grpc/seldon/proto"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+)
+
+// maxFeatures bounds the features summarised from a request so wide inputs, such as images, don't exhaust memory.
+const maxFeatures = 100
+
+// features holds the values of each feature in a request.
+type features struct {
+	numeric     map[string][]float64
+	categorical map[string][]string
+}
+
+func newFeatures() *features {
+	return &features{
+		numeric:     make(map[string][]float64),
+		categorical: make(map[string][]string),
+	}
+}
+
+func (f *features) full(name string) bool {
+	_, numeric := f.numeric[name]
+	_, categorical := f.categorical[name]
+	return !numeric && !categorical && len(f.numeric)+len(f.categorical) >= maxFeatures
+}
+
+func (f *features) addNumber(name string, v float64) {
+	if !f.full(name) {
+		f.numeric[name] = append(f.numeric[name], v)
+	}
+}
+
+func (f *features) addCategory(name string, v string) {
+	if !f.full(name) {
+		f.categorical[name] = append(f.categorical[name], v)
+	}
+}
+
+// addValue adds a value decoded from JSON, ignoring nested arrays and objects.
+func (f *features) addValue(name string, v interface{}) {
+	switch t := v.(type) {
+	case float64:
+		f.addNumber(name, t)
+	case json.Number:
+		if n, err := t.Float64(); err == nil {
+			f.addNumber(name, n)
+		}
+	case string:
+		f.addCategory(name, t)
+	case bool:
+		f.addCategory(name, strconv.FormatBool(t))
+	}
+}
+
+func columnName(names []string, j int) string {
+	if j < len(names) {
+		return names[j]
+	}
+	return strconv.Itoa(j)
+}
+
+// addRows adds the columns of a seldon ndarray. A one dimensional array is a single instance.
+func (f *features) addRows(rows []interface{}, names []string) {
+	for _, row := range rows {
+		cols, ok := row.([]interface{})
+		if !ok {
+			cols = rows
+		}
+		for j, v := range cols {
+			if j >= maxFeatures {
+				break
+			}
+			f.addValue(columnName(names, j), v)
+		}
+		if !ok {
+			return
+		}
+	}
+}
+
+// addTensor adds the columns of a tensor of one or two dimensions with the values in row major order.
+func (f *features) addTensor(shape []int, values []float64, names []string, prefix string) {
+	cols := 1
+	switch len(shape) {
+	case 1:
+		if prefix == "" {
+			// A seldon tensor of one dimension is a single instance
+			cols = shape[0]
+		}
+	case 2:
+		cols = shape[1]
+	default:
+		return
+	}
+	if cols <= 0 {
+		return
+	}
+	for i, v := range values {
+		j := i % cols
+		if j >= maxFeatures {
+			continue
+		}
+		name := columnName(names, j)
+		if prefix != "" {
+			name = prefix
+			if cols > 1 {
+				name = fmt.Sprintf("%s[%d]", prefix, j)
+			}
+		}
+		f.addNumber(name, v)
+	}
+}
+
+type jsonRequest struct {
+	Data *struct {
+		Names   []string      `json:"names"`
+		Ndarray []interface{} `json:"ndarray"`
+		Tensor  *struct {
+			Shape  []int     `json:"shape"`
+			Values []float64 `json:"values"`
+		} `json:"tensor"`
+	} `json:"data"`
+	Inputs []struct {
+		Name     string      `json:"name"`
+		Shape    []int       `json:"shape"`
+		Datatype string      `json:"datatype"`
+		Data     interface{} `json:"data"`
+	} `json:"inputs"`
+}
+
+// flatten returns the values of a possibly nested JSON array in row major order.
+func flatten(v interface{}, out []interface{}) []interface{} {
+	if arr, ok := v.([]interface{}); ok {
+		for _, e := range arr {
+			out = flatten(e, out)
+		}
+		return out
+	}
+	return append(out, v)
+}
+
+func isNumericDatatype(datatype string) bool {
+	return strings.HasPrefix(datatype, "FP") || strings.HasPrefix(datatype, "INT") || strings.HasPrefix(datatype, "UINT")
+}
+
+func (f *features) addJson(data []byte) error {
+	var req jsonRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return err
+	}
+	if req.Data != nil {
+		if req.Data.Ndarray != nil {
+			f.addRows(req.Data.Ndarray, req.Data.Names)
+		} else if req.Data.Tensor != nil {
+			f.addTensor(req.Data.Tensor.Shape, req.Data.Tensor.Values, req.Data.Names, "")
+		}
+	}
+	for _, input := range req.Inputs {
+		values := flatten(input.Data, nil)
+		switch {
+		case isNumericDatatype(input.Datatype):
+			numbers := make([]float64, 0, len(values))
+			for _, v := range values {
+				if n, ok := v.(float64); ok {
+					numbers = append(numbers, n)
+				}
+			}
+			f.addTensor(input.Shape, numbers, nil, input.Name)
+		case input.Datatype == "BYTES":
+			for _, v := range values {
+				f.addValue(input.Name, v)
+			}
+		}
+	}
+	return nil
+}
+
+func (f *features) addSeldonMessage(msg *proto.SeldonMessage) {
+	data := msg.GetData()
+	if data == nil {
+		return
+	}
+	if ndarray := data.GetNdarray(); ndarray != nil {
+		f.addRows(listValues(ndarray), data.GetNames())
+	} else if tensor := data.GetTensor(); tensor != nil {
+		shape := make([]int, len(tensor.GetShape()))
+		for i, dim := range tensor.GetShape() {
+			shape[i] = int(dim)
+		}
+		f.addTensor(shape, tensor.GetValues(), data.GetNames(), "")
+	}
+}
+
+// listValues converts a protobuf list to the form decoded from JSON.
+func listValues(list *_struct.ListValue) []interface{} {
+	values := make([]interface{}, len(list.GetValues()))
+	for i, v := range list.GetValues() {
+		switch kind := v.GetKind().(type) {
+		case *_struct.Value_NumberValue:
+			values[i] = kind.NumberValue
+		case *_struct.Value_StringValue:
+			values[i] = kind.StringValue
+		case *_struct.Value_BoolValue:
+			values[i] = kind.BoolValue
+		case *_struct.Value_ListValue:
+			values[i] = listValues(kind.ListValue)
+		}
+	}
+	return values
+}
+
+// rawValues decodes raw input contents, which hold the values of the datatype in little endian order. Values of
+// other datatypes aren't decoded.
+func rawValues(datatype string, data []byte) []float64 {
+	var size int
+	var value func(b []byte) float64
+	switch datatype {
+	case "FP32":
+		size, value = 4, func(b []byte) float64 { return float64(math.Float32frombits(binary.LittleEndian.Uint32(b))) }
+	case "FP64":
+		size, value = 8, func(b []byte) float64 { return math.Float64frombits(binary.LittleEndian.Uint64(b)) }
+	case "INT8":
+		size, value = 1, func(b []byte) float64 { return float64(int8(b[0])) }
+	case "INT16":
+		size, value = 2, func(b []byte) float64 { return float64(int16(binary.LittleEndian.Uint16(b))) }
+	case "INT32":
+		size, value = 4, func(b []byte) float64 { return float64(int32(binary.LittleEndian.Uint32(b))) }
+	case "INT64":
+		size, value = 8, func(b []byte) float64 { return float64(int64(binary.LittleEndian.Uint64(b))) }
+	case "UINT8":
+		size, value = 1, func(b []byte) float64 { return float64(b[0]) }
+	case "UINT16":
+		size, value = 2, func(b []byte) float64 { return float64(binary.LittleEndian.Uint16(b)) }
+	case "UINT32":
+		size, value = 4, func(b []byte) float64 { return float64(binary.LittleEndian.Uint32(b)) }
+	case "UINT64":
+		size, value = 8, func(b []byte) float64 { return float64(binary.LittleEndian.Uint64(b)) }
+	default:
+		return nil
+	}
+	values := make([]float64, 0, len(data)/size)
+	for i := 0; i+size <= len(data); i += size {
+		values = append(values, value(data[i:i+size]))
+	}
+	return values
+}
+
+// addInferRequest adds the inputs of a v2 request, whose values are either in their contents or, in the same order
+// as the inputs, in the raw input contents.
+func (f *features) addInferRequest(req *inference.ModelInferRequest) {
+	raw := req.GetRawInputContents()
+	for i, input := range req.GetInputs() {
+		shape := make([]int, len(input.GetShape()))
+		for j, dim := range input.GetShape() {
+			shape[j] = int(dim)
+		}
+		contents := input.GetContents()
+		var values []float64
+		switch {
+		case i < len(raw):
+			values = rawValues(input.GetDatatype(), raw[i])
+		case len(contents.GetFp32Contents()) > 0:
+			for _, v := range contents.GetFp32Contents() {
+				values = append(values, float64(v))
+			}
+		case len(contents.GetFp64Contents()) > 0:
+			values = contents.GetFp64Contents()
+		case len(contents.GetIntContents()) > 0:
+			for _, v := range contents.GetIntContents() {
+				values = append(values, float64(v))
+			}
+		case len(contents.GetInt64Contents()) > 0:
+			for _, v := range contents.GetInt64Contents() {
+				values = append(values, float64(v))
+			}
+		case len(contents.GetUintContents()) > 0:
+			for _, v := range contents.GetUintContents() {
+				values = append(values, float64(v))
+			}
+		case len(contents.GetUint64Contents()) > 0:
+			for _, v := range contents.GetUint64Contents() {
+				values = append(values, float64(v))
+			}
+		}
+		f.addTensor(shape, values, nil, input.GetName())
+	}
+}
+
+// extractFeatures returns the features of a seldon or v2 request, in either JSON or protobuf form. Requests of other
+// kinds have no features.
+func extractFeatures(msg payload.SeldonPayload) (*features, error) {
+	f := newFeatures()
+	switch req := msg.GetPayload().(type) {
+	case *proto.SeldonMessage:
+		f.addSeldonMessage(req)
+	case *inference.ModelInferRequest:
+		f.addInferRequest(req)
+	case []byte:
+		if !strings.Contains(msg.GetContentType(), "json") {
+			return f, nil
+		}
+		data, err := payload.DecompressBytes(req, msg.GetContentEncoding())
+		if err != nil {
+			return nil, err
+		}
+		if err := f.addJson(data); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
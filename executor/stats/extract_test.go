@@ -0,0 +1,85 @@
+package stats
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/seldonio/seldon-core/executor/api/grpc/kfserving/inference"
+	"github.com/seldonio/seldon-core/executor/api/grpc/seldon/proto"
+	"github.com/seldonio/seldon-core/executor/api/payload"
+)
+
+func TestExtractSeldonJson(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	msg := &payload.BytesPayload{
+		Msg:         []byte(`{"data":{"names":["age","city"],"ndarray":[[30,"london"],[40,"paris"]]}}`),
+		ContentType: payload.APPLICATION_TYPE_JSON,
+	}
+	f, err := extractFeatures(msg)
+	g.Expect(err).To(BeNil())
+	g.Expect(f.numeric).To(Equal(map[string][]float64{"age": {30, 40}}))
+	g.Expect(f.categorical).To(Equal(map[string][]string{"city": {"london", "paris"}}))
+
+	msg = &payload.BytesPayload{
+		Msg:         []byte(`{"data":{"tensor":{"shape":[2,2],"values":[1,2,3,4]}}}`),
+		ContentType: payload.APPLICATION_TYPE_JSON,
+	}
+	f, err = extractFeatures(msg)
+	g.Expect(err).To(BeNil())
+	g.Expect(f.numeric).To(Equal(map[string][]float64{"0": {1, 3}, "1": {2, 4}}))
+}
+
+func TestExtractV2Json(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	msg := &payload.BytesPayload{
+		Msg: []byte(`{"inputs":[{"name":"x","shape":[2,2],"datatype":"FP32","data":[[1,2],[3,4]]},` +
+			`{"name":"id","shape":[2],"datatype":"INT64","data":[7,8]},{"name":"label","shape":[1],"datatype":"BYTES","data":["a"]}]}`),
+		ContentType: payload.APPLICATION_TYPE_JSON,
+	}
+	f, err := extractFeatures(msg)
+	g.Expect(err).To(BeNil())
+	g.Expect(f.numeric).To(Equal(map[string][]float64{"x[0]": {1, 3}, "x[1]": {2, 4}, "id": {7, 8}}))
+	g.Expect(f.categorical).To(Equal(map[string][]string{"label": {"a"}}))
+}
+
+func TestExtractProto(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	seldon := &payload.ProtoPayload{Msg: &proto.SeldonMessage{
+		DataOneof: &proto.SeldonMessage_Data{Data: &proto.DefaultData{
+			Names:     []string{"a", "b"},
+			DataOneof: &proto.DefaultData_Tensor{Tensor: &proto.Tensor{Shape: []int32{1, 2}, Values: []float64{1.5, 2.5}}},
+		}},
+	}}
+	f, err := extractFeatures(seldon)
+	g.Expect(err).To(BeNil())
+	g.Expect(f.numeric).To(Equal(map[string][]float64{"a": {1.5}, "b": {2.5}}))
+
+	v2 := &payload.ProtoPayload{Msg: &inference.ModelInferRequest{
+		Inputs: []*inference.ModelInferRequest_InferInputTensor{
+			{Name: "x", Datatype: "FP32", Shape: []int64{2}, Contents: &inference.InferTensorContents{Fp32Contents: []float32{1, 2}}},
+		},
+	}}
+	f, err = extractFeatures(v2)
+	g.Expect(err).To(BeNil())
+	g.Expect(f.numeric).To(Equal(map[string][]float64{"x": {1, 2}}))
+
+	raw := make([]byte, 16)
+	binary.LittleEndian.PutUint32(raw, math.Float32bits(1.5))
+	binary.LittleEndian.PutUint32(raw[4:], math.Float32bits(2.5))
+	binary.LittleEndian.PutUint64(raw[8:], uint64(7))
+	v2 = &payload.ProtoPayload{Msg: &inference.ModelInferRequest{
+		Inputs: []*inference.ModelInferRequest_InferInputTensor{
+			{Name: "x", Datatype: "FP32", Shape: []int64{1, 2}},
+			{Name: "id", Datatype: "INT64", Shape: []int64{1}},
+		},
+		RawInputContents: [][]byte{raw[:8], raw[8:]},
+	}}
+	f, err = extractFeatures(v2)
+	g.Expect(err).To(BeNil())
+	g.Expect(f.numeric).To(Equal(map[string][]float64{"x[0]": {1.5}, "x[1]": {2.5}, "id": {7}}))
+}
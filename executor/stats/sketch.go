@@ -0,0 +1,137 @@
+package stats
+
+import (
+	"math"
+	"sort"
+)
+
+const (
+	// sketchRelativeAccuracy bounds the relative error of the quantiles returned by a sketch.
+	sketchRelativeAccuracy = 0.01
+	// sketchMinValue is the smallest magnitude kept apart from zero.
+	sketchMinValue = 1e-9
+)
+
+// quantileSketch estimates quantiles of a stream of values in bounded memory. Values are counted in buckets whose
+// bounds grow geometrically so any quantile is returned within the relative accuracy, as in DDSketch.
+type quantileSketch struct {
+	logGamma float64
+	positive map[int]int64
+	negative map[int]int64
+	zero     int64
+	count    int64
+}
+
+type sketchBucket struct {
+	value float64
+	count int64
+}
+
+func newQuantileSketch() *quantileSketch {
+	gamma := (1 + sketchRelativeAccuracy) / (1 - sketchRelativeAccuracy)
+	return &quantileSketch{
+		logGamma: math.Log(gamma),
+		positive: make(map[int]int64),
+		negative: make(map[int]int64),
+	}
+}
+
+func (s *quantileSketch) key(v float64) int {
+	return int(math.Ceil(math.Log(v) / s.logGamma))
+}
+
+// bucketValue returns the value representing a bucket, which is within the relative accuracy of all its values.
+func (s *quantileSketch) bucketValue(key int) float64 {
+	gamma := math.Exp(s.logGamma)
+	return 2 * math.Pow(gamma, float64(key)) / (gamma + 1)
+}
+
+func (s *quantileSketch) Add(v float64) {
+	switch {
+	case v > sketchMinValue:
+		s.positive[s.key(v)]++
+	case v < -sketchMinValue:
+		s.negative[s.key(-v)]++
+	default:
+		s.zero++
+	}
+	s.count++
+}
+
+// merge adds the values counted by o, which must have the same accuracy.
+func (s *quantileSketch) merge(o *quantileSketch) {
+	for key, n := range o.positive {
+		s.positive[key] += n
+	}
+	for key, n := range o.negative {
+		s.negative[key] += n
+	}
+	s.zero += o.zero
+	s.count += o.count
+}
+
+// buckets returns the non-empty buckets in increasing order of value.
+func (s *quantileSketch) buckets() []sketchBucket {
+	buckets := make([]sketchBucket, 0, len(s.negative)+len(s.positive)+1)
+	keys := make([]int, 0, len(s.negative))
+	for key := range s.negative {
+		keys = append(keys, key)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(keys)))
+	for _, key := range keys {
+		buckets = append(buckets, sketchBucket{value: -s.bucketValue(key), count: s.negative[key]})
+	}
+	if s.zero > 0 {
+		buckets = append(buckets, sketchBucket{value: 0, count: s.zero})
+	}
+	keys = keys[:0]
+	for key := range s.positive {
+		keys = append(keys, key)
+	}
+	sort.Ints(keys)
+	for _, key := range keys {
+		buckets = append(buckets, sketchBucket{value: s.bucketValue(key), count: s.positive[key]})
+	}
+	return buckets
+}
+
+// Quantiles returns the estimates of the quantiles qs, each between 0 and 1 and in increasing order.
+func (s *quantileSketch) Quantiles(qs []float64) []float64 {
+	values := make([]float64, len(qs))
+	if s.count == 0 {
+		return values
+	}
+	buckets := s.buckets()
+	var seen int64
+	i := 0
+	for _, bucket := range buckets {
+		seen += bucket.count
+		for i < len(qs) && float64(seen) > qs[i]*float64(s.count-1) {
+			values[i] = bucket.value
+			i++
+		}
+	}
+	for ; i < len(qs); i++ {
+		values[i] = buckets[len(buckets)-1].value
+	}
+	return values
+}
+
+// CDF returns the estimated fraction of values no greater than each of the points, which are in increasing order.
+func (s *quantileSketch) CDF(points []float64) []float64 {
+	fractions := make([]float64, len(points))
+	if s.count == 0 {
+		return fractions
+	}
+	buckets := s.buckets()
+	var seen int64
+	b := 0
+	for i, point := range points {
+		for b < len(buckets) && buckets[b].value <= point {
+			seen += buckets[b].count
+			b++
+		}
+		fractions[i] = float64(seen) / float64(s.count)
+	}
+	return fractions
+}
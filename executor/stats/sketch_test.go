@@ -0,0 +1,47 @@
+package stats
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestQuantileSketch(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	sketch := newQuantileSketch()
+	r := rand.New(rand.NewSource(1))
+	values := make([]float64, 10000)
+	for i := range values {
+		values[i] = r.NormFloat64()*10 + 5
+		sketch.Add(values[i])
+	}
+	sort.Float64s(values)
+
+	qs := []float64{0.05, 0.25, 0.5, 0.75, 0.95}
+	for i, estimate := range sketch.Quantiles(qs) {
+		exact := values[int(qs[i]*float64(len(values)-1))]
+		g.Expect(math.Abs(estimate-exact)).To(BeNumerically("<=", 0.02*math.Abs(exact)+0.05), "quantile %v", qs[i])
+	}
+
+	cdf := sketch.CDF([]float64{-100, 5, 100})
+	g.Expect(cdf[0]).To(Equal(0.0))
+	g.Expect(cdf[1]).To(BeNumerically("~", 0.5, 0.02))
+	g.Expect(cdf[2]).To(Equal(1.0))
+}
+
+func TestQuantileSketchZeroAndNegative(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	sketch := newQuantileSketch()
+	for _, v := range []float64{-2, 0, 0, 3} {
+		sketch.Add(v)
+	}
+	quantiles := sketch.Quantiles([]float64{0, 0.5, 1})
+	g.Expect(quantiles[0]).To(BeNumerically("~", -2, 0.02))
+	g.Expect(quantiles[1]).To(Equal(0.0))
+	g.Expect(quantiles[2]).To(BeNumerically("~", 3, 0.03))
+}
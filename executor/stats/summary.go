@@ -0,0 +1,190 @@
+package stats
+
+import (
+	"math"
+	"strconv"
+)
+
+const (
+	// maxCategories bounds the distinct values counted for a categorical feature. Further values are counted
+	// as OtherCategory.
+	maxCategories = 50
+	OtherCategory = "__other__"
+)
+
+var (
+	// ReportedQuantiles are the quantiles of numeric features in the summaries.
+	ReportedQuantiles = []float64{0.01, 0.05, 0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9, 0.95, 0.99}
+	// DriftQuantiles are the quantiles of the reference profile bounding the bins compared for drift.
+	DriftQuantiles = []float64{0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9}
+)
+
+// Snapshot holds the summaries of the features of each model. A snapshot saved as JSON can be loaded as the
+// reference profile that drift is measured against.
+type Snapshot struct {
+	Models map[string]*ModelSummary `json:"models"`
+}
+
+type ModelSummary struct {
+	Features map[string]*FeatureSummary `json:"features"`
+}
+
+// FeatureSummary summarises the numeric or categorical values of a feature, or both if it had values of either
+// kind. Drift holds the PSI and KS scores against the reference profile.
+type FeatureSummary struct {
+	Numeric     *NumericSummary     `json:"numeric,omitempty"`
+	Categorical *CategoricalSummary `json:"categorical,omitempty"`
+	Drift       map[string]float64  `json:"drift,omitempty"`
+}
+
+type NumericSummary struct {
+	Count    int64   `json:"count"`
+	Mean     float64 `json:"mean"`
+	Variance float64 `json:"variance"`
+	Min      float64 `json:"min"`
+	Max      float64 `json:"max"`
+	// Quantiles are keyed by the quantile, e.g. "0.5" for the median.
+	Quantiles map[string]float64 `json:"quantiles"`
+}
+
+type CategoricalSummary struct {
+	Count  int64            `json:"count"`
+	Counts map[string]int64 `json:"counts"`
+}
+
+func quantileKey(q float64) string {
+	return strconv.FormatFloat(q, 'f', -1, 64)
+}
+
+// numericStats keeps the count, mean and variance of a feature with Welford's algorithm along with its range and
+// a quantile sketch.
+type numericStats struct {
+	count  int64
+	mean   float64
+	m2     float64
+	min    float64
+	max    float64
+	sketch *quantileSketch
+}
+
+func newNumericStats() *numericStats {
+	return &numericStats{
+		min:    math.Inf(1),
+		max:    math.Inf(-1),
+		sketch: newQuantileSketch(),
+	}
+}
+
+func (s *numericStats) add(v float64) {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return
+	}
+	s.count++
+	delta := v - s.mean
+	s.mean += delta / float64(s.count)
+	s.m2 += delta * (v - s.mean)
+	s.min = math.Min(s.min, v)
+	s.max = math.Max(s.max, v)
+	s.sketch.Add(v)
+}
+
+// merge adds the values summarised by o, combining the moments with Chan's parallel algorithm.
+func (s *numericStats) merge(o *numericStats) {
+	if o.count == 0 {
+		return
+	}
+	count := s.count + o.count
+	delta := o.mean - s.mean
+	s.mean += delta * float64(o.count) / float64(count)
+	s.m2 += o.m2 + delta*delta*float64(s.count)*float64(o.count)/float64(count)
+	s.count = count
+	s.min = math.Min(s.min, o.min)
+	s.max = math.Max(s.max, o.max)
+	s.sketch.merge(o.sketch)
+}
+
+func (s *numericStats) summary() *NumericSummary {
+	summary := &NumericSummary{Count: s.count, Mean: s.mean, Quantiles: make(map[string]float64)}
+	if s.count == 0 {
+		return summary
+	}
+	if s.count > 1 {
+		summary.Variance = s.m2 / float64(s.count-1)
+	}
+	summary.Min, summary.Max = s.min, s.max
+	for i, v := range s.sketch.Quantiles(ReportedQuantiles) {
+		// Estimates are kept within the range seen
+		summary.Quantiles[quantileKey(ReportedQuantiles[i])] = math.Max(s.min, math.Min(s.max, v))
+	}
+	return summary
+}
+
+type categoricalStats struct {
+	count  int64
+	counts map[string]int64
+}
+
+func newCategoricalStats() *categoricalStats {
+	return &categoricalStats{counts: make(map[string]int64)}
+}
+
+func (s *categoricalStats) add(v string) {
+	if _, ok := s.counts[v]; !ok && len(s.counts) >= maxCategories {
+		v = OtherCategory
+	}
+	s.counts[v]++
+	s.count++
+}
+
+func (s *categoricalStats) merge(o *categoricalStats) {
+	for v, n := range o.counts {
+		if _, ok := s.counts[v]; !ok && len(s.counts) >= maxCategories {
+			v = OtherCategory
+		}
+		s.counts[v] += n
+	}
+	s.count += o.count
+}
+
+func (s *categoricalStats) summary() *CategoricalSummary {
+	counts := make(map[string]int64, len(s.counts))
+	for k, v := range s.counts {
+		counts[k] = v
+	}
+	return &CategoricalSummary{Count: s.count, Counts: counts}
+}
+
+type featureStats struct {
+	numeric     *numericStats
+	categorical *categoricalStats
+}
+
+// merge adds the values summarised by o, which may be nil.
+func (s *featureStats) merge(o *featureStats) {
+	if o == nil {
+		return
+	}
+	if o.numeric != nil {
+		if s.numeric == nil {
+			s.numeric = newNumericStats()
+		}
+		s.numeric.merge(o.numeric)
+	}
+	if o.categorical != nil {
+		if s.categorical == nil {
+			s.categorical = newCategoricalStats()
+		}
+		s.categorical.merge(o.categorical)
+	}
+}
+
+func (s *featureStats) summary() *FeatureSummary {
+	summary := &FeatureSummary{}
+	if s.numeric != nil {
+		summary.Numeric = s.numeric.summary()
+	}
+	if s.categorical != nil {
+		summary.Categorical = s.categorical.summary()
+	}
+	return summary
+}
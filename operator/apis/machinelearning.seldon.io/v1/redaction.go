@@ -0,0 +1,87 @@
+/*
+Copyright 2019 The Seldon Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// RedactionPathWildcard is the index of a token selecting every member of an object or array
+	RedactionPathWildcard = -1
+	// RedactionPathKey is the index of a token selecting the key of an object
+	RedactionPathKey = -2
+)
+
+// RedactionPathToken selects a key of an object, an index of an array or, as a wildcard, every member of either.
+// +kubebuilder:object:generate=false
+type RedactionPathToken struct {
+	Key   string
+	Index int
+}
+
+// ParseRedactionPath parses the JSONPath subset $.a.b, $['a'], $.a[0], $.a[*] and $.a.* into tokens.
+func ParseRedactionPath(path string) ([]RedactionPathToken, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("redaction path %s must start with $", path)
+	}
+	rest := path[1:]
+	var tokens []RedactionPathToken
+	for len(rest) > 0 {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			name := rest[:end]
+			if name == "" {
+				return nil, fmt.Errorf("redaction path %s has an empty key", path)
+			}
+			if name == "*" {
+				tokens = append(tokens, RedactionPathToken{Index: RedactionPathWildcard})
+			} else {
+				tokens = append(tokens, RedactionPathToken{Key: name, Index: RedactionPathKey})
+			}
+			rest = rest[end:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("redaction path %s has an unclosed [", path)
+			}
+			selector := rest[1:end]
+			rest = rest[end+1:]
+			if selector == "*" {
+				tokens = append(tokens, RedactionPathToken{Index: RedactionPathWildcard})
+			} else if len(selector) >= 2 && (selector[0] == '\'' || selector[0] == '"') && selector[len(selector)-1] == selector[0] {
+				tokens = append(tokens, RedactionPathToken{Key: selector[1 : len(selector)-1], Index: RedactionPathKey})
+			} else {
+				index, err := strconv.Atoi(selector)
+				if err != nil || index < 0 {
+					return nil, fmt.Errorf("redaction path %s has an invalid index %s", path, selector)
+				}
+				tokens = append(tokens, RedactionPathToken{Index: index})
+			}
+		default:
+			return nil, fmt.Errorf("redaction path %s is invalid at %s", path, rest)
+		}
+	}
+	return tokens, nil
+}
@@ -0,0 +1,26 @@
+package v1
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestParseRedactionPath(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	tokens, err := ParseRedactionPath(`$.data['ndarray'][*][2].*`)
+	g.Expect(err).To(BeNil())
+	g.Expect(tokens).To(Equal([]RedactionPathToken{
+		{Key: "data", Index: RedactionPathKey},
+		{Key: "ndarray", Index: RedactionPathKey},
+		{Index: RedactionPathWildcard},
+		{Index: 2},
+		{Index: RedactionPathWildcard},
+	}))
+
+	for _, path := range []string{"data.names", "$.", "$[0", "$[-1]", "$x"} {
+		_, err = ParseRedactionPath(path)
+		g.Expect(err).ToNot(BeNil(), path)
+	}
+}
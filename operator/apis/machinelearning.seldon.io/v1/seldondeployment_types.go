@@ -213,6 +213,11 @@ func GetContainerServiceName(mlDepName string, predictorSpec PredictorSpec, c *v
 	}
 }
 
+// IsClientTLSEnabled returns whether the orchestrator should use TLS to reach the predictor's models
+func IsClientTLSEnabled(p *PredictorSpec) bool {
+	return p.SSL != nil && p.SSL.ClientTLS != nil && p.SSL.ClientTLS.Mode != "" && p.SSL.ClientTLS.Mode != ClientTLSDisabled
+}
+
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
 // NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
 
@@ -232,6 +237,36 @@ type SeldonDeploymentSpec struct {
 
 type SSL struct {
 	CertSecretName string `json:"certSecretName,omitempty" protobuf:"string,2,opt,name=certSecretName"`
+	// Optional TLS settings for the calls the service orchestrator makes to the graph's model containers.
+	// The CA bundle, client certificate and key are read from the secret in certSecretName.
+	// +optional
+	ClientTLS *ClientTLS `json:"clientTLS,omitempty" protobuf:"bytes,3,opt,name=clientTLS"`
+}
+
+type ClientTLSMode string
+
+const (
+	ClientTLSDisabled ClientTLSMode = "disabled"
+	ClientTLSSimple   ClientTLSMode = "tls"
+	ClientTLSMutual   ClientTLSMode = "mtls"
+)
+
+// ClientTLS configures TLS from the service orchestrator to the model containers
+type ClientTLS struct {
+	// disabled, tls (verify the model's certificate) or mtls (also present a client certificate)
+	Mode ClientTLSMode `json:"mode,omitempty" protobuf:"string,1,opt,name=mode"`
+	// File in the cert secret holding the CA bundle. Defaults to ca.crt
+	// +optional
+	CaFileName string `json:"caFileName,omitempty" protobuf:"string,2,opt,name=caFileName"`
+	// File in the cert secret holding the client certificate for mtls. Defaults to tls.crt
+	// +optional
+	CertFileName string `json:"certFileName,omitempty" protobuf:"string,3,opt,name=certFileName"`
+	// File in the cert secret holding the client key for mtls. Defaults to tls.key
+	// +optional
+	KeyFileName string `json:"keyFileName,omitempty" protobuf:"string,4,opt,name=keyFileName"`
+	// Server name to verify the model certificates against instead of the service host
+	// +optional
+	ServerName string `json:"serverName,omitempty" protobuf:"string,5,opt,name=serverName"`
 }
 
 type PredictorSpec struct {
@@ -271,6 +306,7 @@ type ServerType string
 const (
 	ServerRPC   ServerType = "rpc"
 	ServerKafka ServerType = "kafka"
+	ServerNats  ServerType = "nats"
 )
 
 type SvcOrchSpec struct {
@@ -620,6 +656,46 @@ type Logger struct {
 	Url *string `json:"url,omitempty"`
 	// What payloads to log
 	Mode LoggerMode `json:"mode,omitempty"`
+	// Rules applied to payloads before they are logged, e.g. to remove customer identifiers
+	// +optional
+	Redact []RedactionRule `json:"redact,omitempty"`
+	// Log a sample of calls rather than all of them
+	// +optional
+	Sampling *LoggerSampling `json:"sampling,omitempty"`
+}
+
+// LoggerSampling logs a deterministic sample of calls along with any failed or slow calls
+type LoggerSampling struct {
+	// Fraction of calls to log from "0" to "1". Calls are chosen by a hash of their PUID so the request and
+	// response of a call, and its calls to each node, are logged together. Defaults to "1"
+	// +optional
+	Rate string `json:"rate,omitempty"`
+	// Also log calls which fail, with the error as the response
+	// +optional
+	Errors bool `json:"errors,omitempty"`
+	// Also log calls which take longer than this many milliseconds. 0 disables
+	// +optional
+	LatencyThresholdMs int32 `json:"latencyThresholdMs,omitempty"`
+}
+
+type RedactionAction string
+
+const (
+	RedactDrop RedactionAction = "drop"
+	RedactHash RedactionAction = "hash"
+	RedactMask RedactionAction = "mask"
+)
+
+// RedactionRule selects payload fields by JSONPath or tensor name and removes or obscures them
+type RedactionRule struct {
+	// JSONPath of the fields to redact, e.g. $.jsonData.customer.id or $.data.ndarray[*][0]
+	// +optional
+	Path string `json:"path,omitempty"`
+	// Name of the tensor to redact, either a v2 input or output name or a column in the seldon data names
+	// +optional
+	Tensor string `json:"tensor,omitempty"`
+	// drop, hash (salted SHA-256) or mask
+	Action RedactionAction `json:"action"`
 }
 
 // +genclient
@@ -18,6 +18,7 @@ package v1
 
 import (
 	"os"
+	"strconv"
 
 	"github.com/seldonio/seldon-core/operator/constants"
 	corev1 "k8s.io/api/core/v1"
@@ -125,11 +126,7 @@ func (r *SeldonDeploymentSpec) checkPredictiveUnits(pu *PredictiveUnit, p *Predi
 		}
 	}
 
-	if pu.Logger != nil {
-		if pu.Logger.Mode == "" {
-			allErrs = append(allErrs, field.Invalid(fldPath, pu.Logger.Mode, "No logger mode specified"))
-		}
-	}
+	allErrs = validateLogger(pu, fldPath, allErrs)
 
 	for i := 0; i < len(pu.Children); i++ {
 		allErrs = r.checkPredictiveUnits(&pu.Children[i], p, fldPath.Index(i), allErrs)
@@ -138,6 +135,84 @@ func (r *SeldonDeploymentSpec) checkPredictiveUnits(pu *PredictiveUnit, p *Predi
 	return allErrs
 }
 
+// ValidateGraph checks the parts of the predictor's graph the executor relies on, for graphs which don't pass
+// through the webhook such as those reloaded by a running executor.
+func (p *PredictorSpec) ValidateGraph() error {
+	allErrs := checkGraph(&p.Graph, field.NewPath("graph"), make(map[string]bool), nil)
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(
+		schema.GroupKind{Group: "machinelearing.seldon.io", Kind: "SeldonDeployment"},
+		p.Name, allErrs)
+}
+
+func checkGraph(pu *PredictiveUnit, fldPath *field.Path, names map[string]bool, allErrs field.ErrorList) field.ErrorList {
+	if pu.Name == "" {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("name"), pu.Name, "Predictive unit has no name"))
+	} else if names[pu.Name] {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("name"), pu.Name, "Duplicate predictive unit name"))
+	}
+	names[pu.Name] = true
+
+	if pu.Type != nil && *pu.Type == UNKNOWN_TYPE && (pu.Methods == nil || len(*pu.Methods) == 0) {
+		allErrs = append(allErrs, field.Invalid(fldPath, pu.Name, "Predictive Unit has no implementation methods defined. Change to a known type or add what methods it defines"))
+	}
+
+	allErrs = validateLogger(pu, fldPath, allErrs)
+
+	for i := 0; i < len(pu.Children); i++ {
+		allErrs = checkGraph(&pu.Children[i], fldPath.Child("children").Index(i), names, allErrs)
+	}
+	return allErrs
+}
+
+func validateLogger(pu *PredictiveUnit, fldPath *field.Path, allErrs field.ErrorList) field.ErrorList {
+	if pu.Logger == nil {
+		return allErrs
+	}
+	if pu.Logger.Mode == "" {
+		allErrs = append(allErrs, field.Invalid(fldPath, pu.Logger.Mode, "No logger mode specified"))
+	}
+	allErrs = validateRedactionRules(pu.Logger.Redact, fldPath.Child("logger").Child("redact"), allErrs)
+	if pu.Logger.Sampling != nil {
+		allErrs = validateLoggerSampling(pu.Logger.Sampling, fldPath.Child("logger").Child("sampling"), allErrs)
+	}
+	return allErrs
+}
+
+func validateRedactionRules(rules []RedactionRule, fldPath *field.Path, allErrs field.ErrorList) field.ErrorList {
+	for i, rule := range rules {
+		if (rule.Path == "") == (rule.Tensor == "") {
+			allErrs = append(allErrs, field.Invalid(fldPath.Index(i), rule, "Redaction rule must set exactly one of path or tensor"))
+		}
+		if rule.Path != "" {
+			if _, err := ParseRedactionPath(rule.Path); err != nil {
+				allErrs = append(allErrs, field.Invalid(fldPath.Index(i).Child("path"), rule.Path, err.Error()))
+			}
+		}
+		switch rule.Action {
+		case RedactDrop, RedactHash, RedactMask:
+		default:
+			allErrs = append(allErrs, field.Invalid(fldPath.Index(i).Child("action"), rule.Action, "Invalid redaction action, must be drop, hash or mask"))
+		}
+	}
+	return allErrs
+}
+
+func validateLoggerSampling(sampling *LoggerSampling, fldPath *field.Path, allErrs field.ErrorList) field.ErrorList {
+	if sampling.Rate != "" {
+		rate, err := strconv.ParseFloat(sampling.Rate, 64)
+		if err != nil || rate < 0 || rate > 1 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("rate"), sampling.Rate, "Sampling rate must be a number from 0 to 1"))
+		}
+	}
+	if sampling.LatencyThresholdMs < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("latencyThresholdMs"), sampling.LatencyThresholdMs, "Latency threshold can't be negative"))
+	}
+	return allErrs
+}
+
 func checkTraffic(spec *SeldonDeploymentSpec, fldPath *field.Path, allErrs field.ErrorList) field.ErrorList {
 	var trafficSum int32 = 0
 	var shadows int = 0
@@ -188,6 +263,10 @@ const (
 	ENV_KAFKA_BROKER       = "KAFKA_BROKER"
 	ENV_KAFKA_INPUT_TOPIC  = "KAFKA_INPUT_TOPIC"
 	ENV_KAFKA_OUTPUT_TOPIC = "KAFKA_OUTPUT_TOPIC"
+
+	ENV_NATS_URL            = "NATS_URL"
+	ENV_NATS_INPUT_SUBJECT  = "NATS_INPUT_SUBJECT"
+	ENV_NATS_OUTPUT_SUBJECT = "NATS_OUTPUT_SUBJECT"
 )
 
 func (r *SeldonDeploymentSpec) validateKafka(allErrs field.ErrorList) field.ErrorList {
@@ -214,6 +293,45 @@ func (r *SeldonDeploymentSpec) validateKafka(allErrs field.ErrorList) field.Erro
 	return allErrs
 }
 
+// validateNats checks the subjects are set. The url can come from the operator default so isn't required.
+func (r *SeldonDeploymentSpec) validateNats(allErrs field.ErrorList) field.ErrorList {
+	if r.ServerType == ServerNats {
+		for i, p := range r.Predictors {
+			found := 0
+			for _, env := range p.SvcOrchSpec.Env {
+				switch env.Name {
+				case ENV_NATS_INPUT_SUBJECT, ENV_NATS_OUTPUT_SUBJECT:
+					found = found + 1
+				}
+			}
+			if found < 2 {
+				fldPath := field.NewPath("spec").Child("predictors").Index(i)
+				allErrs = append(allErrs, field.Invalid(fldPath, p.Name, "For nats please supply svcOrchSpec envs NATS_INPUT_SUBJECT, NATS_OUTPUT_SUBJECT"))
+			}
+		}
+	}
+	return allErrs
+}
+
+func (r *SeldonDeploymentSpec) validateClientTLS(allErrs field.ErrorList) field.ErrorList {
+	for i, p := range r.Predictors {
+		if p.SSL == nil || p.SSL.ClientTLS == nil {
+			continue
+		}
+		fldPath := field.NewPath("spec").Child("predictors").Index(i).Child("ssl")
+		switch p.SSL.ClientTLS.Mode {
+		case "", ClientTLSDisabled:
+		case ClientTLSSimple, ClientTLSMutual:
+			if p.SSL.CertSecretName == "" {
+				allErrs = append(allErrs, field.Invalid(fldPath, p.Name, "Client TLS requires certSecretName holding the CA bundle and certificates"))
+			}
+		default:
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("clientTLS").Child("mode"), p.SSL.ClientTLS.Mode, "Invalid client TLS mode, must be disabled, tls or mtls"))
+		}
+	}
+	return allErrs
+}
+
 func (r *SeldonDeploymentSpec) validateShadow(allErrs field.ErrorList) field.ErrorList {
 	if len(r.Predictors) == 1 && r.Predictors[0].Shadow {
 		fldPath := field.NewPath("spec").Child("predictors").Index(0)
@@ -235,13 +353,15 @@ func (r *SeldonDeploymentSpec) ValidateSeldonDeployment() error {
 		allErrs = append(allErrs, field.Invalid(fldPath, r.Transport, "Invalid transport"))
 	}
 
-	if r.ServerType != "" && !(r.ServerType == ServerRPC || r.ServerType == ServerKafka) {
+	if r.ServerType != "" && !(r.ServerType == ServerRPC || r.ServerType == ServerKafka || r.ServerType == ServerNats) {
 		fldPath := field.NewPath("spec")
 		allErrs = append(allErrs, field.Invalid(fldPath, r.ServerType, "Invalid serverType"))
 	}
 
 	allErrs = r.validateKafka(allErrs)
+	allErrs = r.validateNats(allErrs)
 	allErrs = r.validateShadow(allErrs)
+	allErrs = r.validateClientTLS(allErrs)
 
 	transports := make(map[EndpointType]bool)
 
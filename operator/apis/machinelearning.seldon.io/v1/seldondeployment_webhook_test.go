@@ -1475,3 +1475,215 @@ func TestValidateTwoPrepackTwoPods(t *testing.T) {
 	dnsName := containerServiceValue + "." + namespace + constants.DNSClusterLocalSuffix
 	g.Expect(spec.Predictors[0].Graph.Children[0].Endpoint.ServiceHost).To(Equal(dnsName))
 }
+
+func TestValidateClientTLS(t *testing.T) {
+	g := NewGomegaWithT(t)
+	newSpec := func(ssl *SSL) *SeldonDeploymentSpec {
+		return &SeldonDeploymentSpec{
+			Predictors: []PredictorSpec{
+				{
+					Name: "p1",
+					ComponentSpecs: []*SeldonPodSpec{
+						{
+							Spec: v1.PodSpec{
+								Containers: []v1.Container{
+									{
+										Image: "seldonio/mock_classifier:1.0",
+										Name:  "classifier",
+									},
+								},
+							},
+						},
+					},
+					Graph: PredictiveUnit{
+						Name: "classifier",
+					},
+					SSL: ssl,
+				},
+			},
+		}
+	}
+
+	spec := newSpec(&SSL{CertSecretName: "certs", ClientTLS: &ClientTLS{Mode: ClientTLSMutual}})
+	spec.DefaultSeldonDeployment("mydep", "default")
+	g.Expect(spec.ValidateSeldonDeployment()).To(BeNil())
+	g.Expect(IsClientTLSEnabled(&spec.Predictors[0])).To(BeTrue())
+
+	spec = newSpec(&SSL{ClientTLS: &ClientTLS{Mode: ClientTLSSimple}})
+	spec.DefaultSeldonDeployment("mydep", "default")
+	g.Expect(spec.ValidateSeldonDeployment()).ToNot(BeNil())
+
+	spec = newSpec(&SSL{CertSecretName: "certs", ClientTLS: &ClientTLS{Mode: "foo"}})
+	spec.DefaultSeldonDeployment("mydep", "default")
+	g.Expect(spec.ValidateSeldonDeployment()).ToNot(BeNil())
+
+	spec = newSpec(&SSL{CertSecretName: "certs", ClientTLS: &ClientTLS{Mode: ClientTLSDisabled}})
+	spec.DefaultSeldonDeployment("mydep", "default")
+	g.Expect(spec.ValidateSeldonDeployment()).To(BeNil())
+	g.Expect(IsClientTLSEnabled(&spec.Predictors[0])).To(BeFalse())
+}
+
+func TestValidateNats(t *testing.T) {
+	g := NewGomegaWithT(t)
+	spec := &SeldonDeploymentSpec{
+		ServerType: ServerNats,
+		Predictors: []PredictorSpec{
+			{
+				Name: "p1",
+				ComponentSpecs: []*SeldonPodSpec{
+					{
+						Spec: v1.PodSpec{
+							Containers: []v1.Container{
+								{
+									Image: "seldonio/mock_classifier:1.0",
+									Name:  "classifier",
+								},
+							},
+						},
+					},
+				},
+				Graph: PredictiveUnit{
+					Name: "classifier",
+				},
+				SvcOrchSpec: SvcOrchSpec{
+					Env: []*v1.EnvVar{
+						{Name: ENV_NATS_INPUT_SUBJECT, Value: "in"},
+					},
+				},
+			},
+		},
+	}
+
+	spec.DefaultSeldonDeployment("mydep", "default")
+	err := spec.ValidateSeldonDeployment()
+	g.Expect(err).ToNot(BeNil())
+	serr := err.(*errors.StatusError)
+	g.Expect(len(serr.Status().Details.Causes)).To(Equal(1))
+	g.Expect(serr.Status().Details.Causes[0].Field).To(Equal("spec.predictors[0]"))
+
+	spec.Predictors[0].SvcOrchSpec.Env = append(spec.Predictors[0].SvcOrchSpec.Env, &v1.EnvVar{Name: ENV_NATS_OUTPUT_SUBJECT, Value: "out"})
+	err = spec.ValidateSeldonDeployment()
+	g.Expect(err).To(BeNil())
+}
+
+func TestValidateLoggerRedaction(t *testing.T) {
+	g := NewGomegaWithT(t)
+	newSpec := func(rules []RedactionRule) *SeldonDeploymentSpec {
+		return &SeldonDeploymentSpec{
+			Predictors: []PredictorSpec{
+				{
+					Name: "p1",
+					ComponentSpecs: []*SeldonPodSpec{
+						{
+							Spec: v1.PodSpec{
+								Containers: []v1.Container{
+									{
+										Image: "seldonio/mock_classifier:1.0",
+										Name:  "classifier",
+									},
+								},
+							},
+						},
+					},
+					Graph: PredictiveUnit{
+						Name:   "classifier",
+						Logger: &Logger{Mode: LogAll, Redact: rules},
+					},
+				},
+			},
+		}
+	}
+
+	spec := newSpec([]RedactionRule{
+		{Path: "$.jsonData.customer.id", Action: RedactHash},
+		{Tensor: "email", Action: RedactDrop},
+	})
+	spec.DefaultSeldonDeployment("mydep", "default")
+	g.Expect(spec.ValidateSeldonDeployment()).To(BeNil())
+
+	spec = newSpec([]RedactionRule{{Path: "$.jsonData.id", Tensor: "email", Action: RedactMask}})
+	spec.DefaultSeldonDeployment("mydep", "default")
+	g.Expect(spec.ValidateSeldonDeployment()).ToNot(BeNil())
+
+	spec = newSpec([]RedactionRule{{Path: "jsonData.id", Action: RedactMask}})
+	spec.DefaultSeldonDeployment("mydep", "default")
+	g.Expect(spec.ValidateSeldonDeployment()).ToNot(BeNil())
+
+	spec = newSpec([]RedactionRule{{Path: "$.jsonData[0", Action: RedactMask}})
+	spec.DefaultSeldonDeployment("mydep", "default")
+	g.Expect(spec.ValidateSeldonDeployment()).ToNot(BeNil())
+
+	spec = newSpec([]RedactionRule{{Tensor: "email", Action: "encrypt"}})
+	spec.DefaultSeldonDeployment("mydep", "default")
+	g.Expect(spec.ValidateSeldonDeployment()).ToNot(BeNil())
+}
+
+func TestValidateLoggerSampling(t *testing.T) {
+	g := NewGomegaWithT(t)
+	newSpec := func(sampling *LoggerSampling) *SeldonDeploymentSpec {
+		return &SeldonDeploymentSpec{
+			Predictors: []PredictorSpec{
+				{
+					Name: "p1",
+					ComponentSpecs: []*SeldonPodSpec{
+						{
+							Spec: v1.PodSpec{
+								Containers: []v1.Container{
+									{
+										Image: "seldonio/mock_classifier:1.0",
+										Name:  "classifier",
+									},
+								},
+							},
+						},
+					},
+					Graph: PredictiveUnit{
+						Name:   "classifier",
+						Logger: &Logger{Mode: LogAll, Sampling: sampling},
+					},
+				},
+			},
+		}
+	}
+
+	spec := newSpec(&LoggerSampling{Rate: "0.01", Errors: true, LatencyThresholdMs: 500})
+	spec.DefaultSeldonDeployment("mydep", "default")
+	g.Expect(spec.ValidateSeldonDeployment()).To(BeNil())
+
+	spec = newSpec(&LoggerSampling{Rate: "1.5"})
+	spec.DefaultSeldonDeployment("mydep", "default")
+	g.Expect(spec.ValidateSeldonDeployment()).ToNot(BeNil())
+
+	spec = newSpec(&LoggerSampling{Rate: "one percent"})
+	spec.DefaultSeldonDeployment("mydep", "default")
+	g.Expect(spec.ValidateSeldonDeployment()).ToNot(BeNil())
+
+	spec = newSpec(&LoggerSampling{LatencyThresholdMs: -1})
+	spec.DefaultSeldonDeployment("mydep", "default")
+	g.Expect(spec.ValidateSeldonDeployment()).ToNot(BeNil())
+}
+
+func TestValidateGraph(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	model := MODEL
+	unknown := UNKNOWN_TYPE
+	newPredictor := func(child PredictiveUnit) *PredictorSpec {
+		return &PredictorSpec{
+			Name: "p1",
+			Graph: PredictiveUnit{
+				Name:     "transformer",
+				Children: []PredictiveUnit{child},
+			},
+		}
+	}
+
+	g.Expect(newPredictor(PredictiveUnit{Name: "classifier", Type: &model, Logger: &Logger{Mode: LogAll}}).ValidateGraph()).To(BeNil())
+
+	g.Expect(newPredictor(PredictiveUnit{Name: "transformer"}).ValidateGraph()).ToNot(BeNil())
+	g.Expect(newPredictor(PredictiveUnit{}).ValidateGraph()).ToNot(BeNil())
+	g.Expect(newPredictor(PredictiveUnit{Name: "classifier", Type: &unknown}).ValidateGraph()).ToNot(BeNil())
+	g.Expect(newPredictor(PredictiveUnit{Name: "classifier", Logger: &Logger{}}).ValidateGraph()).ToNot(BeNil())
+	g.Expect(newPredictor(PredictiveUnit{Name: "classifier", Logger: &Logger{Mode: LogAll, Sampling: &LoggerSampling{Rate: "2"}}}).ValidateGraph()).ToNot(BeNil())
+	g.Expect(newPredictor(PredictiveUnit{Name: "classifier", Logger: &Logger{Mode: LogAll, Redact: []RedactionRule{{Path: "jsonData.id", Action: RedactMask}}}}).ValidateGraph()).ToNot(BeNil())
+}
@@ -455,6 +455,8 @@ func (r *SeldonDeploymentReconciler) createComponents(ctx context.Context, mlDep
 		if predictorCertConfig != nil {
 			certSecretRefName = predictorCertConfig.CertSecretName
 		}
+		// Deployments from here on belong to this predictor
+		predictorDeploymentsIdx := len(c.deployments)
 		// Add engine deployment if separate
 		hasSeparateEnginePod := strings.ToLower(mlDep.Spec.Annotations[machinelearningv1.ANNOTATION_SEPARATE_ENGINE]) == "true"
 		if hasSeparateEnginePod && !noEngine {
@@ -602,8 +604,15 @@ func (r *SeldonDeploymentReconciler) createComponents(ctx context.Context, mlDep
 
 			}
 
-			// Find the current deployment and add the environment variables for the certificate
-			if len(certSecretRefName) > 0 {
+			// With client TLS every model container has to serve TLS so mount the certificate on all of the predictor's deployments
+			if len(certSecretRefName) > 0 && machinelearningv1.IsClientTLSEnabled(&p) {
+				for _, d := range c.deployments[predictorDeploymentsIdx:] {
+					utils.MountSecretToDeploymentContainers(d, certSecretRefName, envDefaultCertMountPath)
+					certEnvVar := &corev1.EnvVar{Name: SELDON_MOUNT_PATH_ENV_NAME, Value: envDefaultCertMountPath}
+					utils.AddEnvVarToDeploymentContainers(d, certEnvVar)
+				}
+			} else if len(certSecretRefName) > 0 {
+				// Find the current deployment and add the environment variables for the certificate
 				sPodSpec, idx := utils.GetSeldonPodSpecForPredictiveUnit(&p, p.Graph.Name)
 				currentDeployName := machinelearningv1.GetDeploymentName(mlDep, p, sPodSpec, idx)
 				for i := 0; i < len(c.deployments); i++ {
@@ -46,6 +46,7 @@ const (
 	ENV_EXECUTOR_REQUEST_LOGGER_WRITE_TIMEOUT_MS = "EXECUTOR_REQUEST_LOGGER_WRITE_TIMEOUT_MS"
 	ENV_EXECUTOR_FULL_HEALTH_CHECKS              = "EXECUTOR_FULL_HEALTH_CHECKS"
 	ENV_EXECUTOR_USER                            = "EXECUTOR_CONTAINER_USER"
+	ENV_EXECUTOR_DEFAULT_NATS_URL                = "EXECUTOR_DEFAULT_NATS_URL"
 	ENV_USE_EXECUTOR                             = "USE_EXECUTOR"
 
 	DEFAULT_EXECUTOR_CONTAINER_PORT = 8000
@@ -61,6 +62,7 @@ var (
 	envExecutorImageRelated = os.Getenv(ENV_EXECUTOR_IMAGE_RELATED)
 	envExecutorUser         = os.Getenv(ENV_EXECUTOR_USER)
 	envUseExecutor          = os.Getenv(ENV_USE_EXECUTOR)
+	envExecutorNatsUrl      = os.Getenv(ENV_EXECUTOR_DEFAULT_NATS_URL)
 
 	executorMetricsPortName = utils.GetEnv(ENV_EXECUTOR_METRICS_PORT_NAME, constants.DefaultMetricsPortName)
 
@@ -228,6 +230,15 @@ func createExecutorContainer(mlDep *machinelearningv1.SeldonDeployment, p *machi
 		return nil, fmt.Errorf("Failed to parse %s as integer for %s. %w", executorReqLoggerWriteTimeoutMs, ENV_EXECUTOR_REQUEST_LOGGER_WRITE_TIMEOUT_MS, err)
 	}
 
+	env := []corev1.EnvVar{
+		{Name: "ENGINE_PREDICTOR", Value: predictorB64},
+		{Name: "REQUEST_LOGGER_DEFAULT_ENDPOINT", Value: utils.GetEnv("EXECUTOR_REQUEST_LOGGER_DEFAULT_ENDPOINT", "http://default-broker")},
+	}
+	// Use the cluster's default nats server unless the predictor sets its own
+	if serverType == machinelearningv1.ServerNats && envExecutorNatsUrl != "" && !hasSvcOrchEnv(p, machinelearningv1.ENV_NATS_URL) {
+		env = append(env, corev1.EnvVar{Name: machinelearningv1.ENV_NATS_URL, Value: envExecutorNatsUrl})
+	}
+
 	return &corev1.Container{
 		Name:  EngineContainerName,
 		Image: executorImage,
@@ -254,10 +265,7 @@ func createExecutorContainer(mlDep *machinelearningv1.SeldonDeployment, p *machi
 				MountPath: machinelearningv1.PODINFO_VOLUME_PATH,
 			},
 		},
-		Env: []corev1.EnvVar{
-			{Name: "ENGINE_PREDICTOR", Value: predictorB64},
-			{Name: "REQUEST_LOGGER_DEFAULT_ENDPOINT", Value: utils.GetEnv("EXECUTOR_REQUEST_LOGGER_DEFAULT_ENDPOINT", "http://default-broker")},
-		},
+		Env: env,
 		Ports: []corev1.ContainerPort{
 			{ContainerPort: int32(http_port), Protocol: corev1.ProtocolTCP, Name: constants.HttpPortName},
 			{ContainerPort: int32(http_port), Protocol: corev1.ProtocolTCP, Name: executorMetricsPortName},
@@ -279,6 +287,15 @@ func createExecutorContainer(mlDep *machinelearningv1.SeldonDeployment, p *machi
 	}, nil
 }
 
+func hasSvcOrchEnv(p *machinelearningv1.PredictorSpec, name string) bool {
+	for _, env := range p.SvcOrchSpec.Env {
+		if env.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
 // Create the Container for the service orchestrator.
 func createEngineContainer(mlDep *machinelearningv1.SeldonDeployment, p *machinelearningv1.PredictorSpec, engine_http_port, engine_grpc_port int) (*corev1.Container, error) {
 	// Get engine user
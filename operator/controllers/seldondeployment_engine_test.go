@@ -108,6 +108,26 @@ func TestExecutorCreateKafka(t *testing.T) {
 	cleanEnvImagesExecutor()
 }
 
+func TestExecutorCreateNatsDefaultUrl(t *testing.T) {
+	g := NewGomegaWithT(t)
+	cleanEnvImagesExecutor()
+	envExecutorImage = "executor"
+	envExecutorNatsUrl = "nats://nats:4222"
+	defer func() { envExecutorNatsUrl = "" }()
+	mlDep := createTestSeldonDeployment()
+	mlDep.Spec.ServerType = machinelearningv1.ServerNats
+	con, err := createExecutorContainer(mlDep, &mlDep.Spec.Predictors[0], "", 1, 2, &v1.ResourceRequirements{})
+	g.Expect(err).To(BeNil())
+	g.Expect(con.Env).To(ContainElement(v1.EnvVar{Name: machinelearningv1.ENV_NATS_URL, Value: "nats://nats:4222"}))
+
+	// The predictor's own url is kept
+	mlDep.Spec.Predictors[0].SvcOrchSpec.Env = []*v1.EnvVar{{Name: machinelearningv1.ENV_NATS_URL, Value: "nats://edge:4222"}}
+	con, err = createExecutorContainer(mlDep, &mlDep.Spec.Predictors[0], "", 1, 2, &v1.ResourceRequirements{})
+	g.Expect(err).To(BeNil())
+	g.Expect(con.Env).ToNot(ContainElement(HaveField("Name", machinelearningv1.ENV_NATS_URL)))
+	cleanEnvImagesExecutor()
+}
+
 func TestEngineCreateLoggerParams(t *testing.T) {
 	g := NewGomegaWithT(t)
 	cleanEnvImagesExecutor()
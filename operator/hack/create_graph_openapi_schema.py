@@ -14,11 +14,17 @@ def getOpts(cmd_line_args):
     parser.add_argument(
         "--levels", help="the number of levels to create", type=int, default=10
     )
+    parser.add_argument(
+        "--versions",
+        help="the number of CRD versions to patch",
+        type=int,
+        default=3,
+    )
     opts = parser.parse_args(cmd_line_args)
     return opts
 
 
-def expand_tmpl(filename, levels):
+def expand_tmpl(filename, levels, versions):
     with open(filename, "r") as stream:
         y = yaml.safe_load(stream)
         tmpl = y[0]["value"]
@@ -28,22 +34,19 @@ def expand_tmpl(filename, levels):
             tmpl = child
             if i == levels - 1:
                 del tmpl["properties"]["children"]
-        # add replace for second and third schema version
-        y.append(copy.deepcopy(y[0]))
-        y[1][
-            "path"
-        ] = "/spec/versions/1/schema/openAPIV3Schema/properties/spec/properties/predictors/items/properties/graph"
-        y.append(copy.deepcopy(y[0]))
-        y[2][
-            "path"
-        ] = "/spec/versions/2/schema/openAPIV3Schema/properties/spec/properties/predictors/items/properties/graph"
+        # add a replace for each further schema version
+        for version in range(1, versions):
+            y.append(copy.deepcopy(y[0]))
+            y[version][
+                "path"
+            ] = f"/spec/versions/{version}/schema/openAPIV3Schema/properties/spec/properties/predictors/items/properties/graph"
         return y
 
 
 def main(argv):
     opts = getOpts(argv[1:])
     print(opts)
-    y = expand_tmpl(opts.template, opts.levels)
+    y = expand_tmpl(opts.template, opts.levels, opts.versions)
     fdata = yaml.dump(y, width=1000)
     with open(opts.path, "w") as outfile:
         outfile.write(fdata)
@@ -28,6 +28,12 @@ func AddEnvVarToDeploymentContainers(deploy *appsv1.Deployment, envVar *v1.EnvVa
 // Create a volume from the secret provided and mounts it to all the containers of deployment
 func MountSecretToDeploymentContainers(deploy *appsv1.Deployment, secretRefName string, containerMountPath string) {
 	volumeName := "seldon-cert-volume"
+	// Nothing to do if the secret was already mounted, e.g. on a separate service orchestrator
+	for _, vol := range deploy.Spec.Template.Spec.Volumes {
+		if vol.Name == volumeName {
+			return
+		}
+	}
 	volume := v1.Volume{
 		Name: volumeName,
 		VolumeSource: v1.VolumeSource{